@@ -0,0 +1,26 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrAudienceMismatch = errors.New("credential audience mismatch")
+
+// WithAudience makes Create stamp audience on every credential, and Verify reject credentials for any other audience.
+// Use a distinct audience per service to stop a credential issued for one from being accepted by another.
+// Managers without an audience only accept credentials without one, as before.
+func WithAudience(audience string) Option {
+	return func(c *CredentialManager) {
+		c.audience = audience
+	}
+}
+
+// checkAudience fails with ErrAudienceMismatch unless the credential is for the manager's audience
+func (c *CredentialManager) checkAudience(authenticatedCredential *AuthenticatedCredential) error {
+	audience := authenticatedCredential.Credential.GetAudience()
+	if audience != c.audience {
+		return fmt.Errorf("%w: credential is for %q, verifier expects %q", ErrAudienceMismatch, audience, c.audience)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestAudience tests that credentials are only accepted by verifiers for the same audience
+func TestAudience(t *testing.T) {
+	key := []byte("Audience test secret")
+	proxy := NewCredentialManagerWithOptions(key, nil, WithAudience("rescue-proxy"))
+	api := NewCredentialManagerWithOptions(key, nil, WithAudience("rescue-api"))
+	plain := NewCredentialManager(key)
+
+	proxyCred, err := proxy.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proxyCred.Credential.Audience != "rescue-proxy" {
+		t.Fatalf("Expected the audience to be stamped, got %q", proxyCred.Credential.Audience)
+	}
+	plainCred, err := plain.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		verifier *CredentialManager
+		cred     *AuthenticatedCredential
+		ok       bool
+	}{
+		{"SameAudience", proxy, proxyCred, true},
+		{"OtherAudience", api, proxyCred, false},
+		{"VerifierWithoutAudience", plain, proxyCred, false},
+		{"CredentialWithoutAudience", proxy, plainCred, false},
+		{"NeitherHasAudience", plain, plainCred, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.verifier.Verify(tc.cred)
+			if tc.ok && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrAudienceMismatch) {
+				t.Errorf("Expected ErrAudienceMismatch, got %v", err)
+			}
+		})
+	}
+
+	_, err = api.Verify(proxyCred)
+	if err == nil || !strings.Contains(err.Error(), `"rescue-proxy"`) || !strings.Contains(err.Error(), `"rescue-api"`) {
+		t.Errorf("Expected both audiences in the error, got %v", err)
+	}
+
+	// The audience is covered by the MAC
+	proxyCred.Credential.Audience = "rescue-api"
+	if _, err := api.Verify(proxyCred); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+// TestAudienceEncodings tests that the audience survives the JSON and password encodings
+func TestAudienceEncodings(t *testing.T) {
+	cm := NewCredentialManagerWithOptions([]byte("Audience test secret"), nil, WithAudience("rescue-proxy"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON AuthenticatedCredential
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromPassword AuthenticatedCredential
+	if err := fromPassword.Base64URLDecode(cred.Base64URLEncodeUsername(), password); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, decoded := range []*AuthenticatedCredential{&fromJSON, &fromPassword} {
+		if decoded.Credential.Audience != "rescue-proxy" {
+			t.Errorf("Expected audience %q, got %q", "rescue-proxy", decoded.Credential.Audience)
+		}
+		if _, err := cm.Verify(decoded); err != nil {
+			t.Error(err)
+		}
+	}
+}
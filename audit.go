@@ -0,0 +1,156 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// AuditSink records every verification attempt, e.g. to an append-only log.
+// Record is called synchronously, possibly from many goroutines at once. A panicking sink is recovered from.
+type AuditSink interface {
+	// Record is called with the claims of the credential verified, and the error returned to the caller, or nil.
+	// For failures, the claims hold whatever fields the credential has, which are only trustworthy if the MAC
+	// matched, and have no KeyID. The claims are copies, which the sink may keep.
+	Record(ctx context.Context, claims Claims, result error)
+}
+
+// WithAuditSink makes Verify, VerifyWithAAD and the methods built on them record every attempt with sink,
+// whether it succeeds or fails. Credentials which fail to decode, e.g. in VerifyFromBasicAuth, are never verified,
+// so aren't recorded.
+func WithAuditSink(sink AuditSink) Option {
+	return func(c *CredentialManager) {
+		c.auditSink = sink
+	}
+}
+
+// audit records a verification attempt made at attemptedAt with the manager's audit sink and records, if any
+func (c *CredentialManager) audit(ctx context.Context, authenticatedCredential *AuthenticatedCredential, id *ID, result error, attemptedAt time.Time) {
+	c.record(AuditEventVerify, authenticatedCredential, result)
+	if c.auditSink == nil {
+		return
+	}
+	claims := c.claims(authenticatedCredential, id, attemptedAt)
+	claims.NodeID = slices.Clone(claims.NodeID)
+	claims.ScopeNames = slices.Clone(claims.ScopeNames)
+	callHook(func() { c.auditSink.Record(ctx, claims, result) })
+}
+
+// AuditEvent is the kind of event an AuditRecord describes
+type AuditEvent string
+
+const (
+	AuditEventCreate AuditEvent = "create"
+	AuditEventVerify AuditEvent = "verify"
+)
+
+// Outcomes of the events described by AuditRecords
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditRecord describes a credential created or verified by a manager, e.g. for an append-only compliance log.
+// It never holds a MAC or anything derived from the manager's keys.
+type AuditRecord struct {
+	Event AuditEvent `json:"event"`
+	// Timestamp is when the event happened, by the manager's clock
+	Timestamp time.Time `json:"timestamp"`
+	// NodeID is the 0x prefixed hex encoded node ID
+	NodeID       string `json:"node_id"`
+	OperatorType string `json:"operator_type"`
+	// Fingerprint is the credential's Fingerprint, or "" if it has none, e.g. because it is nil
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Outcome is AuditOutcomeSuccess or AuditOutcomeFailure
+	Outcome string `json:"outcome"`
+	// Code is the name of the failure's ErrorCode, or "ok", and Category its ErrorCategory, or "" for successes
+	Code     string `json:"code"`
+	Category string `json:"category,omitempty"`
+}
+
+// WithAuditRecords makes the manager call sink with an AuditRecord for every credential its Create methods issue,
+// including each credential of a batch and those of ResignFrom, and for every attempt by Verify, VerifyWithAAD and
+// the methods built on them, whether it succeeds or fails. Failures are recorded with the category of their error.
+// sink is called synchronously, possibly from many goroutines at once, and a panicking sink is recovered from.
+func WithAuditRecords(sink func(AuditRecord)) Option {
+	return func(c *CredentialManager) {
+		c.auditRecords = sink
+	}
+}
+
+// notifyCreated tells the manager's hooks and audit records of creds
+func (c *CredentialManager) notifyCreated(ctx context.Context, creds ...*AuthenticatedCredential) {
+	c.hooks.created(ctx, creds...)
+	if c.auditRecords == nil {
+		return
+	}
+	for _, cred := range creds {
+		if cred != nil {
+			c.record(AuditEventCreate, cred, nil)
+		}
+	}
+}
+
+// record calls the manager's audit records, if any, with the outcome of event for authenticatedCredential
+func (c *CredentialManager) record(event AuditEvent, authenticatedCredential *AuthenticatedCredential, result error) {
+	if c.auditRecords == nil {
+		return
+	}
+	credential := authenticatedCredential.Pb().GetCredential()
+	r := AuditRecord{
+		Event:        event,
+		Timestamp:    c.now(),
+		NodeID:       fmt.Sprintf("0x%x", credential.GetNodeId()),
+		OperatorType: credential.GetOperatorType().String(),
+		Outcome:      AuditOutcomeSuccess,
+		Code:         ErrorCode(result).String(),
+	}
+	r.Fingerprint, _ = authenticatedCredential.Fingerprint()
+	if result != nil {
+		r.Outcome = AuditOutcomeFailure
+		r.Category = ErrorCategory(result)
+	}
+	callHook(func() { c.auditRecords(r) })
+}
+
+// JSONLinesAuditWriter writes AuditRecords to an io.Writer as JSON lines. Each record is written with a single
+// call to Write, and calls are serialized, so records appended to a file opened with O_APPEND never interleave.
+type JSONLinesAuditWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// NewJSONLinesAuditWriter creates a JSONLinesAuditWriter writing to w. Pass its Record method to WithAuditRecords.
+func NewJSONLinesAuditWriter(w io.Writer) *JSONLinesAuditWriter {
+	return &JSONLinesAuditWriter{w: w}
+}
+
+// Record writes r as one line of JSON
+func (j *JSONLinesAuditWriter) Record(r AuditRecord) {
+	line, err := json.Marshal(r)
+	if err == nil {
+		line = append(line, '\n')
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err == nil {
+		_, err = j.w.Write(line)
+	}
+	if err != nil && j.err == nil {
+		j.err = err
+	}
+}
+
+// Err returns the first error writing a record, if any. Records are still written after an error,
+// so a transient failure loses only the records it hit.
+func (j *JSONLinesAuditWriter) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
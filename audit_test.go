@@ -0,0 +1,225 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+type auditRecord struct {
+	ctx    context.Context
+	claims Claims
+	result error
+}
+
+// recordingSink is an AuditSink which keeps what it is told
+type recordingSink struct {
+	mu      sync.Mutex
+	records []auditRecord
+}
+
+func (s *recordingSink) Record(ctx context.Context, claims Claims, result error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, auditRecord{ctx, claims, result})
+}
+
+type auditCtxKey struct{}
+
+// TestAuditSink tests that successful and failed verifications are recorded with the credential's fields
+func TestAuditSink(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	sink := new(recordingSink)
+	cm := NewCredentialManagerWithOptions([]byte("Audit test secret"), nil, WithAuditSink(sink),
+		WithClock(func() time.Time { return now }), WithMaxAge(time.Hour))
+	nodeID := make([]byte, 20)
+	nodeID[0] = 0xaa
+	original := bytes.Clone(nodeID)
+
+	cred, err := cm.CreateWithScopeNames(now.Add(-time.Minute), nodeID, pb.OperatorType_OT_SOLO, "read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale, err := cm.Create(now.Add(-2*time.Hour), nodeID, pb.OperatorType_OT_ROCKETPOOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.WithValue(context.Background(), auditCtxKey{}, "request")
+	if _, err := cm.VerifyContext(ctx, cred); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(stale); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Expected ErrExpired, got %v", err)
+	}
+	cred.Mac[0] ^= 1
+	if _, err := cm.Verify(cred); !errors.Is(err, MismatchError) {
+		t.Fatalf("Expected MismatchError, got %v", err)
+	}
+	// The recorded claims are copies
+	cred.Credential.NodeId[1] = 0xbb
+	cred.Credential.ScopeNames[0] = "write"
+
+	if len(sink.records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(sink.records))
+	}
+	ok, expired, forged := sink.records[0], sink.records[1], sink.records[2]
+	if ok.result != nil || ok.ctx.Value(auditCtxKey{}) != "request" || ok.claims.KeyID == nil || !ok.claims.KeyID.Equals(cm.ID()) {
+		t.Errorf("Unexpected record of a success: %+v", ok)
+	}
+	if !errors.Is(expired.result, ErrExpired) || expired.claims.OperatorType != pb.OperatorType_OT_ROCKETPOOL ||
+		!expired.claims.ExpiresAt.Equal(now.Add(-time.Hour)) || expired.claims.KeyID != nil {
+		t.Errorf("Unexpected record of an expired credential: %+v", expired)
+	}
+	if !errors.Is(forged.result, MismatchError) || forged.claims.CredentialID != cred.ID() {
+		t.Errorf("Unexpected record of a forged credential: %+v", forged)
+	}
+	for _, r := range sink.records {
+		if !r.claims.VerifiedAt.Equal(now) {
+			t.Errorf("Expected the attempt to be recorded at %s, got %s", now, r.claims.VerifiedAt)
+		}
+	}
+	for _, r := range []auditRecord{ok, forged} {
+		if !bytes.Equal(r.claims.NodeID, original) || len(r.claims.ScopeNames) != 1 || r.claims.ScopeNames[0] != "read" {
+			t.Errorf("Recorded claims changed with the credential: %x %v", r.claims.NodeID, r.claims.ScopeNames)
+		}
+	}
+}
+
+type panickingSink struct{}
+
+func (panickingSink) Record(context.Context, Claims, error) {
+	panic("audit log unavailable")
+}
+
+// TestAuditSinkPanic tests that a panicking sink doesn't affect verification
+func TestAuditSinkPanic(t *testing.T) {
+	cm := NewCredentialManagerWithOptions([]byte("Audit test secret"), nil, WithAuditSink(panickingSink{}))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestAuditRecords tests that creations and verifications are recorded as JSON lines without MACs
+func TestAuditRecords(t *testing.T) {
+	now := time.Unix(1700000000, 0).UTC()
+	var buf bytes.Buffer
+	w := NewJSONLinesAuditWriter(&buf)
+	cm := NewCredentialManagerWithOptions([]byte("Audit test secret"), nil, WithAuditRecords(w.Record),
+		WithClock(func() time.Time { return now }), WithMaxAge(time.Hour))
+	nodeIDs := batchNodeIDs(3)
+
+	cred, err := cm.Create(now, nodeIDs[1], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := cm.CreateMany(now.Add(-2*time.Hour), nodeIDs[1:], pb.OperatorType_OT_ROCKETPOOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(batch[0]); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Expected ErrExpired, got %v", err)
+	}
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ac := range append(batch, cred) {
+		if bytes.Contains(buf.Bytes(), []byte(hex.EncodeToString(ac.Mac))) || bytes.Contains(buf.Bytes(), []byte(base64.URLEncoding.EncodeToString(ac.Mac))) {
+			t.Fatalf("Expected no MACs in the records, got %s", buf.Bytes())
+		}
+	}
+	var records []AuditRecord
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r AuditRecord
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	fingerprint, err := cred.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []AuditRecord{
+		{AuditEventCreate, now, "0x" + hex.EncodeToString(nodeIDs[1]), "OT_SOLO", fingerprint, AuditOutcomeSuccess, "ok", ""},
+		{AuditEventCreate, now, "0x" + hex.EncodeToString(nodeIDs[1]), "OT_ROCKETPOOL", "", AuditOutcomeSuccess, "ok", ""},
+		{AuditEventCreate, now, "0x" + hex.EncodeToString(nodeIDs[2]), "OT_ROCKETPOOL", "", AuditOutcomeSuccess, "ok", ""},
+		{AuditEventVerify, now, "0x" + hex.EncodeToString(nodeIDs[1]), "OT_SOLO", fingerprint, AuditOutcomeSuccess, "ok", ""},
+		{AuditEventVerify, now, "0x" + hex.EncodeToString(nodeIDs[1]), "OT_ROCKETPOOL", "", AuditOutcomeFailure, "expired", "expired"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %+v", len(expected), records)
+	}
+	for i, r := range records {
+		if r.Fingerprint == "" {
+			t.Errorf("Expected record %d to have a fingerprint", i)
+		}
+		if expected[i].Fingerprint == "" {
+			r.Fingerprint = ""
+		}
+		if !r.Timestamp.Equal(expected[i].Timestamp) {
+			t.Errorf("Expected record %d at %s, got %s", i, expected[i].Timestamp, r.Timestamp)
+		}
+		r.Timestamp = expected[i].Timestamp
+		if r != expected[i] {
+			t.Errorf("Expected record %d to be %+v, got %+v", i, expected[i], r)
+		}
+	}
+}
+
+// TestAuditRecordsPanic tests that a panicking sink affects neither creation nor verification
+func TestAuditRecordsPanic(t *testing.T) {
+	cm := NewCredentialManagerWithOptions([]byte("Audit test secret"), nil,
+		WithAuditRecords(func(AuditRecord) { panic("audit log unavailable") }))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Error(err)
+	}
+	cred.Mac[0] ^= 1
+	if _, err := cm.Verify(cred); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+type failingWriter struct{ calls int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.calls++
+	if w.calls == 1 {
+		return 0, errors.New("disk full")
+	}
+	return len(p), nil
+}
+
+// TestJSONLinesAuditWriterError tests that the first write error is kept, and later records still written
+func TestJSONLinesAuditWriterError(t *testing.T) {
+	fw := new(failingWriter)
+	w := NewJSONLinesAuditWriter(fw)
+	w.Record(AuditRecord{Event: AuditEventCreate})
+	w.Record(AuditRecord{Event: AuditEventVerify})
+	if err := w.Err(); err == nil || err.Error() != "disk full" {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+	if fw.calls != 2 {
+		t.Errorf("Expected each record written once, got %d writes", fw.calls)
+	}
+}
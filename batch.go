@@ -0,0 +1,250 @@
+package credentials
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrNilCredential = errors.New("nil credential")
+
+// NodeIDError reports a problem with one node ID of a batch
+type NodeIDError struct {
+	// Index is the position of the node ID in the batch
+	Index  int
+	NodeID []byte
+	Err    error
+}
+
+func (e *NodeIDError) Error() string {
+	return fmt.Sprintf("node ID %d (0x%s): %v", e.Index, hex.EncodeToString(e.NodeID), e.Err)
+}
+
+func (e *NodeIDError) Unwrap() error {
+	return e.Err
+}
+
+type batchConfig struct {
+	failFast bool
+}
+
+// BatchOption configures the batch operations of a CredentialManager
+type BatchOption func(*batchConfig)
+
+// FailFast stops validation at the first invalid node ID, instead of reporting all of them
+func FailFast() BatchOption {
+	return func(c *batchConfig) {
+		c.failFast = true
+	}
+}
+
+// validateNodeIDs checks every node ID, returning the *NodeIDError for each invalid one joined together
+func validateNodeIDs(nodeIDs [][]byte, cfg *batchConfig) error {
+	var errs []error
+	for i, nodeID := range nodeIDs {
+		if err := validateNodeID(nodeID); err != nil {
+			errs = append(errs, &NodeIDError{Index: i, NodeID: nodeID, Err: err})
+			if cfg.failFast {
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CreateMany creates one credential per node ID, all with the same timestamp and operator type.
+// Every node ID is validated before any credential is created; if some are invalid, no credentials are
+// returned and the error joins a *NodeIDError for each of them (or just the first, with FailFast).
+// The whole batch is authenticated with a single pooled hash.
+func (c *CredentialManager) CreateMany(timestamp time.Time, nodeIDs [][]byte, OperatorType OperatorType, opts ...BatchOption) (out []*AuthenticatedCredential, err error) {
+	cfg := new(batchConfig)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if err := validateNodeIDs(nodeIDs, cfg); err != nil {
+		return nil, err
+	}
+	if err := c.validateOperatorType(OperatorType); err != nil {
+		return nil, err
+	}
+	if err := c.validateTimestamp(timestamp); err != nil {
+		return nil, err
+	}
+
+	release, err := c.allowIssuance(nodeIDs...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			release()
+		}
+	}()
+
+	out = make([]*AuthenticatedCredential, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		cred, err := c.newCredential(timestamp, nodeID, OperatorType)
+		if err != nil {
+			return nil, err
+		}
+		c.deriveCredentialID(cred.Credential)
+		out[i] = cred
+	}
+
+	if c.ring != nil || c.macer != nil {
+		for _, cred := range out {
+			if err := c.authenticateCredential(cred, nil); err != nil {
+				return nil, err
+			}
+		}
+		c.notifyCreated(context.Background(), out...)
+		return out, nil
+	}
+
+	v, err := c.getChecker()
+	if err != nil {
+		return nil, err
+	}
+	defer c.putChecker(v)
+
+	for _, cred := range out {
+		if err := c.authenticateWithChecker(v, cred, nil); err != nil {
+			return nil, err
+		}
+	}
+	c.notifyCreated(context.Background(), out...)
+	return out, nil
+}
+
+// CreateBatch is like CreateMany, but authenticates the credentials concurrently on up to GOMAXPROCS goroutines,
+// each reusing one hash state. Validation is all or nothing: if any node ID is invalid, no credentials are returned
+// and the error joins a *NodeIDError for each of them. Failures to authenticate are per credential: the
+// credentials that failed are left nil, and the error joins a *CredentialError for each of them.
+func (c *CredentialManager) CreateBatch(timestamp time.Time, nodeIDs [][]byte, OperatorType OperatorType) ([]*AuthenticatedCredential, error) {
+	if err := validateNodeIDs(nodeIDs, new(batchConfig)); err != nil {
+		return nil, err
+	}
+	if err := c.validateOperatorType(OperatorType); err != nil {
+		return nil, err
+	}
+	if err := c.validateTimestamp(timestamp); err != nil {
+		return nil, err
+	}
+
+	out := make([]*AuthenticatedCredential, len(nodeIDs))
+	errs := make([]error, len(nodeIDs))
+	c.parallel(len(nodeIDs), true, func(v *checker, i int) {
+		release, err := c.allowIssuance(nodeIDs[i])
+		if err != nil {
+			errs[i] = &CredentialError{Index: i, Err: err}
+			return
+		}
+		cred, err := c.newCredential(timestamp, nodeIDs[i], OperatorType)
+		if err == nil {
+			c.deriveCredentialID(cred.Credential)
+			if v != nil {
+				err = c.authenticateWithChecker(v, cred, nil)
+			} else {
+				err = c.authenticateCredential(cred, nil)
+			}
+		}
+		if err != nil {
+			release()
+			errs[i] = &CredentialError{Index: i, Err: err}
+			return
+		}
+		out[i] = cred
+		c.notifyCreated(context.Background(), cred)
+	})
+	return out, errors.Join(errs...)
+}
+
+// parallel calls fn for every index below n, on up to GOMAXPROCS goroutines.
+// With withChecker, managers with fixed secrets pass each goroutine's fn a checker of its own. Otherwise v is nil.
+func (c *CredentialManager) parallel(n int, withChecker bool, fn func(v *checker, i int)) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > n {
+		workers = n
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			var v *checker
+			if withChecker && c.ring == nil && c.macer == nil {
+				if pooled, err := c.getChecker(); err == nil {
+					v = pooled
+					defer c.putChecker(v)
+				}
+			}
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				fn(v, i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// CredentialError reports a problem with one credential of a batch
+type CredentialError struct {
+	// Index is the position of the credential in the batch
+	Index int
+	Err   error
+}
+
+func (e *CredentialError) Error() string {
+	return fmt.Sprintf("credential %d: %v", e.Index, e.Err)
+}
+
+func (e *CredentialError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyBatchResult holds the outcome of VerifyBatch, in the same order as its input
+type VerifyBatchResult struct {
+	// IDs are the IDs of the keys that authenticated each credential, nil for those that failed
+	IDs []*ID
+	// Errs are the errors for each credential, nil for those that verified
+	Errs []error
+}
+
+// Err returns a *CredentialError for each credential that failed, joined together, or nil if all of them verified
+func (r VerifyBatchResult) Err() error {
+	var errs []error
+	for i, err := range r.Errs {
+		if err != nil {
+			errs = append(errs, &CredentialError{Index: i, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// VerifyBatch verifies every credential as Verify would, spreading the work over up to GOMAXPROCS goroutines.
+// Nil credentials fail with ErrNilCredential without affecting the rest of the batch.
+func (c *CredentialManager) VerifyBatch(creds []*AuthenticatedCredential) VerifyBatchResult {
+	out := VerifyBatchResult{
+		IDs:  make([]*ID, len(creds)),
+		Errs: make([]error, len(creds)),
+	}
+
+	c.parallel(len(creds), false, func(_ *checker, i int) {
+		if creds[i] == nil {
+			out.Errs[i] = ErrNilCredential
+			return
+		}
+		out.IDs[i], out.Errs[i] = c.Verify(creds[i])
+	})
+	return out
+}
@@ -0,0 +1,290 @@
+package credentials
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func batchNodeIDs(n int) [][]byte {
+	out := make([][]byte, n)
+	for i := range out {
+		out[i] = make([]byte, 20)
+		out[i][0] = byte(i)
+	}
+	return out
+}
+
+// TestCreateMany tests that every credential in a batch is authenticated as Create would authenticate it
+func TestCreateMany(t *testing.T) {
+	cm := NewCredentialManager([]byte("Batch test secret"))
+	now := time.Now()
+	nodeIDs := batchNodeIDs(10)
+
+	creds, err := cm.CreateMany(now, nodeIDs, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(creds) != len(nodeIDs) {
+		t.Fatalf("Expected %d credentials, got %d", len(nodeIDs), len(creds))
+	}
+
+	seen := make(map[string]bool)
+	for i, cred := range creds {
+		single := (*AuthenticatedCredential)(proto.Clone(cred.Pb()).(*pb.AuthenticatedCredential))
+		if err := cm.authenticateCredential(single, nil); err != nil {
+			t.Fatal(err)
+		}
+		if string(single.Mac) != string(cred.Mac) {
+			t.Errorf("Credential %d has a different MAC than Create produces", i)
+		}
+		if seen[cred.ID()] {
+			t.Errorf("Credential %d has a duplicate ID", i)
+		}
+		seen[cred.ID()] = true
+		if _, err := cm.Verify(cred); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestCreateManyInvalidNodeIDs tests that invalid node IDs are all reported, or just the first with FailFast
+func TestCreateManyInvalidNodeIDs(t *testing.T) {
+	cm := NewCredentialManager([]byte("Batch test secret"))
+	nodeIDs := batchNodeIDs(5)
+	nodeIDs[1] = []byte("short")
+	nodeIDs[3] = nil
+
+	creds, err := cm.CreateMany(time.Now(), nodeIDs, pb.OperatorType_OT_SOLO)
+	if creds != nil {
+		t.Error("Expected no credentials when validation fails")
+	}
+	if !errors.Is(err, ErrInvalidNodeIDLength) {
+		t.Fatalf("Expected ErrInvalidNodeIDLength, got %v", err)
+	}
+
+	var indices []int
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var nodeErr *NodeIDError
+		if !errors.As(e, &nodeErr) {
+			t.Fatalf("Expected a *NodeIDError, got %v", e)
+		}
+		indices = append(indices, nodeErr.Index)
+	}
+	if len(indices) != 2 || indices[0] != 1 || indices[1] != 3 {
+		t.Errorf("Expected invalid indices [1 3], got %v", indices)
+	}
+
+	_, err = cm.CreateMany(time.Now(), nodeIDs, pb.OperatorType_OT_SOLO, FailFast())
+	var nodeErr *NodeIDError
+	if !errors.As(err, &nodeErr) || nodeErr.Index != 1 {
+		t.Fatalf("Expected the first invalid node ID, got %v", err)
+	}
+	if len(err.(interface{ Unwrap() []error }).Unwrap()) != 1 {
+		t.Error("Expected FailFast to report a single error")
+	}
+}
+
+// TestCreateManyKeyRing tests batch creation on a KeyRing backed manager
+func TestCreateManyKeyRing(t *testing.T) {
+	ring, err := NewKeyRing(KeyEntry{ID: "k", Key: []byte("ring key"), NotBefore: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := NewCredentialManagerFromKeyRing(ring)
+	creds, err := cm.CreateMany(time.Now(), batchNodeIDs(3), pb.OperatorType_OT_ROCKETPOOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cred := range creds {
+		if _, err := cm.Verify(cred); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestVerifyBatch tests that batch results line up with the input, and that failures don't affect the rest
+func TestVerifyBatch(t *testing.T) {
+	cm := NewCredentialManager([]byte("Batch test secret"))
+	creds, err := cm.CreateMany(time.Now(), batchNodeIDs(50), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds[7].Mac[0] ^= 1
+	creds[23] = nil
+
+	result := cm.VerifyBatch(creds)
+	if len(result.IDs) != len(creds) || len(result.Errs) != len(creds) {
+		t.Fatalf("Expected %d results, got %d IDs and %d errors", len(creds), len(result.IDs), len(result.Errs))
+	}
+	for i := range creds {
+		switch i {
+		case 7:
+			if !errors.Is(result.Errs[i], MismatchError) || result.IDs[i] != nil {
+				t.Errorf("Expected MismatchError at %d, got %v", i, result.Errs[i])
+			}
+		case 23:
+			if !errors.Is(result.Errs[i], ErrNilCredential) {
+				t.Errorf("Expected ErrNilCredential at %d, got %v", i, result.Errs[i])
+			}
+		default:
+			if result.Errs[i] != nil || !result.IDs[i].Equals(cm.ID()) {
+				t.Errorf("Expected credential %d to verify, got %v", i, result.Errs[i])
+			}
+		}
+	}
+
+	err = result.Err()
+	var indices []int
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var credErr *CredentialError
+		if !errors.As(e, &credErr) {
+			t.Fatalf("Expected a *CredentialError, got %v", e)
+		}
+		indices = append(indices, credErr.Index)
+	}
+	if len(indices) != 2 || indices[0] != 7 || indices[1] != 23 {
+		t.Errorf("Expected failed indices [7 23], got %v", indices)
+	}
+
+	if err := cm.VerifyBatch(nil).Err(); err != nil {
+		t.Errorf("Expected an empty batch to verify, got %v", err)
+	}
+}
+
+// failingMACer fails to compute MACs for node IDs starting with fail
+type failingMACer struct {
+	remoteMACer
+	fail byte
+}
+
+func (m *failingMACer) Compute(data []byte) []byte {
+	// The MAC input starts with the node ID's tag and length
+	if len(data) > 2 && data[2] == m.fail {
+		return nil
+	}
+	return m.remoteMACer.Compute(data)
+}
+
+// TestCreateBatch tests that batches are created in order, and that failures are reported per credential
+func TestCreateBatch(t *testing.T) {
+	cm := NewCredentialManager([]byte("Batch test secret"))
+	nodeIDs := batchNodeIDs(40)
+
+	creds, err := cm.CreateBatch(time.Now(), nodeIDs, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for i, cred := range creds {
+		if string(cred.Credential.NodeId) != string(nodeIDs[i]) {
+			t.Errorf("Credential %d is for the wrong node", i)
+		}
+		if seen[cred.ID()] {
+			t.Errorf("Credential %d has a duplicate ID", i)
+		}
+		seen[cred.ID()] = true
+		if _, err := cm.Verify(cred); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Invalid node IDs fail the whole batch, and are all reported
+	invalid := batchNodeIDs(5)
+	invalid[1] = []byte("short")
+	invalid[4] = nil
+	creds, err = cm.CreateBatch(time.Now(), invalid, pb.OperatorType_OT_SOLO)
+	if creds != nil || !errors.Is(err, ErrInvalidNodeIDLength) {
+		t.Fatalf("Expected ErrInvalidNodeIDLength and no credentials, got %v", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 2 {
+		t.Errorf("Expected 2 invalid node IDs, got %d", n)
+	}
+
+	// MAC failures only affect their own credentials
+	failing := NewCredentialManagerFromMACer(&failingMACer{remoteMACer: remoteMACer{key: []byte("Batch test secret")}, fail: 3})
+	creds, err = failing.CreateBatch(time.Now(), batchNodeIDs(5), pb.OperatorType_OT_SOLO)
+	var credErr *CredentialError
+	if !errors.Is(err, ErrMACFailed) || !errors.As(err, &credErr) || credErr.Index != 3 {
+		t.Fatalf("Expected ErrMACFailed for credential 3, got %v", err)
+	}
+	if len(creds) != 5 || creds[3] != nil {
+		t.Fatal("Expected every credential but the failed one")
+	}
+	for i, cred := range creds {
+		if i == 3 {
+			continue
+		}
+		if _, err := cm.Verify(cred); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func BenchmarkCreateMany(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"))
+	nodeIDs := batchNodeIDs(100)
+	now := time.Now()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cm.CreateMany(now, nodeIDs, pb.OperatorType_OT_SOLO); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCreateBatch(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"))
+	nodeIDs := batchNodeIDs(100)
+	now := time.Now()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cm.CreateBatch(now, nodeIDs, pb.OperatorType_OT_SOLO); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkVerifyCredentials(b *testing.B, cm *CredentialManager) []*AuthenticatedCredential {
+	creds, err := cm.CreateMany(time.Now(), batchNodeIDs(1000), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return creds
+}
+
+// BenchmarkVerifySerial is the loop VerifyBatch replaces
+func BenchmarkVerifySerial(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"))
+	creds := benchmarkVerifyCredentials(b, cm)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, cred := range creds {
+			if _, err := cm.Verify(cred); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"))
+	creds := benchmarkVerifyCredentials(b, cm)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cm.VerifyBatch(creds).Err(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
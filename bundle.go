@@ -0,0 +1,86 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// MaxBundleNodeIDs is the most node IDs a single bundled credential may cover
+const MaxBundleNodeIDs = 16
+
+var (
+	ErrInvalidBundle       = errors.New("invalid credential bundle")
+	ErrNodeNotInCredential = errors.New("node ID not covered by credential")
+)
+
+// CreateBundle makes a single credential covering every node in nodeIDs, for operators running several nodes.
+// Between 1 and MaxBundleNodeIDs distinct node IDs are allowed. The first node ID doubles as the credential's
+// node ID, so it is what the username encodes, and Verify and Claims report.
+// Use VerifyForNode to check a bundle for a specific node.
+func (c *CredentialManager) CreateBundle(timestamp time.Time, nodeIDs [][]byte, OperatorType OperatorType) (*AuthenticatedCredential, error) {
+	if err := validateBundle(nodeIDs, new(batchConfig)); err != nil {
+		return nil, err
+	}
+
+	return c.create(timestamp, nodeIDs[0], OperatorType, nil, func(credential *pb.Credential) error {
+		credential.BundleNodeIds = make([][]byte, len(nodeIDs))
+		for i, nodeID := range nodeIDs {
+			credential.BundleNodeIds[i] = append([]byte(nil), nodeID...)
+		}
+		return nil
+	})
+}
+
+// validateBundle checks that there are between 1 and MaxBundleNodeIDs distinct, valid node IDs. Invalid node IDs
+// are reported as validateNodeIDs does, and only then duplicates.
+func validateBundle(nodeIDs [][]byte, cfg *batchConfig) error {
+	if len(nodeIDs) == 0 || len(nodeIDs) > MaxBundleNodeIDs {
+		return fmt.Errorf("%w: expected 1 to %d node IDs, got %d", ErrInvalidBundle, MaxBundleNodeIDs, len(nodeIDs))
+	}
+	if err := validateNodeIDs(nodeIDs, cfg); err != nil {
+		return err
+	}
+	seen := make(map[string]int, len(nodeIDs))
+	for i, nodeID := range nodeIDs {
+		if first, ok := seen[string(nodeID)]; ok {
+			return &NodeIDError{Index: i, NodeID: nodeID, Err: fmt.Errorf("%w: duplicate of node ID %d", ErrInvalidBundle, first)}
+		}
+		seen[string(nodeID)] = i
+	}
+	return nil
+}
+
+// NodeIDs returns every node ID the credential covers: the bundled node IDs, or just the node ID for single-node credentials
+func (ac *AuthenticatedCredential) NodeIDs() [][]byte {
+	if len(ac.Credential.GetBundleNodeIds()) > 0 {
+		return ac.Credential.GetBundleNodeIds()
+	}
+	return [][]byte{ac.Credential.GetNodeId()}
+}
+
+// CoversNode reports whether the credential was issued for nodeID
+func (ac *AuthenticatedCredential) CoversNode(nodeID []byte) bool {
+	for _, covered := range ac.NodeIDs() {
+		if bytes.Equal(covered, nodeID) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyForNode is like Verify, but additionally requires the credential to cover nodeID,
+// failing with ErrNodeNotInCredential otherwise. It works for bundled and single-node credentials alike.
+func (c *CredentialManager) VerifyForNode(authenticatedCredential *AuthenticatedCredential, nodeID []byte) (*ID, error) {
+	id, err := c.Verify(authenticatedCredential)
+	if err != nil {
+		return nil, err
+	}
+	if !authenticatedCredential.CoversNode(nodeID) {
+		return nil, newVerificationError(authenticatedCredential, fmt.Errorf("%w: 0x%x", ErrNodeNotInCredential, nodeID))
+	}
+	return id, nil
+}
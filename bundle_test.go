@@ -0,0 +1,186 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestCreateBundle tests that bundled credentials verify for exactly their nodes, through every encoding
+func TestCreateBundle(t *testing.T) {
+	cm := NewCredentialManager([]byte("Bundle test secret"))
+	nodeIDs := batchNodeIDs(3)
+	bundle, err := cm.CreateBundle(time.Now(), nodeIDs, pb.OperatorType_OT_ROCKETPOOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bundle.Credential.NodeId, nodeIDs[0]) {
+		t.Error("Expected the first node ID to be the credential's node ID")
+	}
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON AuthenticatedCredential
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	text, err := bundle.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromText AuthenticatedCredential
+	if err := fromText.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if fromText.Base64URLEncodeUsername() != (Encoder{}).EncodeUsername(&AuthenticatedCredential{Credential: &pb.Credential{NodeId: nodeIDs[0]}}) {
+		t.Error("Expected the username to encode the first node ID")
+	}
+
+	outsider := batchNodeIDs(4)[3]
+	for _, decoded := range []*AuthenticatedCredential{bundle, &fromJSON, &fromText} {
+		for i, nodeID := range nodeIDs {
+			if _, err := cm.VerifyForNode(decoded, nodeID); err != nil {
+				t.Errorf("Node %d: %v", i, err)
+			}
+		}
+		if _, err := cm.VerifyForNode(decoded, outsider); !errors.Is(err, ErrNodeNotInCredential) {
+			t.Errorf("Expected ErrNodeNotInCredential, got %v", err)
+		}
+	}
+
+	reissued, err := cm.Reissue(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyForNode(reissued, nodeIDs[2]); err != nil {
+		t.Errorf("Expected reissued bundles to keep their nodes, got %v", err)
+	}
+
+	// Membership is covered by the MAC
+	bundle.Credential.BundleNodeIds[2] = outsider
+	if _, err := cm.VerifyForNode(bundle, outsider); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+// TestCreateBundleInvalid tests that invalid bundles are rejected at creation
+func TestCreateBundleInvalid(t *testing.T) {
+	cm := NewCredentialManager([]byte("Bundle test secret"))
+	duplicate := batchNodeIDs(3)
+	duplicate[2] = duplicate[0]
+	short := batchNodeIDs(2)
+	short[1] = []byte("short")
+
+	testCases := []struct {
+		name     string
+		nodeIDs  [][]byte
+		sentinel error
+	}{
+		{"Empty", nil, ErrInvalidBundle},
+		{"TooMany", batchNodeIDs(MaxBundleNodeIDs + 1), ErrInvalidBundle},
+		{"Duplicate", duplicate, ErrInvalidBundle},
+		{"InvalidNodeID", short, ErrInvalidNodeIDLength},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := cm.CreateBundle(time.Now(), tc.nodeIDs, pb.OperatorType_OT_ROCKETPOOL); !errors.Is(err, tc.sentinel) {
+				t.Errorf("Expected %v, got %v", tc.sentinel, err)
+			}
+		})
+	}
+
+	if _, err := cm.CreateBundle(time.Now(), batchNodeIDs(MaxBundleNodeIDs), pb.OperatorType_OT_ROCKETPOOL); err != nil {
+		t.Errorf("Expected a full bundle to be accepted, got %v", err)
+	}
+}
+
+// TestVerifyForNodeSingle tests that single-node credentials are unaffected by bundling
+func TestVerifyForNodeSingle(t *testing.T) {
+	cm := NewCredentialManager([]byte("Bundle test secret"))
+	nodeIDs := batchNodeIDs(2)
+	cred, err := cm.Create(time.Now(), nodeIDs[0], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cred.Credential.BundleNodeIds) != 0 {
+		t.Error("Expected no bundle on a single-node credential")
+	}
+	if _, err := cm.VerifyForNode(cred, nodeIDs[0]); err != nil {
+		t.Error(err)
+	}
+	if _, err := cm.VerifyForNode(cred, nodeIDs[1]); !errors.Is(err, ErrNodeNotInCredential) {
+		t.Errorf("Expected ErrNodeNotInCredential, got %v", err)
+	}
+}
+
+// TestBundleRevocation tests that revoking any node in a bundle revokes the whole bundle
+func TestBundleRevocation(t *testing.T) {
+	revoker := NewMemoryRevoker()
+	cm := NewCredentialManagerWithOptions([]byte("Bundle test secret"), nil, WithRevoker(revoker))
+	nodeIDs := batchNodeIDs(3)
+	bundle, err := cm.CreateBundle(time.Now(), nodeIDs, pb.OperatorType_OT_ROCKETPOOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyForNode(bundle, nodeIDs[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	revoker.Revoke(nodeIDs[2])
+	for i, nodeID := range nodeIDs {
+		if _, err := cm.VerifyForNode(bundle, nodeID); !errors.Is(err, ErrRevoked) {
+			t.Errorf("Node %d: expected ErrRevoked, got %v", i, err)
+		}
+	}
+	if _, err := cm.Verify(bundle); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Expected ErrRevoked, got %v", err)
+	}
+}
+
+// TestDecodeInvalidBundle tests that decoded bundles are held to the limits of CreateBundle
+func TestDecodeInvalidBundle(t *testing.T) {
+	duplicate := batchNodeIDs(3)
+	duplicate[2] = duplicate[1]
+	short := batchNodeIDs(2)
+	short[1] = []byte("short")
+
+	testCases := []struct {
+		name     string
+		nodeIDs  [][]byte
+		sentinel error
+	}{
+		{"TooMany", batchNodeIDs(MaxBundleNodeIDs + 1), ErrInvalidBundle},
+		{"Duplicate", duplicate, ErrInvalidBundle},
+		{"InvalidNodeID", short, ErrInvalidNodeIDLength},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cred := &AuthenticatedCredential{
+				Credential: &pb.Credential{NodeId: tc.nodeIDs[0], BundleNodeIds: tc.nodeIDs},
+				Mac:        make([]byte, MACLength),
+			}
+			password, err := cred.Base64URLEncodePassword()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := new(AuthenticatedCredential).Base64URLDecode(cred.Base64URLEncodeUsername(), password); !errors.Is(err, tc.sentinel) {
+				t.Errorf("Expected %v from base64url, got %v", tc.sentinel, err)
+			}
+			data, err := json.Marshal(cred)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(data, new(AuthenticatedCredential)); !errors.Is(err, tc.sentinel) {
+				t.Errorf("Expected %v from JSON, got %v", tc.sentinel, err)
+			}
+		})
+	}
+}
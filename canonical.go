@@ -0,0 +1,201 @@
+package credentials
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of map entries
+const (
+	mapEntryKeyField   protowire.Number = 1
+	mapEntryValueField protowire.Number = 2
+)
+
+// Field numbers of pb.AuthenticatedCredential and pb.KeyedMac
+const (
+	authenticatedCredentialField     protowire.Number = 1
+	authenticatedMACField            protowire.Number = 2
+	authenticatedAdditionalMACsField protowire.Number = 3
+	keyedMACKeyIDField               protowire.Number = 1
+	keyedMACMACField                 protowire.Number = 2
+)
+
+// Field numbers of pb.Credential
+const (
+	credentialNodeIDField       protowire.Number = 1
+	credentialTimestampField    protowire.Number = 2
+	credentialOperatorTypeField protowire.Number = 3
+	credentialNonceField        protowire.Number = 4
+	credentialIDField           protowire.Number = 5
+	credentialExpiresAtField    protowire.Number = 6
+	credentialAudienceField     protowire.Number = 7
+	credentialIssuerField       protowire.Number = 8
+	credentialScopesField       protowire.Number = 9
+	credentialChainIDField      protowire.Number = 10
+	credentialPartnerIDField    protowire.Number = 11
+	credentialMetadataField     protowire.Number = 12
+	credentialBundleField       protowire.Number = 13
+	credentialFeeRecipientField protowire.Number = 14
+	credentialVersionField      protowire.Number = 15
+	credentialScopeNamesField   protowire.Number = 16
+)
+
+// appendCredential appends the wire encoding of c to dst and returns the extended buffer.
+// The output is byte-identical to deterministic proto.Marshal(c), which is what the MAC has always been computed over,
+// but skips the reflection-based marshaler entirely. Fields are emitted in field number order,
+// zero values are omitted as proto3 requires, and unknown fields are appended last.
+// Map entries are sorted by key, as the default marshaler's map order is randomized and would break the MAC.
+func appendCredential(dst []byte, c *pb.Credential) []byte {
+	return appendCredentialFields(dst, c, true)
+}
+
+// appendCredentialFields is appendCredential, optionally leaving out the node ID as passwords do
+func appendCredentialFields(dst []byte, c *pb.Credential, withNodeID bool) []byte {
+	if c == nil {
+		return dst
+	}
+
+	if withNodeID && len(c.NodeId) > 0 {
+		dst = protowire.AppendTag(dst, credentialNodeIDField, protowire.BytesType)
+		dst = protowire.AppendBytes(dst, c.NodeId)
+	}
+	if c.Timestamp != 0 {
+		dst = protowire.AppendTag(dst, credentialTimestampField, protowire.VarintType)
+		dst = protowire.AppendVarint(dst, uint64(c.Timestamp))
+	}
+	if c.OperatorType != 0 {
+		// Enums are int32 on the wire and sign-extended like any other varint
+		dst = protowire.AppendTag(dst, credentialOperatorTypeField, protowire.VarintType)
+		dst = protowire.AppendVarint(dst, uint64(int64(c.OperatorType)))
+	}
+	if len(c.Nonce) > 0 {
+		dst = protowire.AppendTag(dst, credentialNonceField, protowire.BytesType)
+		dst = protowire.AppendBytes(dst, c.Nonce)
+	}
+	if len(c.CredentialId) > 0 {
+		dst = protowire.AppendTag(dst, credentialIDField, protowire.BytesType)
+		dst = protowire.AppendBytes(dst, c.CredentialId)
+	}
+	if c.ExpiresAt != 0 {
+		dst = protowire.AppendTag(dst, credentialExpiresAtField, protowire.VarintType)
+		dst = protowire.AppendVarint(dst, uint64(c.ExpiresAt))
+	}
+	if len(c.Audience) > 0 {
+		dst = protowire.AppendTag(dst, credentialAudienceField, protowire.BytesType)
+		dst = protowire.AppendString(dst, c.Audience)
+	}
+	if len(c.Issuer) > 0 {
+		dst = protowire.AppendTag(dst, credentialIssuerField, protowire.BytesType)
+		dst = protowire.AppendString(dst, c.Issuer)
+	}
+	if c.Scopes != 0 {
+		dst = protowire.AppendTag(dst, credentialScopesField, protowire.VarintType)
+		dst = protowire.AppendVarint(dst, c.Scopes)
+	}
+	if c.ChainId != 0 {
+		dst = protowire.AppendTag(dst, credentialChainIDField, protowire.VarintType)
+		dst = protowire.AppendVarint(dst, c.ChainId)
+	}
+	if len(c.PartnerId) > 0 {
+		dst = protowire.AppendTag(dst, credentialPartnerIDField, protowire.BytesType)
+		dst = protowire.AppendString(dst, c.PartnerId)
+	}
+	if len(c.Metadata) > 0 {
+		dst = appendStringMap(dst, credentialMetadataField, c.Metadata)
+	}
+	for _, nodeID := range c.BundleNodeIds {
+		// Repeated bytes are never packed, and empty elements are still emitted
+		dst = protowire.AppendTag(dst, credentialBundleField, protowire.BytesType)
+		dst = protowire.AppendBytes(dst, nodeID)
+	}
+	if len(c.FeeRecipient) > 0 {
+		dst = protowire.AppendTag(dst, credentialFeeRecipientField, protowire.BytesType)
+		dst = protowire.AppendBytes(dst, c.FeeRecipient)
+	}
+	if c.Version != 0 {
+		dst = protowire.AppendTag(dst, credentialVersionField, protowire.VarintType)
+		dst = protowire.AppendVarint(dst, uint64(c.Version))
+	}
+	for _, name := range c.ScopeNames {
+		dst = protowire.AppendTag(dst, credentialScopeNamesField, protowire.BytesType)
+		dst = protowire.AppendString(dst, name)
+	}
+
+	return append(dst, c.ProtoReflect().GetUnknown()...)
+}
+
+// appendAAD appends additional authenticated data to a MAC input.
+// The length is appended after the data so the boundary between credential and aad is unambiguous.
+// Empty aad leaves the input untouched, so credentials without aad keep their original MACs.
+func appendAAD(dst []byte, aad []byte) []byte {
+	if len(aad) == 0 {
+		return dst
+	}
+	dst = append(dst, aad...)
+	return binary.BigEndian.AppendUint64(dst, uint64(len(aad)))
+}
+
+// appendStringMap appends m as a map field, with entries sorted by key like deterministic proto.Marshal.
+// Both the key and the value of every entry are emitted, even when empty, as the Go marshaler does.
+// Maps within the metadata limits are sorted without allocating.
+func appendStringMap(dst []byte, num protowire.Number, m map[string]string) []byte {
+	var small [MaxMetadataEntries]string
+	keys := small[:0]
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		v := m[k]
+		size := protowire.SizeTag(mapEntryKeyField) + protowire.SizeBytes(len(k)) +
+			protowire.SizeTag(mapEntryValueField) + protowire.SizeBytes(len(v))
+		dst = protowire.AppendTag(dst, num, protowire.BytesType)
+		dst = protowire.AppendVarint(dst, uint64(size))
+		dst = protowire.AppendTag(dst, mapEntryKeyField, protowire.BytesType)
+		dst = protowire.AppendString(dst, k)
+		dst = protowire.AppendTag(dst, mapEntryValueField, protowire.BytesType)
+		dst = protowire.AppendString(dst, v)
+	}
+	return dst
+}
+
+// CanonicalBytes returns exactly the bytes the credential's MAC is computed over, so that other systems can verify
+// or countersign credentials independently. This layout is the stable signing input:
+//
+// The bytes are the protobuf wire encoding of the inner Credential message (see proto/credential.proto),
+// with fields in ascending field number order, fields holding their zero value omitted,
+// map entries sorted by key with both key and value always present, and any fields unknown to this version
+// of the library appended last, byte for byte as received. This is identical to deterministic proto.Marshal.
+//
+// The MAC is HMAC-SHA256 over these bytes. Credentials bound to additional authenticated data with CreateWithAAD
+// are authenticated over these bytes, followed by the aad, followed by the aad's length as a big-endian uint64.
+func (ac *AuthenticatedCredential) CanonicalBytes() ([]byte, error) {
+	if ac == nil || ac.Credential == nil {
+		return nil, fmt.Errorf("%w: credential is empty", SerializationError)
+	}
+	return appendCredential(nil, ac.Credential), nil
+}
+
+// ComputeMAC returns the MAC the manager would give cred, without modifying it. It is computed over the MAC input of
+// cred as it is now, under the key Create would use, so tests can assert exact MACs and callers can compare MACs themselves.
+func (c *CredentialManager) ComputeMAC(cred *AuthenticatedCredential) ([]byte, error) {
+	return c.ComputeMACWithAAD(cred, nil)
+}
+
+// ComputeMACWithAAD is like ComputeMAC, but for credentials bound to aad with CreateWithAAD
+func (c *CredentialManager) ComputeMACWithAAD(cred *AuthenticatedCredential, aad []byte) ([]byte, error) {
+	if cred == nil || cred.Credential == nil {
+		return nil, fmt.Errorf("%w: credential is empty", SerializationError)
+	}
+	// Authenticating only sets the outer message's MACs, so a fresh one sharing the inner credential leaves cred untouched
+	scratch := &AuthenticatedCredential{Credential: cred.Credential}
+	if err := c.authenticateCredential(scratch, aad); err != nil {
+		return nil, err
+	}
+	return scratch.Mac, nil
+}
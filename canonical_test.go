@@ -0,0 +1,393 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestAppendCredentialMatchesProto ensures the hand-rolled encoder is byte-identical to deterministic proto.Marshal
+func TestAppendCredentialMatchesProto(t *testing.T) {
+	nodeID, err := hex.DecodeString("1234567890123456789012345678901234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unknown := protowire.AppendTag(nil, 99, protowire.BytesType)
+	unknown = protowire.AppendString(unknown, "from the future")
+
+	withUnknown := &pb.Credential{NodeId: nodeID, Timestamp: 1700000000}
+	withUnknown.ProtoReflect().SetUnknown(unknown)
+
+	testCases := []struct {
+		name       string
+		credential *pb.Credential
+	}{
+		{"Nil", nil},
+		{"Empty", &pb.Credential{}},
+		{"NodeIDOnly", &pb.Credential{NodeId: nodeID}},
+		{"Full", &pb.Credential{NodeId: nodeID, Timestamp: time.Now().Unix(), OperatorType: pb.OperatorType_OT_SOLO}},
+		{"NegativeTimestamp", &pb.Credential{NodeId: nodeID, Timestamp: -1}},
+		{"MaxTimestamp", &pb.Credential{NodeId: nodeID, Timestamp: math.MaxInt64}},
+		{"MinTimestamp", &pb.Credential{NodeId: nodeID, Timestamp: math.MinInt64}},
+		{"NegativeOperatorType", &pb.Credential{NodeId: nodeID, OperatorType: pb.OperatorType(-5)}},
+		{"UnknownOperatorType", &pb.Credential{NodeId: nodeID, OperatorType: pb.OperatorType(300)}},
+		{"Nonce", &pb.Credential{NodeId: nodeID, Timestamp: 1, Nonce: []byte("nonce")}},
+		{"CredentialID", &pb.Credential{NodeId: nodeID, Timestamp: 1, Nonce: []byte("nonce"), CredentialId: []byte("credential id")}},
+		{"ExpiresAt", &pb.Credential{NodeId: nodeID, Timestamp: 1, ExpiresAt: 2}},
+		{"NegativeExpiresAt", &pb.Credential{NodeId: nodeID, ExpiresAt: -1}},
+		{"Audience", &pb.Credential{NodeId: nodeID, Timestamp: 1, Audience: "rescue-proxy"}},
+		{"Issuer", &pb.Credential{NodeId: nodeID, Timestamp: 1, Audience: "rescue-proxy", Issuer: "bot-v2"}},
+		{"Scopes", &pb.Credential{NodeId: nodeID, Timestamp: 1, Scopes: math.MaxUint64}},
+		{"ChainID", &pb.Credential{NodeId: nodeID, Timestamp: 1, ChainId: 17000}},
+		{"PartnerID", &pb.Credential{NodeId: nodeID, Timestamp: 1, ChainId: 1, PartnerId: "acme"}},
+		{"Metadata", &pb.Credential{NodeId: nodeID, Timestamp: 1, PartnerId: "acme", Metadata: map[string]string{
+			"ticket": "1234", "channel": "discord", "a": "", "": "empty key", "zz": "last",
+		}}},
+		{"Bundle", &pb.Credential{NodeId: nodeID, Timestamp: 1, BundleNodeIds: [][]byte{nodeID, {}, make([]byte, 20)}}},
+		{"FeeRecipient", &pb.Credential{NodeId: nodeID, Timestamp: 1, BundleNodeIds: [][]byte{nodeID}, FeeRecipient: nodeID}},
+		{"Version", &pb.Credential{NodeId: nodeID, Timestamp: 1, Version: math.MaxUint32}},
+		{"ScopeNames", &pb.Credential{NodeId: nodeID, Timestamp: 1, Version: 1, ScopeNames: []string{"read", "", "submit"}}},
+		{"UnknownFields", withUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expected, err := proto.MarshalOptions{Deterministic: true}.Marshal(tc.credential)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := appendCredential(nil, tc.credential)
+			if !bytes.Equal(expected, actual) {
+				t.Fatalf("Encoding mismatch:\nexpected %x\ngot      %x", expected, actual)
+			}
+
+			// Appending must not clobber existing contents of the destination
+			prefixed := appendCredential([]byte{0xff}, tc.credential)
+			if prefixed[0] != 0xff || !bytes.Equal(prefixed[1:], expected) {
+				t.Fatalf("Encoding mismatch when appending: %x", prefixed)
+			}
+		})
+	}
+}
+
+// TestCanonicalGolden pins the MAC input byte for byte. Any change to it invalidates every credential
+// already issued, so a failure here means the change must be reverted or given a new version.
+func TestCanonicalGolden(t *testing.T) {
+	nodeID := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+
+	unknown := protowire.AppendTag(nil, 99, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 7)
+	withUnknown := &pb.Credential{Timestamp: 1}
+	withUnknown.ProtoReflect().SetUnknown(unknown)
+
+	testCases := []struct {
+		name       string
+		credential *pb.Credential
+		expected   string
+	}{
+		{"Core", &pb.Credential{NodeId: nodeID, Timestamp: 1700000000, OperatorType: pb.OperatorType_OT_SOLO},
+			"0a140102030405060708090a0b0c0d0e0f10111213141080e2cfaa061801"},
+		{"AllFields", &pb.Credential{
+			NodeId: nodeID, Timestamp: 1700000000, OperatorType: pb.OperatorType_OT_SOLO, Nonce: []byte{0xaa, 0xbb}, CredentialId: []byte{0xcc},
+			ExpiresAt: 1700003600, Audience: "aud", Issuer: "iss", Scopes: 5, ChainId: 17000, PartnerId: "p",
+			Metadata: map[string]string{"b": "2", "a": "1"}, BundleNodeIds: [][]byte{nodeID}, FeeRecipient: nodeID, Version: 1,
+		}, "0a140102030405060708090a0b0c0d0e0f10111213141080e2cfaa0618012202aabb2a01cc3090fecfaa063a036175644203697373480550e884015a017062060a016112013162060a01621201326a140102030405060708090a0b0c0d0e0f101112131472140102030405060708090a0b0c0d0e0f10111213147801"},
+		{"ScopeNames", &pb.Credential{Timestamp: 1, ScopeNames: []string{"read", "submit"}},
+			"1001820104726561648201067375626d6974"},
+		{"UnknownFields", withUnknown, "1001980607"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Map order is randomized, so repeat to make sure the output doesn't depend on it
+			for i := 0; i < 10; i++ {
+				if actual := hex.EncodeToString(appendCredential(nil, tc.credential)); actual != tc.expected {
+					t.Fatalf("MAC input changed:\nexpected %s\ngot      %s", tc.expected, actual)
+				}
+			}
+		})
+	}
+}
+
+// TestCanonicalMacMatchesProto ensures credentials created on the fast path carry the same MAC as before
+func TestCanonicalMacMatchesProto(t *testing.T) {
+	key := []byte("Curiouser and curiouser")
+	cm := NewCredentialManager(key)
+
+	cred, err := cm.Create(time.Unix(1700000000, 0), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	marshaled, err := proto.Marshal(cred.Credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hmac.New(hashAlgo, key)
+	h.Write(marshaled)
+
+	if !hmac.Equal(h.Sum(nil), cred.Mac) {
+		t.Fatal("MAC computed over the canonical encoding differs from the proto.Marshal based MAC")
+	}
+}
+
+// TestCanonicalBytes tests that CanonicalBytes is the documented MAC input
+func TestCanonicalBytes(t *testing.T) {
+	key := []byte("Curiouser and curiouser")
+	cm := NewCredentialManager(key)
+	cred, err := cm.CreateWithMetadata(time.Unix(1700000000, 0), make([]byte, 20), pb.OperatorType_OT_SOLO, map[string]string{"b": "2", "a": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canonical, err := cred.CanonicalBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write(canonical)
+	if !hmac.Equal(h.Sum(nil), cred.Mac) {
+		t.Error("HMAC-SHA256 over CanonicalBytes doesn't match the MAC")
+	}
+
+	// With aad, the aad and its length follow
+	bound, err := cm.CreateWithAAD(time.Unix(1700000000, 0), make([]byte, 20), pb.OperatorType_OT_SOLO, []byte("rescue-proxy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	canonical, err = bound.CanonicalBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Reset()
+	h.Write(canonical)
+	h.Write([]byte("rescue-proxy"))
+	h.Write([]byte{0, 0, 0, 0, 0, 0, 0, 12})
+	if !hmac.Equal(h.Sum(nil), bound.Mac) {
+		t.Error("HMAC-SHA256 over CanonicalBytes and the aad doesn't match the MAC")
+	}
+
+	if _, err := new(AuthenticatedCredential).CanonicalBytes(); !errors.Is(err, SerializationError) {
+		t.Errorf("Expected SerializationError, got %v", err)
+	}
+}
+
+func benchmarkCredential(b *testing.B, cm *CredentialManager) *AuthenticatedCredential {
+	nodeID, err := hex.DecodeString("1234567890123456789012345678901234567890")
+	if err != nil {
+		b.Fatal(err)
+	}
+	cred, err := cm.Create(time.Now(), nodeID, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return cred
+}
+
+func BenchmarkCreate(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"))
+	nodeID := make([]byte, 20)
+	now := time.Now()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cm.Create(now, nodeID, pb.OperatorType_OT_SOLO); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"))
+	cred := benchmarkCredential(b, cm)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cm.Verify(cred); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkCreateContext measures the overhead of CreateContext over Create, which is checking the context
+func BenchmarkCreateContext(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"))
+	nodeID := make([]byte, 20)
+	now := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cm.CreateContext(ctx, now, nodeID, pb.OperatorType_OT_SOLO); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkVerifyContext is BenchmarkVerify through VerifyContext, with a cancellable context
+func BenchmarkVerifyContext(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"))
+	cred := benchmarkCredential(b, cm)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cm.VerifyContext(ctx, cred); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkVerifyProtoMarshal measures the previous verify implementation, which ran proto.Marshal per call
+func BenchmarkVerifyProtoMarshal(b *testing.B) {
+	key := []byte("Benchmark secret")
+	cm := NewCredentialManager(key)
+	cred := benchmarkCredential(b, cm)
+	h := hmac.New(hashAlgo, key)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		marshaled, err := proto.Marshal(cred.Credential)
+		if err != nil {
+			b.Fatal(err)
+		}
+		h.Write(marshaled)
+		if !hmac.Equal(h.Sum(nil), cred.Mac) {
+			b.Fatal("mismatch")
+		}
+		h.Reset()
+	}
+}
+
+// benchmarkGoroutines runs op concurrently on 1, 8 and 64 goroutines
+func benchmarkGoroutines(b *testing.B, op func() error) {
+	for _, goroutines := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("goroutines=%d", goroutines), func(b *testing.B) {
+			// RunParallel starts parallelism * GOMAXPROCS goroutines
+			procs := runtime.GOMAXPROCS(0)
+			if goroutines < procs {
+				defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(goroutines))
+				procs = goroutines
+			}
+			b.SetParallelism(goroutines / procs)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := op(); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkCreateParallel(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"), []byte("Old benchmark secret"))
+	nodeID := make([]byte, 20)
+	now := time.Now()
+	benchmarkGoroutines(b, func() error {
+		_, err := cm.Create(now, nodeID, pb.OperatorType_OT_SOLO)
+		return err
+	})
+}
+
+func BenchmarkVerifyParallel(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"), []byte("Old benchmark secret"))
+	cred := benchmarkCredential(b, cm)
+	benchmarkGoroutines(b, func() error {
+		_, err := cm.Verify(cred)
+		return err
+	})
+}
+
+// BenchmarkCheckerNew measures the cost of a pool miss, which keys a fresh checker
+func BenchmarkCheckerNew(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"), []byte("Old benchmark secret"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if cm.checkers.New() == nil {
+			b.Fatal("nil checker")
+		}
+	}
+}
+
+// TestComputeMAC tests that ComputeMAC returns the MAC Create would give, without modifying the credential
+func TestComputeMAC(t *testing.T) {
+	key := []byte("Curiouser and curiouser")
+	cm := NewCredentialManager(key)
+	cred, err := cm.Create(time.Unix(1700000000, 0), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := proto.Clone(cred.Pb())
+
+	mac, err := cm.ComputeMAC(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(mac, cred.Mac) {
+		t.Errorf("Expected the MAC Create gave, %x, got %x", cred.Mac, mac)
+	}
+	canonical, err := cred.CanonicalBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hmac.New(hashAlgo, key)
+	h.Write(canonical)
+	if !hmac.Equal(h.Sum(nil), mac) {
+		t.Error("Expected the HMAC of the canonical bytes")
+	}
+	if !proto.Equal(before, cred.Pb()) {
+		t.Error("ComputeMAC modified the credential")
+	}
+
+	// MACs computed for edited credentials verify once set
+	cred.Credential.Timestamp++
+	if cred.Mac, err = cm.ComputeMAC(cred); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Errorf("Expected the recomputed MAC to verify, got %v", err)
+	}
+
+	bound, err := cm.CreateWithAAD(time.Unix(1700000000, 0), make([]byte, 20), pb.OperatorType_OT_SOLO, []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mac, err := cm.ComputeMACWithAAD(bound, []byte("aad")); err != nil || !bytes.Equal(mac, bound.Mac) {
+		t.Errorf("Expected the MAC bound to aad, got %x, %v", mac, err)
+	}
+
+	if _, err := cm.ComputeMAC(nil); !errors.Is(err, SerializationError) {
+		t.Errorf("Expected SerializationError, got %v", err)
+	}
+	if _, err := NewCredentialManagerFromMACer(&remoteMACer{key: key, fail: true}).ComputeMAC(cred); !errors.Is(err, ErrMACFailed) {
+		t.Errorf("Expected ErrMACFailed, got %v", err)
+	}
+}
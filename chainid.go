@@ -0,0 +1,45 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrChainIDMismatch = errors.New("credential chain ID mismatch")
+
+// ChainIDMismatchError is returned by Verify for credentials minted for another network. It matches ErrChainIDMismatch.
+type ChainIDMismatchError struct {
+	// Credential is the chain ID in the credential, or 0 if it has none
+	Credential uint64
+	// Verifier is the chain ID configured with WithChainID, or 0 if none was
+	Verifier uint64
+}
+
+func (e *ChainIDMismatchError) Error() string {
+	return fmt.Sprintf("%v: credential is for chain %d, verifier expects chain %d", ErrChainIDMismatch, e.Credential, e.Verifier)
+}
+
+func (e *ChainIDMismatchError) Is(target error) bool {
+	return target == ErrChainIDMismatch
+}
+
+// WithChainID makes Create stamp chainID on every credential, and Verify reject credentials for any other chain.
+// Credentials without a chain ID are only accepted by managers without one.
+func WithChainID(chainID uint64) Option {
+	return func(c *CredentialManager) {
+		c.chainID = chainID
+	}
+}
+
+// ChainID returns the ID of the network the credential was minted for, or 0 if it wasn't recorded
+func (ac *AuthenticatedCredential) ChainID() uint64 {
+	return ac.Credential.GetChainId()
+}
+
+// checkChainID fails with a *ChainIDMismatchError unless the credential is for the manager's chain
+func (c *CredentialManager) checkChainID(authenticatedCredential *AuthenticatedCredential) error {
+	if chainID := authenticatedCredential.ChainID(); chainID != c.chainID {
+		return &ChainIDMismatchError{Credential: chainID, Verifier: c.chainID}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestChainID tests that credentials are only accepted by verifiers for the same chain
+func TestChainID(t *testing.T) {
+	key := []byte("Chain ID test secret")
+	mainnet := NewCredentialManagerWithOptions(key, nil, WithChainID(1))
+	holesky := NewCredentialManagerWithOptions(key, nil, WithChainID(17000))
+	plain := NewCredentialManager(key)
+
+	holeskyCred, err := holesky.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holeskyCred.ChainID() != 17000 {
+		t.Fatalf("Expected chain ID 17000, got %d", holeskyCred.ChainID())
+	}
+	plainCred, err := plain.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(holeskyCred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AuthenticatedCredential
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		verifier *CredentialManager
+		cred     *AuthenticatedCredential
+		mismatch *ChainIDMismatchError
+	}{
+		{"SameChain", holesky, &decoded, nil},
+		{"OtherChain", mainnet, &decoded, &ChainIDMismatchError{Credential: 17000, Verifier: 1}},
+		{"VerifierWithoutChain", plain, &decoded, &ChainIDMismatchError{Credential: 17000}},
+		{"CredentialWithoutChain", mainnet, plainCred, &ChainIDMismatchError{Verifier: 1}},
+		{"NeitherHasChain", plain, plainCred, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.verifier.Verify(tc.cred)
+			if tc.mismatch == nil {
+				if err != nil {
+					t.Errorf("Expected success, got %v", err)
+				}
+				return
+			}
+			var mismatch *ChainIDMismatchError
+			if !errors.Is(err, ErrChainIDMismatch) || !errors.As(err, &mismatch) || *mismatch != *tc.mismatch {
+				t.Errorf("Expected %v, got %v", tc.mismatch, err)
+			}
+		})
+	}
+}
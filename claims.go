@@ -0,0 +1,58 @@
+package credentials
+
+import "time"
+
+// Claims describes the principal a verified credential was issued to
+type Claims struct {
+	NodeID       []byte
+	OperatorType OperatorType
+	IssuedAt     time.Time
+	// ExpiresAt is the embedded expiry, or the end of the manager's max age, or zero if the credential never expires
+	ExpiresAt time.Time
+	// CredentialID is the hex encoded credential ID, or "" if the credential has none
+	CredentialID string
+	Audience     string
+	Issuer       string
+	Scopes       Scope
+	// ScopeNames are the named scopes granted
+	ScopeNames []string
+	ChainID    uint64
+	// PartnerID is "" for first-party credentials
+	PartnerID string
+	// KeyID is the ID of the key that authenticated the credential
+	KeyID *ID
+	// VerifiedAt is when the credential was verified, or for failures recorded by an AuditSink,
+	// when verification was attempted, by the manager's clock
+	VerifiedAt time.Time
+}
+
+// VerifyClaims is like Verify, but returns the claims of the verified credential
+func (c *CredentialManager) VerifyClaims(authenticatedCredential *AuthenticatedCredential) (Claims, error) {
+	id, err := c.Verify(authenticatedCredential)
+	if err != nil {
+		return Claims{}, err
+	}
+	return c.claims(authenticatedCredential, id, c.now()), nil
+}
+
+// claims describes a credential authenticated by the key id at verifiedAt.
+// The claims reference the credential's node ID and scope names.
+func (c *CredentialManager) claims(authenticatedCredential *AuthenticatedCredential, id *ID, verifiedAt time.Time) Claims {
+	credential := authenticatedCredential.Credential
+	expiresAt, _ := c.expiry(authenticatedCredential)
+	return Claims{
+		NodeID:       credential.GetNodeId(),
+		OperatorType: credential.GetOperatorType(),
+		IssuedAt:     time.Unix(credential.GetTimestamp(), 0),
+		ExpiresAt:    expiresAt,
+		CredentialID: authenticatedCredential.ID(),
+		Audience:     credential.GetAudience(),
+		Issuer:       authenticatedCredential.Issuer(),
+		Scopes:       authenticatedCredential.Scopes(),
+		ScopeNames:   authenticatedCredential.ScopeNames(),
+		ChainID:      authenticatedCredential.ChainID(),
+		PartnerID:    authenticatedCredential.PartnerID(),
+		KeyID:        id,
+		VerifiedAt:   verifiedAt,
+	}
+}
@@ -0,0 +1,46 @@
+package credentials
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestVerifyClaims tests that the claims reflect the verified credential
+func TestVerifyClaims(t *testing.T) {
+	cm := NewCredentialManagerWithOptions([]byte("Claims test secret"), nil,
+		WithAudience("rescue-proxy"), WithIssuer("bot-v2"), WithChainID(17000), WithMaxAge(time.Hour))
+	issued := time.Unix(time.Now().Unix(), 0)
+	nodeID := make([]byte, 20)
+	nodeID[0] = 0xaa
+
+	cred, err := cm.CreateWithScopes(issued, nodeID, pb.OperatorType_OT_SOLO, ScopeBeaconAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := cm.VerifyClaims(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(claims.NodeID, nodeID) || claims.OperatorType != pb.OperatorType_OT_SOLO {
+		t.Errorf("Unexpected principal %x %s", claims.NodeID, claims.OperatorType)
+	}
+	if !claims.IssuedAt.Equal(issued) || !claims.ExpiresAt.Equal(issued.Add(time.Hour)) {
+		t.Errorf("Unexpected validity %s - %s", claims.IssuedAt, claims.ExpiresAt)
+	}
+	if claims.CredentialID != cred.ID() || claims.Audience != "rescue-proxy" || claims.Issuer != "bot-v2" ||
+		claims.Scopes != ScopeBeaconAPI || claims.ChainID != 17000 {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+	if claims.KeyID == nil || !claims.KeyID.Equals(cm.ID()) {
+		t.Errorf("Expected key ID %v, got %v", cm.ID(), claims.KeyID)
+	}
+
+	cred.Mac[0] ^= 1
+	if _, err := cm.VerifyClaims(cred); err == nil {
+		t.Error("Expected a mismatch")
+	}
+}
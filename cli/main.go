@@ -12,11 +12,9 @@ import (
 	"time"
 
 	"github.com/Rocket-Rescue-Node/credentials"
-	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/Rocket-Rescue-Node/credentials/ethcredentials"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/crypto/secp256k1"
 )
 
 type SignedMessage struct {
@@ -48,22 +46,13 @@ func validateSignedMessage(inp string) (*SignedMessage, error) {
 	sig := hexutil.MustDecode(sm.Sig)
 
 	// Verify the signature
-	if len(sig) != crypto.SignatureLength {
-		return nil, secp256k1.ErrInvalidSignatureLen
-	}
-	sig[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
-	hash := accounts.TextHash([]byte(sm.Msg))
-	pubKey, err := crypto.SigToPub(hash, sig)
-	// Restore V
-	sig[crypto.RecoveryIDOffset] += 27
-
+	addr, err := ethcredentials.VerifyIssuanceSignature([]byte(sm.Msg), sig)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to recover public key: %w", err)
 	}
 
-	addr := crypto.PubkeyToAddress(*pubKey)
-	if addr != sm.Address {
-		return nil, fmt.Errorf("Recovered address %v doesn't match the provided address %s", addr, sm.Address.Hex())
+	if common.Address(addr) != sm.Address {
+		return nil, fmt.Errorf("Recovered address %v doesn't match the provided address %s", common.Address(addr), sm.Address.Hex())
 	}
 
 	return &sm, nil
@@ -71,13 +60,8 @@ func validateSignedMessage(inp string) (*SignedMessage, error) {
 
 func parseToJson(credential string) (string, error) {
 
-	strs := strings.Split(credential, ":")
-	if len(strs) != 2 {
-		return "", fmt.Errorf("Invalid credential: %s\n", credential)
-	}
-
 	ac := credentials.AuthenticatedCredential{}
-	err := ac.Base64URLDecode(strs[0], strs[1])
+	err := ac.UnmarshalText([]byte(credential))
 	if err != nil {
 		return "", err
 	}
@@ -179,7 +163,12 @@ func main() {
 		return
 	}
 
-	username := cred.Base64URLEncodeUsername()
+	username, err := cred.EncodeUsername()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+		return
+	}
 	password, err := cred.Base64URLEncodePassword()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
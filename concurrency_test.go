@@ -0,0 +1,227 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// concurrencyIterations is how many operations each goroutine of the stress tests performs
+const concurrencyIterations = 200
+
+// stress runs fn on several goroutines per CPU at once, passing each its goroutine number
+func stress(t *testing.T, fn func(g int) error) {
+	t.Helper()
+	goroutines := 4 * runtime.GOMAXPROCS(0)
+	errs := make([]error, goroutines)
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func(g int) {
+			defer wg.Done()
+			errs[g] = fn(g)
+		}(g)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestConcurrentManager tests that one manager can be shared by goroutines creating, verifying and encoding
+// credentials at once, including credentials shared between them. Run it with -race.
+func TestConcurrentManager(t *testing.T) {
+	ring, err := NewKeyRing(KeyEntry{ID: "k1", Key: []byte("Concurrency ring secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created, verified atomic.Int64
+	hooks := WithHooks(Hooks{
+		OnCreate:        func(*AuthenticatedCredential) { created.Add(1) },
+		OnVerifySuccess: func(*AuthenticatedCredential, time.Duration) { verified.Add(1) },
+	})
+	managers := map[string]*CredentialManager{
+		"Secret":   NewCredentialManagerWithOptions([]byte("Concurrency test secret"), [][]byte{[]byte("Old secret")}, hooks),
+		"DualMAC":  NewDualSignManager([]byte("Concurrency test secret"), []byte("Old secret"), hooks, WithRevoker(NewMemoryRevoker())),
+		"Cached":   NewCredentialManagerWithOptions([]byte("Concurrency test secret"), nil, hooks, WithVerificationCache(64, time.Minute)),
+		"KeyRing":  NewCredentialManagerFromKeyRing(ring, hooks),
+		"MACer":    NewCredentialManagerFromMACer(NewHMACSHA256([]byte("Concurrency test secret")), hooks),
+		"Metadata": NewCredentialManagerWithOptions([]byte("Concurrency test secret"), nil, hooks, WithAudience("rescue-proxy"), WithRandomNonce()),
+	}
+
+	nodeIDs := batchNodeIDs(8)
+	for name, cm := range managers {
+		t.Run(name, func(t *testing.T) {
+			created.Store(0)
+			verified.Store(0)
+
+			// Credentials shared by every goroutine, which only read them
+			shared, err := cm.CreateMany(time.Now(), nodeIDs, pb.OperatorType_OT_SOLO)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stress(t, func(g int) error {
+				for i := 0; i < concurrencyIterations; i++ {
+					cred, err := cm.Create(time.Now(), nodeIDs[(g+i)%len(nodeIDs)], pb.OperatorType_OT_SOLO)
+					if err != nil {
+						return err
+					}
+					if _, err := cm.Verify(cred); err != nil {
+						return err
+					}
+
+					s := shared[(g+i)%len(shared)]
+					if _, err := cm.Verify(s); err != nil {
+						return err
+					}
+					username := s.Base64URLEncodeUsername()
+					password, err := s.Base64URLEncodePassword()
+					if err != nil {
+						return err
+					}
+					if _, err := cm.VerifyFromBasicAuth(username, password); err != nil {
+						return err
+					}
+					if _, err := json.Marshal(s); err != nil {
+						return err
+					}
+					if _, err := s.MarshalText(); err != nil {
+						return err
+					}
+					if _, err := cm.ComputeMAC(s); err != nil {
+						return err
+					}
+				}
+				return cm.VerifyBatch(shared).Err()
+			})
+
+			goroutines := int64(4 * runtime.GOMAXPROCS(0))
+			if want := goroutines*concurrencyIterations + int64(len(shared)); created.Load() != want {
+				t.Errorf("Expected %d credentials created, got %d", want, created.Load())
+			}
+			if want := goroutines * (3*concurrencyIterations + int64(len(shared))); verified.Load() != want {
+				t.Errorf("Expected %d credentials verified, got %d", want, verified.Load())
+			}
+
+			// Reading the shared credentials left them as they were
+			for _, s := range shared {
+				if _, err := cm.Verify(s); err != nil {
+					t.Error(err)
+				}
+				if len(s.Credential.NodeId) != NodeIDLength {
+					t.Errorf("Expected the node ID to survive encoding, got %x", s.Credential.NodeId)
+				}
+			}
+		})
+	}
+}
+
+// TestConcurrentEncode tests that encoding one credential from many goroutines gives the same result in each,
+// and leaves the credential unchanged
+func TestConcurrentEncode(t *testing.T) {
+	cm := NewCredentialManager([]byte("Concurrency test secret"))
+	cred, err := cm.CreateWithMetadata(time.Now(), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO, map[string]string{"ticket": "1", "region": "eu"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := proto.Clone(cred.Pb())
+
+	stress(t, func(int) error {
+		for i := 0; i < concurrencyIterations; i++ {
+			for _, e := range []Encoder{{}, RawEncoder, {Compress: true}} {
+				password, err := e.EncodePassword(cred)
+				if err != nil {
+					return err
+				}
+				var decoded AuthenticatedCredential
+				if err := decoded.Base64URLDecode(e.EncodeUsername(cred), password); err != nil {
+					return err
+				}
+			}
+			got, err := cred.Base64URLEncodePassword()
+			if err != nil {
+				return err
+			}
+			if got != want {
+				return errors.New("concurrent encodings differ")
+			}
+			if _, err := cred.CanonicalBytes(); err != nil {
+				return err
+			}
+			if _, err := cred.ToURLValues(); err != nil {
+				return err
+			}
+			if _, err := cred.WriteTo(io.Discard); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if !proto.Equal(before, cred.Pb()) {
+		t.Errorf("Expected encoding to leave the credential unchanged, got %v", cred.Pb())
+	}
+}
+
+// TestConcurrentKeyRotation tests that keys can be added to and removed from a ring while its manager is in use
+func TestConcurrentKeyRotation(t *testing.T) {
+	ring, err := NewKeyRing(KeyEntry{ID: "stable", Key: []byte("Stable ring secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := NewCredentialManagerFromKeyRing(ring)
+	cred, err := cm.Create(time.Now(), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	rotated := make(chan struct{})
+	go func() {
+		defer close(rotated)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// Add a key that only becomes valid in the future, so it never signs, then remove it
+			if err := ring.Add(KeyEntry{ID: "next", Key: []byte("Next ring secret"), NotBefore: time.Now().Add(time.Hour)}); err != nil {
+				t.Error(err)
+				return
+			}
+			ring.Remove("next")
+		}
+	}()
+
+	stress(t, func(int) error {
+		for i := 0; i < concurrencyIterations; i++ {
+			if _, err := cm.Verify(cred); err != nil {
+				return err
+			}
+			fresh, err := cm.Create(time.Now(), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO)
+			if err != nil {
+				return err
+			}
+			if _, err := cm.Verify(fresh); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	close(stop)
+	<-rotated
+}
@@ -1,7 +1,10 @@
 package credentials
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -97,12 +100,19 @@ func TestVerifyErrors(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create valid credential: %v", err)
 	}
-	validCred.Mac = []byte("invalid mac")
+	validCred.Mac = make([]byte, MACLength)
 	_, err = cm.Verify(validCred)
-	if err != MismatchError {
+	if !errors.Is(err, MismatchError) {
 		t.Errorf("Expected MismatchError, got %v", err)
 	}
 
+	// Test with a MAC of the wrong length
+	validCred.Mac = []byte("invalid mac")
+	_, err = cm.Verify(validCred)
+	if !errors.Is(err, ErrInvalidMACLength) || errors.Is(err, MismatchError) {
+		t.Errorf("Expected ErrInvalidMACLength, got %v", err)
+	}
+
 	// Test with nil Credential field
 	nilFieldCred := &AuthenticatedCredential{
 		Credential: nil,
@@ -114,10 +124,25 @@ func TestVerifyErrors(t *testing.T) {
 	}
 }
 
+// TestCheckerPoolNotExposed tests that callers can't reach the manager's pool, and so can't poison it
+func TestCheckerPoolNotExposed(t *testing.T) {
+	managerType := reflect.TypeOf(new(CredentialManager))
+	for _, name := range []string{"Get", "Put"} {
+		if _, ok := managerType.MethodByName(name); ok {
+			t.Errorf("CredentialManager exposes the pool's %s method", name)
+		}
+	}
+	for i := 0; i < managerType.Elem().NumField(); i++ {
+		if field := managerType.Elem().Field(i); field.IsExported() || field.Anonymous {
+			t.Errorf("CredentialManager has exported or embedded field %s", field.Name)
+		}
+	}
+}
+
 // TestMemoryError simulates a memory allocation error
 func TestMemoryError(t *testing.T) {
 	cm := &CredentialManager{
-		p: sync.Pool{
+		checkers: sync.Pool{
 			New: func() interface{} {
 				return "not a checker"
 			},
@@ -144,3 +169,58 @@ func TestMemoryError(t *testing.T) {
 		t.Errorf("Expected MemoryError, got %v", err)
 	}
 }
+
+// TestVerificationErrorFields tests the structured context carried by verification failures
+func TestVerificationErrorFields(t *testing.T) {
+	cm := NewCredentialManager([]byte("test secret"))
+	nodeID := []byte{0xde, 0xad, 0xbe, 0xef, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01}
+	cred, err := cm.Create(time.Unix(1700000000, 0), nodeID, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred.Mac[0] ^= 1
+
+	_, err = cm.Verify(cred)
+	var fe FieldsError
+	if !errors.As(err, &fe) {
+		t.Fatalf("Expected a FieldsError, got %T", err)
+	}
+	if !errors.Is(err, MismatchError) {
+		t.Errorf("Expected the error to wrap MismatchError, got %v", err)
+	}
+
+	// The fields are a snapshot, unaffected by later changes to the credential
+	cred.Credential.NodeId[0] = 0
+	fields := fe.Fields()
+	expected := map[string]any{
+		"node_id":       "0xdeadbeef00000000000000000000000000000001",
+		"timestamp":     int64(1700000000),
+		"operator_type": "OT_SOLO",
+		"reason":        MismatchError.Error() + " (verifier key fp=" + cm.Fingerprint() + ")",
+	}
+	for k, v := range expected {
+		if fields[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, fields[k])
+		}
+	}
+}
+
+// TestErrorCategory tests that specific errors are told apart from the errors they wrap
+func TestErrorCategory(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected string
+	}{
+		{ErrAADMismatch, "aad_mismatch"},
+		{MismatchError, "mac_mismatch"},
+		{ErrPasswordTooLarge, "malformed"},
+		{&VerificationError{Err: ErrRevoked}, "revoked"},
+		{&VerificationError{Err: context.DeadlineExceeded}, "deadline_exceeded"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tc := range testCases {
+		if category := ErrorCategory(tc.err); category != tc.expected {
+			t.Errorf("Expected %s for %v, got %s", tc.expected, tc.err, category)
+		}
+	}
+}
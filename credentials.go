@@ -2,69 +2,335 @@ package credentials
 
 import (
 	"bytes"
+	"context"
+	"crypto/cipher"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"hash"
 	"io"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/Rocket-Rescue-Node/credentials/pb"
 	"github.com/Rocket-Rescue-Node/credentials/words"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 var hashAlgo = sha256.New
 
 type OperatorType = pb.OperatorType
+
+// AuthenticatedCredential is a credential and the MACs authenticating it.
+// Any number of goroutines may read, encode or verify one at once, as none of those modify it.
+// Decoding into it, and the setters such as SetMetadata, must not run concurrently with anything else using it.
 type AuthenticatedCredential pb.AuthenticatedCredential
 
 type jsonAuthenticatedCredential struct {
-	NodeID       string       `json:"node_id"`
-	Timestamp    int64        `json:"timestamp"`
-	OperatorType OperatorType `json:"operator_type"`
-	Mac          string       `json:"mac"`
+	NodeID           string            `json:"node_id"`
+	Timestamp        *jsonTimestamp    `json:"timestamp,omitempty"`
+	IssuedAt         string            `json:"issued_at,omitempty"`
+	OperatorType     *jsonOperatorType `json:"operator_type,omitempty"`
+	OperatorTypeName string            `json:"operator_type_name,omitempty"`
+	Nonce            string            `json:"nonce,omitempty"`
+	CredentialID     string            `json:"credential_id,omitempty"`
+	ExpiresAt        int64             `json:"expires_at,omitempty"`
+	Audience         string            `json:"audience,omitempty"`
+	Issuer           string            `json:"issuer,omitempty"`
+	Scopes           uint64            `json:"scopes,omitempty"`
+	ChainID          uint64            `json:"chain_id,omitempty"`
+	PartnerID        string            `json:"partner_id,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	BundleNodeIDs    []string          `json:"bundle_node_ids,omitempty"`
+	FeeRecipient     string            `json:"fee_recipient,omitempty"`
+	Version          uint32            `json:"version,omitempty"`
+	ScopeNames       []string          `json:"scope_names,omitempty"`
+	// UnknownFields holds the wire encoding of fields of the credential this version doesn't know, so they survive
+	// a round trip through JSON and the MAC, which covers them, still verifies
+	UnknownFields  string         `json:"unknown_fields,omitempty"`
+	Mac            string         `json:"mac"`
+	AdditionalMacs []jsonKeyedMac `json:"additional_macs,omitempty"`
+}
+
+type jsonKeyedMac struct {
+	KeyID string `json:"key_id"`
+	Mac   string `json:"mac"`
+}
+
+// jsonOperatorType marshals as the enum's number, but unmarshals from either the number or the enum's name
+type jsonOperatorType OperatorType
+
+func (o *jsonOperatorType) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var name string
+		if err := json.Unmarshal(data, &name); err != nil {
+			return err
+		}
+		ot, err := operatorTypeFromName(name)
+		if err != nil {
+			return err
+		}
+		*o = jsonOperatorType(ot)
+		return nil
+	}
+
+	var n int32
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*o = jsonOperatorType(n)
+	return nil
+}
+
+// maxJSONTimestampSeconds bounds the magnitude of numeric JSON timestamps. It is in the year 5138, while every
+// timestamp in milliseconds since 1973 exceeds it, so larger numbers are taken to be mistaken milliseconds.
+const maxJSONTimestampSeconds = 100_000_000_000
+
+// jsonTimestamp marshals as Unix seconds, but unmarshals from either Unix seconds or an RFC3339 string
+type jsonTimestamp int64
+
+func (ts *jsonTimestamp) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		seconds, err := parseJSONTime("timestamp", s)
+		if err != nil {
+			return err
+		}
+		*ts = jsonTimestamp(seconds)
+		return nil
+	}
+
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("timestamp %s is neither Unix seconds nor RFC3339", data)
+	}
+	if n >= maxJSONTimestampSeconds || n <= -maxJSONTimestampSeconds {
+		return fmt.Errorf("timestamp %d is too large for Unix seconds, and may be in milliseconds", n)
+	}
+	*ts = jsonTimestamp(n)
+	return nil
+}
+
+// parseJSONTime parses the RFC3339 string s of the named field to Unix seconds.
+// Credentials only hold whole seconds, so strings with fractional seconds are rejected rather than truncated.
+func parseJSONTime(name, s string) (int64, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q is not RFC3339: %w", name, s, err)
+	}
+	if t.Nanosecond() != 0 {
+		return 0, fmt.Errorf("%s %q has fractional seconds", name, s)
+	}
+	return t.Unix(), nil
+}
+
+// operatorTypeName returns the enum name of ot from the proto descriptor, or "" if ot isn't a defined value
+func operatorTypeName(ot OperatorType) string {
+	v := ot.Descriptor().Values().ByNumber(ot.Number())
+	if v == nil {
+		return ""
+	}
+	return string(v.Name())
+}
+
+// operatorTypeFromName looks up an OperatorType by its enum name
+func operatorTypeFromName(name string) (OperatorType, error) {
+	v := OperatorType(0).Descriptor().Values().ByName(protoreflect.Name(name))
+	if v == nil {
+		return 0, fmt.Errorf("%w %q", ErrUnknownOperatorType, name)
+	}
+	return OperatorType(v.Number()), nil
 }
 
 func (ac *AuthenticatedCredential) Pb() *pb.AuthenticatedCredential {
 	return (*pb.AuthenticatedCredential)(ac)
 }
 
+// Reset clears the credential, its MAC and unknown fields included, so it can be reused, e.g. from a sync.Pool,
+// without anything of its previous contents surviving. Decoding into it with Base64URLDecode, UnmarshalJSON or
+// UnmarshalText replaces its contents anyway; Reset ensures a credential whose decoding failed holds nothing stale.
+func (ac *AuthenticatedCredential) Reset() {
+	ac.Pb().Reset()
+}
+
+// ID returns the hex encoded credential ID, or "" for credentials issued without one
+func (ac *AuthenticatedCredential) ID() string {
+	return hex.EncodeToString(ac.Credential.GetCredentialId())
+}
+
 func (ac *AuthenticatedCredential) MarshalJSON() ([]byte, error) {
-	var mac bytes.Buffer
-	nodeID := "0x" + hex.EncodeToString(ac.Credential.NodeId)
-	encoder := base64.NewEncoder(base64.URLEncoding, &mac)
-	_, err := encoder.Write(ac.Mac)
-	if err != nil {
-		return nil, err
+	return ac.marshalJSON(jsonConfig{})
+}
+
+func (ac *AuthenticatedCredential) marshalJSON(cfg jsonConfig) ([]byte, error) {
+	e := jsonEncoders.Get().(*jsonEncoder)
+	defer e.release()
+
+	// Every hex and base64 field is encoded into one buffer, which is converted to a string once and then sliced
+	credential := ac.Credential
+	e.add(appendHex(append(e.buf, "0x"...), credential.NodeId))
+	e.add(appendBase64(e.buf, base64.URLEncoding, credential.Nonce))
+	e.add(appendHex(e.buf, credential.CredentialId))
+	if len(credential.FeeRecipient) > 0 {
+		e.add(appendHex(append(e.buf, "0x"...), credential.FeeRecipient))
+	} else {
+		e.add(e.buf)
+	}
+	e.add(appendBase64(e.buf, base64.URLEncoding, ac.Mac))
+	e.add(appendBase64(e.buf, base64.URLEncoding, credential.ProtoReflect().GetUnknown()))
+	for _, nodeID := range credential.BundleNodeIds {
+		e.add(appendHex(append(e.buf, "0x"...), nodeID))
+	}
+	for _, km := range ac.AdditionalMacs {
+		e.add(appendHex(e.buf, km.KeyId))
+		e.add(appendBase64(e.buf, base64.URLEncoding, km.Mac))
+	}
+	fields := string(e.buf)
+	field := func(i int) string {
+		return fields[e.ends[i]:e.ends[i+1]]
 	}
-	encoder.Close()
 
-	return json.Marshal(&jsonAuthenticatedCredential{
-		NodeID:       nodeID,
-		Timestamp:    ac.Credential.Timestamp,
-		OperatorType: ac.Credential.OperatorType,
-		Mac:          mac.String(),
-	})
+	j := &e.j
+	next := 6
+	for range credential.BundleNodeIds {
+		j.BundleNodeIDs = append(j.BundleNodeIDs, field(next))
+		next++
+	}
+	for range ac.AdditionalMacs {
+		j.AdditionalMacs = append(j.AdditionalMacs, jsonKeyedMac{KeyID: field(next), Mac: field(next + 1)})
+		next += 2
+	}
+
+	e.operatorType = jsonOperatorType(credential.OperatorType)
+	e.timestamp = jsonTimestamp(credential.Timestamp)
+	j.NodeID = field(0)
+	if cfg.timestampFormat != JSONTimestampRFC3339 {
+		j.Timestamp = &e.timestamp
+	}
+	if cfg.timestampFormat != JSONTimestampUnix {
+		issuedAt, err := time.Unix(credential.Timestamp, 0).UTC().MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		j.IssuedAt = string(issuedAt)
+	}
+	j.OperatorType = &e.operatorType
+	j.OperatorTypeName = operatorTypeName(credential.OperatorType)
+	j.Nonce = field(1)
+	j.CredentialID = field(2)
+	j.ExpiresAt = credential.ExpiresAt
+	j.Audience = credential.Audience
+	j.Issuer = credential.Issuer
+	j.Scopes = credential.Scopes
+	j.ChainID = credential.ChainId
+	j.PartnerID = credential.PartnerId
+	j.Metadata = credential.Metadata
+	j.FeeRecipient = field(3)
+	j.Version = credential.Version
+	j.ScopeNames = credential.ScopeNames
+	j.Mac = field(4)
+	j.UnknownFields = field(5)
+	return json.Marshal(j)
+}
+
+// jsonEncoder holds the buffers MarshalJSON reuses between calls
+type jsonEncoder struct {
+	// buf holds the encoded fields back to back, and ends[i+1] is where field i ends
+	buf          []byte
+	ends         []int
+	j            jsonAuthenticatedCredential
+	operatorType jsonOperatorType
+	timestamp    jsonTimestamp
+}
+
+var jsonEncoders = sync.Pool{New: func() any { return &jsonEncoder{ends: []int{0}} }}
+
+// maxPooledBufferSize bounds the buffers returned to pools, so one huge credential doesn't pin its buffers
+const maxPooledBufferSize = 4096
+
+// add records that buf, which was appended to e.buf, ends a field
+func (e *jsonEncoder) add(buf []byte) {
+	e.buf = buf
+	e.ends = append(e.ends, len(buf))
+}
+
+// release resets e and returns it to the pool, dropping the references to the credential marshaled
+func (e *jsonEncoder) release() {
+	if cap(e.buf) > maxPooledBufferSize {
+		return
+	}
+	e.buf = e.buf[:0]
+	e.ends = e.ends[:1]
+	e.j = jsonAuthenticatedCredential{
+		BundleNodeIDs:  clearStrings(e.j.BundleNodeIDs),
+		AdditionalMacs: clearKeyedMacs(e.j.AdditionalMacs),
+	}
+	jsonEncoders.Put(e)
+}
+
+func clearStrings(s []string) []string {
+	for i := range s {
+		s[i] = ""
+	}
+	return s[:0]
+}
+
+func clearKeyedMacs(s []jsonKeyedMac) []jsonKeyedMac {
+	for i := range s {
+		s[i] = jsonKeyedMac{}
+	}
+	return s[:0]
+}
+
+// appendHex appends the hex encoding of src to dst
+func appendHex(dst []byte, src []byte) []byte {
+	n := len(dst)
+	dst = slices.Grow(dst, hex.EncodedLen(len(src)))
+	dst = dst[:n+hex.EncodedLen(len(src))]
+	hex.Encode(dst[n:], src)
+	return dst
+}
+
+// appendBase64 appends the encoding of src to dst
+func appendBase64(dst []byte, encoding *base64.Encoding, src []byte) []byte {
+	n := len(dst)
+	dst = slices.Grow(dst, encoding.EncodedLen(len(src)))
+	dst = dst[:n+encoding.EncodedLen(len(src))]
+	encoding.Encode(dst[n:], src)
+	return dst
 }
 
+// UnmarshalJSON decodes a credential marshaled by MarshalJSON or EncodeJSON. Its timestamp may be given as Unix
+// seconds or an RFC3339 string of whole seconds, in the timestamp field, the issued_at field or both, which must
+// then agree. Numbers too large to be Unix seconds are rejected as likely milliseconds.
+// Its errors match ErrMalformedCredential.
 func (ac *AuthenticatedCredential) UnmarshalJSON(data []byte) error {
+	if err := ac.unmarshalJSON(data, jsonConfig{}); err != nil {
+		return markMalformed(err)
+	}
+	return nil
+}
+
+func (ac *AuthenticatedCredential) unmarshalJSON(data []byte, cfg jsonConfig) error {
 	var j jsonAuthenticatedCredential
 	ac.Pb().Reset()
 
-	if err := json.Unmarshal(data, &j); err != nil {
+	if err := cfg.unmarshal(data, &j); err != nil {
 		return err
 	}
 
 	ac.Credential = &pb.Credential{}
-	decoder := base64.NewDecoder(base64.URLEncoding, bytes.NewReader([]byte(j.Mac)))
-	decoded, err := io.ReadAll(decoder)
+	decoded, err := decodeBase64URL(j.Mac)
 	if err != nil {
 		return err
 	}
@@ -74,79 +340,309 @@ func (ac *AuthenticatedCredential) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	// Either form of the operator type may be present, but they must agree
+	var operatorType OperatorType
+	if j.OperatorType != nil {
+		operatorType = OperatorType(*j.OperatorType)
+	}
+	if j.OperatorTypeName != "" {
+		named, err := operatorTypeFromName(j.OperatorTypeName)
+		if err != nil {
+			return err
+		}
+		if j.OperatorType != nil && named != operatorType {
+			return fmt.Errorf("operator_type %d does not match operator_type_name %q", operatorType, j.OperatorTypeName)
+		}
+		operatorType = named
+	}
+
+	// Either form of the timestamp may be present too, but they must agree
+	var timestamp int64
+	if j.Timestamp != nil {
+		timestamp = int64(*j.Timestamp)
+	}
+	if j.IssuedAt != "" {
+		issuedAt, err := parseJSONTime("issued_at", j.IssuedAt)
+		if err != nil {
+			return err
+		}
+		if j.Timestamp != nil && issuedAt != timestamp {
+			return fmt.Errorf("timestamp %d does not match issued_at %q", timestamp, j.IssuedAt)
+		}
+		timestamp = issuedAt
+	}
+
+	nonce, err := decodeBase64URL(j.Nonce)
+	if err != nil {
+		return err
+	}
+	if len(nonce) > 0 {
+		ac.Credential.Nonce = nonce
+	}
+
+	credentialID, err := hex.DecodeString(j.CredentialID)
+	if err != nil {
+		return err
+	}
+	if len(credentialID) > 0 {
+		ac.Credential.CredentialId = credentialID
+	}
+
+	for _, km := range j.AdditionalMacs {
+		keyID, err := hex.DecodeString(km.KeyID)
+		if err != nil {
+			return err
+		}
+		mac, err := decodeBase64URL(km.Mac)
+		if err != nil {
+			return err
+		}
+		ac.AdditionalMacs = append(ac.AdditionalMacs, &pb.KeyedMac{KeyId: keyID, Mac: mac})
+	}
+
 	ac.Credential.NodeId = nodeID
-	ac.Credential.OperatorType = j.OperatorType
-	ac.Credential.Timestamp = j.Timestamp
+	ac.Credential.OperatorType = operatorType
+	ac.Credential.Timestamp = timestamp
+	ac.Credential.ExpiresAt = j.ExpiresAt
+	ac.Credential.Audience = j.Audience
+	ac.Credential.Issuer = j.Issuer
+	ac.Credential.Scopes = j.Scopes
+	ac.Credential.ChainId = j.ChainID
+	ac.Credential.PartnerId = j.PartnerID
+	if len(j.Metadata) > 0 {
+		ac.Credential.Metadata = j.Metadata
+	}
+	for _, bundleNodeID := range j.BundleNodeIDs {
+		bundled, err := hex.DecodeString(strings.TrimPrefix(bundleNodeID, "0x"))
+		if err != nil {
+			return err
+		}
+		ac.Credential.BundleNodeIds = append(ac.Credential.BundleNodeIds, bundled)
+	}
+	if j.FeeRecipient != "" {
+		feeRecipient, err := hex.DecodeString(strings.TrimPrefix(j.FeeRecipient, "0x"))
+		if err != nil {
+			return err
+		}
+		ac.Credential.FeeRecipient = feeRecipient
+	}
+	ac.Credential.Version = j.Version
+	ac.Credential.ScopeNames = j.ScopeNames
+	if j.UnknownFields != "" {
+		unknown, err := decodeBase64URL(j.UnknownFields)
+		if err != nil {
+			return err
+		}
+		if err := validateUnknownFields(unknown); err != nil {
+			return err
+		}
+		ac.Credential.ProtoReflect().SetUnknown(unknown)
+	}
 	ac.Mac = decoded
+	if err := validateDecodedMACs(ac); err != nil {
+		return err
+	}
+	return validateDecoded(ac.Credential)
+}
+
+// validateUnknownFields checks that unknown holds well-formed fields which pb.Credential doesn't define.
+// Decoding binary credentials guarantees this, but unknown fields from JSON could otherwise smuggle in
+// a second copy of a known field, or bytes which aren't fields at all.
+func validateUnknownFields(unknown []byte) error {
+	if len(unknown) > MaxUnknownFieldBytes {
+		return fmt.Errorf("%w: %d bytes, at most %d allowed", ErrUnknownFieldsTooLarge, len(unknown), MaxUnknownFieldBytes)
+	}
+	fields := (*pb.Credential)(nil).ProtoReflect().Descriptor().Fields()
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeField(unknown)
+		if n < 0 {
+			return fmt.Errorf("%w: unknown fields: %w", ErrMalformedProto, protowire.ParseError(n))
+		}
+		if typ == protowire.StartGroupType || typ == protowire.EndGroupType {
+			return fmt.Errorf("%w: unknown field %d is a group", ErrMalformedProto, num)
+		}
+		if fields.ByNumber(num) != nil {
+			return fmt.Errorf("%w: unknown fields contain known field %d", ErrMalformedProto, num)
+		}
+		unknown = unknown[n:]
+	}
 	return nil
 }
 
+// Base64URLEncodeUsername encodes the node ID as a padded base64url username. See Encoder for other encodings.
+//
+// Deprecated: Use EncodeUsername, which fails for credentials without a node ID instead of returning "",
+// which would make a basic auth header no server accepts.
 func (ac *AuthenticatedCredential) Base64URLEncodeUsername() string {
-	var out bytes.Buffer
+	return Encoder{}.EncodeUsername(ac)
+}
 
-	encoder := base64.NewEncoder(base64.URLEncoding, &out)
-	_, err := encoder.Write(ac.Credential.NodeId)
-	if err != nil {
-		return ""
+// EncodeUsername encodes the node ID as a padded base64url username, failing with a SerializationError
+// for empty credentials and credentials without a node ID. See Encoder for other encodings.
+func (ac *AuthenticatedCredential) EncodeUsername() (string, error) {
+	if ac == nil || ac.Credential == nil {
+		return "", fmt.Errorf("%w: credential is empty", SerializationError)
 	}
-	encoder.Close()
-
-	return out.String()
+	if len(ac.Credential.NodeId) == 0 {
+		return "", fmt.Errorf("%w: credential has no node ID", SerializationError)
+	}
+	return Encoder{}.EncodeUsername(ac), nil
 }
 
+// Base64URLEncodePassword encodes the rest of the credential as a padded base64url password. See Encoder for other encodings.
 func (ac *AuthenticatedCredential) Base64URLEncodePassword() (string, error) {
-	var out bytes.Buffer
-
-	// Save the nodeId
-	nodeID := ac.Credential.NodeId
-	// Strip it to save space
-	ac.Credential.NodeId = nil
-	// Restore it when we're done
-	defer func() {
-		ac.Credential.NodeId = nodeID
-	}()
-
-	marshaled, err := proto.Marshal(ac.Pb())
-	if err != nil {
-		return "", err
-	}
+	return Encoder{}.EncodePassword(ac)
+}
 
-	// Encode the marshaled proto
-	encoder := base64.NewEncoder(base64.URLEncoding, &out)
-	_, err = encoder.Write(marshaled)
-	if err != nil {
-		return "", err
-	}
-	encoder.Close()
+// AppendUsername appends EncodeUsername's output to dst, without allocating if dst has room for it.
+// Unlike EncodeUsername, it doesn't check that the credential has a node ID.
+func (ac *AuthenticatedCredential) AppendUsername(dst []byte) []byte {
+	return Encoder{}.AppendUsername(dst, ac)
+}
+
+// AppendPassword appends Base64URLEncodePassword's output to dst. See Encoder.AppendPassword for when it allocates.
+func (ac *AuthenticatedCredential) AppendPassword(dst []byte) ([]byte, error) {
+	return Encoder{}.AppendPassword(dst, ac)
+}
 
-	return out.String(), nil
+// EncodedUsernameLen returns the length of EncodeUsername's output, without encoding it
+func (ac *AuthenticatedCredential) EncodedUsernameLen() int {
+	return Encoder{}.UsernameLen(ac)
 }
 
+// EncodedPasswordLen returns the length of Base64URLEncodePassword's output, without encoding it
+func (ac *AuthenticatedCredential) EncodedPasswordLen() int {
+	return Encoder{}.PasswordLen(ac)
+}
+
+// Base64URLDecode decodes a username and password produced by any Encoder, with or without padding or compression.
+// Usernames must decode to a whole node ID, or fail with ErrInvalidUsername. Passwords holding nothing fail with
+// ErrEmptyPassword, ones carrying a node ID other than the username's with a *NodeIDMismatchError, and ones with more
+// than MaxUnknownFieldBytes of fields unknown to this version (by default, any) with ErrUnknownFieldsTooLarge.
+// Passwords larger than MaxPasswordBytes, before or after decompression, are rejected.
+// Whatever ac held before is replaced entirely by the decoded credential.
 func (ac *AuthenticatedCredential) Base64URLDecode(username string, password string) error {
-	decoder := base64.NewDecoder(base64.URLEncoding, bytes.NewReader([]byte(username)))
-	nodeID, err := io.ReadAll(decoder)
+	nodeID, decoded, err := decodeUsernamePassword(username, password, MaxPasswordBytes)
 	if err != nil {
 		return err
 	}
 
-	decoder = base64.NewDecoder(base64.URLEncoding, bytes.NewReader([]byte(password)))
-	decoded, err := io.ReadAll(decoder)
+	newCred, err := decodeRaw(nodeID, decoded, MaxPasswordBytes)
 	if err != nil {
 		return err
 	}
 
-	newCred := AuthenticatedCredential{}
-	err = proto.Unmarshal(decoded, newCred.Pb())
+	// Take over the decoded messages, rather than merging them into whatever ac held before,
+	// so nothing of ac's previous contents, unknown fields included, survives
+	ac.Pb().Reset()
+	ac.Credential = newCred.Credential
+	ac.Mac = newCred.Mac
+	ac.AdditionalMacs = newCred.AdditionalMacs
+	ac.Pb().ProtoReflect().SetUnknown(newCred.Pb().ProtoReflect().GetUnknown())
+	return nil
+}
+
+// decodeRaw rebuilds a credential from its node ID and the bytes of its password, after base64 decoding,
+// rejecting passwords larger than limit bytes before or after decompression
+func decodeRaw(nodeID []byte, password []byte, limit int) (*AuthenticatedCredential, error) {
+	if len(nodeID) > MaxUsernameBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrUsernameTooLarge, MaxUsernameBytes)
+	}
+	if len(password) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrPasswordTooLarge, limit)
+	}
+	if err := validateUsernameNodeID(nodeID); err != nil {
+		return nil, err
+	}
+	out, err := decodePassword(password, limit)
 	if err != nil {
+		return nil, err
+	}
+	if err := out.SetNodeID(nodeID); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// validateDecodedMessage applies the checks every binary decoder makes: the bound on unknown fields, then
+// validateDecodedMACs and validateDecoded
+func validateDecodedMessage(ac *AuthenticatedCredential) error {
+	if size := unknownFieldsSize(ac); size > MaxUnknownFieldBytes {
+		return fmt.Errorf("%w: %d bytes, at most %d allowed", ErrUnknownFieldsTooLarge, size, MaxUnknownFieldBytes)
+	}
+	if err := validateDecodedMACs(ac); err != nil {
 		return err
 	}
+	return validateDecoded(ac.Credential)
+}
 
-	ac.Pb().Reset()
-	proto.Merge(ac.Pb(), newCred.Pb())
+// decodePassword rebuilds a credential from the bytes of its password alone, after base64 decoding,
+// rejecting passwords larger than limit bytes before or after decompression
+func decodePassword(password []byte, limit int) (*AuthenticatedCredential, error) {
+	if len(password) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrPasswordTooLarge, limit)
+	}
+	password, err := decompressPassword(password, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown fields are kept, so the check below sees them, rather than them being silently dropped
+	out := new(AuthenticatedCredential)
+	if err := (proto.UnmarshalOptions{DiscardUnknown: false}).Unmarshal(password, out.Pb()); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedProto, err)
+	}
+	if len(out.Mac) == 0 && len(out.AdditionalMacs) == 0 && proto.Size(out.Credential) == 0 {
+		return nil, ErrEmptyPassword
+	}
+	if err := validateDecodedMessage(out); err != nil {
+		return nil, err
+	}
+	if out.Credential == nil {
+		out.Credential = new(pb.Credential)
+	}
+	return out, nil
+}
+
+// SetNodeID sets the node ID of a credential decoded with DecodePassword, e.g. to one decoded with DecodeUsername,
+// completing it as Base64URLDecode would have. nodeID must be NodeIDLength bytes, or it fails with ErrInvalidUsername.
+// The node ID is carried by the username, and encoders strip it from the password, so if the credential already
+// has one it must be the same, or SetNodeID fails with a *NodeIDMismatchError.
+func (ac *AuthenticatedCredential) SetNodeID(nodeID []byte) error {
+	if err := validateUsernameNodeID(nodeID); err != nil {
+		return err
+	}
+	if embedded := ac.Credential.GetNodeId(); len(embedded) > 0 && !bytes.Equal(embedded, nodeID) {
+		return &NodeIDMismatchError{Username: nodeID, Password: embedded}
+	}
+	if ac.Credential == nil {
+		ac.Credential = new(pb.Credential)
+	}
 	ac.Credential.NodeId = nodeID
 	return nil
 }
 
+// validateUsernameNodeID rejects node IDs decoded from usernames which aren't NodeIDLength bytes with ErrInvalidUsername
+func validateUsernameNodeID(nodeID []byte) error {
+	if len(nodeID) != NodeIDLength {
+		return fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidUsername, NodeIDLength, len(nodeID))
+	}
+	return nil
+}
+
+// VerifyRaw rebuilds a credential from its node ID and password bytes, as carried by binary protocols
+// which skip the base64 encoding, and verifies it.
+func (c *CredentialManager) VerifyRaw(nodeID []byte, passwordProto []byte) (*AuthenticatedCredential, error) {
+	out, err := decodeRaw(nodeID, passwordProto, c.passwordLimit())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Verify(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 type secret struct {
 	id   *ID
 	hmac hash.Hash
@@ -155,14 +651,198 @@ type secret struct {
 type checker struct {
 	primary secret
 	extras  []secret
+	// buf holds the canonical encoding of the credential being authenticated
+	buf []byte
+	// sum holds the most recently computed MAC
+	sum []byte
+}
+
+// getChecker takes a checker from the manager's pool
+func (c *CredentialManager) getChecker() (*checker, error) {
+	v, ok := c.checkers.Get().(*checker)
+	if !ok {
+		return nil, MemoryError
+	}
+	return v, nil
+}
+
+// putChecker returns v to the manager's pool
+func (c *CredentialManager) putChecker(v *checker) {
+	c.checkers.Put(v)
+}
+
+// secretForKeyID returns the secret whose key ID is keyID, or nil
+func (v *checker) secretForKeyID(keyID []byte) *secret {
+	if bytes.Equal(v.primary.keyID(), keyID) {
+		return &v.primary
+	}
+	for i := range v.extras {
+		if bytes.Equal(v.extras[i].keyID(), keyID) {
+			return &v.extras[i]
+		}
+	}
+	return nil
+}
+
+// matches reports whether mac authenticates data under secret s, leaving s reset
+func (v *checker) matches(s *secret, data []byte, mac []byte) bool {
+	s.hmac.Write(data)
+	v.sum = s.hmac.Sum(v.sum[:0])
+	s.hmac.Reset()
+	return hmac.Equal(v.sum, mac)
+}
+
+// keyID identifies the key that computed a KeyedMac
+func (s *secret) keyID() []byte {
+	return s.id.bytes[:keyIDLength]
+}
+
+// keyIDLength is the number of ID bytes used to tag additional MACs with the key that computed them
+const keyIDLength = 8
+
+// macKey is a single key with its own pool of hashes, used where keys don't share a checker
+type macKey struct {
+	id          *ID
+	fingerprint string
+	// pool is a pool of hmac hashes keyed with the key
+	pool sync.Pool
+}
+
+func newMACKey(key []byte) *macKey {
+	ks := newKeySchedule(key)
+	return &macKey{
+		id:          idFromKey(key),
+		fingerprint: KeyFingerprint(key),
+		pool: sync.Pool{
+			New: func() any {
+				return ks.newHMAC()
+			},
+		},
+	}
+}
+
+func (k *macKey) keyID() []byte {
+	return k.id.bytes[:keyIDLength]
+}
+
+func (k *macKey) mac(data []byte) []byte {
+	h, ok := k.pool.Get().(hash.Hash)
+	if !ok {
+		return nil
+	}
+	defer k.pool.Put(h)
+
+	h.Write(data)
+	out := h.Sum(nil)
+	h.Reset()
+	return out
+}
+
+// verify reports whether the key authenticates data with the credential's MAC or its own additional MAC
+func (k *macKey) verify(data []byte, credential *AuthenticatedCredential) bool {
+	mac := k.mac(data)
+	if mac == nil {
+		return false
+	}
+	if hmac.Equal(mac, credential.Mac) {
+		return true
+	}
+	for _, km := range credential.AdditionalMacs {
+		if bytes.Equal(km.KeyId, k.keyID()) && hmac.Equal(mac, km.Mac) {
+			return true
+		}
+	}
+	return false
 }
 
-// CredentialManager authenticates and verifies rescue node credentials
+// CredentialManager authenticates and verifies rescue node credentials.
+// Once created, it is safe for concurrent use, so one manager can be shared by a whole process: every Create,
+// Verify, decoding and batch method may be called from any number of goroutines, with each goroutine drawing
+// its own hash state from a pool. Its KeyRing, if any, may be rotated meanwhile. Options must not be changed
+// after it is created, and the MACer, Revoker, hooks and other dependencies it is given must be safe for
+// concurrent use themselves.
 type CredentialManager struct {
-	// pool is a pool of checkers
-	id         *ID
-	partnerIDs []*ID
-	p          sync.Pool
+	id          *ID
+	partnerIDs  []*ID
+	fingerprint string
+	// keyFingerprints lists the fingerprints of all configured keys, for mismatch errors
+	keyFingerprints string
+	// ring, if set, supplies the keys instead of the fixed secrets in checkers
+	ring *KeyRing
+	// macer, if set, computes the MACs instead of the fixed secrets in checkers
+	macer MACer
+	// dual, if set, additionally signs every credential and is accepted by Verify
+	dual *macKey
+	// nonceChecker, if set, is consulted by Verify for credentials carrying a nonce
+	nonceChecker NonceChecker
+	// replayCache, if set, records the nonces accepted by VerifyOnce for replayTTL
+	replayCache ReplayCache
+	replayTTL   time.Duration
+	// requestVerifier, if set, recovers the signers of the requests IssueFromRequest accepts for requestWindow
+	requestVerifier RequestSignatureVerifier
+	requestWindow   time.Duration
+	// issuanceLimiter, if set, bounds how many credentials each node is issued
+	issuanceLimiter IssuanceLimiter
+	// revoker, if set, is consulted for every authentic credential
+	revoker         Revoker
+	revokerFailOpen bool
+	// timingHook, if set, is told how long each Create and Verify call took
+	timingHook TimingHook
+	// hooks, if set, are notified of every credential created and verified
+	hooks *Hooks
+	// auditSink, if set, records every verification attempt
+	auditSink AuditSink
+	// auditRecords, if set, is called with a record of every credential created and verification attempted
+	auditRecords func(AuditRecord)
+	// clock, if set, replaces time.Now
+	clock func() time.Time
+	// audience is stamped on created credentials, and required of verified ones
+	audience string
+	// chainID is stamped on created credentials, and required of verified ones
+	chainID uint64
+	// issuer is stamped on created credentials
+	issuer string
+	// allowedIssuers, if set, are the only issuers Verify accepts
+	allowedIssuers map[string]struct{}
+	// allowedOperatorTypes, if set, are the only operator types Verify accepts
+	allowedOperatorTypes map[OperatorType]struct{}
+	// rejectZeroOperatorType makes Create reject the zero operator type
+	rejectZeroOperatorType bool
+	// allowedPartners, if set, are the only partners besides first-party Verify accepts
+	allowedPartners map[string]struct{}
+	// legacyScopes makes VerifyWithRequiredScopes treat credentials without scopes as having all of them
+	legacyScopes bool
+	// requiredScopeNames, if set, must all be granted by the credentials Verify accepts
+	requiredScopeNames []string
+	// randomNonce makes Create give every credential a random nonce
+	randomNonce bool
+	// deterministicIDs makes Create derive credential IDs from the credentials' contents instead of drawing them
+	deterministicIDs bool
+	// random, if set, replaces crypto/rand as the source of credential IDs and nonces
+	random io.Reader
+	// defaultValidity, if positive, is how long after their timestamp created credentials expire
+	defaultValidity time.Duration
+	// validity is how long credentials without an embedded expiry are valid for
+	validity ValidityPolicy
+	// createTolerance, if non-zero, bounds how far Create's timestamps may be from the clock
+	createTolerance time.Duration
+	// minTimestamp and maxTimestamp, if non-zero, replace the default bounds of Create's timestamps
+	minTimestamp time.Time
+	maxTimestamp time.Time
+	// maxCredentialSize, if positive, replaces MaxPasswordBytes as the bound on the passwords VerifyRaw and
+	// VerifyFromBasicAuth decode
+	maxCredentialSize int
+	// refreshGrace is how long past their expiry Refresh accepts credentials
+	refreshGrace time.Duration
+	// clockSkew, if clockSkewSet, replaces DefaultClockSkew as how far in the future Verify accepts timestamps
+	clockSkew    time.Duration
+	clockSkewSet bool
+	// verificationCache, if set, remembers the keys that authenticated recently verified credentials
+	verificationCache *verificationCache
+	// sealAEADs are derived from the primary secret followed by the extra secrets, for Seal and Open
+	sealAEADs []cipher.AEAD
+	// checkers pools the checkers of managers with fixed secrets. It is only accessed through getChecker and putChecker.
+	checkers sync.Pool
 }
 
 func idFromKey(key []byte) *ID {
@@ -180,24 +860,39 @@ func idFromKey(key []byte) *ID {
 // Credentials are created with `key` but validated against `key` and all `extraSecrets`.
 // Under the hood, the library uses sha256 as an hmac hash, so keys should be at least 32 bytes for full security.
 func NewCredentialManager(key []byte, extraSecrets ...[]byte) *CredentialManager {
+	return NewCredentialManagerWithOptions(key, extraSecrets)
+}
+
+// NewCredentialManagerWithOptions is like NewCredentialManager, but additionally applies opts
+func NewCredentialManagerWithOptions(key []byte, extraSecrets [][]byte, opts ...Option) *CredentialManager {
 	id := idFromKey(key)
 
+	// Key the hashes once, so pool misses only copy their states
+	schedule := newKeySchedule(key)
+	extraIDs := make([]*ID, len(extraSecrets))
+	extraSchedules := make([]*keySchedule, len(extraSecrets))
+	for i, s := range extraSecrets {
+		extraIDs[i] = idFromKey(s)
+		extraSchedules[i] = newKeySchedule(s)
+	}
+
 	out := &CredentialManager{
-		id: id,
-		p: sync.Pool{
+		id:              id,
+		fingerprint:     KeyFingerprint(key),
+		keyFingerprints: fingerprints(key, extraSecrets),
+		checkers: sync.Pool{
 			New: func() any {
-				numExtras := len(extraSecrets)
 				out := new(checker)
 				out.primary = secret{
 					id:   id,
-					hmac: hmac.New(hashAlgo, key),
+					hmac: schedule.newHMAC(),
 				}
-				if numExtras > 0 {
-					out.extras = make([]secret, numExtras)
-					for i, s := range extraSecrets {
+				if len(extraSchedules) > 0 {
+					out.extras = make([]secret, len(extraSchedules))
+					for i, ks := range extraSchedules {
 						out.extras[i] = secret{
-							id:   idFromKey(s),
-							hmac: hmac.New(hashAlgo, s),
+							id:   extraIDs[i],
+							hmac: ks.newHMAC(),
 						}
 					}
 				}
@@ -206,86 +901,491 @@ func NewCredentialManager(key []byte, extraSecrets ...[]byte) *CredentialManager
 		},
 	}
 	out.partnerIDs = make([]*ID, 0)
+	out.sealAEADs = []cipher.AEAD{sealer(key)}
 	for _, s := range extraSecrets {
 		out.partnerIDs = append(out.partnerIDs, idFromKey(s))
+		out.sealAEADs = append(out.sealAEADs, sealer(s))
 	}
+	out.apply(opts)
 	return out
 }
 
-func (c *CredentialManager) authenticateCredential(credential *AuthenticatedCredential) error {
-	// Serialize just the inner message so we can authenticate it and add it to the outer message
-	bytes, err := proto.Marshal(credential.Credential)
+// now returns the current time according to the manager's clock
+func (c *CredentialManager) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return time.Now()
+}
+
+// mismatchError annotates err with the fingerprints of the keys that failed to authenticate a credential
+func (c *CredentialManager) mismatchError(err error) error {
+	if c.keyFingerprints == "" {
+		return err
+	}
+	return fmt.Errorf("%w (verifier key fp=%s)", err, c.keyFingerprints)
+}
+
+// NewCredentialManagerFromKeyRing creates a CredentialManager whose keys are managed by ring.
+// Create signs with the ring's signing key at the time of the call, failing with ErrNoActiveKey if there is none,
+// and Verify accepts any key valid at the time of the call.
+func NewCredentialManagerFromKeyRing(ring *KeyRing, opts ...Option) *CredentialManager {
+	out := &CredentialManager{
+		ring: ring,
+	}
+	out.apply(opts)
+	return out
+}
+
+// ringMismatchError is the KeyRing equivalent of mismatchError, naming the ring keys that were tried
+func (c *CredentialManager) ringMismatchError(aad []byte, fps string) error {
+	err := MismatchError
+	if len(aad) > 0 {
+		err = ErrAADMismatch
+	}
+	if fps == "" {
+		return fmt.Errorf("%w (no valid verifier keys)", err)
+	}
+	return fmt.Errorf("%w (verifier key fp=%s)", err, fps)
+}
+
+func (c *CredentialManager) authenticateCredential(credential *AuthenticatedCredential, aad []byte) error {
+	return c.authenticateCredentialContext(context.Background(), credential, aad)
+}
+
+// authenticateCredentialContext is authenticateCredential, passing ctx to a ContextMACer
+func (c *CredentialManager) authenticateCredentialContext(ctx context.Context, credential *AuthenticatedCredential, aad []byte) error {
+	credential.AdditionalMacs = nil
+
+	if c.ring != nil {
+		data, err := appendMACInput(nil, credential.Credential, aad)
+		if err != nil {
+			return err
+		}
+		mac, err := c.ring.sign(c.now(), data)
+		if err != nil {
+			return err
+		}
+		credential.Mac = mac
+		return c.authenticateDual(credential, data)
+	}
+	if c.macer != nil {
+		return c.authenticateWithMACer(ctx, credential, aad)
+	}
+
+	v, err := c.getChecker()
 	if err != nil {
-		return errors.Join(err, SerializationError)
+		return err
 	}
+	defer c.putChecker(v)
 
-	v, ok := c.p.Get().(*checker)
-	if !ok {
-		return MemoryError
+	return c.authenticateWithChecker(v, credential, aad)
+}
+
+// authenticateWithChecker authenticates credential using the fixed secrets held by v
+func (c *CredentialManager) authenticateWithChecker(v *checker, credential *AuthenticatedCredential, aad []byte) error {
+	// Serialize just the inner message so we can authenticate it and add it to the outer message
+	buf, err := appendMACInput(v.buf[:0], credential.Credential, aad)
+	if err != nil {
+		return err
 	}
-	// defer stacks calls, so Put will always be called after the Reset() below
-	defer c.p.Put(v)
+	v.buf = buf
 
-	v.primary.hmac.Write(bytes)
+	v.primary.hmac.Write(v.buf)
 	credential.Mac = v.primary.hmac.Sum(nil)
-	defer v.primary.hmac.Reset()
+	v.primary.hmac.Reset()
 
+	return c.authenticateDual(credential, v.buf)
+}
+
+// authenticateDual adds a MAC under the dual key, if one is configured
+func (c *CredentialManager) authenticateDual(credential *AuthenticatedCredential, data []byte) error {
+	if c.dual == nil {
+		return nil
+	}
+	mac := c.dual.mac(data)
+	if mac == nil {
+		return MemoryError
+	}
+	credential.AdditionalMacs = append(credential.AdditionalMacs, &pb.KeyedMac{
+		KeyId: c.dual.keyID(),
+		Mac:   mac,
+	})
 	return nil
 }
 
-// Create makes a new credential and authenticates it, returning a protoc struct that can be marshaled/unmarshaled
+// Create makes a new credential and authenticates it, returning a protoc struct that can be marshaled/unmarshaled.
+// Credentials are given a random credential ID, drawn from the manager's random source like random nonces.
+// With WithDeterministicCredentialIDs, calls with identical arguments under the same key return byte-identical
+// credentials, unless WithRandomNonce makes the nonce differ. The same holds for every Create method.
 func (c *CredentialManager) Create(timestamp time.Time, nodeID []byte, OperatorType OperatorType) (*AuthenticatedCredential, error) {
-	if len(nodeID) != 20 {
-		return nil, fmt.Errorf("invalid nodeID length. Expected 20, got %d", len(nodeID))
+	return c.CreateContext(context.Background(), timestamp, nodeID, OperatorType)
+}
+
+// CreateContext is like Create, but passes ctx to the manager's MACer and hooks if they accept one,
+// and returns ctx.Err() if ctx is done before the credential is authenticated
+func (c *CredentialManager) CreateContext(ctx context.Context, timestamp time.Time, nodeID []byte, OperatorType OperatorType) (*AuthenticatedCredential, error) {
+	return c.createContext(ctx, timestamp, nodeID, OperatorType, nil, nil)
+}
+
+// CreateWithAAD is like Create, but additionally binds the credential to aad, e.g. the name of the service it is
+// intended for. The aad is covered by the MAC but not stored in the credential, so it must be passed to VerifyWithAAD.
+func (c *CredentialManager) CreateWithAAD(timestamp time.Time, nodeID []byte, OperatorType OperatorType, aad []byte) (*AuthenticatedCredential, error) {
+	return c.create(timestamp, nodeID, OperatorType, aad, nil)
+}
+
+// create validates the inputs, builds a credential, lets fill set any optional fields, and authenticates it
+func (c *CredentialManager) create(timestamp time.Time, nodeID []byte, OperatorType OperatorType, aad []byte, fill func(*pb.Credential) error) (*AuthenticatedCredential, error) {
+	return c.createContext(context.Background(), timestamp, nodeID, OperatorType, aad, fill)
+}
+
+// createContext is create, under ctx
+func (c *CredentialManager) createContext(ctx context.Context, timestamp time.Time, nodeID []byte, OperatorType OperatorType, aad []byte, fill func(*pb.Credential) error) (*AuthenticatedCredential, error) {
+	if c.timingHook != nil {
+		defer c.observe(OperationCreate, OperatorType, time.Now())
+	}
+	if err := validateNodeID(nodeID); err != nil {
+		return nil, err
+	}
+	if err := c.validateOperatorType(OperatorType); err != nil {
+		return nil, err
+	}
+	if err := c.validateTimestamp(timestamp); err != nil {
+		return nil, err
+	}
+	message, err := c.newCredential(timestamp, nodeID, OperatorType)
+	if err != nil {
+		return nil, err
+	}
+	if fill != nil {
+		if err := fill(message.Credential); err != nil {
+			return nil, err
+		}
+	}
+	c.deriveCredentialID(message.Credential)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	release, err := c.allowIssuance(message.NodeIDs()...)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.authenticateCredentialContext(ctx, message, aad); err != nil {
+		release()
+		return nil, err
+	}
+
+	c.notifyCreated(ctx, message)
+	return message, nil
+}
+
+// NodeIDLength is the length of a node ID, which is an Ethereum address
+const NodeIDLength = 20
+
+// defaultMinTimestamp and defaultMaxTimestamp are the Unix seconds of 2020-01-01 and 2200-01-01 UTC, the default
+// bounds of the timestamps Create accepts, so that uninitialized time.Time values, which are in year 1, and
+// nonsensical far-future ones aren't signed
+const (
+	defaultMinTimestamp = 1577836800
+	defaultMaxTimestamp = 7258118400
+)
+
+// timestampBounds returns the earliest timestamp Create accepts, and the first one after it that it doesn't
+func (c *CredentialManager) timestampBounds() (time.Time, time.Time) {
+	lo, hi := c.minTimestamp, c.maxTimestamp
+	if lo.IsZero() {
+		lo = time.Unix(defaultMinTimestamp, 0).UTC()
+	}
+	if hi.IsZero() {
+		hi = time.Unix(defaultMaxTimestamp, 0).UTC()
+	}
+	return lo, hi
+}
+
+// validateTimestamp checks that timestamp isn't the zero timestamp, lies within the manager's timestamp bounds,
+// and is within the tolerance configured with WithCreateTolerance
+func (c *CredentialManager) validateTimestamp(timestamp time.Time) error {
+	if err := validateTimestampSet(timestamp.Unix()); err != nil {
+		return err
+	}
+	if lo, hi := c.timestampBounds(); timestamp.Before(lo) || !timestamp.Before(hi) {
+		return fmt.Errorf("%w: %s is not between %s and %s", ErrTimestampOutOfRange, timestamp.UTC().Format(time.RFC3339),
+			lo.UTC().Format(time.RFC3339), hi.UTC().Format(time.RFC3339))
 	}
+	if c.createTolerance <= 0 {
+		return nil
+	}
+	now := c.now()
+	if timestamp.Before(now.Add(-c.createTolerance)) || timestamp.After(now.Add(c.createTolerance)) {
+		return fmt.Errorf("%w: %s is more than %s from %s", ErrTimestampOutOfRange, timestamp.UTC().Format(time.RFC3339), c.createTolerance, now.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// CredentialIDLength is the length of the credential IDs assigned by Create
+const CredentialIDLength = 16
+
+func validateNodeID(nodeID []byte) error {
+	if len(nodeID) != NodeIDLength {
+		return fmt.Errorf("%w. Expected %d, got %d", ErrInvalidNodeIDLength, NodeIDLength, len(nodeID))
+	}
+	return nil
+}
+
+// validateDecoded enforces the limits on fields of credentials decoded from untrusted input
+func validateDecoded(credential *pb.Credential) error {
+	if _, err := lookupVersion(credential); err != nil {
+		return err
+	}
+	if err := validateMetadata(credential.GetMetadata()); err != nil {
+		return err
+	}
+	if err := validateScopeNames(credential.GetScopeNames()); err != nil {
+		return err
+	}
+	if bundle := credential.GetBundleNodeIds(); len(bundle) > 0 {
+		if err := validateBundle(bundle, &batchConfig{failFast: true}); err != nil {
+			return err
+		}
+	}
+	return validateFeeRecipient(credential.GetFeeRecipient())
+}
+
+// newCredential builds an unauthenticated credential with a fresh random credential ID, unless they are derived,
+// the manager's audience, issuer and chain ID, and its default expiry and random nonce if it has them
+func (c *CredentialManager) newCredential(timestamp time.Time, nodeID []byte, OperatorType OperatorType) (*AuthenticatedCredential, error) {
+	var credentialID []byte
+	if !c.deterministicIDs {
+		var err error
+		if credentialID, err = c.randomBytes(CredentialIDLength); err != nil {
+			return nil, err
+		}
+	}
+
 	message := AuthenticatedCredential{}
 	message.Credential = &pb.Credential{}
 	message.Credential.NodeId = nodeID
 	message.Credential.OperatorType = OperatorType
 	message.Credential.Timestamp = timestamp.Unix()
-
-	if err := c.authenticateCredential(&message); err != nil {
-		return nil, err
+	message.Credential.CredentialId = credentialID
+	message.Credential.Version = CurrentVersion
+	message.Credential.Audience = c.audience
+	message.Credential.Issuer = c.issuer
+	message.Credential.ChainId = c.chainID
+	if c.defaultValidity > 0 {
+		message.Credential.ExpiresAt = timestamp.Unix() + int64(c.defaultValidity/time.Second)
+	}
+	if c.randomNonce {
+		var err error
+		if message.Credential.Nonce, err = c.randomBytes(NonceLength); err != nil {
+			return nil, err
+		}
 	}
-
 	return &message, nil
 }
 
-// Verify checks that a AuthenticatedCredential has a valid mac
+// credentialIDDomain separates derived credential IDs from every other hash of credentials
+const credentialIDDomain = "\x00rescue-credential-derived-id\x00"
+
+// deriveCredentialID sets the ID of a credential built by newCredential, once all of its fields are filled in,
+// when the manager derives them. The ID is a hash of the rest of the credential, so identical credentials share it.
+func (c *CredentialManager) deriveCredentialID(credential *pb.Credential) {
+	if !c.deterministicIDs {
+		return
+	}
+	h := hashAlgo()
+	h.Write([]byte(credentialIDDomain))
+	h.Write(appendCredential(nil, credential))
+	credential.CredentialId = h.Sum(nil)[:CredentialIDLength]
+}
+
+// Verify checks that a AuthenticatedCredential has a valid mac.
+// Missing MACs fail with ErrMissingMAC and ones of the wrong length with ErrInvalidMACLength, before any comparison,
+// so only MACs which could be authentic fail with MismatchError.
 func (c *CredentialManager) Verify(authenticatedCredential *AuthenticatedCredential) (*ID, error) {
-	// Grab the byte representation of the inner message
-	bytes, err := proto.Marshal(authenticatedCredential.Credential)
+	return c.VerifyContext(context.Background(), authenticatedCredential)
+}
+
+// VerifyContext is like Verify, but passes ctx to the manager's MACer, revoker and hooks if they accept one,
+// and fails with ctx.Err() if ctx is done before the credential is accepted
+func (c *CredentialManager) VerifyContext(ctx context.Context, authenticatedCredential *AuthenticatedCredential) (*ID, error) {
+	return c.verifyWithAAD(ctx, authenticatedCredential, nil)
+}
+
+// VerifyWithAAD checks that a AuthenticatedCredential has a valid mac over the credential and aad.
+// If aad is non-empty and the mac doesn't match, ErrAADMismatch is returned.
+// Failures are returned as a *VerificationError wrapping the reason.
+func (c *CredentialManager) VerifyWithAAD(authenticatedCredential *AuthenticatedCredential, aad []byte) (*ID, error) {
+	return c.verifyWithAAD(context.Background(), authenticatedCredential, aad)
+}
+
+// verifyWithAAD is VerifyWithAAD, under ctx
+func (c *CredentialManager) verifyWithAAD(ctx context.Context, authenticatedCredential *AuthenticatedCredential, aad []byte) (*ID, error) {
+	if c.timingHook != nil {
+		defer c.observe(OperationVerify, authenticatedCredential.Credential.GetOperatorType(), time.Now())
+	}
+	if c.hooks == nil && c.auditSink == nil && c.auditRecords == nil {
+		return c.verify(ctx, authenticatedCredential, aad, 0)
+	}
+	attemptedAt := c.now()
+	start := time.Now()
+	id, err := c.verify(ctx, authenticatedCredential, aad, 0)
+	c.hooks.verified(ctx, authenticatedCredential, err, time.Since(start))
+	c.audit(ctx, authenticatedCredential, id, err, attemptedAt)
+	return id, err
+}
+
+// verify is VerifyWithAAD under ctx, but accepting credentials up to grace past their expiry
+func (c *CredentialManager) verify(ctx context.Context, authenticatedCredential *AuthenticatedCredential, aad []byte, grace time.Duration) (*ID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, newVerificationError(authenticatedCredential, err)
+	}
+	id, err := c.verifyMAC(ctx, authenticatedCredential, aad)
 	if err != nil {
-		return nil, errors.Join(err, SerializationError)
+		return nil, newVerificationError(authenticatedCredential, err)
 	}
+	if err := c.checkAuthenticated(ctx, authenticatedCredential, grace); err != nil {
+		return nil, newVerificationError(authenticatedCredential, err)
+	}
+	return id, nil
+}
 
-	v, ok := c.p.Get().(*checker)
-	if !ok {
-		return nil, MemoryError
+// checkAuthenticated applies the manager's policies to a credential whose MAC has already been verified,
+// accepting credentials up to grace past their expiry
+func (c *CredentialManager) checkAuthenticated(ctx context.Context, authenticatedCredential *AuthenticatedCredential, grace time.Duration) error {
+	if err := c.checkOperatorType(authenticatedCredential); err != nil {
+		return err
 	}
-	// defer stacks calls, so Put will always be called after the Reset() calls below
-	defer c.p.Put(v)
+	if err := c.checkAudience(authenticatedCredential); err != nil {
+		return err
+	}
+	if err := c.checkChainID(authenticatedCredential); err != nil {
+		return err
+	}
+	if err := c.checkIssuer(authenticatedCredential); err != nil {
+		return err
+	}
+	if err := c.checkPartner(authenticatedCredential); err != nil {
+		return err
+	}
+	if err := c.checkScopeNames(authenticatedCredential); err != nil {
+		return err
+	}
+	if err := c.checkTimestamp(authenticatedCredential); err != nil {
+		return err
+	}
+	if err := c.checkExpiry(authenticatedCredential, grace); err != nil {
+		return err
+	}
+	if err := c.checkRevoked(ctx, authenticatedCredential); err != nil {
+		return err
+	}
+	nonce := authenticatedCredential.Credential.GetNonce()
+	if c.nonceChecker != nil && len(nonce) > 0 && c.nonceChecker.SeenNonce(nonce) {
+		return ErrReplayedCredential
+	}
+	return nil
+}
+
+// verifyMAC checks the credential's MACs, returning the ID of the key that authenticated it
+func (c *CredentialManager) verifyMAC(ctx context.Context, authenticatedCredential *AuthenticatedCredential, aad []byte) (*ID, error) {
+	// MACers may compute MACs of any length, which verifyWithMACer checks once it knows it
+	if c.macer == nil {
+		if err := validateMACLength(authenticatedCredential.Mac, MACLength); err != nil {
+			return nil, err
+		}
+	}
+	if c.verificationCache != nil {
+		return c.verifyMACCached(ctx, authenticatedCredential, aad)
+	}
+	return c.verifyMACUncached(ctx, authenticatedCredential, aad)
+}
+
+// verifyMACUncached is verifyMAC, bypassing the verification cache
+func (c *CredentialManager) verifyMACUncached(ctx context.Context, authenticatedCredential *AuthenticatedCredential, aad []byte) (*ID, error) {
+	if c.ring != nil {
+		data, err := appendMACInput(nil, authenticatedCredential.Credential, aad)
+		if err != nil {
+			return nil, err
+		}
+		id, fps := c.ring.verify(c.now(), data, authenticatedCredential)
+		if id != nil {
+			return id, nil
+		}
+		if c.dual != nil && c.dual.verify(data, authenticatedCredential) {
+			return c.dual.id, nil
+		}
+		return nil, c.ringMismatchError(aad, fps)
+	}
+	if c.macer != nil {
+		return c.verifyWithMACer(ctx, authenticatedCredential, aad)
+	}
+
+	v, err := c.getChecker()
+	if err != nil {
+		return nil, err
+	}
+	defer c.putChecker(v)
+
+	// Grab the byte representation of the inner message
+	buf, err := appendMACInput(v.buf[:0], authenticatedCredential.Credential, aad)
+	if err != nil {
+		return nil, err
+	}
+	v.buf = buf
 
-	secrets := append([]secret{v.primary}, v.extras...)
-	for _, s := range secrets {
-		s.hmac.Write(bytes)
-		mac := s.hmac.Sum(nil)
-		defer s.hmac.Reset()
-		if hmac.Equal(mac, authenticatedCredential.Mac) {
+	if v.matches(&v.primary, v.buf, authenticatedCredential.Mac) {
+		return v.primary.id, nil
+	}
+	for i := range v.extras {
+		if v.matches(&v.extras[i], v.buf, authenticatedCredential.Mac) {
 			// A secret was able to auth this credential,
 			// return its ID
+			return v.extras[i].id, nil
+		}
+	}
+	// Credentials issued during a key rotation carry MACs under other keys, tagged with the key that computed them
+	for _, km := range authenticatedCredential.AdditionalMacs {
+		if s := v.secretForKeyID(km.KeyId); s != nil && v.matches(s, v.buf, km.Mac) {
 			return s.id, nil
 		}
 	}
+	if c.dual != nil && c.dual.verify(v.buf, authenticatedCredential) {
+		return c.dual.id, nil
+	}
 	// MAC didn't match. Authenticity cannot be verified.
-	return nil, MismatchError
+	if len(aad) > 0 {
+		// The credential was either tampered with or bound to different aad
+		return nil, c.mismatchError(ErrAADMismatch)
+	}
+	return nil, c.mismatchError(MismatchError)
 }
 
-// ID returns the ID struct of the primary secret
+// ID returns the ID struct of the primary secret.
+// For a KeyRing backed manager it is the ID of the current signing key, or nil if there is none.
 func (c *CredentialManager) ID() *ID {
+	if c.ring != nil {
+		if k := c.ring.signingKey(c.now()); k != nil {
+			return k.id
+		}
+		return nil
+	}
 	return c.id
 }
 
+// Fingerprint returns the KeyFingerprint of the primary secret.
+// For a KeyRing backed manager it is the fingerprint of the current signing key, or "" if there is none.
+func (c *CredentialManager) Fingerprint() string {
+	if c.ring != nil {
+		if k := c.ring.signingKey(c.now()); k != nil {
+			return k.fingerprint
+		}
+		return ""
+	}
+	return c.fingerprint
+}
+
 // PartnerIDs returns a slice of ID structs of partner secrets
 func (c *CredentialManager) PartnerIDs() []*ID {
 	return c.partnerIDs
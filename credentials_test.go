@@ -2,15 +2,19 @@ package credentials
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -372,6 +376,47 @@ func TestBase64URLEncodeDecode(t *testing.T) {
 	}
 }
 
+// TestVerifyRaw tests verifying the unencoded node ID and password, with and without compression
+func TestVerifyRaw(t *testing.T) {
+	cm := NewCredentialManager([]byte("Raw test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []Encoder{{}, {Compress: true}} {
+		password, err := e.EncodePassword(cred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		passwordProto, err := decodeBase64URL(password)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		verified, err := cm.VerifyRaw(cred.Credential.NodeId, passwordProto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(verified.Pb(), cred.Pb()) {
+			t.Error("Credential mismatch after VerifyRaw")
+		}
+
+		otherNode := make([]byte, 20)
+		otherNode[0] = 1
+		if _, err := cm.VerifyRaw(otherNode, passwordProto); !errors.Is(err, MismatchError) {
+			t.Errorf("Expected MismatchError, got %v", err)
+		}
+	}
+
+	if _, err := cm.VerifyRaw(cred.Credential.NodeId, []byte("invalid proto message")); err == nil {
+		t.Error("Expected error for invalid proto message, got nil")
+	}
+	if _, err := cm.VerifyRaw(cred.Credential.NodeId, nil); !errors.Is(err, ErrEmptyPassword) {
+		t.Errorf("Expected ErrEmptyPassword for an empty password, got %v", err)
+	}
+}
+
 // TestInvalidBase64URLDecode tests the Base64URLDecode function with invalid input
 func TestInvalidBase64URLDecode(t *testing.T) {
 	var cred AuthenticatedCredential
@@ -395,3 +440,522 @@ func TestInvalidBase64URLDecode(t *testing.T) {
 		t.Error("Expected error for invalid proto message, got nil")
 	}
 }
+
+// TestBase64URLDecodeSmuggledNodeID tests that passwords carrying a node ID other than their username's are rejected
+func TestBase64URLDecodeSmuggledNodeID(t *testing.T) {
+	cm := NewCredentialManager([]byte("Base64 test secret"))
+	victim := make([]byte, 20)
+	victim[0] = 1
+	cred, err := cm.Create(time.Now(), victim, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Marshal the whole credential, node ID included, as the password
+	marshaled, err := proto.Marshal(cred.Pb())
+	if err != nil {
+		t.Fatal(err)
+	}
+	password := base64.URLEncoding.EncodeToString(marshaled)
+
+	decoded := AuthenticatedCredential{Credential: &pb.Credential{NodeId: []byte("left over")}}
+	err = decoded.Base64URLDecode(base64.URLEncoding.EncodeToString(make([]byte, 20)), password)
+	var mismatch *NodeIDMismatchError
+	if !errors.As(err, &mismatch) || !errors.Is(err, ErrNodeIDMismatch) || !errors.Is(err, ErrMalformedPassword) {
+		t.Errorf("Expected a NodeIDMismatchError, got %v", err)
+	} else if !bytes.Equal(mismatch.Username, make([]byte, 20)) || !bytes.Equal(mismatch.Password, victim) {
+		t.Errorf("Unexpected node IDs in %v", mismatch)
+	}
+	if string(decoded.Credential.NodeId) != "left over" {
+		t.Error("Failed decode modified the target")
+	}
+	if _, err := cm.VerifyRaw(make([]byte, 20), marshaled); !errors.Is(err, ErrNodeIDMismatch) {
+		t.Errorf("Expected ErrNodeIDMismatch from VerifyRaw, got %v", err)
+	}
+
+	// A node ID agreeing with the username is redundant, but harmless
+	if err := decoded.Base64URLDecode(cred.Base64URLEncodeUsername(), password); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyRaw(victim, marshaled); err != nil {
+		t.Error(err)
+	}
+
+	// The same credential with the node ID stripped decodes to the username's node ID
+	stripped, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded = AuthenticatedCredential{}
+	if err := decoded.Base64URLDecode(cred.Base64URLEncodeUsername(), stripped); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Credential.NodeId, victim) {
+		t.Error("Expected the username's node ID")
+	}
+}
+
+// TestCredentialAAD tests that credentials bound to additional authenticated data only verify with the same data
+func TestCredentialAAD(t *testing.T) {
+	cm := NewCredentialManager([]byte("Curiouser and curiouser"))
+
+	nodeID, err := hex.DecodeString("1234567890123456789012345678901234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := cm.CreateWithAAD(time.Now(), nodeID, pb.OperatorType_OT_SOLO, []byte("service-a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cm.VerifyWithAAD(cred, []byte("service-a")); err != nil {
+		t.Errorf("Failed to verify with matching AAD: %v", err)
+	}
+
+	_, err = cm.VerifyWithAAD(cred, []byte("service-b"))
+	if !errors.Is(err, ErrAADMismatch) || !errors.Is(err, MismatchError) {
+		t.Errorf("Expected ErrAADMismatch, got %v", err)
+	}
+
+	// The AAD is not stored, so it is required at verify time
+	_, err = cm.Verify(cred)
+	if !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+
+	// Moving bytes between the credential and the AAD must not produce a valid MAC
+	cred2, err := cm.CreateWithAAD(time.Now(), nodeID, pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyWithAAD(cred2, []byte{}); err != nil {
+		t.Errorf("Empty AAD should behave like no AAD: %v", err)
+	}
+	if _, err := cm.Verify(cred2); err != nil {
+		t.Errorf("Failed to verify credential created without AAD: %v", err)
+	}
+	_, err = cm.VerifyWithAAD(cred2, []byte("service-a"))
+	if !errors.Is(err, ErrAADMismatch) {
+		t.Errorf("Expected ErrAADMismatch, got %v", err)
+	}
+}
+
+// TestJSONOperatorTypeName tests that the operator type is emitted by name and accepted in either form
+func TestJSONOperatorTypeName(t *testing.T) {
+	cm := NewCredentialManager([]byte("JSON test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonData, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(jsonData, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["operator_type"] != float64(1) || fields["operator_type_name"] != "OT_SOLO" {
+		t.Fatalf("Unexpected operator type fields in %s", jsonData)
+	}
+
+	nodeID := "0x0000000000000000000000000000000000000000"
+	testCases := []struct {
+		name     string
+		json     string
+		expected OperatorType
+		wantErr  bool
+	}{
+		{"Number", `{"node_id":"` + nodeID + `","operator_type":1}`, pb.OperatorType_OT_SOLO, false},
+		{"StringInNumberField", `{"node_id":"` + nodeID + `","operator_type":"OT_SOLO"}`, pb.OperatorType_OT_SOLO, false},
+		{"NameOnly", `{"node_id":"` + nodeID + `","operator_type_name":"OT_SOLO"}`, pb.OperatorType_OT_SOLO, false},
+		{"Both", `{"node_id":"` + nodeID + `","operator_type":0,"operator_type_name":"OT_ROCKETPOOL"}`, pb.OperatorType_OT_ROCKETPOOL, false},
+		{"Neither", `{"node_id":"` + nodeID + `"}`, pb.OperatorType_OT_ROCKETPOOL, false},
+		{"Disagree", `{"node_id":"` + nodeID + `","operator_type":0,"operator_type_name":"OT_SOLO"}`, 0, true},
+		{"UnknownName", `{"node_id":"` + nodeID + `","operator_type":"OT_NOPE"}`, 0, true},
+		{"UnknownNameField", `{"node_id":"` + nodeID + `","operator_type_name":"OT_NOPE"}`, 0, true},
+		{"WrongType", `{"node_id":"` + nodeID + `","operator_type":true}`, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ac AuthenticatedCredential
+			err := json.Unmarshal([]byte(tc.json), &ac)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ac.Credential.OperatorType != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, ac.Credential.OperatorType)
+			}
+		})
+	}
+}
+
+// TestJSONTimestamp tests that timestamps unmarshal from Unix seconds or RFC3339, and always marshal as seconds
+func TestJSONTimestamp(t *testing.T) {
+	nodeID := "0x0000000000000000000000000000000000000000"
+	testCases := []struct {
+		name      string
+		timestamp string
+		expected  int64
+		wantErr   bool
+	}{
+		{"Seconds", `1700000000`, 1700000000, false},
+		{"Negative", `-1`, -1, false},
+		{"RFC3339", `"2023-11-14T22:13:20Z"`, 1700000000, false},
+		{"RFC3339Offset", `"2023-11-15T00:13:20+02:00"`, 1700000000, false},
+		{"RFC3339Fraction", `"2023-11-14T22:13:20.5Z"`, 0, true},
+		{"SecondsString", `"1700000000"`, 0, true},
+		{"DateOnly", `"2023-11-14"`, 0, true},
+		{"Float", `1700000000.5`, 0, true},
+		{"Milliseconds", `1700000000000`, 0, true},
+		{"NegativeMilliseconds", `-1700000000000`, 0, true},
+		{"WrongType", `true`, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ac AuthenticatedCredential
+			err := json.Unmarshal([]byte(`{"node_id":"`+nodeID+`","timestamp":`+tc.timestamp+`,"mac":""}`), &ac)
+			if tc.wantErr {
+				if !errors.Is(err, ErrMalformedCredential) || !strings.Contains(err.Error(), "timestamp") {
+					t.Fatalf("Expected a malformed timestamp error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ac.Credential.Timestamp != tc.expected {
+				t.Errorf("Expected %d, got %d", tc.expected, ac.Credential.Timestamp)
+			}
+
+			jsonData, err := json.Marshal(&ac)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(jsonData), fmt.Sprintf(`"timestamp":%d,`, tc.expected)) {
+				t.Errorf("Expected the timestamp marshaled as seconds, got %s", jsonData)
+			}
+		})
+	}
+}
+
+// TestCredentialID tests that credentials get unique IDs which survive every encoding
+func TestCredentialID(t *testing.T) {
+	cm := NewCredentialManager([]byte("Curiouser and curiouser"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cred.Credential.CredentialId) != CredentialIDLength {
+		t.Fatalf("Expected a %d byte credential ID, got %d", CredentialIDLength, len(cred.Credential.CredentialId))
+	}
+	if cred.ID() != hex.EncodeToString(cred.Credential.CredentialId) {
+		t.Errorf("Unexpected ID() %q", cred.ID())
+	}
+	other, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.ID() == cred.ID() {
+		t.Error("Expected distinct credential IDs")
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["credential_id"] != cred.ID() {
+		t.Errorf("Expected credential_id %q in JSON, got %v", cred.ID(), fields["credential_id"])
+	}
+	var fromJSON AuthenticatedCredential
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromPassword AuthenticatedCredential
+	if err := fromPassword.Base64URLDecode(cred.Base64URLEncodeUsername(), password); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, decoded := range []*AuthenticatedCredential{&fromJSON, &fromPassword} {
+		if decoded.ID() != cred.ID() {
+			t.Errorf("Expected ID %q after decoding, got %q", cred.ID(), decoded.ID())
+		}
+		if _, err := cm.Verify(decoded); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// The ID is covered by the MAC
+	cred.Credential.CredentialId[0] ^= 1
+	if _, err := cm.Verify(cred); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+// TestCredentialWithoutID tests that credentials issued before credential IDs existed still verify
+func TestCredentialWithoutID(t *testing.T) {
+	cm := NewCredentialManager([]byte("Curiouser and curiouser"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred.Credential.CredentialId = nil
+	if err := cm.authenticateCredential(cred, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cred.ID() != "" {
+		t.Errorf("Expected an empty ID, got %q", cred.ID())
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Error(err)
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("credential_id")) {
+		t.Errorf("Expected credential_id to be omitted, got %s", data)
+	}
+	var decoded AuthenticatedCredential
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(&decoded); err != nil {
+		t.Error(err)
+	}
+}
+
+// marshalJSONCredential returns a credential with every field MarshalJSON encodes set
+func marshalJSONCredential() *AuthenticatedCredential {
+	return &AuthenticatedCredential{
+		Credential: &pb.Credential{
+			NodeId:        bytes.Repeat([]byte{0xab}, 20),
+			Timestamp:     1700000000,
+			OperatorType:  pb.OperatorType_OT_SOLO,
+			Nonce:         []byte("nonce"),
+			CredentialId:  bytes.Repeat([]byte{0x01}, CredentialIDLength),
+			ExpiresAt:     1700003600,
+			Audience:      "rescue-proxy",
+			Issuer:        "bot",
+			Scopes:        3,
+			ChainId:       17000,
+			PartnerId:     "partner",
+			Metadata:      map[string]string{"ticket": "42"},
+			BundleNodeIds: [][]byte{bytes.Repeat([]byte{0xab}, 20), bytes.Repeat([]byte{0xcd}, 20)},
+			FeeRecipient:  bytes.Repeat([]byte{0xef}, FeeRecipientLength),
+			Version:       CurrentVersion,
+			ScopeNames:    []string{"read", "submit"},
+		},
+		Mac:            bytes.Repeat([]byte{0xfe}, 32),
+		AdditionalMacs: []*pb.KeyedMac{{KeyId: []byte("keyid"), Mac: bytes.Repeat([]byte{0x02}, 32)}},
+	}
+}
+
+func BenchmarkMarshalJSON(b *testing.B) {
+	cred := marshalJSONCredential()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(cred); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestMarshalJSONGolden pins MarshalJSON's output, which other systems parse
+func TestMarshalJSONGolden(t *testing.T) {
+	expected := `{"node_id":"0xabababababababababababababababababababab","timestamp":1700000000,"operator_type":1,"operator_type_name":"OT_SOLO","nonce":"bm9uY2U=","credential_id":"01010101010101010101010101010101","expires_at":1700003600,"audience":"rescue-proxy","issuer":"bot","scopes":3,"chain_id":17000,"partner_id":"partner","metadata":{"ticket":"42"},"bundle_node_ids":["0xabababababababababababababababababababab","0xcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcdcd"],"fee_recipient":"0xefefefefefefefefefefefefefefefefefefefef","version":1,"scope_names":["read","submit"],"mac":"_v7-_v7-_v7-_v7-_v7-_v7-_v7-_v7-_v7-_v7-_v4=","additional_macs":[{"key_id":"6b65796964","mac":"AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI="}]}`
+	data, err := json.Marshal(marshalJSONCredential())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	// Credentials without optional fields
+	data, err = json.Marshal(&AuthenticatedCredential{Credential: &pb.Credential{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"node_id":"0x","timestamp":0,"operator_type":0,"operator_type_name":"OT_ROCKETPOOL","mac":""}`; string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+// allowUnknownFields sets MaxUnknownFieldBytes to n for the rest of the test
+func allowUnknownFields(t *testing.T, n int) {
+	t.Helper()
+	old := MaxUnknownFieldBytes
+	MaxUnknownFieldBytes = n
+	t.Cleanup(func() { MaxUnknownFieldBytes = old })
+}
+
+// TestUnknownFieldsRoundTrip tests that a credential with a field this version doesn't know survives every encoding,
+// and still verifies afterwards, as the field is covered by the MAC
+func TestUnknownFieldsRoundTrip(t *testing.T) {
+	allowUnknownFields(t, 256)
+	cm := NewCredentialManager([]byte("Unknown fields test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a newer issuer, which knows field 17 and included it in the MAC
+	future := protowire.AppendTag(nil, 17, protowire.BytesType)
+	future = protowire.AppendString(future, "from the future")
+	future = protowire.AppendTag(future, 18, protowire.VarintType)
+	future = protowire.AppendVarint(future, 7)
+	cred.Credential.ProtoReflect().SetUnknown(future)
+	if cred.Mac, err = cm.ComputeMAC(cred); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(t *testing.T, decoded *AuthenticatedCredential) {
+		t.Helper()
+		if !bytes.Equal(decoded.Credential.ProtoReflect().GetUnknown(), future) {
+			t.Errorf("Expected unknown fields %x, got %x", future, decoded.Credential.ProtoReflect().GetUnknown())
+		}
+		if _, err := cm.Verify(decoded); err != nil {
+			t.Errorf("Expected the decoded credential to verify, got %v", err)
+		}
+		// Re-encoding is deterministic, so the decoded credential encodes as the original did
+		want, err := cred.Base64URLEncodePassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := decoded.Base64URLEncodePassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("Expected re-encoding to give %s, got %s", want, got)
+		}
+	}
+
+	t.Run("Base64URL", func(t *testing.T) {
+		username, err := cred.EncodeUsername()
+		if err != nil {
+			t.Fatal(err)
+		}
+		password, err := cred.Base64URLEncodePassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := new(AuthenticatedCredential)
+		if err := decoded.Base64URLDecode(username, password); err != nil {
+			t.Fatal(err)
+		}
+		check(t, decoded)
+	})
+	t.Run("Text", func(t *testing.T) {
+		text, err := cred.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := new(AuthenticatedCredential)
+		if err := decoded.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		check(t, decoded)
+	})
+	t.Run("JSON", func(t *testing.T) {
+		data, err := json.Marshal(cred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := new(AuthenticatedCredential)
+		if err := json.Unmarshal(data, decoded); err != nil {
+			t.Fatal(err)
+		}
+		check(t, decoded)
+	})
+}
+
+// TestUnknownFieldsJSONInvalid tests that JSON unknown fields must be well-formed fields which aren't known
+func TestUnknownFieldsJSONInvalid(t *testing.T) {
+	allowUnknownFields(t, 256)
+	cm := NewCredentialManager([]byte("Unknown fields test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var j map[string]any
+	if err := json.Unmarshal(data, &j); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		unknown []byte
+	}{
+		{"Truncated", protowire.AppendTag(nil, 17, protowire.BytesType)},
+		{"KnownField", protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), make([]byte, 20))},
+		{"Group", protowire.AppendTag(nil, 17, protowire.StartGroupType)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			j["unknown_fields"] = base64.URLEncoding.EncodeToString(tc.unknown)
+			data, err := json.Marshal(j)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = new(AuthenticatedCredential).UnmarshalJSON(data)
+			if !errors.Is(err, ErrMalformedProto) {
+				t.Errorf("Expected ErrMalformedProto, got %v", err)
+			}
+		})
+	}
+}
+
+// TestContextCanceled tests that CreateContext and VerifyContext fail with the context's error once it is done
+func TestContextCanceled(t *testing.T) {
+	cm := NewCredentialManager([]byte("Context test secret"))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cred, err := cm.CreateContext(ctx, time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyContext(ctx, cred); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if _, err := cm.CreateContext(ctx, time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	_, err = cm.VerifyContext(ctx, cred)
+	var verificationErr *VerificationError
+	if !errors.Is(err, context.Canceled) || !errors.As(err, &verificationErr) {
+		t.Errorf("Expected a VerificationError wrapping context.Canceled, got %v", err)
+	}
+	// Create's input validation comes first, so invalid arguments are reported as such
+	if _, err := cm.CreateContext(ctx, time.Now(), make([]byte, 3), pb.OperatorType_OT_SOLO); !errors.Is(err, ErrInvalidNodeIDLength) {
+		t.Errorf("Expected ErrInvalidNodeIDLength, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package credentials
+
+import (
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DiffCredentials is a debugging aid which lists the names of the fields that differ between a and b,
+// e.g. node_id, timestamp, operator_type or mac. Fields are named after their proto fields, and the inner
+// credential is compared field by field, so every field covered by the MAC is reported individually.
+// A nil credential is treated as empty.
+func DiffCredentials(a, b *AuthenticatedCredential) []string {
+	var out []string
+	out = diffMessages(out, a.pbCredential().ProtoReflect(), b.pbCredential().ProtoReflect())
+	return diffMessages(out, a.Pb().ProtoReflect(), b.Pb().ProtoReflect())
+}
+
+// diffMessages appends the names of the fields that differ between a and b, which must be the same message type.
+// Message fields are skipped, as DiffCredentials compares the credential separately.
+func diffMessages(dst []string, a, b protoreflect.Message) []string {
+	fields := a.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Kind() == protoreflect.MessageKind && !fd.IsList() {
+			continue
+		}
+		if !a.Get(fd).Equal(b.Get(fd)) {
+			dst = append(dst, string(fd.Name()))
+		}
+	}
+	if string(a.GetUnknown()) != string(b.GetUnknown()) {
+		dst = append(dst, string(a.Descriptor().Name())+".unknown_fields")
+	}
+	return dst
+}
+
+// pbCredential returns the inner credential, tolerating a nil receiver
+func (ac *AuthenticatedCredential) pbCredential() *pb.Credential {
+	if ac == nil {
+		return nil
+	}
+	return ac.Credential
+}
@@ -0,0 +1,58 @@
+package credentials
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestDiffCredentials tests that DiffCredentials reports exactly the fields that differ
+func TestDiffCredentials(t *testing.T) {
+	cm := NewCredentialManager([]byte("Diff test secret"))
+	signed, err := cm.Create(time.Unix(1700000000, 0), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := func() *AuthenticatedCredential {
+		return (*AuthenticatedCredential)(proto.Clone(signed.Pb()).(*pb.AuthenticatedCredential))
+	}
+
+	testCases := []struct {
+		name     string
+		mutate   func(*AuthenticatedCredential)
+		expected []string
+	}{
+		{"Identical", func(*AuthenticatedCredential) {}, nil},
+		{"Timestamp", func(ac *AuthenticatedCredential) { ac.Credential.Timestamp++ }, []string{"timestamp"}},
+		{"NodeIDAndMac", func(ac *AuthenticatedCredential) {
+			ac.Credential.NodeId = make([]byte, 20)
+			ac.Credential.NodeId[0] = 1
+			ac.Mac = []byte("mac")
+		}, []string{"node_id", "mac"}},
+		{"OperatorType", func(ac *AuthenticatedCredential) { ac.Credential.OperatorType = pb.OperatorType_OT_ROCKETPOOL }, []string{"operator_type"}},
+		{"AdditionalMacs", func(ac *AuthenticatedCredential) {
+			ac.AdditionalMacs = append(ac.AdditionalMacs, &pb.KeyedMac{KeyId: []byte("key"), Mac: []byte("mac")})
+		}, []string{"additional_macs"}},
+		{"NilCredential", func(ac *AuthenticatedCredential) { ac.Credential = nil }, []string{"node_id", "timestamp", "operator_type", "credential_id", "version"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			arrived := clone()
+			tc.mutate(arrived)
+			if diff := DiffCredentials(signed, arrived); !reflect.DeepEqual(diff, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, diff)
+			}
+		})
+	}
+
+	if diff := DiffCredentials(nil, nil); len(diff) != 0 {
+		t.Errorf("Expected no differences between nil credentials, got %v", diff)
+	}
+	if diff := DiffCredentials(signed, nil); len(diff) == 0 {
+		t.Error("Expected differences against a nil credential")
+	}
+}
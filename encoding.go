@@ -0,0 +1,460 @@
+package credentials
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Encoder selects how credentials are base64url encoded as a username and password.
+// The zero value pads the output, as Base64URLEncodeUsername and Base64URLEncodePassword do.
+// Base64URLDecode accepts the output of any Encoder.
+type Encoder struct {
+	// Raw omits the padding, for downstreams with strict parsers which reject it
+	Raw bool
+	// Compress deflates the password's proto when that makes it shorter.
+	// Compressed passwords start with compressedMarker, which can't begin an uncompressed proto.
+	Compress bool
+}
+
+// RawEncoder encodes without padding
+var RawEncoder = Encoder{Raw: true}
+
+func (e Encoder) encoding() *base64.Encoding {
+	if e.Raw {
+		return base64.RawURLEncoding
+	}
+	return base64.URLEncoding
+}
+
+// EncodeUsername encodes the credential's node ID
+func (e Encoder) EncodeUsername(ac *AuthenticatedCredential) string {
+	buf := encodeBuffers.Get().(*[]byte)
+	defer putEncodeBuffer(buf)
+	*buf = e.AppendUsername((*buf)[:0], ac)
+	return string(*buf)
+}
+
+// AppendUsername appends EncodeUsername's output to dst. It doesn't allocate if dst has room for UsernameLen bytes.
+func (e Encoder) AppendUsername(dst []byte, ac *AuthenticatedCredential) []byte {
+	nodeID := ac.Credential.GetNodeId()
+	n := len(dst)
+	dst = slices.Grow(dst, e.encoding().EncodedLen(len(nodeID)))
+	dst = dst[:n+e.encoding().EncodedLen(len(nodeID))]
+	e.encoding().Encode(dst[n:], nodeID)
+	return dst
+}
+
+// EncodePassword encodes everything but the credential's node ID, which is carried by the username
+func (e Encoder) EncodePassword(ac *AuthenticatedCredential) (string, error) {
+	buf := encodeBuffers.Get().(*[]byte)
+	defer putEncodeBuffer(buf)
+	password, err := e.AppendPassword((*buf)[:0], ac)
+	if err != nil {
+		return "", err
+	}
+	*buf = password
+	return string(password), nil
+}
+
+// encodeBuffers pools the buffers the string encoding methods build their output in
+var encodeBuffers = sync.Pool{New: func() any { return new([]byte) }}
+
+func putEncodeBuffer(buf *[]byte) {
+	if cap(*buf) <= maxPooledBufferSize {
+		encodeBuffers.Put(buf)
+	}
+}
+
+// AppendPassword appends EncodePassword's output to dst. The password's proto is marshaled into dst's spare capacity
+// and encoded past it, before being moved into place, so without Compress it doesn't allocate if dst has room for
+// PasswordLen bytes plus the proto, as it does when a buffer is reused.
+func (e Encoder) AppendPassword(dst []byte, ac *AuthenticatedCredential) ([]byte, error) {
+	n := len(dst)
+	dst, err := appendPassword(dst, ac)
+	if err != nil {
+		return dst[:n], err
+	}
+	size := len(dst) - n
+	if e.Compress {
+		compressed := compressPassword(dst[n:])
+		dst = append(dst[:n], compressed...)
+		size = len(compressed)
+	}
+
+	encodedLen := e.encoding().EncodedLen(size)
+	dst = slices.Grow(dst, encodedLen)
+	e.encoding().Encode(dst[n+size:n+size+encodedLen], dst[n:n+size])
+	copy(dst[n:n+encodedLen], dst[n+size:n+size+encodedLen])
+	return dst[:n+encodedLen], nil
+}
+
+// appendPassword appends the wire encoding of ac with the node ID stripped, as the username carries it.
+// Like proto.Marshal, it fails on strings which aren't valid UTF-8.
+func appendPassword(dst []byte, ac *AuthenticatedCredential) ([]byte, error) {
+	return appendAuthenticatedCredential(dst, ac, false)
+}
+
+// appendAuthenticatedCredential appends the deterministic wire encoding of ac, with or without the node ID
+func appendAuthenticatedCredential(dst []byte, ac *AuthenticatedCredential, withNodeID bool) ([]byte, error) {
+	if c := ac.Credential; c != nil {
+		if err := validateUTF8(c); err != nil {
+			return dst, err
+		}
+		dst = protowire.AppendTag(dst, authenticatedCredentialField, protowire.BytesType)
+		start := len(dst)
+		dst = appendCredentialFields(dst, c, withNodeID)
+		dst = insertLengthPrefix(dst, start)
+	}
+	if len(ac.Mac) > 0 {
+		dst = protowire.AppendTag(dst, authenticatedMACField, protowire.BytesType)
+		dst = protowire.AppendBytes(dst, ac.Mac)
+	}
+	for _, km := range ac.AdditionalMacs {
+		dst = protowire.AppendTag(dst, authenticatedAdditionalMACsField, protowire.BytesType)
+		start := len(dst)
+		if len(km.GetKeyId()) > 0 {
+			dst = protowire.AppendTag(dst, keyedMACKeyIDField, protowire.BytesType)
+			dst = protowire.AppendBytes(dst, km.KeyId)
+		}
+		if len(km.GetMac()) > 0 {
+			dst = protowire.AppendTag(dst, keyedMACMACField, protowire.BytesType)
+			dst = protowire.AppendBytes(dst, km.Mac)
+		}
+		if km != nil {
+			dst = append(dst, km.ProtoReflect().GetUnknown()...)
+		}
+		dst = insertLengthPrefix(dst, start)
+	}
+	return append(dst, ac.Pb().ProtoReflect().GetUnknown()...), nil
+}
+
+// insertLengthPrefix inserts the length of dst[start:] as a varint at start, making it a length-delimited field.
+// Sizing nested messages up front with proto.Size allocates for maps, so they are written first and shifted instead.
+func insertLengthPrefix(dst []byte, start int) []byte {
+	length := len(dst) - start
+	var prefix [binary.MaxVarintLen64]byte
+	k := len(protowire.AppendVarint(prefix[:0], uint64(length)))
+	dst = append(dst, prefix[:k]...)
+	copy(dst[start+k:], dst[start:start+length])
+	copy(dst[start:], prefix[:k])
+	return dst
+}
+
+// validateUTF8 checks the credential's strings, which proto3 requires to be valid UTF-8
+func validateUTF8(c *pb.Credential) error {
+	if !utf8.ValidString(c.Audience) || !utf8.ValidString(c.Issuer) || !utf8.ValidString(c.PartnerId) {
+		return fmt.Errorf("%w: string field contains invalid UTF-8", SerializationError)
+	}
+	for k, v := range c.Metadata {
+		if !utf8.ValidString(k) || !utf8.ValidString(v) {
+			return fmt.Errorf("%w: metadata contains invalid UTF-8", SerializationError)
+		}
+	}
+	for _, name := range c.ScopeNames {
+		if !utf8.ValidString(name) {
+			return fmt.Errorf("%w: scope name contains invalid UTF-8", SerializationError)
+		}
+	}
+	return nil
+}
+
+// credentialSizeWithoutNodeID returns the size of the marshaled credential with its node ID stripped
+func credentialSizeWithoutNodeID(c *pb.Credential) int {
+	size := proto.Size(c)
+	if len(c.NodeId) == 0 {
+		return size
+	}
+	return size - protowire.SizeTag(credentialNodeIDField) - protowire.SizeBytes(len(c.NodeId))
+}
+
+// UsernameLen returns the length of EncodeUsername's output, without encoding it
+func (e Encoder) UsernameLen(ac *AuthenticatedCredential) int {
+	return e.encoding().EncodedLen(len(ac.Credential.GetNodeId()))
+}
+
+// PasswordLen returns the length of EncodePassword's output, without encoding it.
+// With Compress set the length depends on how well the password compresses, and the uncompressed length,
+// an upper bound, is returned.
+func (e Encoder) PasswordLen(ac *AuthenticatedCredential) int {
+	return e.encoding().EncodedLen(passwordSize(ac))
+}
+
+// passwordSize returns the size of the marshaled credential with its node ID stripped
+func passwordSize(ac *AuthenticatedCredential) int {
+	size := proto.Size(ac.Pb())
+	if ac.Credential == nil || len(ac.Credential.NodeId) == 0 {
+		return size
+	}
+	// Stripping the node ID removes its field from the inner message, which shrinks the inner message's length prefix
+	return size - protowire.SizeBytes(proto.Size(ac.Credential)) + protowire.SizeBytes(credentialSizeWithoutNodeID(ac.Credential))
+}
+
+// compressedMarker prefixes compressed passwords. Field number 0 is invalid in protobuf,
+// so no uncompressed password starts with a zero byte.
+const compressedMarker = 0x00
+
+var ErrInvalidCompression = errors.New("invalid compressed credential")
+
+// compressPassword returns the marker and deflated marshaled, or marshaled itself if compression doesn't help
+func compressPassword(marshaled []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(compressedMarker)
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return marshaled
+	}
+	if _, err := w.Write(marshaled); err != nil {
+		return marshaled
+	}
+	if err := w.Close(); err != nil {
+		return marshaled
+	}
+	if buf.Len() >= len(marshaled) {
+		return marshaled
+	}
+	return buf.Bytes()
+}
+
+// decompressPassword inflates a password compressed by compressPassword, and passes others through.
+// Passwords inflating to more than limit bytes are rejected, to defuse compression bombs.
+func decompressPassword(decoded []byte, limit int) ([]byte, error) {
+	if len(decoded) == 0 || decoded[0] != compressedMarker {
+		return decoded, nil
+	}
+	r := flate.NewReader(bytes.NewReader(decoded[1:]))
+	defer r.Close()
+	inflated, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, errors.Join(ErrInvalidCompression, err)
+	}
+	if len(inflated) > limit {
+		return nil, fmt.Errorf("%w: inflates to more than %d bytes", ErrInvalidCompression, limit)
+	}
+	return inflated, nil
+}
+
+// DefaultMaxCredentialSize is the default bound on the size of decoded passwords, both before and after decompression.
+// Credentials with every optional field at its limit, and long audience, issuer and partner names, are under 3 KiB.
+const DefaultMaxCredentialSize = 8 << 10
+
+// MaxPasswordBytes bounds the base64 decoded size of passwords, which is checked before decoding them,
+// so oversized passwords can't force large allocations, and their size once decompressed.
+// It may be changed before any credentials are decoded. WithMaxCredentialSize overrides it for a manager.
+var MaxPasswordBytes = DefaultMaxCredentialSize
+
+// MaxUsernameBytes bounds the base64 decoded size of usernames, which carry a single node ID
+const MaxUsernameBytes = NodeIDLength
+
+// MaxUnknownFieldBytes bounds the total size of the fields unknown to this version in a decoded credential.
+// By default credentials with unknown fields are rejected, so padding can't make this version store and re-marshal
+// data it doesn't understand. Raising it lets credentials with fields added by newer issuers decode, keeping the
+// fields, so they still verify. It may be changed before any credentials are decoded.
+var MaxUnknownFieldBytes = 0
+
+var (
+	ErrPasswordTooLarge      = fmt.Errorf("%w: password too large", ErrMalformedCredential)
+	ErrUsernameTooLarge      = fmt.Errorf("%w: username too large", ErrMalformedCredential)
+	ErrInvalidUsername       = fmt.Errorf("%w: username isn't a node ID", ErrMalformedCredential)
+	ErrEmptyPassword         = fmt.Errorf("%w: password holds no credential", ErrMalformedCredential)
+	ErrUnknownFieldsTooLarge = fmt.Errorf("%w: unknown fields too large", ErrMalformedCredential)
+	ErrNodeIDMismatch        = fmt.Errorf("%w: node ID doesn't match the username", ErrMalformedPassword)
+)
+
+// NodeIDMismatchError is returned when a password carries a node ID other than its username's.
+// It matches ErrNodeIDMismatch and ErrMalformedPassword.
+type NodeIDMismatchError struct {
+	Username []byte
+	Password []byte
+}
+
+func (e *NodeIDMismatchError) Error() string {
+	return fmt.Sprintf("%v: username carries 0x%x, password 0x%x", ErrNodeIDMismatch, e.Username, e.Password)
+}
+
+func (e *NodeIDMismatchError) Unwrap() error {
+	return ErrNodeIDMismatch
+}
+
+// unknownFieldsSize sums the sizes of the unknown fields of ac and its messages
+func unknownFieldsSize(ac *AuthenticatedCredential) int {
+	size := len(ac.Pb().ProtoReflect().GetUnknown()) + len(ac.Credential.ProtoReflect().GetUnknown())
+	for _, km := range ac.AdditionalMacs {
+		size += len(km.ProtoReflect().GetUnknown())
+	}
+	return size
+}
+
+type usernameConfig struct {
+	nodeIDLength int
+}
+
+// UsernameOption configures NodeIDFromUsername
+type UsernameOption func(*usernameConfig)
+
+// WithNodeIDLength makes NodeIDFromUsername reject node IDs which aren't length bytes long
+func WithNodeIDLength(length int) UsernameOption {
+	return func(c *usernameConfig) {
+		c.nodeIDLength = length
+	}
+}
+
+// NodeIDFromUsername decodes the node ID from a username produced by any Encoder, without decoding or verifying
+// the rest of the credential. It is meant for keying rate limits and logs before authentication, so the node ID
+// must not be trusted. Usernames longer than MaxUsernameBytes fail with ErrUsernameTooLarge, and invalid
+// base64url with ErrMalformedBase64.
+func NodeIDFromUsername(username string, opts ...UsernameOption) ([]byte, error) {
+	return AppendNodeIDFromUsername(nil, username, opts...)
+}
+
+// DecodeUsername decodes the node ID from a username produced by any Encoder, checking it as Base64URLDecode would:
+// usernames which don't decode to NodeIDLength bytes fail with ErrInvalidUsername. With DecodePassword and SetNodeID,
+// it splits Base64URLDecode in two, for usernames and passwords which arrive separately.
+func DecodeUsername(username string) ([]byte, error) {
+	nodeID, err := NodeIDFromUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateUsernameNodeID(nodeID); err != nil {
+		return nil, err
+	}
+	return nodeID, nil
+}
+
+// DecodePassword decodes a password produced by any Encoder, checking it as Base64URLDecode would,
+// without its username. Encoders strip the node ID from passwords, so the credential's is nil until SetNodeID
+// fills it in. The credential is not verified, and can't be until it has its node ID.
+func DecodePassword(password string) (*AuthenticatedCredential, error) {
+	decoded, err := appendDecodedPassword(nil, password, MaxPasswordBytes)
+	if err != nil {
+		return nil, err
+	}
+	return decodePassword(decoded, MaxPasswordBytes)
+}
+
+// ParseUnverified decodes a username and password exactly as Base64URLDecode does, for tooling such as log enrichment
+// which displays the fields of credentials it has no key to verify.
+//
+// Its MAC is NOT checked: anyone can make a token which parses, claiming any node ID, operator type or timestamp, so
+// the credential must never be used to make authorization decisions, nor passed along as if it were authentic.
+// Use Verify, or VerifyFromBasicAuth, for that.
+func ParseUnverified(username string, password string) (*AuthenticatedCredential, error) {
+	out := &AuthenticatedCredential{}
+	if err := out.Base64URLDecode(username, password); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AppendNodeIDFromUsername is like NodeIDFromUsername, but appends the node ID to dst.
+// It doesn't allocate if dst has room for MaxUsernameBytes more bytes, and no options are given.
+func AppendNodeIDFromUsername(dst []byte, username string, opts ...UsernameOption) ([]byte, error) {
+	var nodeIDLength int
+	if len(opts) > 0 {
+		nodeIDLength = usernameNodeIDLength(opts)
+	}
+
+	username = strings.TrimRight(username, "=")
+	if base64.RawURLEncoding.DecodedLen(len(username)) > MaxUsernameBytes {
+		return dst, fmt.Errorf("%w: more than %d bytes", ErrUsernameTooLarge, MaxUsernameBytes)
+	}
+	n := len(dst)
+	dst, err := appendDecodeBase64URL(dst, username)
+	if err != nil {
+		return dst, fmt.Errorf("%w: username: %w", ErrMalformedBase64, err)
+	}
+	if nodeIDLength > 0 && len(dst)-n != nodeIDLength {
+		return dst[:n], fmt.Errorf("%w. Expected %d, got %d", ErrInvalidNodeIDLength, nodeIDLength, len(dst)-n)
+	}
+	return dst, nil
+}
+
+// usernameNodeIDLength applies opts. It is kept apart from AppendNodeIDFromUsername,
+// so the config only escapes to the heap when there are options.
+func usernameNodeIDLength(opts []UsernameOption) int {
+	cfg := new(usernameConfig)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg.nodeIDLength
+}
+
+// AppendDecodedPassword base64url decodes a password produced by any Encoder and appends it to dst,
+// for use with VerifyRaw. Passwords longer than MaxPasswordBytes fail with ErrPasswordTooLarge before being decoded,
+// and invalid base64url with ErrMalformedBase64. It doesn't allocate if dst has room for the decoded password.
+func AppendDecodedPassword(dst []byte, password string) ([]byte, error) {
+	return appendDecodedPassword(dst, password, MaxPasswordBytes)
+}
+
+// appendDecodedPassword is AppendDecodedPassword, with limit in place of MaxPasswordBytes
+func appendDecodedPassword(dst []byte, password string, limit int) ([]byte, error) {
+	password = strings.TrimRight(password, "=")
+	if base64.RawURLEncoding.DecodedLen(len(password)) > limit {
+		return dst, fmt.Errorf("%w: more than %d bytes", ErrPasswordTooLarge, limit)
+	}
+	dst, err := appendDecodeBase64URL(dst, password)
+	if err != nil {
+		return dst, fmt.Errorf("%w: password: %w", ErrMalformedBase64, err)
+	}
+	return dst, nil
+}
+
+// decodeUsernamePassword decodes a base64url username and password, rejecting passwords decoding to more than
+// limit bytes before decoding them
+func decodeUsernamePassword(username, password string, limit int) ([]byte, []byte, error) {
+	if base64.RawURLEncoding.DecodedLen(len(strings.TrimRight(password, "="))) > limit {
+		return nil, nil, fmt.Errorf("%w: more than %d bytes", ErrPasswordTooLarge, limit)
+	}
+
+	nodeID, err := NodeIDFromUsername(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	decoded, err := appendDecodedPassword(nil, password, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nodeID, decoded, nil
+}
+
+// base64DecodeChunk is the number of base64 characters appendDecodeBase64URL decodes at a time.
+// It is a multiple of 4, so every chunk but the last decodes without partial quanta.
+const base64DecodeChunk = 256
+
+// appendDecodeBase64URL decodes unpadded base64url to dst. Decoding takes []byte, and converting s would allocate,
+// so s is copied to a stack buffer a chunk at a time instead. On error dst is returned unchanged.
+func appendDecodeBase64URL(dst []byte, s string) ([]byte, error) {
+	n := len(dst)
+	dst = slices.Grow(dst, base64.RawURLEncoding.DecodedLen(len(s)))
+	var chunk [base64DecodeChunk]byte
+	for offset := 0; offset < len(s); offset += base64DecodeChunk {
+		m := copy(chunk[:], s[offset:])
+		written, err := base64.RawURLEncoding.Decode(dst[len(dst):cap(dst)], chunk[:m])
+		if err != nil {
+			var corrupt base64.CorruptInputError
+			if errors.As(err, &corrupt) {
+				err = corrupt + base64.CorruptInputError(offset)
+			}
+			return dst[:n], err
+		}
+		dst = dst[:len(dst)+written]
+	}
+	return dst, nil
+}
+
+// decodeBase64URL decodes base64url with or without padding
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
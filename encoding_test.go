@@ -0,0 +1,990 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestEncoder tests that padded and raw encodings both decode, whichever produced them
+func TestEncoder(t *testing.T) {
+	cm := NewCredentialManager([]byte("Encoding test secret"))
+	// 20 byte node IDs always need padding
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		encoder Encoder
+		padded  bool
+	}{
+		{"Padded", Encoder{}, true},
+		{"Raw", RawEncoder, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			username := tc.encoder.EncodeUsername(cred)
+			password, err := tc.encoder.EncodePassword(cred)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasSuffix(username, "=") != tc.padded {
+				t.Errorf("Unexpected padding in username %q", username)
+			}
+			if !tc.padded && strings.Contains(password, "=") {
+				t.Errorf("Unexpected padding in password %q", password)
+			}
+
+			var decoded AuthenticatedCredential
+			if err := decoded.Base64URLDecode(username, password); err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(decoded.Pb(), cred.Pb()) {
+				t.Error("Decoded credential doesn't match")
+			}
+			if _, err := cm.Verify(&decoded); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username := cred.Base64URLEncodeUsername(); username != (Encoder{}).EncodeUsername(cred) {
+		t.Errorf("Expected Base64URLEncodeUsername to pad, got %q", username)
+	}
+	if p, _ := (Encoder{}).EncodePassword(cred); p != password {
+		t.Errorf("Expected Base64URLEncodePassword to pad, got %q", password)
+	}
+}
+
+// TestEncodeUsername tests that usernames are encoded as before, and that credentials without a node ID fail
+// instead of producing an empty username
+func TestEncodeUsername(t *testing.T) {
+	cm := NewCredentialManager([]byte("Encoding test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username, err := cred.EncodeUsername(); err != nil || username != cred.Base64URLEncodeUsername() {
+		t.Errorf("Expected %q, got %q, %v", cred.Base64URLEncodeUsername(), username, err)
+	}
+
+	testCases := []struct {
+		name string
+		cred *AuthenticatedCredential
+	}{
+		{"Nil", nil},
+		{"NilCredential", &AuthenticatedCredential{}},
+		{"NoNodeID", &AuthenticatedCredential{Credential: &pb.Credential{Timestamp: 1}, Mac: cred.Mac}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if username, err := tc.cred.EncodeUsername(); !errors.Is(err, SerializationError) || username != "" {
+				t.Errorf("Expected SerializationError, got %q, %v", username, err)
+			}
+			if tc.cred == nil {
+				return
+			}
+			if _, err := tc.cred.MarshalText(); !errors.Is(err, SerializationError) {
+				t.Errorf("Expected MarshalText to fail with SerializationError, got %v", err)
+			}
+			if _, err := tc.cred.ToURLValues(); !errors.Is(err, SerializationError) {
+				t.Errorf("Expected ToURLValues to fail with SerializationError, got %v", err)
+			}
+		})
+	}
+}
+
+// TestEncoderCompress tests that compression is only used when it helps, and always decodes
+func TestEncoderCompress(t *testing.T) {
+	cm := NewCredentialManager([]byte("Encoding test secret"))
+	small, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := cm.CreateWithMetadata(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, map[string]string{
+		"note":    strings.Repeat("rescue ", MaxMetadataValueLength/7),
+		"comment": strings.Repeat("rescue ", MaxMetadataValueLength/7),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compressing := Encoder{Compress: true}
+	testCases := []struct {
+		name       string
+		cred       *AuthenticatedCredential
+		compressed bool
+	}{
+		{"Small", small, false},
+		{"Large", large, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plain, err := tc.cred.Base64URLEncodePassword()
+			if err != nil {
+				t.Fatal(err)
+			}
+			password, err := compressing.EncodePassword(tc.cred)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.compressed != (len(password) < len(plain)) || (!tc.compressed && password != plain) {
+				t.Errorf("Unexpected compression: %d bytes, %d uncompressed", len(password), len(plain))
+			}
+
+			var decoded AuthenticatedCredential
+			if err := decoded.Base64URLDecode(tc.cred.Base64URLEncodeUsername(), password); err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(decoded.Pb(), tc.cred.Pb()) {
+				t.Error("Decoded credential doesn't match")
+			}
+			if _, err := cm.Verify(&decoded); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestDecompressPasswordErrors tests that corrupt and oversized compressed passwords are rejected
+func TestDecompressPasswordErrors(t *testing.T) {
+	bomb := compressPassword(make([]byte, MaxPasswordBytes+1))
+	if bomb[0] != compressedMarker {
+		t.Fatal("Expected the bomb to compress")
+	}
+
+	testCases := []struct {
+		name     string
+		password []byte
+	}{
+		{"Corrupt", []byte{compressedMarker, 0xff, 0xff}},
+		{"Bomb", bomb},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var decoded AuthenticatedCredential
+			err := decoded.Base64URLDecode(base64.URLEncoding.EncodeToString(make([]byte, NodeIDLength)), base64.URLEncoding.EncodeToString(tc.password))
+			if !errors.Is(err, ErrInvalidCompression) {
+				t.Errorf("Expected ErrInvalidCompression, got %v", err)
+			}
+		})
+	}
+}
+
+// TestEncodedLen tests that the predicted lengths match the encodings, with and without padding
+func TestEncodedLen(t *testing.T) {
+	cm := NewCredentialManager([]byte("Encoding test secret"), []byte("Second secret"))
+	dual := NewDualSignManager([]byte("Encoding test secret"), []byte("Second secret"))
+
+	plain, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	large, err := dual.CreateWithMetadata(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, map[string]string{
+		"a": strings.Repeat("a", MaxMetadataValueLength), "b": strings.Repeat("b", MaxMetadataValueLength),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	noNodeID := &AuthenticatedCredential{Credential: &pb.Credential{Timestamp: 1}, Mac: []byte("mac")}
+
+	for _, cred := range []*AuthenticatedCredential{plain, large, noNodeID} {
+		for _, e := range []Encoder{{}, RawEncoder} {
+			password, err := e.EncodePassword(cred)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := e.PasswordLen(cred); got != len(password) {
+				t.Errorf("Raw %v: expected a password length of %d, got %d", e.Raw, len(password), got)
+			}
+			if got, expected := e.UsernameLen(cred), len(e.EncodeUsername(cred)); got != expected {
+				t.Errorf("Raw %v: expected a username length of %d, got %d", e.Raw, expected, got)
+			}
+		}
+	}
+
+	password, err := large.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if large.EncodedPasswordLen() != len(password) || large.EncodedUsernameLen() != len(large.Base64URLEncodeUsername()) {
+		t.Error("Encoded lengths don't match Base64URLEncodeUsername and Base64URLEncodePassword")
+	}
+
+	// Compression never makes passwords longer than predicted
+	compressed, err := Encoder{Compress: true}.EncodePassword(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) > (Encoder{Compress: true}).PasswordLen(large) {
+		t.Error("Compressed password is longer than the predicted upper bound")
+	}
+}
+
+// TestDecodeSizeLimits tests that oversized usernames and passwords are rejected, and the largest credentials aren't
+func TestDecodeSizeLimits(t *testing.T) {
+	cm := NewDualSignManager([]byte("Encoding test secret"), []byte("Second secret"), WithAudience("rescue-proxy"), WithIssuer("bot"), WithChainID(17000))
+	cred, err := cm.CreateBundle(time.Now(), batchNodeIDs(MaxBundleNodeIDs), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fill the metadata with the largest entries allowed
+	for i := 0; i < MaxMetadataEntries; i++ {
+		if err := cred.SetMetadata(strings.Repeat(string(rune('a'+i)), MaxMetadataKeyLength), strings.Repeat("v", MaxMetadataValueLength)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cred.Credential.Nonce = make([]byte, 32)
+	cred.Credential.FeeRecipient = make([]byte, FeeRecipientLength)
+	for i := 0; i < MaxScopeNames; i++ {
+		cred.Credential.ScopeNames = append(cred.Credential.ScopeNames, strings.Repeat(string(rune('a'+i)), MaxScopeNameLength))
+	}
+	cred.Credential.Audience = strings.Repeat("a", 64)
+	cred.Credential.Issuer = strings.Repeat("i", 64)
+	cred.Credential.PartnerId = strings.Repeat("p", 64)
+
+	// Leave room for fields yet to be added
+	if size := proto.Size(cred.Pb()); size > DefaultMaxCredentialSize/2 {
+		t.Errorf("Expected the largest credential to be at most half of DefaultMaxCredentialSize, got %d bytes", size)
+	}
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AuthenticatedCredential
+	if err := decoded.Base64URLDecode(cred.Base64URLEncodeUsername(), password); err != nil {
+		t.Fatalf("Expected the largest credential to decode, got %v", err)
+	}
+
+	huge := strings.Repeat("A", base64.RawURLEncoding.EncodedLen(MaxPasswordBytes+1))
+	if err := decoded.Base64URLDecode(cred.Base64URLEncodeUsername(), huge); !errors.Is(err, ErrPasswordTooLarge) {
+		t.Errorf("Expected ErrPasswordTooLarge, got %v", err)
+	}
+	if _, err := cm.VerifyFromBasicAuth(cred.Base64URLEncodeUsername(), huge); !errors.Is(err, ErrPasswordTooLarge) {
+		t.Errorf("Expected ErrPasswordTooLarge from VerifyFromBasicAuth, got %v", err)
+	}
+	if _, err := cm.VerifyRaw(cred.Credential.NodeId, make([]byte, MaxPasswordBytes+1)); !errors.Is(err, ErrPasswordTooLarge) {
+		t.Errorf("Expected ErrPasswordTooLarge from VerifyRaw, got %v", err)
+	}
+
+	longUsername := base64.URLEncoding.EncodeToString(make([]byte, MaxUsernameBytes+1))
+	if err := decoded.Base64URLDecode(longUsername, password); !errors.Is(err, ErrUsernameTooLarge) {
+		t.Errorf("Expected ErrUsernameTooLarge, got %v", err)
+	}
+	if !errors.Is(ErrPasswordTooLarge, ErrMalformedCredential) || !errors.Is(ErrUsernameTooLarge, ErrMalformedCredential) {
+		t.Error("Expected the size errors to be malformed credential errors")
+	}
+}
+
+// TestWithMaxCredentialSize tests that managers can decode passwords larger or smaller than MaxPasswordBytes
+func TestWithMaxCredentialSize(t *testing.T) {
+	secret := []byte("Encoding test secret")
+	audience := strings.Repeat("a", DefaultMaxCredentialSize)
+	cred, err := NewCredentialManagerWithOptions(secret, nil, WithAudience(audience)).Create(time.Now(), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	username := cred.Base64URLEncodeUsername()
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := Encoder{Compress: true}.EncodePassword(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewCredentialManagerWithOptions(secret, nil, WithAudience(audience)).VerifyFromBasicAuth(username, password); !errors.Is(err, ErrPasswordTooLarge) {
+		t.Errorf("Expected ErrPasswordTooLarge by default, got %v", err)
+	}
+	if _, err := NewCredentialManagerWithOptions(secret, nil, WithAudience(audience)).VerifyFromBasicAuth(username, compressed); !errors.Is(err, ErrInvalidCompression) {
+		t.Errorf("Expected compressed passwords inflating past the limit to fail with ErrInvalidCompression, got %v", err)
+	}
+
+	larger := NewCredentialManagerWithOptions(secret, nil, WithAudience(audience), WithMaxCredentialSize(2*DefaultMaxCredentialSize))
+	for _, p := range []string{password, compressed} {
+		if _, err := larger.VerifyFromBasicAuth(username, p); err != nil {
+			t.Errorf("Expected a larger limit to accept the credential, got %v", err)
+		}
+	}
+	raw, err := appendPassword(nil, cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := larger.VerifyRaw(cred.Credential.NodeId, raw); err != nil {
+		t.Errorf("Expected a larger limit to accept the raw credential, got %v", err)
+	}
+
+	small, err := NewCredentialManager(secret).Create(time.Now(), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallPassword, err := small.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	smaller := NewCredentialManagerWithOptions(secret, nil, WithMaxCredentialSize(16))
+	if _, err := smaller.VerifyFromBasicAuth(username, smallPassword); !errors.Is(err, ErrPasswordTooLarge) {
+		t.Errorf("Expected a smaller limit to reject the credential, got %v", err)
+	}
+}
+
+// TestBase64URLDecodeReplaces tests that decoding into a credential leaves nothing of its previous contents
+func TestBase64URLDecodeReplaces(t *testing.T) {
+	cm := NewDualSignManager([]byte("Encoding test secret"), []byte("Second secret"))
+	old, err := cm.CreateWithMetadata(time.Now(), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO, map[string]string{"ticket": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	junk := protowire.AppendBytes(protowire.AppendTag(nil, 99, protowire.BytesType), make([]byte, 1<<10))
+	old.Pb().ProtoReflect().SetUnknown(junk)
+	old.Credential.ProtoReflect().SetUnknown(junk)
+
+	cred, err := NewCredentialManager([]byte("Encoding test secret")).Create(time.Now(), batchNodeIDs(3)[2], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := old.Base64URLDecode(cred.Base64URLEncodeUsername(), password); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(old.Pb(), cred.Pb()) {
+		t.Errorf("Expected exactly the decoded credential, got %v", old.Pb())
+	}
+	if len(old.Pb().ProtoReflect().GetUnknown()) != 0 || len(old.Credential.ProtoReflect().GetUnknown()) != 0 {
+		t.Error("Expected the previous unknown fields to be gone")
+	}
+}
+
+// TestReset tests that pooled credentials keep nothing of their previous contents
+func TestReset(t *testing.T) {
+	cm := NewCredentialManager([]byte("Encoding test secret"))
+	pool := sync.Pool{New: func() any { return new(AuthenticatedCredential) }}
+
+	for i, nodeID := range batchNodeIDs(3) {
+		cred, err := cm.CreateWithMetadata(time.Now(), nodeID, pb.OperatorType_OT_SOLO, map[string]string{"i": fmt.Sprint(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		password, err := cred.Base64URLEncodePassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ac := pool.Get().(*AuthenticatedCredential)
+		ac.Reset()
+		if err := ac.Base64URLDecode(cred.Base64URLEncodeUsername(), password); err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(ac.Pb(), cred.Pb()) {
+			t.Errorf("Expected exactly the decoded credential, got %v", ac.Pb())
+		}
+		ac.Pb().ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 1))
+		pool.Put(ac)
+	}
+
+	ac := pool.Get().(*AuthenticatedCredential)
+	ac.Reset()
+	if err := ac.Base64URLDecode("not base64!", ""); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if ac.Credential != nil || ac.Mac != nil || len(ac.Pb().ProtoReflect().GetUnknown()) != 0 {
+		t.Errorf("Expected nothing to survive Reset, got %v", ac.Pb())
+	}
+}
+
+// TestNodeIDFromUsername tests extracting node IDs from usernames, with and without a length requirement
+func TestNodeIDFromUsername(t *testing.T) {
+	nodeID := make([]byte, 20)
+	nodeID[19] = 0xff
+	cred := &AuthenticatedCredential{Credential: &pb.Credential{NodeId: nodeID}}
+
+	for _, e := range []Encoder{{}, RawEncoder} {
+		decoded, err := NodeIDFromUsername(e.EncodeUsername(cred), WithNodeIDLength(NodeIDLength))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(decoded) != string(nodeID) {
+			t.Errorf("Expected %x, got %x", nodeID, decoded)
+		}
+	}
+
+	short := base64.URLEncoding.EncodeToString([]byte("short"))
+	if decoded, err := NodeIDFromUsername(short); err != nil || string(decoded) != "short" {
+		t.Errorf("Expected any length without WithNodeIDLength, got %x, %v", decoded, err)
+	}
+	if _, err := NodeIDFromUsername(short, WithNodeIDLength(NodeIDLength)); !errors.Is(err, ErrInvalidNodeIDLength) {
+		t.Errorf("Expected ErrInvalidNodeIDLength, got %v", err)
+	}
+	if _, err := NodeIDFromUsername("not base64!"); !errors.Is(err, ErrMalformedBase64) {
+		t.Errorf("Expected ErrMalformedBase64, got %v", err)
+	}
+	if _, err := NodeIDFromUsername(strings.Repeat("A", 1000)); !errors.Is(err, ErrUsernameTooLarge) {
+		t.Errorf("Expected ErrUsernameTooLarge, got %v", err)
+	}
+}
+
+// roundTripCredential builds a credential from fuzzer input, or returns nil if the input can't form a valid credential
+func roundTripCredential(nodeID []byte, timestamp int64, operatorType int32, mac []byte, nonce []byte, audience string, scopes uint64, metadataValue string) *AuthenticatedCredential {
+	// proto3 strings must be valid UTF-8, and usernames only carry node IDs up to MaxUsernameBytes
+	if len(nodeID) > MaxUsernameBytes || !utf8.ValidString(audience) || !utf8.ValidString(metadataValue) {
+		return nil
+	}
+	cred := &AuthenticatedCredential{
+		Credential: &pb.Credential{
+			NodeId:       nodeID,
+			Timestamp:    timestamp,
+			OperatorType: OperatorType(operatorType),
+			Nonce:        nonce,
+			Audience:     audience,
+			Scopes:       scopes,
+			Version:      CurrentVersion,
+		},
+		Mac: mac,
+	}
+	if metadataValue != "" {
+		if err := cred.SetMetadata("key", metadataValue); err != nil {
+			return nil
+		}
+	}
+	return cred
+}
+
+// assertRoundTrip checks that cred survives JSON and every base64url encoding unchanged,
+// except that JSON rejects timestamps so large they look like milliseconds
+func assertRoundTrip(t *testing.T, cred *AuthenticatedCredential) {
+	t.Helper()
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timestamp := cred.Credential.Timestamp
+	jsonTimestampValid := timestamp < maxJSONTimestampSeconds && timestamp > -maxJSONTimestampSeconds
+	if len(cred.Mac) > 0 && len(cred.Mac) != MACLength {
+		// No manager computes such MACs, so they are rejected when decoded
+		if err := json.Unmarshal(data, new(AuthenticatedCredential)); jsonTimestampValid && !errors.Is(err, ErrInvalidMACLength) {
+			t.Fatalf("Expected ErrInvalidMACLength from JSON, got %v", err)
+		}
+		password, err := cred.Base64URLEncodePassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = new(AuthenticatedCredential).Base64URLDecode(cred.Base64URLEncodeUsername(), password)
+		if !errors.Is(err, ErrInvalidMACLength) && !errors.Is(err, ErrPasswordTooLarge) {
+			t.Fatalf("Expected ErrInvalidMACLength from base64url, got %v", err)
+		}
+		return
+	}
+	var fromJSON AuthenticatedCredential
+	if err := json.Unmarshal(data, &fromJSON); !jsonTimestampValid {
+		if !errors.Is(err, ErrMalformedCredential) {
+			t.Fatalf("Expected timestamp %d to be rejected from JSON, got %v", timestamp, err)
+		}
+	} else if err != nil {
+		t.Fatalf("Failed to decode %s: %v", data, err)
+	} else if !proto.Equal(cred.Pb(), fromJSON.Pb()) {
+		t.Fatalf("JSON round trip changed the credential: %v != %v", cred.Pb(), fromJSON.Pb())
+	}
+
+	for _, e := range []Encoder{{}, RawEncoder, {Compress: true}} {
+		password, err := e.EncodePassword(cred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded AuthenticatedCredential
+		err = decoded.Base64URLDecode(e.EncodeUsername(cred), password)
+		if errors.Is(err, ErrPasswordTooLarge) && len(password) > base64.URLEncoding.EncodedLen(MaxPasswordBytes) {
+			continue
+		}
+		if errors.Is(err, ErrInvalidCompression) && proto.Size(cred.Pb()) > MaxPasswordBytes {
+			// Compressed passwords inflating past the limit are rejected too
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%+v: %v", e, err)
+		}
+		if !proto.Equal(cred.Pb(), decoded.Pb()) {
+			t.Fatalf("%+v: base64url round trip changed the credential: %v != %v", e, cred.Pb(), decoded.Pb())
+		}
+	}
+}
+
+// FuzzRoundTrip tests that valid credentials survive the JSON and base64url encodings unchanged
+func FuzzRoundTrip(f *testing.F) {
+	// Boundary lengths of node IDs and MACs, and extreme numbers
+	for _, nodeIDLen := range []int{0, 1, 19, NodeIDLength} {
+		for _, macLen := range []int{0, 1, 31, 32, 33} {
+			f.Add(make([]byte, nodeIDLen), int64(1700000000), int32(pb.OperatorType_OT_SOLO), make([]byte, macLen), []byte(nil), "", uint64(0), "")
+		}
+	}
+	f.Add(bytes.Repeat([]byte{0xff}, NodeIDLength), int64(math.MaxInt64), int32(math.MaxInt32), []byte{0}, make([]byte, 32), "rescue-proxy", uint64(math.MaxUint64), "value")
+	f.Add([]byte{0}, int64(math.MinInt64), int32(math.MinInt32), []byte{0xff}, []byte{0}, "é", uint64(1), "")
+	f.Add(make([]byte, NodeIDLength+1), int64(0), int32(0), []byte(nil), []byte(nil), "", uint64(0), "")
+	f.Add(make([]byte, NodeIDLength), int64(-1), int32(-1), make([]byte, MaxPasswordBytes), []byte(nil), "", uint64(0), strings.Repeat("v", MaxMetadataValueLength))
+
+	f.Fuzz(func(t *testing.T, nodeID []byte, timestamp int64, operatorType int32, mac []byte, nonce []byte, audience string, scopes uint64, metadataValue string) {
+		cred := roundTripCredential(nodeID, timestamp, operatorType, mac, nonce, audience, scopes, metadataValue)
+		if cred == nil {
+			t.Skip()
+		}
+		if len(nodeID) != NodeIDLength {
+			// Such credentials can be created, but their usernames don't decode
+			password, err := cred.Base64URLEncodePassword()
+			if err != nil {
+				t.Skip()
+			}
+			var decoded AuthenticatedCredential
+			if err := decoded.Base64URLDecode(cred.Base64URLEncodeUsername(), password); !errors.Is(err, ErrInvalidUsername) && !errors.Is(err, ErrPasswordTooLarge) {
+				t.Fatalf("Expected ErrInvalidUsername, got %v", err)
+			}
+			return
+		}
+		assertRoundTrip(t, cred)
+	})
+}
+
+type base64URLDecodeCase struct {
+	name     string
+	username string
+	password string
+	// err is the error expected, or nil if the credential decodes
+	err error
+}
+
+// base64URLDecodeCases are the regression cases of Base64URLDecode's validation, around a credential created by cm
+func base64URLDecodeCases(tb testing.TB, cm *CredentialManager) []base64URLDecodeCase {
+	nodeID := batchNodeIDs(1)[0]
+	cred, err := cm.Create(time.Unix(1700000000, 0), nodeID, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	username := cred.Base64URLEncodeUsername()
+	valid, err := appendPassword(nil, cred)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	withNodeID, err := proto.Marshal(cred.Pb())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	encode := func(parts ...[]byte) string {
+		return base64.URLEncoding.EncodeToString(bytes.Join(parts, nil))
+	}
+	unknownField := func(size int) []byte {
+		return protowire.AppendBytes(protowire.AppendTag(nil, 99, protowire.BytesType), make([]byte, size))
+	}
+
+	return []base64URLDecodeCase{
+		{"Valid", username, encode(valid), nil},
+		{"MatchingNodeID", username, encode(withNodeID), nil},
+
+		{"EmptyUsername", "", encode(valid), ErrInvalidUsername},
+		{"ShortUsername", base64.URLEncoding.EncodeToString(nodeID[:NodeIDLength-1]), encode(valid), ErrInvalidUsername},
+		{"OneByteUsername", "AA", encode(valid), ErrInvalidUsername},
+		{"LongUsername", base64.URLEncoding.EncodeToString(make([]byte, NodeIDLength+1)), encode(valid), ErrUsernameTooLarge},
+		{"EmptyPassword", username, "", ErrEmptyPassword},
+		{"EmptyCredential", username, encode([]byte{0x0a, 0x00}), ErrEmptyPassword},
+		{"EmptyCompressed", username, encode(compressPassword(nil)), ErrEmptyPassword},
+		{"MismatchedNodeID", base64.URLEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, NodeIDLength)), encode(withNodeID), ErrNodeIDMismatch},
+		{"UnknownField", username, encode(valid, unknownField(0)), ErrUnknownFieldsTooLarge},
+		{"UnknownFieldsTooLarge", username, encode(valid, unknownField(MaxUnknownFieldBytes)), ErrUnknownFieldsTooLarge},
+		{"TrailingGarbage", username, encode(valid, []byte{0xff, 0xff}), ErrMalformedProto},
+		{"TrailingZero", username, encode(valid, []byte{0}), ErrMalformedProto},
+		{"Truncated", username, encode(valid[:len(valid)-1]), ErrMalformedProto},
+	}
+}
+
+// TestBase64URLDecodeValidation tests that malformed usernames and passwords fail with distinct errors
+func TestBase64URLDecodeValidation(t *testing.T) {
+	cm := NewCredentialManager([]byte("Decode validation secret"))
+	for _, tc := range base64URLDecodeCases(t, cm) {
+		t.Run(tc.name, func(t *testing.T) {
+			var decoded AuthenticatedCredential
+			err := decoded.Base64URLDecode(tc.username, tc.password)
+			if tc.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := cm.Verify(&decoded); err != nil {
+					t.Errorf("Expected the decoded credential to verify, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("Expected %v, got %v", tc.err, err)
+			}
+			if !errors.Is(err, ErrMalformedCredential) && !errors.Is(err, ErrMalformedPassword) {
+				t.Errorf("Expected %v to be a malformed credential error", err)
+			}
+		})
+	}
+}
+
+// TestDecodeUsernamePassword tests that decoding a username and password separately and setting the node ID
+// accepts and rejects the same credentials as Base64URLDecode
+func TestDecodeUsernamePassword(t *testing.T) {
+	cm := NewCredentialManager([]byte("Decode validation secret"))
+	for _, tc := range base64URLDecodeCases(t, cm) {
+		t.Run(tc.name, func(t *testing.T) {
+			var whole AuthenticatedCredential
+			wholeErr := whole.Base64URLDecode(tc.username, tc.password)
+
+			nodeID, err := DecodeUsername(tc.username)
+			var cred *AuthenticatedCredential
+			if err == nil {
+				cred, err = DecodePassword(tc.password)
+			}
+			if err == nil {
+				err = cred.SetNodeID(nodeID)
+			}
+
+			if tc.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !proto.Equal(cred.Pb(), whole.Pb()) {
+					t.Errorf("Expected %v, got %v", whole.Pb(), cred.Pb())
+				}
+				if _, err := cm.Verify(cred); err != nil {
+					t.Errorf("Expected the credential to verify, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.err) || !errors.Is(wholeErr, tc.err) {
+				t.Errorf("Expected %v, got %v separately and %v together", tc.err, err, wholeErr)
+			}
+		})
+	}
+
+	// Passwords produced by an Encoder carry no node ID until it's set
+	cred, err := cm.Create(time.Now(), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodePassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Credential.NodeId != nil {
+		t.Errorf("Expected a nil node ID, got %x", decoded.Credential.NodeId)
+	}
+	if _, err := cm.Verify(decoded); err == nil {
+		t.Error("Expected a credential without its node ID not to verify")
+	}
+	if err := decoded.SetNodeID(make([]byte, NodeIDLength-1)); !errors.Is(err, ErrInvalidUsername) {
+		t.Errorf("Expected ErrInvalidUsername, got %v", err)
+	}
+	if err := decoded.SetNodeID(cred.Credential.NodeId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(decoded); err != nil {
+		t.Errorf("Expected the merged credential to verify, got %v", err)
+	}
+	if err := decoded.SetNodeID(batchNodeIDs(3)[2]); !errors.Is(err, ErrNodeIDMismatch) {
+		t.Errorf("Expected ErrNodeIDMismatch when changing the node ID, got %v", err)
+	}
+}
+
+// FuzzBase64URLDecode tests that whatever Base64URLDecode accepts is a credential for a whole node ID,
+// which encodes back to a password that decodes to the same credential
+func FuzzBase64URLDecode(f *testing.F) {
+	cm := NewCredentialManager([]byte("Fuzz secret"))
+	cred, err := cm.CreateWithMetadata(time.Unix(1700000000, 0), batchNodeIDs(1)[0], pb.OperatorType_OT_SOLO, map[string]string{"k": "v"})
+	if err != nil {
+		f.Fatal(err)
+	}
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(cred.Base64URLEncodeUsername(), password)
+	for _, tc := range base64URLDecodeCases(f, cm) {
+		f.Add(tc.username, tc.password)
+	}
+
+	f.Fuzz(func(t *testing.T, username, password string) {
+		var decoded AuthenticatedCredential
+		if err := decoded.Base64URLDecode(username, password); err != nil {
+			return
+		}
+		if len(decoded.Credential.NodeId) != NodeIDLength {
+			t.Fatalf("Decoded a %d byte node ID", len(decoded.Credential.NodeId))
+		}
+		if size := unknownFieldsSize(&decoded); size > MaxUnknownFieldBytes {
+			t.Fatalf("Decoded %d bytes of unknown fields", size)
+		}
+		reencoded, err := decoded.Base64URLEncodePassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again AuthenticatedCredential
+		if err := again.Base64URLDecode(decoded.Base64URLEncodeUsername(), reencoded); err != nil {
+			t.Fatalf("Re-encoded credential doesn't decode: %v", err)
+		}
+		if !proto.Equal(decoded.Pb(), again.Pb()) {
+			t.Fatalf("Re-encoding changed the credential: %v != %v", decoded.Pb(), again.Pb())
+		}
+	})
+}
+
+// TestBase64URLRoundTripProperty tests that decoding the encoded username and password reproduces any credential
+func TestBase64URLRoundTripProperty(t *testing.T) {
+	property := func(nodeID []byte, timestamp int64, operatorType int32, mac []byte, nonce []byte, scopes uint64) bool {
+		nodeID = append(nodeID, make([]byte, NodeIDLength)...)[:NodeIDLength]
+		if len(mac) > 0 {
+			mac = append(mac, make([]byte, MACLength)...)[:MACLength]
+		}
+		cred := roundTripCredential(nodeID, timestamp, operatorType, mac, nonce, "", scopes, "")
+		password, err := cred.Base64URLEncodePassword()
+		if err != nil {
+			return false
+		}
+		var decoded AuthenticatedCredential
+		if err := decoded.Base64URLDecode(cred.Base64URLEncodeUsername(), password); err != nil {
+			return false
+		}
+		return proto.Equal(cred.Pb(), decoded.Pb())
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestAppendEncoders tests that the append encoders and decoders match the string ones, and don't allocate
+func TestAppendEncoders(t *testing.T) {
+	cm := NewDualSignManager([]byte("Encoding test secret"), []byte("Second secret"), WithAudience("rescue-proxy"))
+	cred, err := cm.CreateWithMetadata(time.Now(), batchNodeIDs(1)[0], pb.OperatorType_OT_SOLO, map[string]string{"a": "1", "b": "2", "c": "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The password proto matches deterministic proto.Marshal of the credential without its node ID, unknown fields included
+	withUnknown := (*AuthenticatedCredential)(proto.Clone(cred.Pb()).(*pb.AuthenticatedCredential))
+	unknown := protowire.AppendVarint(protowire.AppendTag(nil, 100, protowire.VarintType), 1)
+	withUnknown.Credential.ProtoReflect().SetUnknown(unknown)
+	withUnknown.AdditionalMacs[0].ProtoReflect().SetUnknown(unknown)
+	withUnknown.Pb().ProtoReflect().SetUnknown(unknown)
+	stripped := proto.Clone(withUnknown.Pb()).(*pb.AuthenticatedCredential)
+	stripped.Credential.NodeId = nil
+	expected, err := proto.MarshalOptions{Deterministic: true}.Marshal(stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marshaled, err := appendPassword(nil, withUnknown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(marshaled, expected) {
+		t.Errorf("Expected password proto %x, got %x", expected, marshaled)
+	}
+
+	prefix := []byte("prefix:")
+	for _, e := range []Encoder{{}, RawEncoder, {Compress: true}} {
+		password, err := e.EncodePassword(cred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		appended, err := e.AppendPassword(slices.Clone(prefix), cred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(appended) != string(prefix)+password {
+			t.Errorf("%+v: expected %q, got %q", e, string(prefix)+password, appended)
+		}
+		if username := e.AppendUsername(slices.Clone(prefix), cred); string(username) != string(prefix)+e.EncodeUsername(cred) {
+			t.Errorf("%+v: unexpected username %q", e, username)
+		}
+
+		nodeID, err := AppendNodeIDFromUsername(slices.Clone(prefix), e.EncodeUsername(cred))
+		if err != nil || !bytes.Equal(nodeID, append(slices.Clone(prefix), cred.Credential.NodeId...)) {
+			t.Errorf("%+v: unexpected node ID %x, %v", e, nodeID, err)
+		}
+		decoded, err := AppendDecodedPassword(nil, password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cm.VerifyRaw(cred.Credential.NodeId, decoded); err != nil {
+			t.Errorf("%+v: %v", e, err)
+		}
+	}
+
+	// Reused buffers don't allocate
+	username := cred.AppendUsername(nil)
+	password, err := cred.AppendPassword(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedUsername, encodedPassword := string(username), string(password)
+	nodeID := make([]byte, 0, MaxUsernameBytes)
+	decoded := make([]byte, 0, len(password))
+	allocs := testing.AllocsPerRun(100, func() {
+		username = cred.AppendUsername(username[:0])
+		password, _ = cred.AppendPassword(password[:0])
+		nodeID, _ = AppendNodeIDFromUsername(nodeID[:0], encodedUsername)
+		decoded, _ = AppendDecodedPassword(decoded[:0], encodedPassword)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %v", allocs)
+	}
+}
+
+// TestAppendDecodeErrors tests that the append decoders leave dst alone on errors, and report where input is corrupt
+func TestAppendDecodeErrors(t *testing.T) {
+	prefix := []byte("prefix")
+	password := strings.Repeat("A", 300) + "!"
+	dst, err := AppendDecodedPassword(prefix, password)
+	var corrupt base64.CorruptInputError
+	if !errors.Is(err, ErrMalformedBase64) || !errors.As(err, &corrupt) || corrupt != 300 {
+		t.Errorf("Expected ErrMalformedBase64 at offset 300, got %v", err)
+	}
+	if string(dst) != string(prefix) {
+		t.Errorf("Expected dst to be unchanged, got %q", dst)
+	}
+
+	username := base64.URLEncoding.EncodeToString([]byte("short"))
+	if dst, err := AppendNodeIDFromUsername(prefix, username, WithNodeIDLength(NodeIDLength)); !errors.Is(err, ErrInvalidNodeIDLength) || string(dst) != string(prefix) {
+		t.Errorf("Expected ErrInvalidNodeIDLength and an unchanged dst, got %q, %v", dst, err)
+	}
+
+	invalid := &AuthenticatedCredential{Credential: &pb.Credential{NodeId: make([]byte, 20), Audience: "\xff"}}
+	if _, err := invalid.AppendPassword(nil); !errors.Is(err, SerializationError) {
+		t.Errorf("Expected SerializationError for invalid UTF-8, got %v", err)
+	}
+}
+
+func BenchmarkBase64URLEncode(b *testing.B) {
+	cm := NewCredentialManager([]byte("Encoding test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("String", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = cred.Base64URLEncodeUsername()
+			if _, err := cred.Base64URLEncodePassword(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Append", func(b *testing.B) {
+		b.ReportAllocs()
+		var username, password []byte
+		for i := 0; i < b.N; i++ {
+			username = cred.AppendUsername(username[:0])
+			if password, err = cred.AppendPassword(password[:0]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkBase64URLDecode(b *testing.B) {
+	cm := NewCredentialManager([]byte("Encoding test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		b.Fatal(err)
+	}
+	username := cred.Base64URLEncodeUsername()
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("String", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := decodeUsernamePassword(username, password, MaxPasswordBytes); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Append", func(b *testing.B) {
+		b.ReportAllocs()
+		var nodeID, decoded []byte
+		for i := 0; i < b.N; i++ {
+			if nodeID, err = AppendNodeIDFromUsername(nodeID[:0], username); err != nil {
+				b.Fatal(err)
+			}
+			if decoded, err = AppendDecodedPassword(decoded[:0], password); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// TestParseUnverified tests that ParseUnverified decodes as Base64URLDecode does, including credentials with bad MACs
+func TestParseUnverified(t *testing.T) {
+	cm := NewCredentialManager([]byte("Decode validation secret"))
+	for _, tc := range base64URLDecodeCases(t, cm) {
+		t.Run(tc.name, func(t *testing.T) {
+			var whole AuthenticatedCredential
+			wholeErr := whole.Base64URLDecode(tc.username, tc.password)
+			cred, err := ParseUnverified(tc.username, tc.password)
+			if tc.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !proto.Equal(cred.Pb(), whole.Pb()) {
+					t.Errorf("Expected %v, got %v", whole.Pb(), cred.Pb())
+				}
+				return
+			}
+			if cred != nil || !errors.Is(err, tc.err) || !errors.Is(wholeErr, tc.err) {
+				t.Errorf("Expected %v, got %v, %v", tc.err, cred, err)
+			}
+		})
+	}
+
+	// Credentials from another key parse, but don't verify
+	forged, err := NewCredentialManager([]byte("Some other secret")).Create(time.Now(), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := forged.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := ParseUnverified(forged.Base64URLEncodeUsername(), password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cred.Credential.NodeId, forged.Credential.NodeId) || cred.Credential.Timestamp != forged.Credential.Timestamp {
+		t.Errorf("Expected the forged credential's fields, got %v", cred.Credential)
+	}
+	if _, err := cm.Verify(cred); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
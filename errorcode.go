@@ -0,0 +1,82 @@
+package credentials
+
+import "errors"
+
+// Code is a coarse classification of the errors returned by this package, stable across versions,
+// for callers which need to branch on or report the kind of a failure without matching every sentinel
+type Code int
+
+const (
+	// CodeOK is the code of a nil error
+	CodeOK Code = iota
+	// CodeInternal means the failure wasn't the credential's fault, e.g. a MAC backend or revocation check failed.
+	// It is also the code of errors from outside this package.
+	CodeInternal
+	// CodeMissing means there was no credential
+	CodeMissing
+	// CodeMalformed means the credential couldn't be decoded
+	CodeMalformed
+	// CodeMACMismatch means the credential isn't authentic
+	CodeMACMismatch
+	// CodeExpired means the credential is authentic, but has expired
+	CodeExpired
+	// CodeNotYetValid means the credential is authentic, but its timestamp is in the future
+	CodeNotYetValid
+	// CodeRevoked means the credential is authentic, but has been revoked
+	CodeRevoked
+	// CodeReplayed means the credential is authentic, but its nonce has already been used
+	CodeReplayed
+	// CodeRejected means the credential is authentic, but is not accepted here, e.g. for its audience or scopes
+	CodeRejected
+	// CodeInvalidArgument means a call was made with invalid arguments, e.g. a node ID of the wrong length
+	CodeInvalidArgument
+	// CodeRateLimited means a node has been issued too many credentials lately, and may retry later
+	CodeRateLimited
+)
+
+var codeNames = []string{"ok", "internal", "missing", "malformed", "mac_mismatch", "expired", "not_yet_valid", "revoked", "replayed", "rejected", "invalid_argument", "rate_limited"}
+
+func (c Code) String() string {
+	if c < 0 || int(c) >= len(codeNames) {
+		return "unknown"
+	}
+	return codeNames[c]
+}
+
+// ErrorCode returns the code of err, which may be any error returned by this package
+func ErrorCode(err error) Code {
+	if err == nil {
+		return CodeOK
+	}
+	for _, c := range categories {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return CodeInternal
+}
+
+// malformedError marks an error as matching ErrMalformedCredential, without changing its message
+type malformedError struct {
+	err error
+}
+
+func (e *malformedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *malformedError) Unwrap() error {
+	return e.err
+}
+
+func (e *malformedError) Is(target error) bool {
+	return target == ErrMalformedCredential
+}
+
+// markMalformed makes err match ErrMalformedCredential, if it doesn't already
+func markMalformed(err error) error {
+	if errors.Is(err, ErrMalformedCredential) {
+		return err
+	}
+	return &malformedError{err: err}
+}
@@ -0,0 +1,253 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestErrorCode tests the sentinels and codes of the errors returned by the exported functions
+func TestErrorCode(t *testing.T) {
+	key := []byte("Error code test secret")
+	now := time.Now()
+	cm := NewCredentialManager(key)
+	nodeID := make([]byte, 20)
+
+	valid, err := cm.Create(now, nodeID, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := &AuthenticatedCredential{
+		Credential: &pb.Credential{NodeId: nodeID, Timestamp: valid.Credential.Timestamp + 1, OperatorType: valid.Credential.OperatorType},
+		Mac:        valid.Mac,
+	}
+	stale, err := NewCredentialManagerWithOptions(key, nil, WithDefaultValidity(time.Hour)).Create(now.Add(-2*time.Hour), nodeID, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	future, err := cm.Create(now.Add(time.Hour), nodeID, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withAAD, err := cm.CreateWithAAD(now, nodeID, pb.OperatorType_OT_SOLO, []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoker := NewMemoryRevoker()
+	revoker.Revoke(nodeID)
+	replayCache := NewMemoryReplayCache(time.Minute)
+	defer replayCache.Close()
+	password, err := valid.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name     string
+		call     func() error
+		sentinel error
+		code     Code
+	}{
+		{"Create/NodeIDLength", func() error {
+			_, err := cm.Create(now, make([]byte, 3), pb.OperatorType_OT_SOLO)
+			return err
+		}, ErrInvalidNodeIDLength, CodeInvalidArgument},
+		{"CreateFromHex", func() error {
+			_, err := cm.CreateFromHex(now, "0xzz", pb.OperatorType_OT_SOLO)
+			return err
+		}, ErrInvalidNodeIDHex, CodeInvalidArgument},
+		{"CreateWithExpiry", func() error {
+			_, err := cm.CreateWithExpiry(now, nodeID, pb.OperatorType_OT_SOLO, now)
+			return err
+		}, ErrInvalidExpiry, CodeInvalidArgument},
+		{"CreateWithMetadata", func() error {
+			_, err := cm.CreateWithMetadata(now, nodeID, pb.OperatorType_OT_SOLO, map[string]string{"k": string(make([]byte, MaxMetadataValueLength+1))})
+			return err
+		}, ErrMetadataTooLarge, CodeInvalidArgument},
+		{"CreateWithScopeNames", func() error {
+			_, err := cm.CreateWithScopeNames(now, nodeID, pb.OperatorType_OT_SOLO, "")
+			return err
+		}, ErrInvalidScopeNames, CodeInvalidArgument},
+		{"CreateWithFeeRecipient", func() error {
+			_, err := cm.CreateWithFeeRecipient(now, nodeID, pb.OperatorType_OT_SOLO, make([]byte, 3))
+			return err
+		}, ErrInvalidFeeRecipient, CodeInvalidArgument},
+		{"CreateBundle", func() error {
+			_, err := cm.CreateBundle(now, nil, pb.OperatorType_OT_SOLO)
+			return err
+		}, ErrInvalidBundle, CodeInvalidArgument},
+		{"Create/OperatorTypeUnset", func() error {
+			_, err := NewCredentialManagerWithOptions(key, nil, WithRejectZeroOperatorType()).Create(now, nodeID, pb.OperatorType_OT_ROCKETPOOL)
+			return err
+		}, ErrOperatorTypeUnset, CodeInvalidArgument},
+		{"Create/TimestampOutOfRange", func() error {
+			_, err := NewCredentialManagerWithOptions(key, nil, WithCreateTolerance(time.Minute)).Create(now.Add(time.Hour), nodeID, pb.OperatorType_OT_SOLO)
+			return err
+		}, ErrTimestampOutOfRange, CodeInvalidArgument},
+		{"NewKeyRing", func() error {
+			_, err := NewKeyRing(KeyEntry{ID: "a", Key: key}, KeyEntry{ID: "a", Key: key})
+			return err
+		}, ErrDuplicateKeyID, CodeInvalidArgument},
+		{"SplitKey", func() error {
+			_, err := SplitKey(key, 2, 3)
+			return err
+		}, ErrInvalidSplitParams, CodeInvalidArgument},
+		{"CombineKey", func() error {
+			_, err := CombineKey(nil)
+			return err
+		}, ErrInsufficientShares, CodeInvalidArgument},
+
+		{"Verify/OK", func() error {
+			_, err := cm.Verify(valid)
+			return err
+		}, nil, CodeOK},
+		{"Verify/Mismatch", func() error {
+			_, err := cm.Verify(tampered)
+			return err
+		}, MismatchError, CodeMACMismatch},
+		{"VerifyWithAAD", func() error {
+			_, err := cm.VerifyWithAAD(withAAD, []byte("other"))
+			return err
+		}, ErrAADMismatch, CodeMACMismatch},
+		{"Verify/Expired", func() error {
+			_, err := cm.Verify(stale)
+			return err
+		}, ErrExpired, CodeExpired},
+		{"Verify/Future", func() error {
+			_, err := cm.Verify(future)
+			return err
+		}, ErrTimestampInFuture, CodeNotYetValid},
+		{"Verify/Revoked", func() error {
+			_, err := NewCredentialManagerWithOptions(key, nil, WithRevoker(revoker)).Verify(valid)
+			return err
+		}, ErrRevoked, CodeRevoked},
+		{"VerifyContext/Canceled", func() error {
+			_, err := cm.VerifyContext(canceled, valid)
+			return err
+		}, context.Canceled, CodeInternal},
+		{"Verify/Audience", func() error {
+			_, err := NewCredentialManagerWithOptions(key, nil, WithAudience("elsewhere")).Verify(valid)
+			return err
+		}, ErrAudienceMismatch, CodeRejected},
+		{"Verify/Issuer", func() error {
+			_, err := NewCredentialManagerWithOptions(key, nil, WithIssuer("elsewhere")).Verify(valid)
+			return err
+		}, ErrIssuerMismatch, CodeRejected},
+		{"Verify/OperatorType", func() error {
+			_, err := NewCredentialManagerWithOptions(key, nil, WithAllowedOperatorTypes(pb.OperatorType_OT_ROCKETPOOL)).Verify(valid)
+			return err
+		}, ErrOperatorTypeNotAllowed, CodeRejected},
+		{"Verify/ScopeNames", func() error {
+			_, err := NewCredentialManagerWithOptions(key, nil, WithRequiredScopeNames("read")).Verify(valid)
+			return err
+		}, ErrMissingScopes, CodeRejected},
+		{"VerifyWithRequiredScopes", func() error {
+			_, err := cm.VerifyWithRequiredScopes(valid, ScopeMetrics)
+			return err
+		}, ErrMissingScopes, CodeRejected},
+		{"VerifyForNode", func() error {
+			_, err := cm.VerifyForNode(valid, batchNodeIDs(2)[1])
+			return err
+		}, ErrNodeNotInCredential, CodeRejected},
+		{"VerifyOnce/NoCache", func() error {
+			_, err := cm.VerifyOnce(valid)
+			return err
+		}, ErrNoReplayCache, CodeInternal},
+		{"VerifyOnce/NoNonce", func() error {
+			_, err := NewCredentialManagerWithOptions(key, nil, WithReplayCache(replayCache, time.Hour)).VerifyOnce(valid)
+			return err
+		}, ErrMissingNonce, CodeRejected},
+		{"VerifyOnce/NotRecordable", func() error {
+			cred, err := cm.CreateWithNonce(now, nodeID, pb.OperatorType_OT_SOLO, nil)
+			if err != nil {
+				return err
+			}
+			_, err = NewCredentialManagerWithOptions(key, nil, WithReplayCache(replayCache, 0)).VerifyOnce(cred)
+			return err
+		}, ErrNonceNotRecordable, CodeRejected},
+
+		{"VerifyFromBasicAuth/Missing", func() error {
+			_, err := cm.VerifyFromBasicAuth("", "")
+			return err
+		}, ErrMissingCredentials, CodeMissing},
+		{"VerifyFromBasicAuth/Base64", func() error {
+			_, err := cm.VerifyFromBasicAuth("not base64!", password)
+			return err
+		}, ErrMalformedBase64, CodeMalformed},
+		{"VerifyFromBasicAuth/Proto", func() error {
+			_, err := cm.VerifyFromBasicAuth(valid.Base64URLEncodeUsername(), "_w")
+			return err
+		}, ErrMalformedProto, CodeMalformed},
+		{"FromURLValues", func() error {
+			_, err := FromURLValues(url.Values{})
+			return err
+		}, ErrMissingURLParam, CodeMissing},
+		{"UnmarshalText", func() error {
+			return new(AuthenticatedCredential).UnmarshalText([]byte("no delimiter"))
+		}, ErrMalformedToken, CodeMalformed},
+		{"UnmarshalJSON/Hex", func() error {
+			return json.Unmarshal([]byte(`{"node_id":"0xzz","mac":""}`), new(AuthenticatedCredential))
+		}, ErrMalformedCredential, CodeMalformed},
+		{"UnmarshalJSON/OperatorType", func() error {
+			return json.Unmarshal([]byte(`{"node_id":"0x","operator_type_name":"OT_NONE","mac":""}`), new(AuthenticatedCredential))
+		}, ErrUnknownOperatorType, CodeMalformed},
+		{"IssueFromRequest/NoVerifier", func() error {
+			_, err := cm.IssueFromRequest(&CredentialRequest{}, now)
+			return err
+		}, ErrNoRequestVerifier, CodeInternal},
+		{"IssueFromRequest/Invalid", func() error {
+			_, err := NewCredentialManagerWithOptions(key, nil, WithRequestVerifier(recoverRequestSigner, 0)).IssueFromRequest(&CredentialRequest{NodeID: nodeID}, now)
+			return err
+		}, ErrInvalidRequest, CodeMalformed},
+		{"IssueFromRequest/Expired", func() error {
+			req := &CredentialRequest{NodeID: nodeID, Timestamp: now.Add(-time.Hour).Unix(), Nonce: []byte("nonce"), Signature: []byte("sig")}
+			_, err := NewCredentialManagerWithOptions(key, nil, WithRequestVerifier(recoverRequestSigner, 0)).IssueFromRequest(req, now)
+			return err
+		}, ErrRequestExpired, CodeExpired},
+		{"Create/RateLimited", func() error {
+			_, err := NewCredentialManagerWithOptions(key, nil, WithIssuanceLimiter(NewMemoryIssuanceLimiter(0, time.Minute))).Create(now, nodeID, pb.OperatorType_OT_SOLO)
+			return err
+		}, ErrIssuanceRateLimited, CodeRateLimited},
+		{"Open", func() error {
+			_, err := cm.Open("AAAA")
+			return err
+		}, ErrInvalidSealedToken, CodeMalformed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			if tc.sentinel == nil && err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if tc.sentinel != nil && !errors.Is(err, tc.sentinel) {
+				t.Fatalf("Expected %v, got %v", tc.sentinel, err)
+			}
+			if code := ErrorCode(err); code != tc.code {
+				t.Errorf("Expected code %s, got %s for %v", tc.code, code, err)
+			}
+		})
+	}
+}
+
+// TestErrorCodeStrings tests that the messages of errors external systems may match on are unchanged
+func TestErrorCodeStrings(t *testing.T) {
+	var cred AuthenticatedCredential
+	err := json.Unmarshal([]byte(`{"node_id":"0x","operator_type_name":"OT_NONE","mac":""}`), &cred)
+	if err == nil || err.Error() != `unknown operator type "OT_NONE"` {
+		t.Errorf("Unexpected message %v", err)
+	}
+	if CodeMACMismatch.String() != "mac_mismatch" || CodeRateLimited.String() != "rate_limited" || Code(-1).String() != "unknown" {
+		t.Errorf("Unexpected code names %s, %s, %s", CodeMACMismatch, CodeRateLimited, Code(-1))
+	}
+	if ErrorCode(errors.New("boom")) != CodeInternal {
+		t.Error("Expected errors from outside the package to be internal")
+	}
+}
@@ -1,6 +1,11 @@
 package credentials
 
-import "errors"
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
 
 type Error error
 
@@ -8,4 +13,143 @@ var (
 	MismatchError      = errors.New("credential MAC mismatch")
 	MemoryError        = errors.New("memory allocation error")
 	SerializationError = errors.New("error serializing HMAC protobuf body")
+	ErrAADMismatch     = fmt.Errorf("%w: additional authenticated data mismatch", MismatchError)
+
+	ErrInvalidNodeIDLength = errors.New("invalid nodeID length")
+	ErrInvalidNodeIDHex    = errors.New("invalid nodeID hex")
+	ErrTimestampOutOfRange = errors.New("credential timestamp out of range")
+	ErrMalformedPassword   = errors.New("malformed credential password")
+	ErrUnknownOperatorType = errors.New("unknown operator type")
 )
+
+// FieldsError is implemented by errors which carry structured context for logging
+type FieldsError interface {
+	error
+	Fields() map[string]any
+}
+
+// VerificationError is returned by Verify and its variants when a credential is rejected.
+// It records the credential's contents at the time of the failure, so callers can log them
+// without re-extracting fields from a possibly partially decoded credential.
+type VerificationError struct {
+	Err          error
+	NodeID       []byte
+	Timestamp    int64
+	OperatorType OperatorType
+	// CredentialID is empty for credentials issued without one
+	CredentialID []byte
+}
+
+func newVerificationError(authenticatedCredential *AuthenticatedCredential, err error) *VerificationError {
+	credential := authenticatedCredential.Credential
+	return &VerificationError{
+		Err:          err,
+		NodeID:       append([]byte(nil), credential.GetNodeId()...),
+		Timestamp:    credential.GetTimestamp(),
+		OperatorType: credential.GetOperatorType(),
+		CredentialID: append([]byte(nil), credential.GetCredentialId()...),
+	}
+}
+
+// Error returns the message of the underlying error
+func (e *VerificationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// Fields returns the rejected credential's node_id (hex), timestamp, operator_type and the failure reason,
+// and its credential_id (hex) if it has one
+func (e *VerificationError) Fields() map[string]any {
+	out := map[string]any{
+		"node_id":       "0x" + hex.EncodeToString(e.NodeID),
+		"timestamp":     e.Timestamp,
+		"operator_type": e.OperatorType.String(),
+		"reason":        e.Err.Error(),
+	}
+	if len(e.CredentialID) > 0 {
+		out["credential_id"] = hex.EncodeToString(e.CredentialID)
+	}
+	return out
+}
+
+// categories maps the errors of this package to their categories and codes, most specific first
+var categories = []struct {
+	err      error
+	category string
+	code     Code
+}{
+	{ErrMissingCredentials, "missing", CodeMissing},
+	{ErrNilCredential, "missing", CodeMissing},
+	{ErrMalformedCredential, "malformed", CodeMalformed},
+	{ErrMissingURLParam, "missing", CodeMissing},
+	{ErrMalformedToken, "malformed", CodeMalformed},
+	{ErrMalformedPassword, "malformed", CodeMalformed},
+	{ErrInvalidCompression, "malformed", CodeMalformed},
+	{ErrInvalidSealedToken, "malformed", CodeMalformed},
+	{ErrUnknownOperatorType, "malformed", CodeMalformed},
+	{ErrMissingMAC, "malformed", CodeMalformed},
+	{ErrInvalidMACLength, "malformed", CodeMalformed},
+	{ErrAADMismatch, "aad_mismatch", CodeMACMismatch},
+	{MismatchError, "mac_mismatch", CodeMACMismatch},
+	{ErrExpired, "expired", CodeExpired},
+	{ErrTimestampInFuture, "future_timestamp", CodeNotYetValid},
+	{ErrRevoked, "revoked", CodeRevoked},
+	{ErrRevocationCheck, "revocation_check_failed", CodeInternal},
+	{context.Canceled, "canceled", CodeInternal},
+	{context.DeadlineExceeded, "deadline_exceeded", CodeInternal},
+	{ErrReplayedCredential, "replayed", CodeReplayed},
+	{ErrMissingNonce, "missing_nonce", CodeRejected},
+	{ErrNonceNotRecordable, "nonce_not_recordable", CodeRejected},
+	{ErrAudienceMismatch, "audience_mismatch", CodeRejected},
+	{ErrChainIDMismatch, "chain_id_mismatch", CodeRejected},
+	{ErrIssuerNotAllowed, "issuer_not_allowed", CodeRejected},
+	{ErrIssuerMismatch, "issuer_mismatch", CodeRejected},
+	{ErrPartnerNotAllowed, "partner_not_allowed", CodeRejected},
+	{ErrMissingScopes, "missing_scopes", CodeRejected},
+	{ErrOperatorTypeNotAllowed, "operator_type_not_allowed", CodeRejected},
+	{ErrFeeRecipientMismatch, "fee_recipient_mismatch", CodeRejected},
+	{ErrNodeNotInCredential, "node_not_in_credential", CodeRejected},
+	{ErrUnsupportedVersion, "unsupported_version", CodeMalformed},
+	{ErrPolicyRejected, "policy_rejected", CodeRejected},
+	{ErrNoRequestVerifier, "no_request_verifier", CodeInternal},
+	{ErrInvalidRequest, "invalid_request", CodeMalformed},
+	{ErrRequestExpired, "request_expired", CodeExpired},
+	{ErrRequestInFuture, "future_timestamp", CodeNotYetValid},
+	{ErrInvalidRequestSignature, "invalid_request_signature", CodeMACMismatch},
+	{ErrRequestSignerMismatch, "request_signer_mismatch", CodeMACMismatch},
+	{ErrReplayedRequest, "replayed", CodeReplayed},
+	{ErrMethodNotAllowed, "method_not_allowed", CodeInvalidArgument},
+	{ErrUnsupportedMediaType, "unsupported_media_type", CodeInvalidArgument},
+	{ErrRequestTooLarge, "request_too_large", CodeInvalidArgument},
+	{ErrIssuanceRateLimited, "rate_limited", CodeRateLimited},
+	{ErrInvalidNodeIDLength, "invalid_node_id", CodeInvalidArgument},
+	{ErrInvalidNodeIDHex, "invalid_node_id", CodeInvalidArgument},
+	{ErrTimestampOutOfRange, "timestamp_out_of_range", CodeInvalidArgument},
+	{ErrZeroTimestamp, "invalid_timestamp", CodeInvalidArgument},
+	{ErrOperatorTypeUnset, "operator_type_unset", CodeInvalidArgument},
+	{ErrInvalidExpiry, "invalid_expiry", CodeInvalidArgument},
+	{ErrMetadataTooLarge, "invalid_metadata", CodeInvalidArgument},
+	{ErrInvalidScopeNames, "invalid_scope_names", CodeInvalidArgument},
+	{ErrInvalidFeeRecipient, "invalid_fee_recipient", CodeInvalidArgument},
+	{ErrInvalidBundle, "invalid_bundle", CodeInvalidArgument},
+	{ErrInvalidKey, "invalid_key", CodeInvalidArgument},
+	{ErrDuplicateKeyID, "invalid_key", CodeInvalidArgument},
+	{ErrInvalidSplitParams, "invalid_key_share", CodeInvalidArgument},
+	{ErrInvalidShare, "invalid_key_share", CodeInvalidArgument},
+	{ErrShareMismatch, "invalid_key_share", CodeInvalidArgument},
+	{ErrInsufficientShares, "invalid_key_share", CodeInvalidArgument},
+}
+
+// ErrorCategory names the kind of failure err is, for use as a metric label or span status.
+// The categories are a small fixed set, so they keep label cardinality bounded. Errors from outside this package are "other".
+func ErrorCategory(err error) string {
+	for _, c := range categories {
+		if errors.Is(err, c.err) {
+			return c.category
+		}
+	}
+	return "other"
+}
@@ -0,0 +1,110 @@
+// Package ethcredentials converts between credentials and go-ethereum's common.Address.
+// It is kept apart from the credentials package so that only users of go-ethereum depend on it.
+package ethcredentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CreateForAddress is credentials.CredentialManager.Create, for the node at addr
+func CreateForAddress(cm *credentials.CredentialManager, timestamp time.Time, addr common.Address, operatorType credentials.OperatorType) (*credentials.AuthenticatedCredential, error) {
+	return cm.Create(timestamp, addr.Bytes(), operatorType)
+}
+
+// NodeAddress returns the credential's node ID as an address. Node IDs which aren't common.AddressLength bytes
+// fail with credentials.ErrInvalidNodeIDLength rather than being truncated or padded, and nil credentials with
+// credentials.ErrNilCredential.
+func NodeAddress(cred *credentials.AuthenticatedCredential) (common.Address, error) {
+	if cred == nil || cred.Credential == nil {
+		return common.Address{}, credentials.ErrNilCredential
+	}
+	return toAddress(cred.Credential.NodeId)
+}
+
+// toAddress converts b to an address, if it is exactly one long
+func toAddress(b []byte) (common.Address, error) {
+	if len(b) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("%w. Expected %d, got %d", credentials.ErrInvalidNodeIDLength, common.AddressLength, len(b))
+	}
+	return common.BytesToAddress(b), nil
+}
+
+// MarshalJSON marshals cred as its MarshalJSON does, but with the node IDs and fee recipient as EIP-55 checksummed
+// addresses, as Ethereum tooling displays them. The output is otherwise identical, field order included, and
+// decodes with the credential's UnmarshalJSON, which accepts either case.
+// Values which aren't address long are left as MarshalJSON writes them.
+func MarshalJSON(cred *credentials.AuthenticatedCredential) ([]byte, error) {
+	if cred == nil || cred.Credential == nil {
+		return nil, credentials.ErrNilCredential
+	}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	credential := cred.Credential
+	replacements := map[string]json.RawMessage{
+		"node_id": checksummed(credential.NodeId),
+	}
+	if len(credential.FeeRecipient) > 0 {
+		replacements["fee_recipient"] = checksummed(credential.FeeRecipient)
+	}
+	if len(credential.BundleNodeIds) > 0 {
+		bundle := make([]json.RawMessage, len(credential.BundleNodeIds))
+		for i, nodeID := range credential.BundleNodeIds {
+			bundle[i] = checksummed(nodeID)
+		}
+		if replacements["bundle_node_ids"], err = json.Marshal(bundle); err != nil {
+			return nil, err
+		}
+	}
+	return replaceFields(data, replacements)
+}
+
+// checksummed returns b as a JSON string holding its EIP-55 checksummed address,
+// or 0x prefixed lowercase hex like MarshalJSON's if it isn't address long
+func checksummed(b []byte) json.RawMessage {
+	if addr, err := toAddress(b); err == nil {
+		return json.RawMessage(`"` + addr.Hex() + `"`)
+	}
+	return json.RawMessage(`"` + fmt.Sprintf("0x%x", b) + `"`)
+}
+
+// replaceFields rewrites the values of the top-level fields of the JSON object data named in replacements,
+// keeping every field in its place
+func replaceFields(data []byte, replacements map[string]json.RawMessage) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	out := append(make([]byte, 0, len(data)), '{')
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		name, _ := token.(string)
+		if replacement, ok := replacements[name]; ok {
+			value = replacement
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > 1 {
+			out = append(out, ',')
+		}
+		out = append(append(append(out, key...), ':'), value...)
+	}
+	return append(out, '}'), nil
+}
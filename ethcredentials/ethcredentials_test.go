@@ -0,0 +1,125 @@
+package ethcredentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials"
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"github.com/ethereum/go-ethereum/common"
+	"google.golang.org/protobuf/proto"
+)
+
+// The EIP-55 test vectors
+var (
+	addr      = common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	recipient = common.HexToAddress("0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359")
+	bundled   = common.HexToAddress("0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB")
+)
+
+// TestCreateForAddress tests that credentials created for an address carry it as their node ID
+func TestCreateForAddress(t *testing.T) {
+	cm := credentials.NewCredentialManager([]byte("Ethereum test secret"))
+	cred, err := CreateForAddress(cm, time.Now(), addr, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cred.Credential.NodeId, addr.Bytes()) {
+		t.Errorf("Expected node ID %x, got %x", addr.Bytes(), cred.Credential.NodeId)
+	}
+	got, err := NodeAddress(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != addr {
+		t.Errorf("Expected %s, got %s", addr, got)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestNodeAddressInvalid tests that node IDs which aren't addresses are rejected rather than truncated
+func TestNodeAddressInvalid(t *testing.T) {
+	for _, length := range []int{0, 19, 21, 32} {
+		cred := &credentials.AuthenticatedCredential{Credential: &pb.Credential{NodeId: make([]byte, length)}}
+		if _, err := NodeAddress(cred); !errors.Is(err, credentials.ErrInvalidNodeIDLength) {
+			t.Errorf("%d bytes: expected ErrInvalidNodeIDLength, got %v", length, err)
+		}
+	}
+	for _, cred := range []*credentials.AuthenticatedCredential{nil, {}} {
+		if _, err := NodeAddress(cred); !errors.Is(err, credentials.ErrNilCredential) {
+			t.Errorf("Expected ErrNilCredential, got %v", err)
+		}
+		if _, err := MarshalJSON(cred); !errors.Is(err, credentials.ErrNilCredential) {
+			t.Errorf("Expected ErrNilCredential from MarshalJSON, got %v", err)
+		}
+	}
+}
+
+// TestMarshalJSON tests that addresses are checksummed, everything else is as MarshalJSON writes it,
+// and the output decodes to the same credential
+func TestMarshalJSON(t *testing.T) {
+	cm := credentials.NewCredentialManager([]byte("Ethereum test secret"))
+	cred, err := cm.CreateBundle(time.Now(), [][]byte{addr.Bytes(), bundled.Bytes()}, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred.Credential.FeeRecipient = recipient.Bytes()
+	// A metadata value spelling the address in lowercase is left alone
+	if err := cred.SetMetadata("owner", strings.ToLower(addr.Hex())); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := MarshalJSON(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lower := func(a common.Address) string { return `"` + strings.ToLower(a.Hex()) + `"` }
+	checksummed := func(a common.Address) string { return `"` + a.Hex() + `"` }
+	for old, replacement := range map[string]string{
+		`"node_id":` + lower(addr):                                 `"node_id":` + checksummed(addr),
+		`"bundle_node_ids":[` + lower(addr) + `,` + lower(bundled): `"bundle_node_ids":[` + checksummed(addr) + `,` + checksummed(bundled),
+		`"fee_recipient":` + lower(recipient):                      `"fee_recipient":` + checksummed(recipient),
+	} {
+		if !bytes.Contains(plain, []byte(old)) {
+			t.Fatalf("Expected %s in %s", old, plain)
+		}
+		plain = bytes.Replace(plain, []byte(old), []byte(replacement), 1)
+	}
+	if !bytes.Equal(data, plain) {
+		t.Errorf("Expected\n%s\ngot\n%s", plain, data)
+	}
+
+	var decoded credentials.AuthenticatedCredential
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(cred.Pb(), decoded.Pb()) {
+		t.Errorf("Expected %v, got %v", cred.Pb(), decoded.Pb())
+	}
+}
+
+// TestMarshalJSONNotAddress tests that node IDs which aren't address long are written as MarshalJSON writes them
+func TestMarshalJSONNotAddress(t *testing.T) {
+	cred := &credentials.AuthenticatedCredential{Credential: &pb.Credential{NodeId: []byte{0xab, 0xcd}, Timestamp: 1}}
+	data, err := MarshalJSON(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, plain) {
+		t.Errorf("Expected %s, got %s", plain, data)
+	}
+}
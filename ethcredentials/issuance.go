@@ -0,0 +1,62 @@
+package ethcredentials
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ErrInvalidSignatureLength = errors.New("invalid issuance signature length")
+	ErrMalleableSignature     = errors.New("issuance signature has a high S value")
+	ErrInvalidSignature       = errors.New("invalid issuance signature")
+)
+
+// secp256k1HalfN is half the order of the secp256k1 curve. Signatures with S values above it are the malleable
+// twins of ones below it, which is all Ethereum wallets produce.
+var secp256k1HalfN = new(big.Int).Rsh(crypto.S256().Params().N, 1)
+
+// IssuanceMessage returns the message node operators sign with their node wallet to request a credential at ts for
+// purpose, so that whoever asks for a signature and VerifyIssuanceSignature build the exact same preimage.
+// The purpose is quoted, so that it can't be made to look like another field.
+func IssuanceMessage(ts time.Time, purpose string) []byte {
+	return []byte(fmt.Sprintf("Rescue Node credential request\nPurpose: %s\nTimestamp: %d", strconv.Quote(purpose), ts.Unix()))
+}
+
+// VerifyIssuanceSignature recovers the address of the node wallet which signed msg as an EIP-191 personal message,
+// i.e. with personal_sign or eth_sign, proving that whoever requests a credential for it controls the node.
+// sig is r || s || v, with v either 0/1 or 27/28. Signatures which aren't crypto.SignatureLength bytes long fail with
+// ErrInvalidSignatureLength, ones with high S values with ErrMalleableSignature, and ones which don't recover a
+// public key with ErrInvalidSignature. It is up to the caller to check msg, e.g. that it is IssuanceMessage's.
+func VerifyIssuanceSignature(msg []byte, sig []byte) (nodeID [20]byte, err error) {
+	if len(sig) != crypto.SignatureLength {
+		return nodeID, fmt.Errorf("%w. Expected %d, got %d", ErrInvalidSignatureLength, crypto.SignatureLength, len(sig))
+	}
+
+	// Copy the signature rather than transforming the caller's V in place
+	rsv := make([]byte, crypto.SignatureLength)
+	copy(rsv, sig)
+	if v := rsv[crypto.RecoveryIDOffset]; v == 27 || v == 28 {
+		rsv[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
+	}
+
+	r := new(big.Int).SetBytes(rsv[:32])
+	s := new(big.Int).SetBytes(rsv[32:64])
+	if s.Cmp(secp256k1HalfN) > 0 {
+		return nodeID, ErrMalleableSignature
+	}
+	if !crypto.ValidateSignatureValues(rsv[crypto.RecoveryIDOffset], r, s, true) {
+		return nodeID, ErrInvalidSignature
+	}
+
+	pubKey, err := crypto.SigToPub(accounts.TextHash(msg), rsv)
+	if err != nil {
+		return nodeID, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
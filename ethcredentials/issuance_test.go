@@ -0,0 +1,143 @@
+package ethcredentials
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials"
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TestIssuanceMessage tests that the issuance message is stable, and that purposes can't forge other fields
+func TestIssuanceMessage(t *testing.T) {
+	want := "Rescue Node credential request\nPurpose: \"rescue-proxy\"\nTimestamp: 1700000000"
+	if got := string(IssuanceMessage(time.Unix(1700000000, 0), "rescue-proxy")); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	forged := IssuanceMessage(time.Unix(1700000000, 0), "x\"\nTimestamp: 1")
+	if bytes.Contains(forged, []byte("\nTimestamp: 1\"")) || bytes.Count(forged, []byte("\n")) != 2 {
+		t.Errorf("Expected the purpose to be quoted, got %q", forged)
+	}
+}
+
+// TestVerifyIssuanceSignature tests that signatures of issuance messages recover the signer's address
+func TestVerifyIssuanceSignature(t *testing.T) {
+	key, err := crypto.HexToECDSA("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	msg := IssuanceMessage(time.Unix(1700000000, 0), "rescue-proxy")
+	sig, err := crypto.Sign(accounts.TextHash(msg), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wallets return the yellow paper V of 27/28
+	wallet := bytes.Clone(sig)
+	wallet[crypto.RecoveryIDOffset] += 27
+	for name, sig := range map[string][]byte{"V0/1": sig, "V27/28": wallet} {
+		t.Run(name, func(t *testing.T) {
+			before := bytes.Clone(sig)
+			nodeID, err := VerifyIssuanceSignature(msg, sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nodeID != signer {
+				t.Errorf("Expected %s, got %x", signer, nodeID)
+			}
+			if !bytes.Equal(sig, before) {
+				t.Error("Expected the signature to be left unchanged")
+			}
+		})
+	}
+
+	// Another message recovers another address
+	nodeID, err := VerifyIssuanceSignature(IssuanceMessage(time.Unix(1700000001, 0), "rescue-proxy"), sig)
+	if err == nil && nodeID == signer {
+		t.Error("Expected the signature not to recover the signer for another message")
+	}
+}
+
+// TestVerifyIssuanceSignatureRejects tests that malformed and malleable signatures are rejected
+func TestVerifyIssuanceSignatureRejects(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := IssuanceMessage(time.Now(), "rescue-proxy")
+	sig, err := crypto.Sign(accounts.TextHash(msg), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The malleable twin of sig, with S replaced by N - S and V flipped, recovers the same key
+	highS := bytes.Clone(sig)
+	s := new(big.Int).Sub(crypto.S256().Params().N, new(big.Int).SetBytes(sig[32:64]))
+	s.FillBytes(highS[32:64])
+	highS[crypto.RecoveryIDOffset] ^= 1
+
+	badV := bytes.Clone(sig)
+	badV[crypto.RecoveryIDOffset] = 2
+
+	zeroR := bytes.Clone(sig)
+	clear(zeroR[:32])
+
+	for _, tc := range []struct {
+		name string
+		sig  []byte
+		want error
+	}{
+		{"Empty", nil, ErrInvalidSignatureLength},
+		{"Short", sig[:64], ErrInvalidSignatureLength},
+		{"Long", append(bytes.Clone(sig), 0), ErrInvalidSignatureLength},
+		{"HighS", highS, ErrMalleableSignature},
+		{"InvalidV", badV, ErrInvalidSignature},
+		{"ZeroR", zeroR, ErrInvalidSignature},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := VerifyIssuanceSignature(msg, tc.sig); !errors.Is(err, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+// TestVerifyIssuanceSignatureRequests tests that VerifyIssuanceSignature verifies credential requests signed by wallets
+func TestVerifyIssuanceSignatureRequests(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	req, err := credentials.NewCredentialRequest(crypto.PubkeyToAddress(key.PublicKey).Bytes(), pb.OperatorType_OT_SOLO, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = req.Sign(func(digest []byte) ([]byte, error) {
+		sig, err := crypto.Sign(digest, key)
+		if err != nil {
+			return nil, err
+		}
+		sig[crypto.RecoveryIDOffset] += 27
+		return sig, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm := credentials.NewCredentialManagerWithOptions([]byte("Ethereum test secret"), nil, credentials.WithRequestVerifier(VerifyIssuanceSignature, time.Minute))
+	cred, err := cm.IssueFromRequest(req, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := NodeAddress(cred); err != nil || got != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("Expected a credential for %s, got %s, %v", crypto.PubkeyToAddress(key.PublicKey), got, err)
+	}
+}
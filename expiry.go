@@ -0,0 +1,131 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+var (
+	ErrExpired       = errors.New("credential has expired")
+	ErrInvalidExpiry = errors.New("credential expiry is not after its timestamp")
+)
+
+// ExpiredError is returned by Verify for credentials past their expiry. It matches ErrExpired.
+type ExpiredError struct {
+	ExpiresAt time.Time
+	// Window is the validity window from the manager's ValidityPolicy, or zero if the credential embeds its expiry
+	Window time.Duration
+}
+
+func (e *ExpiredError) Error() string {
+	if e.Window > 0 {
+		return fmt.Sprintf("%v at %s, %s after it was issued", ErrExpired, e.ExpiresAt.UTC().Format(time.RFC3339), e.Window)
+	}
+	return fmt.Sprintf("%v at %s", ErrExpired, e.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+func (e *ExpiredError) Is(target error) bool {
+	return target == ErrExpired
+}
+
+// NoExpiry is returned by TimeUntilExpiry for credentials without an embedded expiry
+const NoExpiry time.Duration = math.MaxInt64
+
+// TimeUntilExpiry returns how long after now the credential's embedded expiry is, which is negative once it has passed,
+// or NoExpiry if it has none. Verifiers may additionally enforce a ValidityPolicy, which isn't reflected here.
+func (ac *AuthenticatedCredential) TimeUntilExpiry(now time.Time) time.Duration {
+	expiresAt := ac.Credential.GetExpiresAt()
+	if expiresAt == 0 {
+		return NoExpiry
+	}
+	return time.Unix(expiresAt, 0).Sub(now)
+}
+
+// IsExpired reports whether the credential's embedded expiry has passed at now, as Verify would, and false if it has none
+func (ac *AuthenticatedCredential) IsExpired(now time.Time) bool {
+	expiresAt := ac.Credential.GetExpiresAt()
+	return expiresAt != 0 && now.After(time.Unix(expiresAt, 0))
+}
+
+// ValidFor returns how long after now the credential remains valid, which is zero or negative once it has expired.
+// It uses the embedded expiry if there is one, or else the credential's timestamp plus ttl. Unlike a ValidityPolicy,
+// where a ttl of zero or less means credentials never expire, such a ttl leaves credentials without an embedded expiry
+// no validity at all, so callers enforcing a policy should pass the TTL only when it reports one. Nil credentials and
+// those without a Credential have no validity left. It doesn't consult the clock, so callers choose now.
+func (ac *AuthenticatedCredential) ValidFor(ttl time.Duration, now time.Time) time.Duration {
+	if ac == nil || ac.Credential == nil {
+		return 0
+	}
+	if expiresAt := ac.Credential.GetExpiresAt(); expiresAt != 0 {
+		return time.Unix(expiresAt, 0).Sub(now)
+	}
+	return time.Unix(ac.Credential.GetTimestamp(), 0).Add(ttl).Sub(now)
+}
+
+// Expired reports whether ValidFor(ttl, now) is zero or negative, i.e. whether the credential has no validity left.
+// This is stricter than IsExpired and Verify by one instant: at exactly its expiry, a credential is expired here but
+// still accepted by Verify.
+func (ac *AuthenticatedCredential) Expired(ttl time.Duration, now time.Time) bool {
+	return ac.ValidFor(ttl, now) <= 0
+}
+
+// WithMaxAge makes Verify reject credentials without an embedded expiry once they are older than maxAge.
+// Credentials created with CreateWithExpiry are always checked against their own expiry instead.
+// It sets the default of the manager's ValidityPolicy.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(c *CredentialManager) {
+		c.validity.Default = maxAge
+	}
+}
+
+// WithDefaultValidity makes every Create method embed an expiry d after the credential's timestamp, which Verify
+// enforces, so call sites can't forget to set one. CreateWithExpiry overrides it for individual credentials.
+// The expiry has a resolution of seconds, so a validity under a second disables it.
+func WithDefaultValidity(d time.Duration) Option {
+	return func(c *CredentialManager) {
+		c.defaultValidity = d.Truncate(time.Second)
+	}
+}
+
+// CreateWithExpiry is like Create, but the credential embeds expires, which Verify enforces
+func (c *CredentialManager) CreateWithExpiry(timestamp time.Time, nodeID []byte, OperatorType OperatorType, expires time.Time) (*AuthenticatedCredential, error) {
+	return c.create(timestamp, nodeID, OperatorType, nil, func(credential *pb.Credential) error {
+		if expires.Unix() <= timestamp.Unix() {
+			return ErrInvalidExpiry
+		}
+		credential.ExpiresAt = expires.Unix()
+		return nil
+	})
+}
+
+// expiry returns when a credential stops being valid, and false if it never does
+func (c *CredentialManager) expiry(authenticatedCredential *AuthenticatedCredential) (time.Time, bool) {
+	expiresAt, _, ok := c.expiryWindow(authenticatedCredential)
+	return expiresAt, ok
+}
+
+// expiryWindow is like expiry, but additionally returns the validity window applied, if any
+func (c *CredentialManager) expiryWindow(authenticatedCredential *AuthenticatedCredential) (time.Time, time.Duration, bool) {
+	credential := authenticatedCredential.Credential
+	if credential.GetExpiresAt() != 0 {
+		return time.Unix(credential.GetExpiresAt(), 0), 0, true
+	}
+	ttl, ok := c.validity.TTL(credential.GetOperatorType())
+	if !ok {
+		return time.Time{}, 0, false
+	}
+	return time.Unix(credential.GetTimestamp(), 0).Add(ttl), ttl, true
+}
+
+// checkExpiry fails with an *ExpiredError if the credential expired more than grace ago
+func (c *CredentialManager) checkExpiry(authenticatedCredential *AuthenticatedCredential, grace time.Duration) error {
+	expiresAt, window, ok := c.expiryWindow(authenticatedCredential)
+	if ok && c.now().After(expiresAt.Add(grace)) {
+		return &ExpiredError{ExpiresAt: expiresAt, Window: window}
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestCreateWithExpiry tests that embedded expiries are enforced, and take precedence over the max age
+func TestCreateWithExpiry(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	now := issued
+	clock := func() time.Time { return now }
+	cm := NewCredentialManagerWithOptions([]byte("Expiry test secret"), nil, WithClock(clock), WithMaxAge(time.Hour))
+
+	cred, err := cm.CreateWithExpiry(issued, make([]byte, 20), pb.OperatorType_OT_SOLO, issued.Add(7*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := cm.Create(issued, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name         string
+		now          time.Time
+		credExpired  bool
+		plainExpired bool
+	}{
+		{"Fresh", issued, false, false},
+		{"PastMaxAge", issued.Add(2 * time.Hour), false, true},
+		{"AtExpiry", issued.Add(7 * 24 * time.Hour), false, true},
+		{"PastExpiry", issued.Add(7*24*time.Hour + time.Second), true, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now = tc.now
+			if _, err := cm.Verify(cred); errors.Is(err, ErrExpired) != tc.credExpired {
+				t.Errorf("Unexpected result for embedded expiry: %v", err)
+			}
+			if _, err := cm.Verify(plain); errors.Is(err, ErrExpired) != tc.plainExpired {
+				t.Errorf("Unexpected result for max age: %v", err)
+			}
+		})
+	}
+
+	var expired *ExpiredError
+	if _, err := cm.Verify(cred); !errors.As(err, &expired) || !expired.ExpiresAt.Equal(issued.Add(7*24*time.Hour)) {
+		t.Errorf("Expected an ExpiredError with the embedded expiry, got %v", err)
+	}
+
+	// Without a max age, credentials without an expiry verify as before
+	if _, err := NewCredentialManagerWithOptions([]byte("Expiry test secret"), nil, WithClock(clock)).Verify(plain); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := cm.CreateWithExpiry(issued, make([]byte, 20), pb.OperatorType_OT_SOLO, issued); !errors.Is(err, ErrInvalidExpiry) {
+		t.Errorf("Expected ErrInvalidExpiry, got %v", err)
+	}
+}
+
+// TestExpiryEncodings tests that the expiry survives the JSON and password encodings
+// TestDefaultValidity tests that the default validity is embedded in created credentials, and can be overridden
+func TestDefaultValidity(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	now := issued
+	clock := func() time.Time { return now }
+	key := []byte("Expiry test secret")
+	cm := NewCredentialManagerWithOptions(key, nil, WithClock(clock), WithDefaultValidity(time.Hour))
+
+	cred, err := cm.Create(issued, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := cm.CreateMany(issued, batchNodeIDs(2), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	override, err := cm.CreateWithExpiry(issued, make([]byte, 20), pb.OperatorType_OT_SOLO, issued.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range append(batch, cred) {
+		if c.Credential.ExpiresAt != issued.Add(time.Hour).Unix() {
+			t.Errorf("Expected the default expiry, got %d", c.Credential.ExpiresAt)
+		}
+	}
+	if override.Credential.ExpiresAt != issued.Add(24*time.Hour).Unix() {
+		t.Errorf("Expected the overridden expiry, got %d", override.Credential.ExpiresAt)
+	}
+
+	// The expiry is embedded, so any verifier enforces it
+	verifier := NewCredentialManagerWithOptions(key, nil, WithClock(clock))
+	now = issued.Add(time.Hour + time.Second)
+	if _, err := verifier.Verify(cred); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+	if _, err := verifier.Verify(override); err != nil {
+		t.Errorf("Expected the overridden expiry to apply, got %v", err)
+	}
+
+	// Validities under a second are disabled
+	now = issued
+	plain, err := NewCredentialManagerWithOptions(key, nil, WithDefaultValidity(time.Millisecond)).Create(issued, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Credential.ExpiresAt != 0 {
+		t.Errorf("Expected no expiry, got %d", plain.Credential.ExpiresAt)
+	}
+}
+
+func TestExpiryEncodings(t *testing.T) {
+	cm := NewCredentialManager([]byte("Expiry test secret"))
+	issued := time.Now()
+	cred, err := cm.CreateWithExpiry(issued, make([]byte, 20), pb.OperatorType_OT_SOLO, issued.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["expires_at"] != float64(issued.Add(time.Hour).Unix()) {
+		t.Errorf("Expected expires_at in JSON, got %v", fields["expires_at"])
+	}
+	var fromJSON AuthenticatedCredential
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := cred.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromText AuthenticatedCredential
+	if err := fromText.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, decoded := range []*AuthenticatedCredential{&fromJSON, &fromText} {
+		if decoded.Credential.ExpiresAt != cred.Credential.ExpiresAt {
+			t.Errorf("Expected expiry %d, got %d", cred.Credential.ExpiresAt, decoded.Credential.ExpiresAt)
+		}
+		if _, err := cm.Verify(decoded); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestTimeUntilExpiry tests the client side expiry helpers, with and without an embedded expiry
+func TestTimeUntilExpiry(t *testing.T) {
+	cm := NewCredentialManager([]byte("Expiry test secret"))
+	issued := time.Unix(1700000000, 0)
+	cred, err := cm.CreateWithExpiry(issued, make([]byte, 20), pb.OperatorType_OT_SOLO, issued.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		now     time.Time
+		until   time.Duration
+		expired bool
+	}{
+		{"Fresh", issued, time.Hour, false},
+		{"AtExpiry", issued.Add(time.Hour), 0, false},
+		{"Expired", issued.Add(90 * time.Minute), -30 * time.Minute, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if until := cred.TimeUntilExpiry(tc.now); until != tc.until {
+				t.Errorf("Expected %s until expiry, got %s", tc.until, until)
+			}
+			if cred.IsExpired(tc.now) != tc.expired {
+				t.Errorf("Expected IsExpired to be %v", tc.expired)
+			}
+		})
+	}
+
+	plain, err := cm.Create(issued, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := plain.TimeUntilExpiry(issued.Add(1000 * time.Hour)); until != NoExpiry {
+		t.Errorf("Expected NoExpiry, got %s", until)
+	}
+	if plain.IsExpired(issued.Add(1000 * time.Hour)) {
+		t.Error("Expected a credential without expiry never to expire")
+	}
+}
+
+// TestValidFor tests that validity comes from the embedded expiry if any, or else the timestamp and ttl
+func TestValidFor(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	cm := NewCredentialManager([]byte("Expiry test secret"))
+	plain, err := cm.Create(issued, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded, err := cm.CreateWithExpiry(issued, make([]byte, 20), pb.OperatorType_OT_SOLO, issued.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		cred     *AuthenticatedCredential
+		ttl      time.Duration
+		now      time.Time
+		expected time.Duration
+		expired  bool
+	}{
+		{"TTL", plain, time.Hour, issued.Add(time.Minute), 59 * time.Minute, false},
+		{"TTLElapsed", plain, time.Hour, issued.Add(time.Hour), 0, true},
+		{"TTLPast", plain, time.Hour, issued.Add(2 * time.Hour), -time.Hour, true},
+		{"NoTTL", plain, 0, issued.Add(time.Minute), -time.Minute, true},
+		{"NegativeTTL", plain, -time.Hour, issued, -time.Hour, true},
+		{"EmbeddedOverridesTTL", embedded, time.Hour, issued.Add(30 * time.Second), 30 * time.Second, false},
+		{"EmbeddedAtExpiry", embedded, 0, issued.Add(time.Minute), 0, true},
+		{"EmbeddedWithoutTTL", embedded, 0, issued.Add(2 * time.Minute), -time.Minute, true},
+		{"NilCredential", &AuthenticatedCredential{}, time.Hour, issued, 0, true},
+		{"Nil", nil, time.Hour, issued, 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if validFor := tc.cred.ValidFor(tc.ttl, tc.now); validFor != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, validFor)
+			}
+			if expired := tc.cred.Expired(tc.ttl, tc.now); expired != tc.expired {
+				t.Errorf("Expected expired to be %t", tc.expired)
+			}
+		})
+	}
+}
+
+// TestExpiredAtExpiry tests that Expired treats credentials as expired from the exact instant they expire, while
+// IsExpired and Verify still accept them then, and that all of them agree a second after
+func TestExpiredAtExpiry(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	expiresAt := issued.Add(time.Minute)
+	now := expiresAt
+	cm := NewCredentialManagerWithOptions([]byte("Expiry test secret"), nil, WithClock(func() time.Time { return now }))
+	cred, err := cm.CreateWithExpiry(issued, make([]byte, 20), pb.OperatorType_OT_SOLO, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, at := range []time.Time{expiresAt, expiresAt.Add(time.Second)} {
+		now = at
+		if !cred.Expired(0, at) {
+			t.Errorf("Expected Expired at %s", at)
+		}
+		accepted := !at.After(expiresAt)
+		if cred.IsExpired(at) == accepted {
+			t.Errorf("Expected IsExpired to be %t at %s", !accepted, at)
+		}
+		if _, err := cm.Verify(cred); errors.Is(err, ErrExpired) == accepted {
+			t.Errorf("Expected Verify to accept the credential to be %t at %s, got %v", accepted, at, err)
+		}
+	}
+}
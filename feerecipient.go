@@ -0,0 +1,56 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// FeeRecipientLength is the length of a fee recipient, which is an Ethereum address
+const FeeRecipientLength = 20
+
+var (
+	ErrInvalidFeeRecipient  = errors.New("invalid fee recipient length")
+	ErrFeeRecipientMismatch = errors.New("credential fee recipient mismatch")
+)
+
+// validateFeeRecipient accepts absent or FeeRecipientLength byte fee recipients
+func validateFeeRecipient(feeRecipient []byte) error {
+	if len(feeRecipient) != 0 && len(feeRecipient) != FeeRecipientLength {
+		return fmt.Errorf("%w. Expected %d, got %d", ErrInvalidFeeRecipient, FeeRecipientLength, len(feeRecipient))
+	}
+	return nil
+}
+
+// CreateWithFeeRecipient is like Create, but binds the credential to the fee recipient the operator attested to.
+// The fee recipient is covered by the MAC, and must be FeeRecipientLength bytes.
+func (c *CredentialManager) CreateWithFeeRecipient(timestamp time.Time, nodeID []byte, OperatorType OperatorType, feeRecipient []byte) (*AuthenticatedCredential, error) {
+	if len(feeRecipient) != FeeRecipientLength {
+		return nil, fmt.Errorf("%w. Expected %d, got %d", ErrInvalidFeeRecipient, FeeRecipientLength, len(feeRecipient))
+	}
+	return c.create(timestamp, nodeID, OperatorType, nil, func(credential *pb.Credential) error {
+		credential.FeeRecipient = append([]byte(nil), feeRecipient...)
+		return nil
+	})
+}
+
+// FeeRecipient returns the fee recipient the credential is bound to, or nil if it isn't bound to one
+func (ac *AuthenticatedCredential) FeeRecipient() []byte {
+	return ac.Credential.GetFeeRecipient()
+}
+
+// VerifyFeeRecipient is like Verify, but additionally requires the credential to be bound to addr,
+// failing with ErrFeeRecipientMismatch otherwise
+func (c *CredentialManager) VerifyFeeRecipient(authenticatedCredential *AuthenticatedCredential, addr []byte) (*ID, error) {
+	id, err := c.Verify(authenticatedCredential)
+	if err != nil {
+		return nil, err
+	}
+	if feeRecipient := authenticatedCredential.FeeRecipient(); !bytes.Equal(feeRecipient, addr) {
+		return nil, newVerificationError(authenticatedCredential, fmt.Errorf("%w: credential is bound to 0x%x, got 0x%x", ErrFeeRecipientMismatch, feeRecipient, addr))
+	}
+	return id, nil
+}
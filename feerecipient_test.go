@@ -0,0 +1,102 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestCreateWithFeeRecipient tests that fee recipient bound credentials survive encodings and are checked
+func TestCreateWithFeeRecipient(t *testing.T) {
+	cm := NewCredentialManager([]byte("Fee recipient test secret"))
+	feeRecipient := make([]byte, FeeRecipientLength)
+	feeRecipient[19] = 0xfe
+	other := make([]byte, FeeRecipientLength)
+
+	cred, err := cm.CreateWithFeeRecipient(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, feeRecipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["fee_recipient"] != "0x00000000000000000000000000000000000000fe" {
+		t.Errorf("Unexpected fee_recipient %v", fields["fee_recipient"])
+	}
+	var fromJSON AuthenticatedCredential
+	if err := json.Unmarshal(data, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	text, err := cred.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromText AuthenticatedCredential
+	if err := fromText.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, decoded := range []*AuthenticatedCredential{&fromJSON, &fromText} {
+		if _, err := cm.VerifyFeeRecipient(decoded, feeRecipient); err != nil {
+			t.Error(err)
+		}
+		if _, err := cm.VerifyFeeRecipient(decoded, other); !errors.Is(err, ErrFeeRecipientMismatch) {
+			t.Errorf("Expected ErrFeeRecipientMismatch, got %v", err)
+		}
+	}
+
+	// Unbound credentials verify as before, but not for a specific fee recipient
+	unbound, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(unbound); err != nil {
+		t.Error(err)
+	}
+	if _, err := cm.VerifyFeeRecipient(unbound, feeRecipient); !errors.Is(err, ErrFeeRecipientMismatch) {
+		t.Errorf("Expected ErrFeeRecipientMismatch, got %v", err)
+	}
+}
+
+// TestFeeRecipientLength tests that fee recipients of the wrong length are rejected at creation and decoding
+func TestFeeRecipientLength(t *testing.T) {
+	cm := NewCredentialManager([]byte("Fee recipient test secret"))
+	if _, err := cm.CreateWithFeeRecipient(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, make([]byte, 19)); !errors.Is(err, ErrInvalidFeeRecipient) {
+		t.Errorf("Expected ErrInvalidFeeRecipient, got %v", err)
+	}
+	if _, err := cm.CreateWithFeeRecipient(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, nil); !errors.Is(err, ErrInvalidFeeRecipient) {
+		t.Errorf("Expected ErrInvalidFeeRecipient, got %v", err)
+	}
+
+	crafted, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crafted.Credential.FeeRecipient = make([]byte, 21)
+
+	data, err := json.Marshal(crafted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON AuthenticatedCredential
+	if err := json.Unmarshal(data, &fromJSON); !errors.Is(err, ErrInvalidFeeRecipient) {
+		t.Errorf("Expected ErrInvalidFeeRecipient from JSON, got %v", err)
+	}
+	text, err := crafted.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromText AuthenticatedCredential
+	if err := fromText.UnmarshalText(text); !errors.Is(err, ErrInvalidFeeRecipient) {
+		t.Errorf("Expected ErrInvalidFeeRecipient from text, got %v", err)
+	}
+}
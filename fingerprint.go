@@ -0,0 +1,120 @@
+package credentials
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"strings"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// fingerprintDomain separates fingerprint hashes from every other use of the key
+const fingerprintDomain = "rescue-credential-fingerprint"
+
+// KeyFingerprint returns a short, stable identifier for key, suitable for logs and diagnostics.
+// It is the first 8 hex characters of a domain-separated SHA-256 of the key, so it never changes
+// across processes or versions, and does not reveal the key itself.
+func KeyFingerprint(key []byte) string {
+	h := hashAlgo()
+	h.Write([]byte(fingerprintDomain))
+	h.Write(key)
+	return hex.EncodeToString(h.Sum(nil)[:4])
+}
+
+// keyCheckValueLabel is the message whose MAC is a manager's key check value. Its leading zero byte is
+// an invalid protobuf tag, so it can never be the MAC input of a credential.
+const keyCheckValueLabel = "\x00rescue-credential-key-fingerprint"
+
+// KeyCheckValue returns the HMAC-SHA256 of a fixed label under the manager's primary key, so that the
+// configurations of separate services can be compared without exposing their keys. Managers with the same
+// primary key always have the same check value, and it reveals nothing about the key.
+// It is not the KeyFingerprint of the key, nor the manager's Fingerprint, which are short hashes for logs;
+// the check value is 32 bytes, and unlike them it can also be computed for KeyRings and MACers.
+// For a KeyRing backed manager it is the check value of the current signing key. It returns nil if the MAC
+// couldn't be computed, e.g. because a KeyRing has no active key or a MACer failed.
+func (c *CredentialManager) KeyCheckValue() []byte {
+	label := []byte(keyCheckValueLabel)
+	if c.ring != nil {
+		mac, err := c.ring.sign(c.now(), label)
+		if err != nil {
+			return nil
+		}
+		return mac
+	}
+	if c.macer != nil {
+		return c.macer.Compute(label)
+	}
+
+	v, err := c.getChecker()
+	if err != nil {
+		return nil
+	}
+	defer c.putChecker(v)
+	v.primary.hmac.Write(label)
+	checkValue := v.primary.hmac.Sum(nil)
+	v.primary.hmac.Reset()
+	return checkValue
+}
+
+// MatchesKeyCheckValue reports, in constant time, whether checkValue is the manager's KeyCheckValue,
+// e.g. one published by another service
+func (c *CredentialManager) MatchesKeyCheckValue(checkValue []byte) bool {
+	own := c.KeyCheckValue()
+	return own != nil && hmac.Equal(own, checkValue)
+}
+
+// fingerprints joins the fingerprints of the primary key and extra secrets for use in error messages
+func fingerprints(key []byte, extraSecrets [][]byte) string {
+	out := make([]string, 0, len(extraSecrets)+1)
+	out = append(out, KeyFingerprint(key))
+	for _, s := range extraSecrets {
+		out = append(out, KeyFingerprint(s))
+	}
+	return strings.Join(out, ",")
+}
+
+// Domains of the credential fingerprints. The leading zero byte keeps them apart from the key derivation domains,
+// and the trailing one from each other.
+const (
+	credentialFingerprintDomain = "\x00rescue-credential-fingerprint\x00"
+	identityFingerprintDomain   = "\x00rescue-credential-identity-fingerprint\x00"
+)
+
+// Fingerprint returns the hex SHA-256 of the credential's deterministic wire encoding, MACs included, suitable as a
+// cache key or for deduplicating credentials in logs. It is stable across processes and versions of this library and
+// of protobuf, as the encoding is the canonical one of CanonicalBytes rather than proto.Marshal's.
+// It isn't secret, and proves nothing about the credential: anyone holding the credential can compute it.
+// It fails with ErrNilCredential for nil credentials, and like Base64URLEncodePassword on invalid UTF-8.
+func (ac *AuthenticatedCredential) Fingerprint() (string, error) {
+	if ac == nil || ac.Credential == nil {
+		return "", ErrNilCredential
+	}
+	data, err := appendAuthenticatedCredential(nil, ac, true)
+	if err != nil {
+		return "", err
+	}
+	return fingerprintHex(credentialFingerprintDomain, data), nil
+}
+
+// IdentityFingerprint is like Fingerprint, but only covers the node ID, operator type and timestamp,
+// so copies of a credential that were MACed again, or which differ in other fields, share it.
+// It isn't secret either.
+func (ac *AuthenticatedCredential) IdentityFingerprint() (string, error) {
+	if ac == nil || ac.Credential == nil {
+		return "", ErrNilCredential
+	}
+	identity := &pb.Credential{
+		NodeId:       ac.Credential.NodeId,
+		Timestamp:    ac.Credential.Timestamp,
+		OperatorType: ac.Credential.OperatorType,
+	}
+	return fingerprintHex(identityFingerprintDomain, appendCredential(nil, identity)), nil
+}
+
+// fingerprintHex returns the hex SHA-256 of domain followed by data
+func fingerprintHex(domain string, data []byte) string {
+	h := hashAlgo()
+	h.Write([]byte(domain))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,225 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestKeyFingerprintStable pins fingerprint values so they can't silently change between versions
+func TestKeyFingerprintStable(t *testing.T) {
+	testCases := []struct {
+		key      []byte
+		expected string
+	}{
+		{[]byte("Curiouser and curiouser"), "21d758b5"},
+		{nil, "1614acd2"},
+	}
+
+	for _, tc := range testCases {
+		fp := KeyFingerprint(tc.key)
+		if fp != tc.expected {
+			t.Errorf("KeyFingerprint(%q) = %s, expected %s", tc.key, fp, tc.expected)
+		}
+	}
+
+	if KeyFingerprint([]byte("a")) == KeyFingerprint([]byte("b")) {
+		t.Error("Different keys produced the same fingerprint")
+	}
+}
+
+// TestCredentialManagerFingerprint tests that the manager reports its primary key's fingerprint
+func TestCredentialManagerFingerprint(t *testing.T) {
+	cm := NewCredentialManager([]byte("Curiouser and curiouser"), []byte("extra"))
+	if cm.Fingerprint() != KeyFingerprint([]byte("Curiouser and curiouser")) {
+		t.Errorf("Unexpected fingerprint %s", cm.Fingerprint())
+	}
+}
+
+// TestMismatchErrorFingerprint tests that MAC mismatches name the fingerprints of the verifier's keys
+func TestMismatchErrorFingerprint(t *testing.T) {
+	creator := NewCredentialManager([]byte("T'was brillig"))
+	verifier := NewCredentialManager([]byte("And the slithy toves did gyre"), []byte("Alice"))
+
+	cred, err := creator.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_ROCKETPOOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = verifier.Verify(cred)
+	if !errors.Is(err, MismatchError) {
+		t.Fatalf("Expected MismatchError, got %v", err)
+	}
+
+	expected := "verifier key fp=" + verifier.Fingerprint() + "," + KeyFingerprint([]byte("Alice"))
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected %q in error, got %q", expected, err.Error())
+	}
+	if strings.Contains(err.Error(), creator.Fingerprint()) {
+		t.Error("Mismatch error unexpectedly contains the creator's fingerprint")
+	}
+}
+
+// TestKeyCheckValue tests that managers configured with the same key have the same key check value,
+// however the key is held
+func TestKeyCheckValue(t *testing.T) {
+	key := []byte("Curiouser and curiouser")
+	ring, err := NewKeyRing(KeyEntry{ID: "current", Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := NewCredentialManager(key)
+	checkValue := cm.KeyCheckValue()
+	// Pinned, as services on different versions compare check values
+	if hex.EncodeToString(checkValue) != "c6f97e8a9972ef7d1e6049505a5f40af156395112bb67ef004e705815d5e4815" {
+		t.Fatalf("Unexpected check value %x", checkValue)
+	}
+
+	for name, other := range map[string]*CredentialManager{
+		"ExtraSecrets": NewCredentialManager(key, []byte("extra")),
+		"MACer":        NewCredentialManagerFromMACer(&remoteMACer{key: key}),
+		"KeyRing":      NewCredentialManagerFromKeyRing(ring),
+	} {
+		if !other.MatchesKeyCheckValue(checkValue) {
+			t.Errorf("%s: expected check value %x, got %x", name, checkValue, other.KeyCheckValue())
+		}
+	}
+
+	other := NewCredentialManager([]byte("Curiouser and curiouser!"))
+	if other.MatchesKeyCheckValue(checkValue) || cm.MatchesKeyCheckValue(nil) {
+		t.Error("Expected check values of different keys to differ")
+	}
+
+	// Failing MACers and rings without an active key have no check value
+	failing := NewCredentialManagerFromMACer(&remoteMACer{key: key, fail: true})
+	empty := NewCredentialManagerFromKeyRing(new(KeyRing))
+	if failing.KeyCheckValue() != nil || empty.KeyCheckValue() != nil || empty.MatchesKeyCheckValue(nil) {
+		t.Error("Expected no check value")
+	}
+}
+
+// fingerprintCredential is a credential with fixed contents, for the golden fingerprints
+func fingerprintCredential() *AuthenticatedCredential {
+	return &AuthenticatedCredential{
+		Credential: &pb.Credential{
+			NodeId:       bytes.Repeat([]byte{0xab}, NodeIDLength),
+			Timestamp:    1700000000,
+			OperatorType: pb.OperatorType_OT_SOLO,
+			Metadata:     map[string]string{"b": "2", "a": "1"},
+			Version:      CurrentVersion,
+		},
+		Mac:            bytes.Repeat([]byte{0x01}, MACLength),
+		AdditionalMacs: []*pb.KeyedMac{{KeyId: []byte{1, 2, 3, 4, 5, 6, 7, 8}, Mac: bytes.Repeat([]byte{0x02}, MACLength)}},
+	}
+}
+
+// TestCredentialFingerprintStable tests that credential fingerprints are fixed hashes of the canonical encoding
+func TestCredentialFingerprintStable(t *testing.T) {
+	cred := fingerprintCredential()
+	fingerprint, err := cred.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := cred.IdentityFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Changing these breaks every cache keyed by them
+	if fingerprint != "1515502f7c2eab7eb5effad1e4fd043925054c803630fa4874d90d36223c25c0" {
+		t.Errorf("Fingerprint changed: %s", fingerprint)
+	}
+	if identity != "904e57512a2517aecdbd3dd3e923dd8f7084bb4432ef1b4738830d86fec20136" {
+		t.Errorf("IdentityFingerprint changed: %s", identity)
+	}
+
+	// The encoding is deterministic proto.Marshal's, without depending on it
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(cred.Pb())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.New()
+	h.Write([]byte(credentialFingerprintDomain))
+	h.Write(data)
+	if want := hex.EncodeToString(h.Sum(nil)); fingerprint != want {
+		t.Errorf("Expected the hash of the deterministic encoding %s, got %s", want, fingerprint)
+	}
+}
+
+// TestCredentialFingerprint tests which differences between credentials change their fingerprints
+func TestCredentialFingerprint(t *testing.T) {
+	key := []byte("Fingerprint test secret")
+	timestamp := time.Unix(1700000000, 0)
+	cred, err := NewCredentialManager(key).Create(timestamp, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint, err := cred.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := cred.IdentityFingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Copies decoded from any encoding share both
+	text, err := cred.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AuthenticatedCredential
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := decoded.Fingerprint(); err != nil || got != fingerprint {
+		t.Errorf("Expected the decoded credential's fingerprint %s, got %s (%v)", fingerprint, got, err)
+	}
+
+	// Re-MACing changes the fingerprint, but not the identity
+	remaced := &AuthenticatedCredential{Credential: cred.Credential}
+	if remaced.Mac, err = NewCredentialManager([]byte("Other secret")).ComputeMAC(cred); err != nil {
+		t.Fatal(err)
+	}
+	other, err := remaced.Fingerprint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == fingerprint {
+		t.Error("Expected a different MAC to change the fingerprint")
+	}
+	if got, err := remaced.IdentityFingerprint(); err != nil || got != identity {
+		t.Errorf("Expected the re-MACed credential's identity %s, got %s (%v)", identity, got, err)
+	}
+
+	// So do credentials issued again for the same node, operator type and time, with a fresh credential ID
+	again, err := NewDualSignManager(key, []byte("Other secret")).Create(timestamp, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := again.IdentityFingerprint(); err != nil || got != identity {
+		t.Errorf("Expected the reissued credential's identity %s, got %s (%v)", identity, got, err)
+	}
+	later, err := NewCredentialManager(key).Create(timestamp.Add(time.Second), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := later.IdentityFingerprint(); got == identity {
+		t.Error("Expected a different timestamp to change the identity")
+	}
+
+	for _, c := range []*AuthenticatedCredential{nil, {}} {
+		if _, err := c.Fingerprint(); !errors.Is(err, ErrNilCredential) {
+			t.Errorf("Expected ErrNilCredential, got %v", err)
+		}
+		if _, err := c.IdentityFingerprint(); !errors.Is(err, ErrNilCredential) {
+			t.Errorf("Expected ErrNilCredential from IdentityFingerprint, got %v", err)
+		}
+	}
+}
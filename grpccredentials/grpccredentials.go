@@ -0,0 +1,94 @@
+// Package grpccredentials serves a credentials.CredentialManager as the gRPC servicepb.CredentialService, so that the
+// credential signer can run apart from whatever takes requests from node operators.
+// The service and its messages are generated into the servicepb package from proto/servicepb/service.proto,
+// apart from the pb package, so that importing credentials doesn't pull in gRPC.
+package grpccredentials
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials"
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"github.com/Rocket-Rescue-Node/credentials/pb/servicepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements servicepb.CredentialServiceServer with a manager. Issue only mints credentials for requests
+// signed by their node, so the manager must be created with credentials.WithRequestVerifier, and with
+// credentials.WithReplayCache for each request to be accepted only once.
+type Server struct {
+	servicepb.UnimplementedCredentialServiceServer
+
+	cm  *credentials.CredentialManager
+	now func() time.Time
+}
+
+var _ servicepb.CredentialServiceServer = (*Server)(nil)
+
+// NewServer returns a Server issuing and verifying credentials with cm
+func NewServer(cm *credentials.CredentialManager) *Server {
+	return &Server{cm: cm, now: time.Now}
+}
+
+// Issue mints a credential for a signed request, as credentials.CredentialManager.IssueFromRequest does
+func (s *Server) Issue(ctx context.Context, req *servicepb.CredentialRequest) (*pb.AuthenticatedCredential, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, statusError(err)
+	}
+	cred, err := s.cm.IssueFromRequest(&credentials.CredentialRequest{
+		NodeID:       req.GetNodeId(),
+		OperatorType: req.GetOperatorType(),
+		Timestamp:    req.GetTimestamp(),
+		Nonce:        req.GetNonce(),
+		Signature:    req.GetSignature(),
+	}, s.now())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return cred.Pb(), nil
+}
+
+// Verify verifies a credential, as credentials.CredentialManager.VerifyContext does
+func (s *Server) Verify(ctx context.Context, ac *pb.AuthenticatedCredential) (*servicepb.VerifyResponse, error) {
+	id, err := s.cm.VerifyContext(ctx, (*credentials.AuthenticatedCredential)(ac))
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &servicepb.VerifyResponse{Credential: ac.GetCredential(), KeyId: id.String()}, nil
+}
+
+// Code returns the gRPC status code for err, which may be any error returned by the credentials package:
+// InvalidArgument for requests and credentials which are missing, malformed or invalid, Unauthenticated for ones
+// which aren't authentic or no longer (or not yet) valid, PermissionDenied for authentic ones which aren't accepted,
+// e.g. for their scopes or because they were replayed, and ResourceExhausted for nodes refused by an IssuanceLimiter.
+// Canceled and expired contexts keep their codes, and other failures, which aren't the caller's fault, are Internal.
+func Code(err error) codes.Code {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	}
+	switch credentials.ErrorCode(err) {
+	case credentials.CodeOK:
+		return codes.OK
+	case credentials.CodeMissing, credentials.CodeMalformed, credentials.CodeInvalidArgument:
+		return codes.InvalidArgument
+	case credentials.CodeMACMismatch, credentials.CodeExpired, credentials.CodeNotYetValid, credentials.CodeRevoked:
+		return codes.Unauthenticated
+	case credentials.CodeReplayed, credentials.CodeRejected:
+		return codes.PermissionDenied
+	case credentials.CodeRateLimited:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// statusError converts err to a gRPC status error with its Code
+func statusError(err error) error {
+	return status.Error(Code(err), err.Error())
+}
@@ -0,0 +1,205 @@
+package grpccredentials
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials"
+	"github.com/Rocket-Rescue-Node/credentials/ethcredentials"
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"github.com/Rocket-Rescue-Node/credentials/pb/servicepb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// serve serves cm on an in-process listener, returning a client connected to it
+func serve(t *testing.T, cm *credentials.CredentialManager) servicepb.CredentialServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	servicepb.RegisterCredentialServiceServer(srv, NewServer(cm))
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			t.Error(err)
+		}
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return servicepb.NewCredentialServiceClient(conn)
+}
+
+// signedRequest makes a request for the node of key, signed with it
+func signedRequest(t *testing.T, key *ecdsa.PrivateKey) *servicepb.CredentialRequest {
+	t.Helper()
+	req, err := credentials.NewCredentialRequest(crypto.PubkeyToAddress(key.PublicKey).Bytes(), pb.OperatorType_OT_SOLO, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Sign(func(digest []byte) ([]byte, error) { return crypto.Sign(digest, key) }); err != nil {
+		t.Fatal(err)
+	}
+	return &servicepb.CredentialRequest{
+		NodeId:       req.NodeID,
+		OperatorType: req.OperatorType,
+		Timestamp:    req.Timestamp,
+		Nonce:        req.Nonce,
+		Signature:    req.Signature,
+	}
+}
+
+// newManager returns a manager issuing credentials for requests verified by ethcredentials, each only once
+func newManager(t *testing.T) *credentials.CredentialManager {
+	t.Helper()
+	replayCache := credentials.NewMemoryReplayCache(time.Minute)
+	t.Cleanup(replayCache.Close)
+	return credentials.NewCredentialManagerWithOptions([]byte("gRPC test secret"), nil,
+		credentials.WithRequestVerifier(ethcredentials.VerifyIssuanceSignature, time.Minute),
+		credentials.WithReplayCache(replayCache, time.Hour))
+}
+
+// TestIssueVerify tests issuing a credential for a signed request and verifying it, end to end over gRPC
+func TestIssueVerify(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := newManager(t)
+	client := serve(t, cm)
+	ctx := context.Background()
+
+	req := signedRequest(t, key)
+	cred, err := client.Issue(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address, err := ethcredentials.NodeAddress((*credentials.AuthenticatedCredential)(cred))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("Expected a credential for %s, got %s", crypto.PubkeyToAddress(key.PublicKey), address)
+	}
+	if _, err := cm.Verify((*credentials.AuthenticatedCredential)(cred)); err != nil {
+		t.Errorf("Expected the issued credential to verify locally, got %v", err)
+	}
+
+	resp, err := client.Verify(ctx, cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(resp.GetCredential(), cred.GetCredential()) {
+		t.Errorf("Expected %v, got %v", cred.GetCredential(), resp.GetCredential())
+	}
+	if resp.GetKeyId() != cm.ID().String() {
+		t.Errorf("Expected key ID %s, got %s", cm.ID(), resp.GetKeyId())
+	}
+
+	// The same request can't be issued twice
+	if _, err := client.Issue(ctx, req); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a replayed request, got %v", err)
+	}
+}
+
+// TestStatusCodes tests that failures are reported with the right gRPC status codes
+func TestStatusCodes(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := newManager(t)
+	client := serve(t, cm)
+	unconfigured := serve(t, credentials.NewCredentialManager([]byte("gRPC test secret")))
+	ctx := context.Background()
+
+	cred, err := client.Issue(ctx, signedRequest(t, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := proto.Clone(cred).(*pb.AuthenticatedCredential)
+	tampered.Credential.OperatorType = pb.OperatorType_OT_ROCKETPOOL
+	foreign, err := credentials.NewCredentialManager([]byte("Some other secret")).Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stolen := signedRequest(t, key)
+	stolen.NodeId = crypto.PubkeyToAddress(other.PublicKey).Bytes()
+	unsigned := signedRequest(t, key)
+	unsigned.Signature = nil
+	malleable := signedRequest(t, key)
+	malleable.Signature = malleable.Signature[:64]
+
+	testCases := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"Issue/Unsigned", func() error { _, err := client.Issue(ctx, unsigned); return err }, codes.InvalidArgument},
+		{"Issue/OtherSigner", func() error { _, err := client.Issue(ctx, stolen); return err }, codes.Unauthenticated},
+		{"Issue/BadSignature", func() error { _, err := client.Issue(ctx, malleable); return err }, codes.Unauthenticated},
+		{"Issue/NoVerifier", func() error { _, err := unconfigured.Issue(ctx, signedRequest(t, key)); return err }, codes.Internal},
+		{"Verify/Tampered", func() error { _, err := client.Verify(ctx, tampered); return err }, codes.Unauthenticated},
+		{"Verify/Empty", func() error { _, err := client.Verify(ctx, &pb.AuthenticatedCredential{}); return err }, codes.InvalidArgument},
+		{"Verify/OtherKey", func() error { _, err := client.Verify(ctx, foreign.Pb()); return err }, codes.Unauthenticated},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if code := status.Code(tc.call()); code != tc.want {
+				t.Errorf("Expected %s, got %s", tc.want, code)
+			}
+		})
+	}
+}
+
+// TestCode tests the status codes of the credentials package's error codes
+func TestCode(t *testing.T) {
+	testCases := []struct {
+		err  error
+		want codes.Code
+	}{
+		{nil, codes.OK},
+		{credentials.ErrNilCredential, codes.InvalidArgument},
+		{credentials.ErrMalformedCredential, codes.InvalidArgument},
+		{credentials.ErrInvalidNodeIDLength, codes.InvalidArgument},
+		{credentials.ErrInvalidRequest, codes.InvalidArgument},
+		{credentials.MismatchError, codes.Unauthenticated},
+		{credentials.ErrExpired, codes.Unauthenticated},
+		{credentials.ErrRequestExpired, codes.Unauthenticated},
+		{credentials.ErrTimestampInFuture, codes.Unauthenticated},
+		{credentials.ErrRevoked, codes.Unauthenticated},
+		{credentials.ErrRequestSignerMismatch, codes.Unauthenticated},
+		{credentials.ErrReplayedRequest, codes.PermissionDenied},
+		{credentials.ErrMissingScopes, codes.PermissionDenied},
+		{&credentials.IssuanceRateLimitedError{RetryAfter: time.Minute}, codes.ResourceExhausted},
+		{credentials.ErrNoRequestVerifier, codes.Internal},
+		{fmt.Errorf("wrapped: %w", context.Canceled), codes.Canceled},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{errors.New("other"), codes.Internal},
+	}
+	for _, tc := range testCases {
+		if code := Code(tc.err); code != tc.want {
+			t.Errorf("%v: expected %s, got %s", tc.err, tc.want, code)
+		}
+	}
+}
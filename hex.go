@@ -0,0 +1,31 @@
+package credentials
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// decodeNodeIDHex decodes a node ID written as hex, with or without a 0x prefix
+func decodeNodeIDHex(nodeIDHex string) ([]byte, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(nodeIDHex, "0x"), "0X")
+	nodeID, err := hex.DecodeString(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidNodeIDHex, nodeIDHex, err)
+	}
+	if err := validateNodeID(nodeID); err != nil {
+		return nil, err
+	}
+	return nodeID, nil
+}
+
+// CreateFromHex is like Create, but takes the node ID as a hex string, optionally 0x prefixed, as found in config files.
+// Strings which aren't valid hex return ErrInvalidNodeIDHex, and those of the wrong length ErrInvalidNodeIDLength.
+func (c *CredentialManager) CreateFromHex(timestamp time.Time, nodeIDHex string, OperatorType OperatorType) (*AuthenticatedCredential, error) {
+	nodeID, err := decodeNodeIDHex(nodeIDHex)
+	if err != nil {
+		return nil, err
+	}
+	return c.Create(timestamp, nodeID, OperatorType)
+}
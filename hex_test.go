@@ -0,0 +1,53 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestCreateFromHex tests that hex node IDs are accepted with and without a prefix, and invalid ones rejected
+func TestCreateFromHex(t *testing.T) {
+	cm := NewCredentialManager([]byte("Hex test secret"))
+	expected := []byte{0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90, 0x12, 0x34, 0x56, 0x78, 0x90}
+
+	for _, nodeIDHex := range []string{
+		"1234567890123456789012345678901234567890",
+		"0x1234567890123456789012345678901234567890",
+		"0X1234567890123456789012345678901234567890",
+	} {
+		cred, err := cm.CreateFromHex(time.Now(), nodeIDHex, pb.OperatorType_OT_SOLO)
+		if err != nil {
+			t.Fatalf("%s: %v", nodeIDHex, err)
+		}
+		if !bytes.Equal(cred.Credential.NodeId, expected) {
+			t.Errorf("%s: expected node ID %x, got %x", nodeIDHex, expected, cred.Credential.NodeId)
+		}
+		if _, err := cm.Verify(cred); err != nil {
+			t.Error(err)
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		nodeIDHex string
+		expected  error
+	}{
+		{"NotHex", "0x123456789012345678901234567890123456789z", ErrInvalidNodeIDHex},
+		{"OddLength", "0x123", ErrInvalidNodeIDHex},
+		{"Empty", "", ErrInvalidNodeIDLength},
+		{"PrefixOnly", "0x", ErrInvalidNodeIDLength},
+		{"Short", "0x1234", ErrInvalidNodeIDLength},
+		{"Long", "0x123456789012345678901234567890123456789012", ErrInvalidNodeIDLength},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := cm.CreateFromHex(time.Now(), tc.nodeIDHex, pb.OperatorType_OT_SOLO); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package credentials
+
+import (
+	"context"
+	"time"
+)
+
+// Hooks are callbacks notified of the manager's events, e.g. to count them in a metrics system.
+// Any of them may be nil. They are called synchronously, possibly from many goroutines at once, and never
+// while the manager holds a lock. A panicking hook is recovered from, and doesn't affect the call that triggered it.
+type Hooks struct {
+	// OnCreate is called with every credential created, including each credential of a batch
+	OnCreate func(cred *AuthenticatedCredential)
+	// OnVerifySuccess is called with every credential that verifies, and how long verifying it took
+	OnVerifySuccess func(cred *AuthenticatedCredential, elapsed time.Duration)
+	// OnVerifyFailure is called with the reason every failed verification failed, and how long it took.
+	// The reason is the error returned to the caller, so errors.Is tells e.g. MismatchError and ErrExpired apart.
+	OnVerifyFailure func(reason error, elapsed time.Duration)
+
+	// The Context variants are called after the hooks above, with the context passed to CreateContext or VerifyContext,
+	// or context.Background() for the methods without one, e.g. to attach events to the caller's trace
+	OnCreateContext        func(ctx context.Context, cred *AuthenticatedCredential)
+	OnVerifySuccessContext func(ctx context.Context, cred *AuthenticatedCredential, elapsed time.Duration)
+	OnVerifyFailureContext func(ctx context.Context, reason error, elapsed time.Duration)
+}
+
+// WithHooks makes the manager call hooks for the credentials created by its Create methods, and verified by
+// Verify, VerifyWithAAD and the methods built on them, such as VerifyFromBasicAuth.
+func WithHooks(hooks Hooks) Option {
+	return func(c *CredentialManager) {
+		c.hooks = &hooks
+	}
+}
+
+// created calls the OnCreate hook, if any, for each of creds
+func (h *Hooks) created(ctx context.Context, creds ...*AuthenticatedCredential) {
+	if h == nil || (h.OnCreate == nil && h.OnCreateContext == nil) {
+		return
+	}
+	for _, cred := range creds {
+		if cred == nil {
+			continue
+		}
+		if h.OnCreate != nil {
+			callHook(func() { h.OnCreate(cred) })
+		}
+		if h.OnCreateContext != nil {
+			callHook(func() { h.OnCreateContext(ctx, cred) })
+		}
+	}
+}
+
+// verified calls the OnVerifySuccess or OnVerifyFailure hooks, if any, depending on err
+func (h *Hooks) verified(ctx context.Context, cred *AuthenticatedCredential, err error, elapsed time.Duration) {
+	if h == nil {
+		return
+	}
+	if err == nil {
+		if h.OnVerifySuccess != nil {
+			callHook(func() { h.OnVerifySuccess(cred, elapsed) })
+		}
+		if h.OnVerifySuccessContext != nil {
+			callHook(func() { h.OnVerifySuccessContext(ctx, cred, elapsed) })
+		}
+		return
+	}
+	if h.OnVerifyFailure != nil {
+		callHook(func() { h.OnVerifyFailure(err, elapsed) })
+	}
+	if h.OnVerifyFailureContext != nil {
+		callHook(func() { h.OnVerifyFailureContext(ctx, err, elapsed) })
+	}
+}
+
+// callHook calls hook, recovering from any panic so that a faulty hook can't break the manager's callers
+func callHook(hook func()) {
+	defer func() {
+		_ = recover()
+	}()
+	hook()
+}
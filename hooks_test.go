@@ -0,0 +1,159 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestHooks tests that hooks are told about every credential created and verified
+func TestHooks(t *testing.T) {
+	var created, succeeded atomic.Int64
+	var failures []error
+	cm := NewCredentialManagerWithOptions([]byte("Hooks test secret"), nil, WithHooks(Hooks{
+		OnCreate: func(cred *AuthenticatedCredential) {
+			if cred.Mac == nil {
+				t.Error("OnCreate called before the credential was authenticated")
+			}
+			created.Add(1)
+		},
+		OnVerifySuccess: func(cred *AuthenticatedCredential, elapsed time.Duration) {
+			succeeded.Add(1)
+		},
+		OnVerifyFailure: func(reason error, elapsed time.Duration) {
+			failures = append(failures, reason)
+		},
+	}))
+
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.CreateMany(time.Now(), batchNodeIDs(3), pb.OperatorType_OT_SOLO); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.CreateBatch(time.Now(), batchNodeIDs(2), pb.OperatorType_OT_SOLO); err != nil {
+		t.Fatal(err)
+	}
+	// Failed creations aren't reported
+	if _, err := cm.Create(time.Now(), make([]byte, 3), pb.OperatorType_OT_SOLO); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if created.Load() != 6 {
+		t.Errorf("Expected 6 credentials created, got %d", created.Load())
+	}
+
+	if _, err := cm.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+	cred.Credential.Timestamp++
+	if _, err := cm.Verify(cred); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if succeeded.Load() != 1 || len(failures) != 1 || !errors.Is(failures[0], MismatchError) {
+		t.Errorf("Unexpected verifications: %d succeeded, failures %v", succeeded.Load(), failures)
+	}
+}
+
+// TestHooksPanic tests that panicking and missing hooks don't break the manager
+func TestHooksPanic(t *testing.T) {
+	cm := NewCredentialManagerWithOptions([]byte("Hooks test secret"), nil, WithHooks(Hooks{
+		OnCreate:        func(*AuthenticatedCredential) { panic("create") },
+		OnVerifySuccess: func(*AuthenticatedCredential, time.Duration) { panic("verify") },
+	}))
+
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+	// OnVerifyFailure is nil
+	cred.Credential.Timestamp++
+	if _, err := cm.Verify(cred); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+type hookCtxKey struct{}
+
+// TestHooksContext tests that the Context hooks are passed the context of the call they report
+func TestHooksContext(t *testing.T) {
+	var created, succeeded, failed []any
+	cm := NewCredentialManagerWithOptions([]byte("Hooks test secret"), nil, WithHooks(Hooks{
+		OnCreateContext: func(ctx context.Context, cred *AuthenticatedCredential) {
+			created = append(created, ctx.Value(hookCtxKey{}))
+		},
+		OnVerifySuccessContext: func(ctx context.Context, cred *AuthenticatedCredential, elapsed time.Duration) {
+			succeeded = append(succeeded, ctx.Value(hookCtxKey{}))
+		},
+		OnVerifyFailureContext: func(ctx context.Context, reason error, elapsed time.Duration) {
+			failed = append(failed, ctx.Value(hookCtxKey{}))
+		},
+	}))
+	ctx := context.WithValue(context.Background(), hookCtxKey{}, "request")
+
+	cred, err := cm.CreateContext(ctx, time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyContext(ctx, cred); err != nil {
+		t.Fatal(err)
+	}
+	cred.Credential.Timestamp++
+	if _, err := cm.VerifyContext(ctx, cred); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, err := cm.Verify(cred); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if fmt.Sprint(created, succeeded, failed) != "[request <nil>] [request] [request <nil>]" {
+		t.Errorf("Unexpected hook contexts: created %v, succeeded %v, failed %v", created, succeeded, failed)
+	}
+}
+
+func ExampleWithHooks() {
+	var (
+		issued     = expvar.NewInt("credentials_issued")
+		verified   = expvar.NewInt("credentials_verified")
+		mismatches = expvar.NewInt("credentials_mac_mismatches")
+		expired    = expvar.NewInt("credentials_expired")
+	)
+	cm := NewCredentialManagerWithOptions([]byte("Example secret"), nil, WithMaxAge(time.Hour), WithHooks(Hooks{
+		OnCreate: func(*AuthenticatedCredential) {
+			issued.Add(1)
+		},
+		OnVerifySuccess: func(*AuthenticatedCredential, time.Duration) {
+			verified.Add(1)
+		},
+		OnVerifyFailure: func(reason error, _ time.Duration) {
+			switch {
+			case errors.Is(reason, MismatchError):
+				mismatches.Add(1)
+			case errors.Is(reason, ErrExpired):
+				expired.Add(1)
+			}
+		},
+	}))
+
+	fresh, _ := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	stale, _ := cm.Create(time.Now().Add(-2*time.Hour), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	_, _ = cm.Verify(fresh)
+	_, _ = cm.Verify(stale)
+	stale.Credential.Timestamp++
+	_, _ = cm.Verify(stale)
+
+	fmt.Println(issued, verified, mismatches, expired)
+	// Output: 2 1 1 1
+}
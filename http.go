@@ -0,0 +1,110 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrMissingCredentials means a request carried no credential at all
+	ErrMissingCredentials = errors.New("request has no credentials")
+	// ErrMalformedCredential means a request carried a credential which couldn't be decoded
+	ErrMalformedCredential = errors.New("malformed credential")
+	// ErrMalformedBase64 means a username or password wasn't valid base64url
+	ErrMalformedBase64 = fmt.Errorf("%w: invalid base64url", ErrMalformedCredential)
+	// ErrMalformedProto means a password decoded to something other than a credential
+	ErrMalformedProto = fmt.Errorf("%w: invalid protobuf", ErrMalformedCredential)
+)
+
+// FromRequest decodes the credential from a request's basic auth header, or failing that,
+// from its query string as understood by FromURLValues.
+// Errors wrap ErrMissingCredentials or ErrMalformedCredential.
+func FromRequest(r *http.Request) (*AuthenticatedCredential, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		query := r.URL.Query()
+		if !query.Has(DefaultUsernameParam) && !query.Has(DefaultPasswordParam) {
+			return nil, ErrMissingCredentials
+		}
+		out, err := FromURLValues(query)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMalformedCredential, err)
+		}
+		return out, nil
+	}
+
+	out := new(AuthenticatedCredential)
+	if err := out.Base64URLDecode(username, password); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedCredential, err)
+	}
+	return out, nil
+}
+
+// VerifyFromBasicAuth decodes a credential from a basic auth username and password, and verifies it.
+// Empty usernames or passwords fail with ErrMissingCredentials, oversized ones with ErrUsernameTooLarge or
+// ErrPasswordTooLarge (see WithMaxCredentialSize), invalid base64url with ErrMalformedBase64, and passwords that
+// don't unmarshal with ErrMalformedProto.
+// See Base64URLDecode for the other checks on their contents. Credentials that fail Verify return its error.
+func (c *CredentialManager) VerifyFromBasicAuth(username, password string) (*AuthenticatedCredential, error) {
+	if username == "" || password == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	limit := c.passwordLimit()
+	nodeID, decoded, err := decodeUsernamePassword(username, password, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := decodeRaw(nodeID, decoded, limit)
+	if err != nil {
+		if !errors.Is(err, ErrMalformedCredential) {
+			err = fmt.Errorf("%w: %w", ErrMalformedCredential, err)
+		}
+		return nil, err
+	}
+	if _, err := c.Verify(out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthenticateRequest decodes the credential carried by r with FromRequest, and verifies it with VerifyClaims.
+// Credentials are then checked against policies, as with VerifyWithPolicy.
+// Use HTTPStatus to map its errors to a response.
+func (c *CredentialManager) AuthenticateRequest(r *http.Request, policies ...Policy) (Claims, error) {
+	authenticatedCredential, err := FromRequest(r)
+	if err != nil {
+		return Claims{}, err
+	}
+	claims, err := c.VerifyClaims(authenticatedCredential)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err := applyPolicies(authenticatedCredential, policies...); err != nil {
+		return Claims{}, err
+	}
+	return claims, nil
+}
+
+// HTTPStatus maps an error from AuthenticateRequest to a response status:
+// 400 for malformed credentials, 401 for missing or rejected credentials, 403 for credentials rejected by a policy,
+// and 500 for anything else.
+func HTTPStatus(err error) int {
+	var verificationErr *VerificationError
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, MemoryError):
+		return http.StatusInternalServerError
+	case errors.Is(err, ErrMalformedCredential):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrPolicyRejected):
+		return http.StatusForbidden
+	case errors.Is(err, ErrMissingCredentials), errors.As(err, &verificationErr):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,146 @@
+package credentials
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestAuthenticateRequest tests decoding and verifying credentials from requests, and the resulting statuses
+func TestAuthenticateRequest(t *testing.T) {
+	cm := NewCredentialManager([]byte("HTTP test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, err := cred.ToURLValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.Mac[0] ^= 1
+	forgedPassword, err := forged.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		request  func() *http.Request
+		sentinel error
+		status   int
+	}{
+		{"BasicAuth", func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.SetBasicAuth(cred.Base64URLEncodeUsername(), password)
+			return r
+		}, nil, http.StatusOK},
+		{"Query", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil)
+		}, nil, http.StatusOK},
+		{"Missing", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/", nil)
+		}, ErrMissingCredentials, http.StatusUnauthorized},
+		{"Malformed", func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.SetBasicAuth("not base64!", password)
+			return r
+		}, ErrMalformedCredential, http.StatusBadRequest},
+		{"MalformedQuery", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/?u=AAAA", nil)
+		}, ErrMalformedCredential, http.StatusBadRequest},
+		{"Forged", func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.SetBasicAuth(forged.Base64URLEncodeUsername(), forgedPassword)
+			return r
+		}, MismatchError, http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := cm.AuthenticateRequest(tc.request())
+			if tc.sentinel == nil && err != nil {
+				t.Fatal(err)
+			}
+			if tc.sentinel != nil && !errors.Is(err, tc.sentinel) {
+				t.Errorf("Expected %v, got %v", tc.sentinel, err)
+			}
+			if err == nil && claims.CredentialID != cred.ID() {
+				t.Errorf("Unexpected claims %+v", claims)
+			}
+			if status := HTTPStatus(err); status != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, status)
+			}
+		})
+	}
+
+	if status := HTTPStatus(MemoryError); status != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 for MemoryError, got %d", status)
+	}
+}
+
+// TestVerifyFromBasicAuth tests that each kind of bad credential fails with its own error
+func TestVerifyFromBasicAuth(t *testing.T) {
+	cm := NewCredentialManager([]byte("HTTP test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	username := cred.Base64URLEncodeUsername()
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verified, err := cm.VerifyFromBasicAuth(username, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.ID() != cred.ID() {
+		t.Error("Unexpected credential returned")
+	}
+
+	otherNode := make([]byte, 20)
+	otherNode[0] = 1
+	testCases := []struct {
+		name     string
+		username string
+		password string
+		sentinel error
+		status   int
+	}{
+		{"EmptyUsername", "", password, ErrMissingCredentials, http.StatusUnauthorized},
+		{"EmptyPassword", username, "", ErrMissingCredentials, http.StatusUnauthorized},
+		{"Base64Username", "not base64!", password, ErrMalformedBase64, http.StatusBadRequest},
+		{"Base64Password", username, "not base64!", ErrMalformedBase64, http.StatusBadRequest},
+		{"Proto", username, "__8", ErrMalformedProto, http.StatusBadRequest},
+		{"Mismatch", Encoder{}.EncodeUsername(&AuthenticatedCredential{Credential: &pb.Credential{NodeId: otherNode}}), password, MismatchError, http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := cm.VerifyFromBasicAuth(tc.username, tc.password)
+			if !errors.Is(err, tc.sentinel) {
+				t.Fatalf("Expected %v, got %v", tc.sentinel, err)
+			}
+			for _, other := range []error{ErrMissingCredentials, ErrMalformedBase64, ErrMalformedProto, MismatchError} {
+				if other != tc.sentinel && errors.Is(err, other) {
+					t.Errorf("Expected only %v, but error also matches %v", tc.sentinel, other)
+				}
+			}
+			if status := HTTPStatus(err); status != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// DefaultIssueMaxBodyBytes bounds the size of the requests NewIssueHandler reads, unless changed with WithIssueMaxBodyBytes.
+// Signed credential requests are a few hundred bytes.
+const DefaultIssueMaxBodyBytes = 4 << 10
+
+var (
+	ErrMethodNotAllowed     = errors.New("method not allowed")
+	ErrUnsupportedMediaType = errors.New("unsupported content type")
+	ErrRequestTooLarge      = errors.New("request body too large")
+)
+
+// IssueHandlerOption configures NewIssueHandler
+type IssueHandlerOption func(*issueHandler)
+
+// WithIssueMaxBodyBytes makes the handler reject request bodies larger than n bytes with 413
+func WithIssueMaxBodyBytes(n int64) IssueHandlerOption {
+	return func(h *issueHandler) {
+		h.maxBodyBytes = n
+	}
+}
+
+type issueHandler struct {
+	cm           *CredentialManager
+	maxBodyBytes int64
+}
+
+// IssueResponse is the body of the handler's successful responses
+type IssueResponse struct {
+	Credential *AuthenticatedCredential `json:"credential"`
+	// Username and Password are the credential encoded for basic auth
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ErrorResponse is the body of the handler's error responses. Code is the name of the error's Code, and Category
+// its ErrorCategory, so clients can branch on either without parsing Message.
+type ErrorResponse struct {
+	Error struct {
+		Code     string `json:"code"`
+		Category string `json:"category"`
+		Message  string `json:"message"`
+	} `json:"error"`
+}
+
+// NewIssueHandler returns a handler minting credentials with c for signed requests, for environments without gRPC.
+// It accepts POSTs of a CredentialRequest as application/json, and issues it with IssueFromRequest as of c's clock,
+// so c must be created with WithRequestVerifier. It responds with an IssueResponse, or an ErrorResponse with
+// a status of 400 for malformed requests, 401 for ones which aren't authentic or valid now, 403 for replayed ones,
+// 405, 413 and 415 for the wrong method, size or content type, 429 with Retry-After for nodes refused by the
+// manager's IssuanceLimiter, and 500 for failures which aren't the client's fault.
+func NewIssueHandler(c *CredentialManager, opts ...IssueHandlerOption) http.Handler {
+	h := &issueHandler{cm: c, maxBodyBytes: DefaultIssueMaxBodyBytes}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *issueHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%w: %s", ErrMethodNotAllowed, r.Method))
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("%w: expected application/json", ErrUnsupportedMediaType))
+		return
+	}
+
+	var req CredentialRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.maxBodyBytes))
+	err := dec.Decode(&req)
+	if err == nil {
+		// Like jsonConfig.unmarshal, reject anything after the request, including the stray '}' and ']' dec.More misses
+		if _, tokenErr := dec.Token(); !errors.Is(tokenErr, io.EOF) {
+			err = errors.New("trailing data after request")
+			if tokenErr != nil {
+				err = fmt.Errorf("%w: %w", err, tokenErr)
+			}
+		}
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("%w: more than %d bytes", ErrRequestTooLarge, tooLarge.Limit))
+			return
+		}
+		if !errors.Is(err, ErrInvalidRequest) {
+			err = fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cred, err := h.cm.IssueFromRequest(&req, h.cm.now())
+	if err != nil {
+		var limited *IssuanceRateLimitedError
+		if errors.As(err, &limited) {
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(limited.RetryAfter.Seconds())), 10))
+		}
+		writeError(w, issueStatus(err), err)
+		return
+	}
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// The response carries a secret, which mustn't be cached
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, &IssueResponse{Credential: cred, Username: cred.Base64URLEncodeUsername(), Password: password})
+}
+
+// issueStatus maps an error from IssueFromRequest to a response status by its Code
+func issueStatus(err error) int {
+	switch ErrorCode(err) {
+	case CodeMissing, CodeMalformed, CodeInvalidArgument:
+		return http.StatusBadRequest
+	case CodeMACMismatch, CodeExpired, CodeNotYetValid, CodeRevoked:
+		return http.StatusUnauthorized
+	case CodeReplayed, CodeRejected:
+		return http.StatusForbidden
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError writes an ErrorResponse for err. The messages of internal errors aren't the client's business,
+// and may describe the server's infrastructure, so they are replaced by the status text.
+func writeError(w http.ResponseWriter, status int, err error) {
+	var resp ErrorResponse
+	resp.Error.Code = ErrorCode(err).String()
+	resp.Error.Category = ErrorCategory(err)
+	resp.Error.Message = err.Error()
+	if status == http.StatusInternalServerError {
+		resp.Error.Message = http.StatusText(status)
+	}
+	writeJSON(w, status, &resp)
+}
+
+// writeJSON writes v as the JSON body of a response with status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
@@ -0,0 +1,188 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestIssueHandler tests issuing a credential from request JSON over HTTP, and verifying what comes back
+func TestIssueHandler(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := NewCredentialManagerWithOptions([]byte("Issue handler test secret"), nil, WithRequestVerifier(recoverRequestSigner, time.Minute))
+	srv := httptest.NewServer(NewIssueHandler(cm))
+	defer srv.Close()
+
+	body, err := json.Marshal(signedRequest(t, key, time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(srv.URL, "application/json; charset=utf-8", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("Expected the response not to be cached, got %q", cc)
+	}
+
+	var issued IssueResponse
+	if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(issued.Credential.Credential.NodeId, crypto.PubkeyToAddress(key.PublicKey).Bytes()) {
+		t.Errorf("Expected a credential for the signer, got %x", issued.Credential.Credential.NodeId)
+	}
+	if _, err := cm.Verify(issued.Credential); err != nil {
+		t.Errorf("Expected the JSON credential to verify, got %v", err)
+	}
+	fromBasicAuth, err := cm.VerifyFromBasicAuth(issued.Username, issued.Password)
+	if err != nil {
+		t.Fatalf("Expected the username and password to verify, got %v", err)
+	}
+	if !proto.Equal(fromBasicAuth.Pb(), issued.Credential.Pb()) {
+		t.Error("Expected the username and password to carry the JSON credential")
+	}
+}
+
+// TestIssueHandlerErrors tests the statuses and error bodies of rejected requests
+func TestIssueHandlerErrors(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayCache := NewMemoryReplayCache(time.Minute)
+	defer replayCache.Close()
+	cm := NewCredentialManagerWithOptions([]byte("Issue handler test secret"), nil,
+		WithRequestVerifier(recoverRequestSigner, time.Minute), WithReplayCache(replayCache, time.Hour))
+
+	marshal := func(req *CredentialRequest) string {
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+	replayed := marshal(signedRequest(t, key, time.Now()))
+	if _, err := cm.IssueFromRequest(signedRequestFromJSON(t, replayed), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	stolen := signedRequest(t, key, time.Now())
+	stolen.NodeID = crypto.PubkeyToAddress(other.PublicKey).Bytes()
+
+	testCases := []struct {
+		name        string
+		handler     http.Handler
+		method      string
+		contentType string
+		body        string
+		status      int
+		code        string
+		category    string
+	}{
+		{"Method", NewIssueHandler(cm), http.MethodGet, "application/json", "", http.StatusMethodNotAllowed, "invalid_argument", "method_not_allowed"},
+		{"ContentType", NewIssueHandler(cm), http.MethodPost, "text/plain", marshal(signedRequest(t, key, time.Now())), http.StatusUnsupportedMediaType, "invalid_argument", "unsupported_media_type"},
+		{"NoContentType", NewIssueHandler(cm), http.MethodPost, "", "{}", http.StatusUnsupportedMediaType, "invalid_argument", "unsupported_media_type"},
+		{"TooLarge", NewIssueHandler(cm, WithIssueMaxBodyBytes(64)), http.MethodPost, "application/json", marshal(signedRequest(t, key, time.Now())), http.StatusRequestEntityTooLarge, "invalid_argument", "request_too_large"},
+		{"Syntax", NewIssueHandler(cm), http.MethodPost, "application/json", "{", http.StatusBadRequest, "malformed", "invalid_request"},
+		{"Trailing", NewIssueHandler(cm), http.MethodPost, "application/json", marshal(signedRequest(t, key, time.Now())) + "{}", http.StatusBadRequest, "malformed", "invalid_request"},
+		{"TrailingBrace", NewIssueHandler(cm), http.MethodPost, "application/json", marshal(signedRequest(t, key, time.Now())) + "}", http.StatusBadRequest, "malformed", "invalid_request"},
+		{"TrailingBracket", NewIssueHandler(cm), http.MethodPost, "application/json", marshal(signedRequest(t, key, time.Now())) + "]", http.StatusBadRequest, "malformed", "invalid_request"},
+		{"Unsigned", NewIssueHandler(cm), http.MethodPost, "application/json", marshal(&CredentialRequest{NodeID: stolen.NodeID, Timestamp: 1, Nonce: []byte{1}}), http.StatusBadRequest, "malformed", "invalid_request"},
+		{"Expired", NewIssueHandler(cm), http.MethodPost, "application/json", marshal(signedRequest(t, key, time.Now().Add(-time.Hour))), http.StatusUnauthorized, "expired", "request_expired"},
+		{"OtherSigner", NewIssueHandler(cm), http.MethodPost, "application/json", marshal(stolen), http.StatusUnauthorized, "mac_mismatch", "request_signer_mismatch"},
+		{"Replayed", NewIssueHandler(cm), http.MethodPost, "application/json", replayed, http.StatusForbidden, "replayed", "replayed"},
+		{"NoVerifier", NewIssueHandler(NewCredentialManager([]byte("Issue handler test secret"))), http.MethodPost, "application/json", marshal(signedRequest(t, key, time.Now())), http.StatusInternalServerError, "internal", "no_request_verifier"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, "/issue", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			w := httptest.NewRecorder()
+			tc.handler.ServeHTTP(w, r)
+
+			if w.Code != tc.status {
+				t.Errorf("Expected %d, got %d: %s", tc.status, w.Code, w.Body)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Expected a JSON error, got %q", ct)
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Code != tc.code || resp.Error.Category != tc.category || resp.Error.Message == "" {
+				t.Errorf("Expected code %s and category %s, got %+v", tc.code, tc.category, resp.Error)
+			}
+		})
+	}
+}
+
+// signedRequestFromJSON decodes a request marshaled for a test
+func signedRequestFromJSON(t *testing.T, body string) *CredentialRequest {
+	t.Helper()
+	var req CredentialRequest
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatal(err)
+	}
+	return &req
+}
+
+// TestIssueHandlerRateLimited tests that nodes refused by the limiter are told when to retry
+func TestIssueHandlerRateLimited(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter := NewMemoryIssuanceLimiter(1, time.Minute)
+	defer limiter.Close()
+	cm := NewCredentialManagerWithOptions([]byte("Issue handler test secret"), nil,
+		WithRequestVerifier(recoverRequestSigner, time.Minute), WithIssuanceLimiter(limiter))
+	handler := NewIssueHandler(cm)
+
+	statuses := make([]int, 2)
+	var last *httptest.ResponseRecorder
+	for i := range statuses {
+		body, err := json.Marshal(signedRequest(t, key, time.Now()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/issue", bytes.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		last = httptest.NewRecorder()
+		handler.ServeHTTP(last, r)
+		statuses[i] = last.Code
+	}
+	if statuses[0] != http.StatusOK || statuses[1] != http.StatusTooManyRequests {
+		t.Fatalf("Expected 200 then 429, got %v", statuses)
+	}
+	if retryAfter := last.Header().Get("Retry-After"); retryAfter != "60" && retryAfter != "59" {
+		t.Errorf("Expected to retry after about a minute, got %q", retryAfter)
+	}
+	var resp ErrorResponse
+	if err := json.Unmarshal(last.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Code != "rate_limited" || resp.Error.Category != "rate_limited" {
+		t.Errorf("Unexpected error %+v", resp.Error)
+	}
+}
@@ -0,0 +1,67 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrIssuerNotAllowed = errors.New("credential issuer not allowed")
+	ErrIssuerMismatch   = errors.New("credential issuer mismatch")
+)
+
+// IssuerNotAllowedError is returned by Verify for credentials minted by an issuer outside the allow-list.
+// It matches ErrIssuerNotAllowed.
+type IssuerNotAllowedError struct {
+	Issuer string
+}
+
+func (e *IssuerNotAllowedError) Error() string {
+	return fmt.Sprintf("%v: %q", ErrIssuerNotAllowed, e.Issuer)
+}
+
+func (e *IssuerNotAllowedError) Is(target error) bool {
+	return target == ErrIssuerNotAllowed
+}
+
+// WithIssuer makes Create record issuer, the name of the system minting the credential, in every credential,
+// and Verify reject credentials from any other issuer with ErrIssuerMismatch. The issuer is covered by the MAC,
+// so managers of different tenants sharing a transport don't accept each other's credentials, even under a shared key.
+// WithAllowedIssuers replaces the check, for managers which accept credentials from issuers besides themselves.
+func WithIssuer(issuer string) Option {
+	return func(c *CredentialManager) {
+		c.issuer = issuer
+	}
+}
+
+// WithAllowedIssuers makes Verify reject credentials from any other issuer with an *IssuerNotAllowedError.
+// Include "" to accept credentials which don't record an issuer.
+// Without an allow-list, credentials from any issuer are accepted.
+func WithAllowedIssuers(issuers ...string) Option {
+	return func(c *CredentialManager) {
+		c.allowedIssuers = make(map[string]struct{}, len(issuers))
+		for _, issuer := range issuers {
+			c.allowedIssuers[issuer] = struct{}{}
+		}
+	}
+}
+
+// Issuer returns the name of the system that minted the credential, or "" if it wasn't recorded
+func (ac *AuthenticatedCredential) Issuer() string {
+	return ac.Credential.GetIssuer()
+}
+
+// checkIssuer enforces the allow-list configured with WithAllowedIssuers, or failing that the manager's own issuer
+func (c *CredentialManager) checkIssuer(authenticatedCredential *AuthenticatedCredential) error {
+	issuer := authenticatedCredential.Issuer()
+	if c.allowedIssuers != nil {
+		if _, ok := c.allowedIssuers[issuer]; !ok {
+			return &IssuerNotAllowedError{Issuer: issuer}
+		}
+		return nil
+	}
+	if c.issuer != "" && issuer != c.issuer {
+		return fmt.Errorf("%w: credential is from %q, verifier expects %q", ErrIssuerMismatch, issuer, c.issuer)
+	}
+	return nil
+}
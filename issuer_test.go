@@ -0,0 +1,109 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestIssuer tests that the issuer is recorded, round-trips through JSON, and is checked against the allow-list
+func TestIssuer(t *testing.T) {
+	key := []byte("Issuer test secret")
+	bot := NewCredentialManagerWithOptions(key, nil, WithIssuer("bot-v2"))
+	cred, err := bot.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Issuer() != "bot-v2" {
+		t.Fatalf("Expected issuer bot-v2, got %q", cred.Issuer())
+	}
+	anonymous, err := NewCredentialManager(key).Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["issuer"] != "bot-v2" {
+		t.Errorf("Expected issuer in JSON, got %v", fields["issuer"])
+	}
+	var decoded AuthenticatedCredential
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		verifier *CredentialManager
+		cred     *AuthenticatedCredential
+		ok       bool
+	}{
+		{"NoAllowList", NewCredentialManager(key), &decoded, true},
+		{"NoAllowListNoIssuer", NewCredentialManager(key), anonymous, true},
+		{"Allowed", NewCredentialManagerWithOptions(key, nil, WithAllowedIssuers("portal", "bot-v2")), &decoded, true},
+		{"NotAllowed", NewCredentialManagerWithOptions(key, nil, WithAllowedIssuers("portal")), &decoded, false},
+		{"NoIssuerNotAllowed", NewCredentialManagerWithOptions(key, nil, WithAllowedIssuers("portal")), anonymous, false},
+		{"NoIssuerAllowed", NewCredentialManagerWithOptions(key, nil, WithAllowedIssuers("portal", "")), anonymous, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.verifier.Verify(tc.cred)
+			if tc.ok && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+			var notAllowed *IssuerNotAllowedError
+			if !tc.ok && (!errors.Is(err, ErrIssuerNotAllowed) || !errors.As(err, &notAllowed) || notAllowed.Issuer != tc.cred.Issuer()) {
+				t.Errorf("Expected an IssuerNotAllowedError, got %v", err)
+			}
+		})
+	}
+}
+
+// TestIssuerMismatch tests that managers with an issuer only accept their own credentials, unless given an allow-list
+func TestIssuerMismatch(t *testing.T) {
+	key := []byte("Shared tenant secret")
+	tenantA := NewCredentialManagerWithOptions(key, nil, WithIssuer("tenant-a"))
+	tenantB := NewCredentialManagerWithOptions(key, nil, WithIssuer("tenant-b"))
+	cred, err := tenantA.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anonymous, err := NewCredentialManager(key).Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tenantA.Verify(cred); err != nil {
+		t.Errorf("Expected the issuing tenant to accept its credential, got %v", err)
+	}
+	if _, err := tenantB.Verify(cred); !errors.Is(err, ErrIssuerMismatch) {
+		t.Errorf("Expected ErrIssuerMismatch from another tenant, got %v", err)
+	}
+	if _, err := tenantB.Verify(anonymous); !errors.Is(err, ErrIssuerMismatch) {
+		t.Errorf("Expected ErrIssuerMismatch for a credential without an issuer, got %v", err)
+	}
+
+	// The issuer is covered by the MAC, so it can't be rewritten to pass
+	forged := proto.Clone(cred.Credential).(*pb.Credential)
+	forged.Issuer = "tenant-b"
+	if _, err := tenantB.Verify(&AuthenticatedCredential{Credential: forged, Mac: cred.Mac}); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError for a rewritten issuer, got %v", err)
+	}
+
+	// An allow-list replaces the manager's own issuer
+	portal := NewCredentialManagerWithOptions(key, nil, WithIssuer("tenant-b"), WithAllowedIssuers("tenant-a", "tenant-b"))
+	if _, err := portal.Verify(cred); err != nil {
+		t.Errorf("Expected the allow-list to accept tenant-a, got %v", err)
+	}
+}
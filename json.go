@@ -0,0 +1,85 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+type jsonConfig struct {
+	strict          bool
+	timestampFormat JSONTimestampFormat
+}
+
+// JSONOption configures DecodeJSON and EncodeJSON. Each ignores the options meant for the other.
+type JSONOption func(*jsonConfig)
+
+// JSONTimestampFormat is how EncodeJSON represents a credential's timestamp
+type JSONTimestampFormat int
+
+const (
+	// JSONTimestampUnix is the numeric timestamp field of Unix seconds alone, as MarshalJSON produces
+	JSONTimestampUnix JSONTimestampFormat = iota
+	// JSONTimestampBoth adds an RFC3339 issued_at field in UTC, for people reading the JSON, to the numeric timestamp
+	JSONTimestampBoth
+	// JSONTimestampRFC3339 is the issued_at field alone, which versions of this package before it can't decode
+	JSONTimestampRFC3339
+)
+
+// WithJSONTimestampFormat makes EncodeJSON represent timestamps in format. Every format keeps the exact second.
+func WithJSONTimestampFormat(format JSONTimestampFormat) JSONOption {
+	return func(c *jsonConfig) {
+		c.timestampFormat = format
+	}
+}
+
+// DecodeJSONStrict makes DecodeJSON reject objects with fields MarshalJSON never produces, e.g. misspelt ones,
+// with an error naming the field, rather than ignoring them as UnmarshalJSON does
+func DecodeJSONStrict() JSONOption {
+	return func(c *jsonConfig) {
+		c.strict = true
+	}
+}
+
+// DecodeJSON decodes a credential marshaled by MarshalJSON, as UnmarshalJSON does unless configured otherwise.
+// Its errors match ErrMalformedCredential.
+func DecodeJSON(data []byte, opts ...JSONOption) (*AuthenticatedCredential, error) {
+	out := new(AuthenticatedCredential)
+	if err := out.unmarshalJSON(data, newJSONConfig(opts)); err != nil {
+		return nil, markMalformed(err)
+	}
+	return out, nil
+}
+
+// EncodeJSON encodes the credential as MarshalJSON does unless configured otherwise, e.g. with
+// WithJSONTimestampFormat. UnmarshalJSON and DecodeJSON decode every format it produces.
+func EncodeJSON(ac *AuthenticatedCredential, opts ...JSONOption) ([]byte, error) {
+	return ac.marshalJSON(newJSONConfig(opts))
+}
+
+func newJSONConfig(opts []JSONOption) jsonConfig {
+	var cfg jsonConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// unmarshal decodes data into v, as json.Unmarshal does, but rejecting unknown fields if the config is strict
+func (c jsonConfig) unmarshal(data []byte, v any) error {
+	if !c.strict {
+		return json.Unmarshal(data, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	// Like json.Unmarshal, reject anything after the object
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestDecodeJSONStrict tests that strict decoding accepts everything MarshalJSON produces, and nothing else
+func TestDecodeJSONStrict(t *testing.T) {
+	cm := NewDualSignManager([]byte("JSON test secret"), []byte("Old JSON test secret"), WithAudience("relay"),
+		WithDefaultValidity(time.Hour))
+	cred, err := cm.CreateWithMetadata(time.Now(), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO, map[string]string{"ticket": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := cm.CreateBundle(time.Now(), batchNodeIDs(3)[1:], pb.OperatorType_OT_ROCKETPOOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ac := range []*AuthenticatedCredential{cred, bundle} {
+		data, err := json.Marshal(ac)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeJSON(data, DecodeJSONStrict())
+		if err != nil {
+			t.Fatalf("Expected %s to decode strictly, got %v", data, err)
+		}
+		if !proto.Equal(decoded.Pb(), ac.Pb()) {
+			t.Errorf("Expected %v, got %v", ac.Pb(), decoded.Pb())
+		}
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := string(data)
+	if !strings.Contains(valid, `"additional_macs":[{`) {
+		t.Fatalf("Expected additional MACs in %s", valid)
+	}
+	testCases := []struct {
+		name  string
+		json  string
+		field string
+	}{
+		{"Typo", strings.Replace(valid, `{`, `{"operator_typ":2,`, 1), "operator_typ"},
+		{"Nested", strings.Replace(valid, `"additional_macs":[{`, `"additional_macs":[{"kid":"a",`, 1), "kid"},
+		{"TrailingData", valid + ` {}`, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if lenient, err := DecodeJSON([]byte(tc.json)); tc.field != "" && (err != nil || !proto.Equal(lenient.Pb(), cred.Pb())) {
+				t.Errorf("Expected lenient decoding to ignore the field, got %v", err)
+			}
+			_, err := DecodeJSON([]byte(tc.json), DecodeJSONStrict())
+			if !errors.Is(err, ErrMalformedCredential) {
+				t.Fatalf("Expected ErrMalformedCredential, got %v", err)
+			}
+			if !strings.Contains(err.Error(), `"`+tc.field+`"`) && tc.field != "" {
+				t.Errorf("Expected the error to name %q, got %v", tc.field, err)
+			}
+		})
+	}
+}
+
+// TestEncodeJSONTimestampFormat tests that every timestamp format round trips to the exact second
+func TestEncodeJSONTimestampFormat(t *testing.T) {
+	cm := NewCredentialManager([]byte("JSON test secret"))
+	cred, err := cm.Create(time.Date(2024, 5, 1, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		format   JSONTimestampFormat
+		contains []string
+		omits    []string
+	}{
+		{JSONTimestampUnix, []string{`"timestamp":1714564800,`}, []string{`issued_at`}},
+		{JSONTimestampBoth, []string{`"timestamp":1714564800,"issued_at":"2024-05-01T12:00:00Z",`}, nil},
+		{JSONTimestampRFC3339, []string{`"issued_at":"2024-05-01T12:00:00Z",`}, []string{`"timestamp"`}},
+	}
+
+	for _, tc := range testCases {
+		data, err := EncodeJSON(cred, WithJSONTimestampFormat(tc.format))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range tc.contains {
+			if !strings.Contains(string(data), s) {
+				t.Errorf("Expected %s in %s", s, data)
+			}
+		}
+		for _, s := range tc.omits {
+			if strings.Contains(string(data), s) {
+				t.Errorf("Expected no %s in %s", s, data)
+			}
+		}
+		decoded, err := DecodeJSON(data, DecodeJSONStrict())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(decoded.Pb(), cred.Pb()) {
+			t.Errorf("Expected %v, got %v", cred.Pb(), decoded.Pb())
+		}
+		if _, err := cm.Verify(decoded); err != nil {
+			t.Error(err)
+		}
+	}
+
+	marshaled, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := EncodeJSON(cred); err != nil || string(data) != string(marshaled) {
+		t.Errorf("Expected EncodeJSON to default to MarshalJSON's output, got %s, %v", data, err)
+	}
+	if _, err := EncodeJSON(&AuthenticatedCredential{Credential: &pb.Credential{Timestamp: math.MaxInt64}}, WithJSONTimestampFormat(JSONTimestampBoth)); err == nil {
+		t.Error("Expected timestamps past RFC3339's years to fail")
+	}
+}
+
+// TestJSONIssuedAt tests decoding issued_at alone, and alongside a timestamp it must agree with
+func TestJSONIssuedAt(t *testing.T) {
+	nodeID := "0x0000000000000000000000000000000000000000"
+	testCases := []struct {
+		name     string
+		fields   string
+		expected int64
+		wantErr  bool
+	}{
+		{"IssuedAtOnly", `"issued_at":"2024-05-01T12:00:00Z"`, 1714564800, false},
+		{"Agree", `"timestamp":1714564800,"issued_at":"2024-05-01T14:00:00+02:00"`, 1714564800, false},
+		{"AgreeStrings", `"timestamp":"2024-05-01T12:00:00Z","issued_at":"2024-05-01T12:00:00Z"`, 1714564800, false},
+		{"Disagree", `"timestamp":1714564801,"issued_at":"2024-05-01T12:00:00Z"`, 0, true},
+		{"DisagreeWithZero", `"timestamp":0,"issued_at":"2024-05-01T12:00:00Z"`, 0, true},
+		{"Numeric", `"issued_at":1714564800`, 0, true},
+		{"Fraction", `"issued_at":"2024-05-01T12:00:00.5Z"`, 0, true},
+		{"Neither", `"operator_type":1`, 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ac, err := DecodeJSON([]byte(`{"node_id":"` + nodeID + `",` + tc.fields + `,"mac":""}`))
+			if tc.wantErr {
+				if !errors.Is(err, ErrMalformedCredential) {
+					t.Fatalf("Expected ErrMalformedCredential, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ac.Credential.Timestamp != tc.expected {
+				t.Errorf("Expected %d, got %d", tc.expected, ac.Credential.Timestamp)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoActiveKey    = errors.New("no active signing key in key ring")
+	ErrDuplicateKeyID = errors.New("duplicate key ID in key ring")
+	ErrInvalidKey     = errors.New("invalid key ring entry")
+)
+
+// KeyEntry is a single secret in a KeyRing, valid for signing and verification between NotBefore and NotAfter
+type KeyEntry struct {
+	// ID is an operator-assigned name for the key, unique within its ring
+	ID  string
+	Key []byte
+	// NotBefore is the first instant the key is valid
+	NotBefore time.Time
+	// NotAfter is the instant the key stops being valid. The zero value means the key never expires.
+	NotAfter time.Time
+}
+
+// ActiveAt reports whether the entry is valid at instant t
+func (e *KeyEntry) ActiveAt(t time.Time) bool {
+	if t.Before(e.NotBefore) {
+		return false
+	}
+	return e.NotAfter.IsZero() || t.Before(e.NotAfter)
+}
+
+type ringKey struct {
+	*macKey
+	entry KeyEntry
+}
+
+// copyEntry returns the key's entry with a copy of its key, so callers can't modify the ring's
+func (k *ringKey) copyEntry() KeyEntry {
+	out := k.entry
+	out.Key = slices.Clone(k.entry.Key)
+	return out
+}
+
+// KeyRing holds a schedule of secrets and resolves which ones are valid at a given instant.
+// When several keys are active, the one with the latest NotBefore signs new credentials, and all of them verify.
+// A KeyRing is safe for concurrent use, so keys can be added and removed while it is in use.
+type KeyRing struct {
+	mu   sync.RWMutex
+	keys []*ringKey
+}
+
+// NewKeyRing creates a KeyRing with the provided entries
+func NewKeyRing(entries ...KeyEntry) (*KeyRing, error) {
+	out := new(KeyRing)
+	for _, e := range entries {
+		if err := out.Add(e); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Add schedules a new key. The entry's key is copied, so the caller may reuse it.
+func (r *KeyRing) Add(e KeyEntry) error {
+	if e.ID == "" || len(e.Key) == 0 {
+		return fmt.Errorf("%w: ID and Key are required", ErrInvalidKey)
+	}
+	if !e.NotAfter.IsZero() && !e.NotAfter.After(e.NotBefore) {
+		return fmt.Errorf("%w: key %q expires before it becomes valid", ErrInvalidKey, e.ID)
+	}
+
+	e.Key = append([]byte(nil), e.Key...)
+	k := &ringKey{
+		macKey: newMACKey(e.Key),
+		entry:  e,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.keys {
+		if existing.entry.ID == e.ID {
+			return fmt.Errorf("%w: %q", ErrDuplicateKeyID, e.ID)
+		}
+	}
+	r.keys = append(r.keys, k)
+	return nil
+}
+
+// Remove retires the key with the given ID immediately, returning false if there was no such key
+func (r *KeyRing) Remove(keyID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, k := range r.keys {
+		if k.entry.ID == keyID {
+			r.keys = append(r.keys[:i:i], r.keys[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (r *KeyRing) signingKey(at time.Time) *ringKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out *ringKey
+	for _, k := range r.keys {
+		if !k.entry.ActiveAt(at) {
+			continue
+		}
+		if out == nil || !k.entry.NotBefore.Before(out.entry.NotBefore) {
+			out = k
+		}
+	}
+	return out
+}
+
+func (r *KeyRing) verificationKeys(at time.Time) []*ringKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]*ringKey, 0, len(r.keys))
+	for _, k := range r.keys {
+		if k.entry.ActiveAt(at) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// SigningKey returns the entry used to sign credentials at instant at. Its key is a copy, so the caller may modify it.
+func (r *KeyRing) SigningKey(at time.Time) (KeyEntry, error) {
+	k := r.signingKey(at)
+	if k == nil {
+		return KeyEntry{}, ErrNoActiveKey
+	}
+	return k.copyEntry(), nil
+}
+
+// VerificationKeys returns the entries that are accepted for verification at instant at, with copies of their keys
+func (r *KeyRing) VerificationKeys(at time.Time) []KeyEntry {
+	keys := r.verificationKeys(at)
+	out := make([]KeyEntry, len(keys))
+	for i, k := range keys {
+		out[i] = k.copyEntry()
+	}
+	return out
+}
+
+// sign computes the MAC of data under the signing key at instant at
+func (r *KeyRing) sign(at time.Time, data []byte) ([]byte, error) {
+	k := r.signingKey(at)
+	if k == nil {
+		return nil, ErrNoActiveKey
+	}
+	mac := k.mac(data)
+	if mac == nil {
+		return nil, MemoryError
+	}
+	return mac, nil
+}
+
+// verify returns the ID of the key that authenticates data with any of the credential's MACs at instant at,
+// or nil and the fingerprints of the keys that were tried
+func (r *KeyRing) verify(at time.Time, data []byte, credential *AuthenticatedCredential) (*ID, string) {
+	keys := r.verificationKeys(at)
+	fps := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k.verify(data, credential) {
+			return k.id, ""
+		}
+		fps = append(fps, k.fingerprint)
+	}
+	return nil, strings.Join(fps, ",")
+}
@@ -0,0 +1,213 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestKeyRingSchedule tests signing key selection and verification key sets over a rotation
+func TestKeyRingSchedule(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	ring, err := NewKeyRing(
+		KeyEntry{ID: "old", Key: []byte("old key"), NotBefore: t0, NotAfter: t0.Add(2 * time.Hour)},
+		KeyEntry{ID: "new", Key: []byte("new key"), NotBefore: t0.Add(time.Hour)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		at       time.Time
+		signing  string
+		verifies []string
+	}{
+		{"BeforeAll", t0.Add(-time.Second), "", nil},
+		{"OldOnly", t0, "old", []string{"old"}},
+		{"Overlap", t0.Add(90 * time.Minute), "new", []string{"old", "new"}},
+		{"OldRetired", t0.Add(2 * time.Hour), "new", []string{"new"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, err := ring.SigningKey(tc.at)
+			if tc.signing == "" {
+				if !errors.Is(err, ErrNoActiveKey) {
+					t.Fatalf("Expected ErrNoActiveKey, got %v", err)
+				}
+			} else if err != nil || entry.ID != tc.signing {
+				t.Fatalf("Expected signing key %s, got %s (%v)", tc.signing, entry.ID, err)
+			}
+
+			keys := ring.VerificationKeys(tc.at)
+			if len(keys) != len(tc.verifies) {
+				t.Fatalf("Expected %d verification keys, got %d", len(tc.verifies), len(keys))
+			}
+			for i, k := range keys {
+				if k.ID != tc.verifies[i] {
+					t.Errorf("Expected verification key %s, got %s", tc.verifies[i], k.ID)
+				}
+			}
+		})
+	}
+}
+
+// TestKeyRingInvalidEntries tests that malformed or duplicate entries are rejected
+func TestKeyRingInvalidEntries(t *testing.T) {
+	now := time.Now()
+	if _, err := NewKeyRing(KeyEntry{ID: "", Key: []byte("k")}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for missing ID, got %v", err)
+	}
+	if _, err := NewKeyRing(KeyEntry{ID: "a"}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for missing key, got %v", err)
+	}
+	if _, err := NewKeyRing(KeyEntry{ID: "a", Key: []byte("k"), NotBefore: now, NotAfter: now}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected ErrInvalidKey for empty validity window, got %v", err)
+	}
+	if _, err := NewKeyRing(KeyEntry{ID: "a", Key: []byte("k")}, KeyEntry{ID: "a", Key: []byte("j")}); !errors.Is(err, ErrDuplicateKeyID) {
+		t.Errorf("Expected ErrDuplicateKeyID, got %v", err)
+	}
+}
+
+// TestKeyRingEntriesAreCopies tests that modifying the keys of returned entries doesn't affect the ring
+func TestKeyRingEntriesAreCopies(t *testing.T) {
+	now := time.Now()
+	ring, err := NewKeyRing(KeyEntry{ID: "a", Key: []byte("ring key")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := NewCredentialManagerFromKeyRing(ring)
+	cred, err := cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signing, err := ring.SigningKey(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero(signing.Key)
+	for _, e := range ring.VerificationKeys(now) {
+		zero(e.Key)
+	}
+
+	if signing, err := ring.SigningKey(now); err != nil || string(signing.Key) != "ring key" {
+		t.Errorf("Expected the ring's key to be unchanged, got %q (%v)", signing.Key, err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestKeyRingCredentialManager tests creating and verifying credentials through a rotation
+func TestKeyRingCredentialManager(t *testing.T) {
+	now := time.Now()
+	ring, err := NewKeyRing(KeyEntry{ID: "old", Key: []byte("old key"), NotBefore: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := NewCredentialManagerFromKeyRing(ring)
+
+	oldCred, err := cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cm.ID().Equals(idFromKey([]byte("old key"))) {
+		t.Error("Expected the manager ID to be the old key's ID")
+	}
+
+	// Introduce a new key; it takes over signing but the old one still verifies
+	if err := ring.Add(KeyEntry{ID: "new", Key: []byte("new key"), NotBefore: now.Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	newCred, err := cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := cm.Verify(oldCred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id.Equals(idFromKey([]byte("old key"))) {
+		t.Error("Expected old credential to verify under the old key")
+	}
+	id, err = cm.Verify(newCred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !id.Equals(idFromKey([]byte("new key"))) {
+		t.Error("Expected new credential to verify under the new key")
+	}
+
+	// Retire the old key
+	if !ring.Remove("old") {
+		t.Fatal("Expected the old key to be removed")
+	}
+	if _, err := cm.Verify(oldCred); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError after retirement, got %v", err)
+	}
+	if _, err := cm.Verify(newCred); err != nil {
+		t.Error(err)
+	}
+
+	// With no active keys left, Create must fail
+	ring.Remove("new")
+	if _, err := cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO); !errors.Is(err, ErrNoActiveKey) {
+		t.Errorf("Expected ErrNoActiveKey, got %v", err)
+	}
+	if cm.ID() != nil || cm.Fingerprint() != "" {
+		t.Error("Expected no ID or fingerprint without a signing key")
+	}
+}
+
+// TestKeyRingConcurrentRotation rotates keys while other goroutines create and verify credentials
+func TestKeyRingConcurrentRotation(t *testing.T) {
+	ring, err := NewKeyRing(KeyEntry{ID: "base", Key: []byte("base key"), NotBefore: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := NewCredentialManagerFromKeyRing(ring)
+	base, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				// The credential may have been signed by a key that was just retired
+				if _, err := cm.Verify(cred); err != nil && !errors.Is(err, MismatchError) {
+					t.Error(err)
+				}
+				if _, err := cm.Verify(base); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("rotating-%d", i)
+		if err := ring.Add(KeyEntry{ID: id, Key: []byte(id), NotBefore: time.Now().Add(-time.Minute)}); err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 {
+			ring.Remove(fmt.Sprintf("rotating-%d", i-1))
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,104 @@
+package credentials
+
+import (
+	"encoding"
+	"hash"
+)
+
+// HMAC pads
+const (
+	hmacInnerPad = 0x36
+	hmacOuterPad = 0x5c
+)
+
+// keySchedule holds the hash states of an HMAC key after absorbing its padded key blocks,
+// so new HMAC states can be made by copying them, instead of rekeying as hmac.New does
+type keySchedule struct {
+	inner []byte
+	outer []byte
+}
+
+func newKeySchedule(key []byte) *keySchedule {
+	h := hashAlgo()
+	blockSize := h.BlockSize()
+	// The hash of an oversized key, and the padded key, are as secret as the key itself
+	if len(key) > blockSize {
+		h.Write(key)
+		key = h.Sum(nil)
+		defer zero(key)
+		h.Reset()
+	}
+
+	pad := make([]byte, blockSize)
+	defer zero(pad)
+	copy(pad, key)
+	for i := range pad {
+		pad[i] ^= hmacInnerPad
+	}
+	h.Write(pad)
+	inner, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		// hashAlgo's states always marshal
+		panic(err)
+	}
+
+	h.Reset()
+	for i := range pad {
+		pad[i] ^= hmacInnerPad ^ hmacOuterPad
+	}
+	h.Write(pad)
+	outer, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	return &keySchedule{inner: inner, outer: outer}
+}
+
+// newHMAC returns an HMAC hash equivalent to hmac.New(hashAlgo, key)
+func (ks *keySchedule) newHMAC() hash.Hash {
+	h := &scheduledHMAC{
+		ks:    ks,
+		inner: hashAlgo(),
+		outer: hashAlgo(),
+	}
+	h.Reset()
+	return h
+}
+
+// scheduledHMAC computes HMAC from the states precomputed in a keySchedule
+type scheduledHMAC struct {
+	ks    *keySchedule
+	inner hash.Hash
+	outer hash.Hash
+}
+
+func (h *scheduledHMAC) Write(p []byte) (int, error) {
+	return h.inner.Write(p)
+}
+
+func (h *scheduledHMAC) Sum(in []byte) []byte {
+	n := len(in)
+	in = h.inner.Sum(in)
+	restore(h.outer, h.ks.outer)
+	h.outer.Write(in[n:])
+	return h.outer.Sum(in[:n])
+}
+
+func (h *scheduledHMAC) Reset() {
+	restore(h.inner, h.ks.inner)
+}
+
+func (h *scheduledHMAC) Size() int {
+	return h.outer.Size()
+}
+
+func (h *scheduledHMAC) BlockSize() int {
+	return h.inner.BlockSize()
+}
+
+// restore sets h to a state marshaled from a hash of the same type
+func restore(h hash.Hash, state []byte) {
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,74 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestKeyScheduleMatchesHMAC tests that HMACs from a key schedule match crypto/hmac for keys around the block size
+func TestKeyScheduleMatchesHMAC(t *testing.T) {
+	for _, keyLen := range []int{0, 1, 32, 64, 65, 200} {
+		key := bytes.Repeat([]byte{0xa5}, keyLen)
+		scheduled := newKeySchedule(key).newHMAC()
+		std := hmac.New(sha256.New, key)
+
+		for _, dataLen := range []int{0, 1, 55, 64, 1000} {
+			data := bytes.Repeat([]byte{byte(dataLen)}, dataLen)
+			// Reuse both hashes across data sizes, as the pool does
+			scheduled.Reset()
+			std.Reset()
+			scheduled.Write(data)
+			std.Write(data)
+
+			prefix := []byte("prefix")
+			got := scheduled.Sum(append([]byte(nil), prefix...))
+			expected := std.Sum(append([]byte(nil), prefix...))
+			if !bytes.Equal(got, expected) {
+				t.Errorf("key length %d, data length %d: expected %x, got %x", keyLen, dataLen, expected, got)
+			}
+			// Sum must not disturb the running state
+			if !bytes.Equal(scheduled.Sum(nil), std.Sum(nil)) {
+				t.Errorf("key length %d, data length %d: repeated Sum differs", keyLen, dataLen)
+			}
+		}
+
+		if scheduled.Size() != std.Size() || scheduled.BlockSize() != std.BlockSize() {
+			t.Errorf("key length %d: unexpected sizes", keyLen)
+		}
+	}
+}
+
+// TestKeyScheduleConcurrent tests that managers sharing a key schedule stay correct under concurrent use
+func TestKeyScheduleConcurrent(t *testing.T) {
+	key := []byte("Curiouser and curiouser")
+	cm := NewCredentialManagerWithOptions(key, nil)
+	reference := NewCredentialManager(key)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			nodeID := make([]byte, 20)
+			nodeID[0] = byte(g)
+			for i := 0; i < 100; i++ {
+				cred, err := cm.Create(time.Unix(int64(1700000000+i), 0), nodeID, pb.OperatorType_OT_SOLO)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := reference.Verify(cred); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,215 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrIssuanceRateLimited = errors.New("credential issuance rate limited")
+
+// IssuanceRateLimitedError is returned by Create and its variants, and by IssueFromRequest, for nodes the manager's
+// IssuanceLimiter refuses more credentials. It matches ErrIssuanceRateLimited.
+type IssuanceRateLimitedError struct {
+	NodeID []byte
+	// RetryAfter is how long until the node may be issued another credential
+	RetryAfter time.Duration
+}
+
+func (e *IssuanceRateLimitedError) Error() string {
+	return fmt.Sprintf("%v: node 0x%x may retry after %s", ErrIssuanceRateLimited, e.NodeID, e.RetryAfter)
+}
+
+func (e *IssuanceRateLimitedError) Is(target error) bool {
+	return target == ErrIssuanceRateLimited
+}
+
+// IssuanceLimiter bounds how many credentials each node is issued. It is called concurrently.
+type IssuanceLimiter interface {
+	// Allow must atomically record an issuance to every node in nodeIDs at now, or fail without recording any,
+	// preferably with an *IssuanceRateLimitedError, if one has been issued too many. Nodes appearing more than once
+	// are counted once per appearance.
+	Allow(nodeIDs [][]byte, now time.Time) error
+	// Release forgets issuances recorded by a successful Allow of nodeIDs at now, for credentials which couldn't be
+	// issued after all
+	Release(nodeIDs [][]byte, now time.Time)
+}
+
+// WithIssuanceLimiter makes every Create method, and so IssueFromRequest and Reissue, consult l before issuing a
+// credential, as of the manager's clock, and fail with l's error if it refuses. Bundles are counted against every
+// node they cover. CreateMany consults it once for all its nodes, and fails as a whole if any is refused, without
+// counting the others. CreateBatch fails just the refused nodes' credentials. Issuances which fail after l allowed
+// them are released again. ResignFrom doesn't issue new credentials, so it is not limited.
+func WithIssuanceLimiter(l IssuanceLimiter) Option {
+	return func(c *CredentialManager) {
+		c.issuanceLimiter = l
+	}
+}
+
+// allowIssuance consults the manager's IssuanceLimiter, if it has one. release undoes the issuance if its
+// credentials can't be created after all.
+func (c *CredentialManager) allowIssuance(nodeIDs ...[]byte) (release func(), err error) {
+	if c.issuanceLimiter == nil {
+		return func() {}, nil
+	}
+	now := c.now()
+	if err := c.issuanceLimiter.Allow(nodeIDs, now); err != nil {
+		return nil, err
+	}
+	return func() { c.issuanceLimiter.Release(nodeIDs, now) }, nil
+}
+
+// MemoryIssuanceLimiter is an in-memory IssuanceLimiter allowing each node n credentials in any window long period,
+// which periodically forgets nodes with no issuances in the last window. It is safe for concurrent use.
+type MemoryIssuanceLimiter struct {
+	n      int
+	window time.Duration
+
+	mu sync.Mutex
+	// issued holds the times of each node's issuances in the last window
+	issued map[string][]time.Time
+	now    func() time.Time
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewMemoryIssuanceLimiter creates a MemoryIssuanceLimiter allowing each node n credentials per sliding window.
+// With n below 1, every issuance is refused, and with a window of zero or less, none are remembered.
+// Nodes with no recent issuances are forgotten every window, or every minute if the window isn't positive. Call Close
+// to stop the background collection.
+func NewMemoryIssuanceLimiter(n int, window time.Duration) *MemoryIssuanceLimiter {
+	out := &MemoryIssuanceLimiter{
+		n:      n,
+		window: window,
+		issued: make(map[string][]time.Time),
+		now:    time.Now,
+		stop:   make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(gcIntervalOrDefault(window))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				out.collect()
+			case <-out.stop:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Allow implements IssuanceLimiter
+func (m *MemoryIssuanceLimiter) Allow(nodeIDs [][]byte, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Count each node's issuances in this call, then check them all before recording any
+	wanted := make(map[string]int, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		wanted[string(nodeID)]++
+	}
+	for _, nodeID := range nodeIDs {
+		key := string(nodeID)
+		k, ok := wanted[key]
+		if !ok {
+			continue
+		}
+		delete(wanted, key)
+
+		// recent prunes the node's times in place, so they must be stored back whatever happens
+		recent := m.recent(m.issued[key], now)
+		if len(recent) > 0 {
+			m.issued[key] = recent
+		} else {
+			delete(m.issued, key)
+		}
+		if excess := len(recent) + k - m.n; excess > 0 {
+			// The node may be issued these once enough of its recent issuances leave the window
+			retryAfter := m.window
+			if excess <= len(recent) {
+				retryAfter = recent[excess-1].Add(m.window).Sub(now)
+			}
+			return &IssuanceRateLimitedError{NodeID: append([]byte(nil), nodeID...), RetryAfter: retryAfter}
+		}
+	}
+
+	for _, nodeID := range nodeIDs {
+		key := string(nodeID)
+		// Concurrent callers may read the clock in one order and get here in another, so keep the times sorted
+		recent := m.issued[key]
+		i := sort.Search(len(recent), func(i int) bool { return recent[i].After(now) })
+		recent = append(recent, time.Time{})
+		copy(recent[i+1:], recent[i:])
+		recent[i] = now
+		m.issued[key] = recent
+	}
+	return nil
+}
+
+// Release implements IssuanceLimiter
+func (m *MemoryIssuanceLimiter) Release(nodeIDs [][]byte, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, nodeID := range nodeIDs {
+		key := string(nodeID)
+		times := m.issued[key]
+		for i := len(times) - 1; i >= 0; i-- {
+			if times[i].Equal(now) {
+				times = append(times[:i], times[i+1:]...)
+				break
+			}
+		}
+		if len(times) > 0 {
+			m.issued[key] = times
+		} else {
+			delete(m.issued, key)
+		}
+	}
+}
+
+// recent returns the issuances in the window ending at now, oldest first, reusing times
+func (m *MemoryIssuanceLimiter) recent(times []time.Time, now time.Time) []time.Time {
+	start := now.Add(-m.window)
+	out := times[:0]
+	for _, t := range times {
+		if t.After(start) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Len returns the number of nodes currently remembered
+func (m *MemoryIssuanceLimiter) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.issued)
+}
+
+// collect forgets nodes with no issuances in the last window
+func (m *MemoryIssuanceLimiter) collect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for key, times := range m.issued {
+		if recent := m.recent(times, now); len(recent) > 0 {
+			m.issued[key] = recent
+		} else {
+			delete(m.issued, key)
+		}
+	}
+}
+
+// Close stops the background garbage collection
+func (m *MemoryIssuanceLimiter) Close() {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+}
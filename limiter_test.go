@@ -0,0 +1,249 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestMemoryIssuanceLimiter tests that each node is allowed n issuances per sliding window
+func TestMemoryIssuanceLimiter(t *testing.T) {
+	l := NewMemoryIssuanceLimiter(2, time.Minute)
+	defer l.Close()
+	start := time.Unix(1700000000, 0)
+	node, other := batchNodeIDs(2)[0], batchNodeIDs(2)[1]
+
+	for _, at := range []time.Duration{0, 10 * time.Second} {
+		if err := l.Allow([][]byte{node}, start.Add(at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := l.Allow([][]byte{node}, start.Add(20*time.Second))
+	var limited *IssuanceRateLimitedError
+	if !errors.As(err, &limited) || !errors.Is(err, ErrIssuanceRateLimited) {
+		t.Fatalf("Expected an *IssuanceRateLimitedError, got %v", err)
+	}
+	if limited.RetryAfter != 40*time.Second {
+		t.Errorf("Expected to retry after 40s, got %s", limited.RetryAfter)
+	}
+
+	// Other nodes have their own allowance
+	if err := l.Allow([][]byte{other}, start.Add(20*time.Second)); err != nil {
+		t.Error(err)
+	}
+
+	// Refusals aren't counted, so the node is allowed again once its first issuance leaves the window
+	if err := l.Allow([][]byte{node}, start.Add(time.Minute+time.Second)); err != nil {
+		t.Error(err)
+	}
+	if err := l.Allow([][]byte{node}, start.Add(time.Minute+2*time.Second)); !errors.Is(err, ErrIssuanceRateLimited) {
+		t.Errorf("Expected ErrIssuanceRateLimited, got %v", err)
+	}
+
+	// Issuances arriving out of order still leave the window oldest first
+	late := NewMemoryIssuanceLimiter(2, time.Minute)
+	defer late.Close()
+	for _, at := range []time.Duration{10 * time.Second, 0} {
+		if err := late.Allow([][]byte{node}, start.Add(at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := late.Allow([][]byte{node}, start.Add(30*time.Second)); !errors.As(err, &limited) || limited.RetryAfter != 30*time.Second {
+		t.Errorf("Expected to retry after 30s, got %v", err)
+	}
+
+	// Nodes with no recent issuances are forgotten
+	l.now = func() time.Time { return start.Add(time.Minute + 30*time.Second) }
+	l.collect()
+	if l.Len() != 1 {
+		t.Errorf("Expected 1 node remembered, got %d", l.Len())
+	}
+	l.now = func() time.Time { return start.Add(3 * time.Minute) }
+	l.collect()
+	if l.Len() != 0 {
+		t.Errorf("Expected no nodes remembered, got %d", l.Len())
+	}
+
+	none := NewMemoryIssuanceLimiter(0, time.Minute)
+	defer none.Close()
+	if err := none.Allow([][]byte{node}, start); !errors.Is(err, ErrIssuanceRateLimited) {
+		t.Errorf("Expected every issuance to be refused, got %v", err)
+	}
+}
+
+// TestMemoryIssuanceLimiterMany tests that issuances to several nodes are allowed or refused together, and released
+func TestMemoryIssuanceLimiterMany(t *testing.T) {
+	l := NewMemoryIssuanceLimiter(2, time.Minute)
+	defer l.Close()
+	start := time.Unix(1700000000, 0)
+	nodeIDs := batchNodeIDs(3)
+
+	if err := l.Allow(nodeIDs[:1], start); err != nil {
+		t.Fatal(err)
+	}
+	// Repeated nodes count once per appearance, so the first node would be issued three
+	err := l.Allow([][]byte{nodeIDs[1], nodeIDs[0], nodeIDs[0]}, start.Add(10*time.Second))
+	var limited *IssuanceRateLimitedError
+	if !errors.As(err, &limited) || !bytes.Equal(limited.NodeID, nodeIDs[0]) || limited.RetryAfter != 50*time.Second {
+		t.Fatalf("Expected the first node to be refused until its issuance leaves the window, got %v", err)
+	}
+	if l.Len() != 1 {
+		t.Errorf("Expected the refusal to count no issuances, got %d nodes remembered", l.Len())
+	}
+
+	at := start.Add(20 * time.Second)
+	if err := l.Allow(nodeIDs, at); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Allow(nodeIDs[:1], at); !errors.Is(err, ErrIssuanceRateLimited) {
+		t.Errorf("Expected ErrIssuanceRateLimited, got %v", err)
+	}
+	l.Release(nodeIDs, at)
+	if l.Len() != 1 {
+		t.Errorf("Expected released nodes to be forgotten, got %d nodes remembered", l.Len())
+	}
+	if err := l.Allow(nodeIDs[:1], at); err != nil {
+		t.Errorf("Expected the released issuance not to count, got %v", err)
+	}
+}
+
+// TestMemoryIssuanceLimiterNonPositiveWindow tests that limiters can be created with no positive window, and then
+// remember no issuances
+func TestMemoryIssuanceLimiterNonPositiveWindow(t *testing.T) {
+	node := batchNodeIDs(1)[0]
+	for _, window := range []time.Duration{0, -time.Minute} {
+		l := NewMemoryIssuanceLimiter(1, window)
+		for i := 0; i < 2; i++ {
+			if err := l.Allow([][]byte{node}, time.Now()); err != nil {
+				t.Errorf("Expected every issuance with window %s to be allowed, got %v", window, err)
+			}
+		}
+		l.Close()
+	}
+}
+
+// TestIssuanceLimiterCreate tests that every Create method consults the limiter, as of the manager's clock
+func TestIssuanceLimiterCreate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewMemoryIssuanceLimiter(1, time.Hour)
+	defer l.Close()
+	cm := NewCredentialManagerWithOptions([]byte("Limiter test secret"), nil, WithIssuanceLimiter(l), WithClock(func() time.Time { return now }))
+	nodeIDs := batchNodeIDs(3)
+
+	old, err := cm.Create(now, nodeIDs[0], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.CreateWithMetadata(now, nodeIDs[0], pb.OperatorType_OT_SOLO, map[string]string{"ticket": "1"}); !errors.Is(err, ErrIssuanceRateLimited) {
+		t.Errorf("Expected ErrIssuanceRateLimited, got %v", err)
+	}
+	if _, err := cm.Reissue(old); !errors.Is(err, ErrIssuanceRateLimited) {
+		t.Errorf("Expected Reissue to be limited, got %v", err)
+	}
+	if _, err := cm.ResignFrom(old, cm); err != nil {
+		t.Errorf("Expected ResignFrom not to be limited, got %v", err)
+	}
+
+	// CreateMany fails as a whole, without counting the nodes which weren't refused
+	if _, err := cm.CreateMany(now, nodeIDs, pb.OperatorType_OT_SOLO); !errors.Is(err, ErrIssuanceRateLimited) {
+		t.Errorf("Expected ErrIssuanceRateLimited, got %v", err)
+	}
+	if _, err := cm.CreateMany(now, nodeIDs[1:], pb.OperatorType_OT_SOLO); err != nil {
+		t.Fatalf("Expected the refused batch to leave the counts unchanged, got %v", err)
+	}
+
+	// CreateBatch fails just the refused nodes
+	now = now.Add(2 * time.Hour)
+	if _, err := cm.Create(now, nodeIDs[1], pb.OperatorType_OT_SOLO); err != nil {
+		t.Fatal(err)
+	}
+	batch, err := cm.CreateBatch(now, nodeIDs, pb.OperatorType_OT_SOLO)
+	var credErr *CredentialError
+	if !errors.As(err, &credErr) || credErr.Index != 1 || !errors.Is(err, ErrIssuanceRateLimited) {
+		t.Errorf("Expected the second credential to be limited, got %v", err)
+	}
+	if batch[0] == nil || batch[1] != nil || batch[2] == nil {
+		t.Errorf("Expected only the limited credential to be missing, got %v", batch)
+	}
+}
+
+// TestIssuanceLimiterBundle tests that bundles are counted against every node they cover
+func TestIssuanceLimiterBundle(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewMemoryIssuanceLimiter(1, time.Hour)
+	defer l.Close()
+	cm := NewCredentialManagerWithOptions([]byte("Limiter test secret"), nil, WithIssuanceLimiter(l), WithClock(func() time.Time { return now }))
+	nodeIDs := batchNodeIDs(4)
+
+	if _, err := cm.CreateBundle(now, nodeIDs[:3], pb.OperatorType_OT_SOLO); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Create(now, nodeIDs[2], pb.OperatorType_OT_SOLO); !errors.Is(err, ErrIssuanceRateLimited) {
+		t.Errorf("Expected the last bundled node to be limited, got %v", err)
+	}
+	if _, err := cm.CreateBundle(now, [][]byte{nodeIDs[3], nodeIDs[1]}, pb.OperatorType_OT_SOLO); !errors.Is(err, ErrIssuanceRateLimited) {
+		t.Errorf("Expected ErrIssuanceRateLimited, got %v", err)
+	}
+	if _, err := cm.Create(now, nodeIDs[3], pb.OperatorType_OT_SOLO); err != nil {
+		t.Errorf("Expected the refused bundle not to count against its other nodes, got %v", err)
+	}
+}
+
+// TestIssuanceLimiterRelease tests that issuances which fail to authenticate don't count
+func TestIssuanceLimiterRelease(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewMemoryIssuanceLimiter(1, time.Hour)
+	defer l.Close()
+	macer := &failingMACer{remoteMACer: remoteMACer{key: []byte("Limiter test secret")}, fail: 1}
+	cm := NewCredentialManagerFromMACer(macer, WithIssuanceLimiter(l), WithClock(func() time.Time { return now }))
+	nodeIDs := batchNodeIDs(3)
+
+	if _, err := cm.Create(now, nodeIDs[1], pb.OperatorType_OT_SOLO); errors.Is(err, ErrIssuanceRateLimited) || err == nil {
+		t.Fatalf("Expected the MAC to fail, got %v", err)
+	}
+	if _, err := cm.CreateMany(now, nodeIDs, pb.OperatorType_OT_SOLO); errors.Is(err, ErrIssuanceRateLimited) || err == nil {
+		t.Fatalf("Expected the MAC to fail, got %v", err)
+	}
+	if _, err := cm.CreateBatch(now, nodeIDs, pb.OperatorType_OT_SOLO); errors.Is(err, ErrIssuanceRateLimited) || err == nil {
+		t.Fatalf("Expected the MAC to fail, got %v", err)
+	}
+	if l.Len() != 2 {
+		t.Errorf("Expected only the batch's authenticated nodes to be counted, got %d nodes remembered", l.Len())
+	}
+
+	macer.fail = 0xff
+	if _, err := cm.Create(now, nodeIDs[1], pb.OperatorType_OT_SOLO); err != nil {
+		t.Errorf("Expected the failed issuances not to count, got %v", err)
+	}
+}
+
+// TestConcurrentIssuanceLimiter tests that concurrent creates for one node are all counted. Run it with -race.
+func TestConcurrentIssuanceLimiter(t *testing.T) {
+	const n = 100
+	l := NewMemoryIssuanceLimiter(n, time.Hour)
+	defer l.Close()
+	cm := NewCredentialManagerWithOptions([]byte("Limiter test secret"), nil, WithIssuanceLimiter(l))
+	nodeID := batchNodeIDs(2)[1]
+
+	var issued atomic.Int64
+	stress(t, func(int) error {
+		for i := 0; i < n; i++ {
+			_, err := cm.Create(time.Now(), nodeID, pb.OperatorType_OT_SOLO)
+			if errors.Is(err, ErrIssuanceRateLimited) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			issued.Add(1)
+		}
+		return nil
+	})
+	if issued.Load() != n {
+		t.Errorf("Expected exactly %d credentials issued, got %d", n, issued.Load())
+	}
+}
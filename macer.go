@@ -0,0 +1,145 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+)
+
+var ErrMACFailed = errors.New("MAC backend failed to compute a MAC")
+
+// MACer computes and compares credential MACs, so that keys can live outside the process, e.g. in an HSM or KMS.
+// Implementations must be safe for concurrent use. Base64URLDecode and UnmarshalJSON only accept MACs of MACLength
+// bytes, so the MACs of credentials passed around encoded must be that long.
+type MACer interface {
+	// Compute returns the MAC of data, or nil if it couldn't be computed
+	Compute(data []byte) []byte
+	// Equal reports whether two MACs are equal, in constant time
+	Equal(a, b []byte) bool
+}
+
+// ContextMACer is optionally implemented by a MACer whose Compute does I/O, e.g. calling a KMS.
+// The manager calls ComputeContext instead of Compute, with the context passed to CreateContext or VerifyContext,
+// or context.Background() for the methods without one.
+type ContextMACer interface {
+	ComputeContext(ctx context.Context, data []byte) ([]byte, error)
+}
+
+// identifiedMACer is optionally implemented by a MACer which knows its key's ID and fingerprint
+type identifiedMACer interface {
+	ID() *ID
+	Fingerprint() string
+}
+
+// macerIDDomain is MACed to derive IDs for MACers which don't provide their own
+const macerIDDomain = "rescue-credential-macer-id"
+
+// NewHMACSHA256 returns a MACer computing HMAC-SHA256 under key, the MAC NewCredentialManager uses
+func NewHMACSHA256(key []byte) MACer {
+	return newMACKey(key)
+}
+
+func (k *macKey) Compute(data []byte) []byte {
+	return k.mac(data)
+}
+
+func (k *macKey) Equal(a, b []byte) bool {
+	return hmac.Equal(a, b)
+}
+
+func (k *macKey) ID() *ID {
+	return k.id
+}
+
+func (k *macKey) Fingerprint() string {
+	return k.fingerprint
+}
+
+// NewCredentialManagerFromMACer creates a CredentialManager which creates and verifies credentials with m,
+// and so never holds the key itself. Seal and Open aren't supported, as they need the key.
+// The manager's ID and fingerprint are derived from a MAC computed by m, unless m provides its own.
+func NewCredentialManagerFromMACer(m MACer, opts ...Option) *CredentialManager {
+	out := &CredentialManager{
+		macer: m,
+	}
+	if identified, ok := m.(identifiedMACer); ok {
+		out.id = identified.ID()
+		out.fingerprint = identified.Fingerprint()
+	} else {
+		derived := m.Compute([]byte(macerIDDomain))
+		out.id = idFromKey(derived)
+		out.fingerprint = KeyFingerprint(derived)
+	}
+	out.keyFingerprints = out.fingerprint
+	out.apply(opts)
+	return out
+}
+
+// authenticateWithMACer authenticates credential with the manager's MACer
+func (c *CredentialManager) authenticateWithMACer(ctx context.Context, credential *AuthenticatedCredential, aad []byte) error {
+	data, err := appendMACInput(nil, credential.Credential, aad)
+	if err != nil {
+		return err
+	}
+	mac, err := c.compute(ctx, data)
+	if err != nil {
+		return err
+	}
+	credential.Mac = mac
+	return c.authenticateDual(credential, data)
+}
+
+// verifyWithMACer is the MACer equivalent of verifyMAC
+func (c *CredentialManager) verifyWithMACer(ctx context.Context, authenticatedCredential *AuthenticatedCredential, aad []byte) (*ID, error) {
+	data, err := appendMACInput(nil, authenticatedCredential.Credential, aad)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := c.compute(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMACLength(authenticatedCredential.Mac, len(mac)); err != nil {
+		return nil, err
+	}
+	if c.macer.Equal(mac, authenticatedCredential.Mac) {
+		return c.id, nil
+	}
+	for _, km := range authenticatedCredential.AdditionalMacs {
+		if bytes.Equal(km.KeyId, c.id.bytes[:keyIDLength]) && c.macer.Equal(mac, km.Mac) {
+			return c.id, nil
+		}
+	}
+	if c.dual != nil && c.dual.verify(data, authenticatedCredential) {
+		return c.dual.id, nil
+	}
+	if len(aad) > 0 {
+		return nil, c.mismatchError(ErrAADMismatch)
+	}
+	return nil, c.mismatchError(MismatchError)
+}
+
+// compute MACs data with the manager's MACer, under ctx if it is a ContextMACer
+func (c *CredentialManager) compute(ctx context.Context, data []byte) ([]byte, error) {
+	cm, ok := c.macer.(ContextMACer)
+	if !ok {
+		if mac := c.macer.Compute(data); len(mac) > 0 {
+			return mac, nil
+		}
+		return nil, ErrMACFailed
+	}
+	mac, err := cm.ComputeContext(ctx, data)
+	if err != nil {
+		// Report cancellation as such, rather than as a failure of the backend
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("%w: %w", ErrMACFailed, err)
+	}
+	if len(mac) == 0 {
+		return nil, ErrMACFailed
+	}
+	return mac, nil
+}
@@ -0,0 +1,185 @@
+package credentials
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// remoteMACer stands in for an HSM, which computes HMAC-SHA256 without handing out its key
+type remoteMACer struct {
+	key   []byte
+	calls atomic.Int64
+	fail  bool
+}
+
+func (m *remoteMACer) Compute(data []byte) []byte {
+	m.calls.Add(1)
+	if m.fail {
+		return nil
+	}
+	h := hmac.New(sha256.New, m.key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func (m *remoteMACer) Equal(a, b []byte) bool {
+	return hmac.Equal(a, b)
+}
+
+// TestMACer tests that MACer backed managers interoperate with key backed ones
+func TestMACer(t *testing.T) {
+	key := []byte("MACer test secret")
+	remote := &remoteMACer{key: key}
+	cm := NewCredentialManagerFromMACer(remote)
+	local := NewCredentialManager(key)
+
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote.calls.Load() == 0 {
+		t.Fatal("Create didn't use the MACer")
+	}
+	if _, err := local.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+
+	localCred, err := local.CreateWithAAD(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyWithAAD(localCred, []byte("aad")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(localCred); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+
+	creds, err := cm.CreateMany(time.Now(), batchNodeIDs(3), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range creds {
+		if _, err := local.Verify(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cred.Credential.Timestamp++
+	if _, err := cm.Verify(cred); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+
+	if _, err := cm.Seal(localCred); !errors.Is(err, ErrNoActiveKey) {
+		t.Errorf("Expected ErrNoActiveKey, got %v", err)
+	}
+
+	// Without an ID of its own, the MACer's ID is stable but not the key's
+	if !cm.ID().Equals(NewCredentialManagerFromMACer(&remoteMACer{key: key}).ID()) {
+		t.Error("Expected MACers with the same key to have the same ID")
+	}
+	if cm.ID().Equals(local.ID()) {
+		t.Error("Expected the derived ID to differ from the key's ID")
+	}
+
+	remote.fail = true
+	if _, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO); !errors.Is(err, ErrMACFailed) {
+		t.Errorf("Expected ErrMACFailed, got %v", err)
+	}
+	if _, err := cm.Verify(localCred); !errors.Is(err, ErrMACFailed) {
+		t.Errorf("Expected ErrMACFailed, got %v", err)
+	}
+}
+
+// TestHMACSHA256 tests that the default MACer matches NewCredentialManager, including its ID and fingerprint
+func TestHMACSHA256(t *testing.T) {
+	key := []byte("MACer test secret")
+	cm := NewCredentialManagerFromMACer(NewHMACSHA256(key))
+	local := NewCredentialManager(key)
+
+	if !cm.ID().Equals(local.ID()) || cm.Fingerprint() != local.Fingerprint() {
+		t.Error("Expected the same ID and fingerprint as NewCredentialManager")
+	}
+
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := (*AuthenticatedCredential)(proto.Clone(cred.Pb()).(*pb.AuthenticatedCredential))
+	if err := local.authenticateCredential(expected, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !hmac.Equal(cred.Mac, expected.Mac) {
+		t.Error("Expected the same MAC as NewCredentialManager")
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blockingMACer is a ContextMACer whose calls block until their context is done, like a KMS that stopped answering
+type blockingMACer struct {
+	remoteMACer
+	started chan struct{}
+}
+
+func (m *blockingMACer) ComputeContext(ctx context.Context, data []byte) ([]byte, error) {
+	if ctx.Value(blockingMACerKey{}) == nil {
+		return m.Compute(data), nil
+	}
+	m.started <- struct{}{}
+	<-ctx.Done()
+	return nil, errors.New("request aborted")
+}
+
+type blockingMACerKey struct{}
+
+// TestContextMACer tests that ContextMACers are passed the call's context, and their cancellation is reported as such
+func TestContextMACer(t *testing.T) {
+	macer := &blockingMACer{remoteMACer: remoteMACer{key: []byte("MACer test secret")}, started: make(chan struct{}, 1)}
+	cm := NewCredentialManagerFromMACer(macer)
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), blockingMACerKey{}, true))
+	go func() {
+		<-macer.started
+		cancel()
+	}()
+	if _, err := cm.VerifyContext(ctx, cred); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.WithValue(context.Background(), blockingMACerKey{}, true), time.Millisecond)
+	defer cancel()
+	if _, err := cm.CreateContext(ctx, time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	<-macer.started
+}
+
+type failingContextMACer struct {
+	remoteMACer
+}
+
+func (m *failingContextMACer) ComputeContext(context.Context, []byte) ([]byte, error) {
+	return nil, errors.New("KMS unavailable")
+}
+
+// TestContextMACerError tests that ContextMACer errors which aren't cancellations are reported as ErrMACFailed
+func TestContextMACerError(t *testing.T) {
+	cm := NewCredentialManagerFromMACer(&failingContextMACer{remoteMACer{key: []byte("MACer test secret")}})
+	if _, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO); !errors.Is(err, ErrMACFailed) {
+		t.Errorf("Expected ErrMACFailed, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// Limits on credential metadata, so tokens can't balloon
+const (
+	MaxMetadataEntries     = 8
+	MaxMetadataKeyLength   = 32
+	MaxMetadataValueLength = 128
+)
+
+var ErrMetadataTooLarge = errors.New("credential metadata exceeds limits")
+
+// validateMetadata enforces the metadata limits
+func validateMetadata(metadata map[string]string) error {
+	if len(metadata) > MaxMetadataEntries {
+		return fmt.Errorf("%w: %d entries, at most %d allowed", ErrMetadataTooLarge, len(metadata), MaxMetadataEntries)
+	}
+	for k, v := range metadata {
+		if len(k) > MaxMetadataKeyLength {
+			return fmt.Errorf("%w: key %.32q... is longer than %d bytes", ErrMetadataTooLarge, k, MaxMetadataKeyLength)
+		}
+		if len(v) > MaxMetadataValueLength {
+			return fmt.Errorf("%w: value of %q is longer than %d bytes", ErrMetadataTooLarge, k, MaxMetadataValueLength)
+		}
+	}
+	return nil
+}
+
+// CreateWithMetadata is like Create, but the credential carries a copy of metadata, which is covered by the MAC.
+// Metadata exceeding the limits fails with ErrMetadataTooLarge.
+func (c *CredentialManager) CreateWithMetadata(timestamp time.Time, nodeID []byte, OperatorType OperatorType, metadata map[string]string) (*AuthenticatedCredential, error) {
+	return c.create(timestamp, nodeID, OperatorType, nil, func(credential *pb.Credential) error {
+		if err := validateMetadata(metadata); err != nil {
+			return err
+		}
+		if len(metadata) == 0 {
+			return nil
+		}
+		credential.Metadata = make(map[string]string, len(metadata))
+		for k, v := range metadata {
+			credential.Metadata[k] = v
+		}
+		return nil
+	})
+}
+
+// GetMetadata returns the metadata value for key, and whether it was present
+func (ac *AuthenticatedCredential) GetMetadata(key string) (string, bool) {
+	v, ok := ac.Credential.GetMetadata()[key]
+	return v, ok
+}
+
+// SetMetadata sets the metadata value for key, failing with ErrMetadataTooLarge if that would exceed the limits.
+// Metadata is covered by the MAC, so this invalidates an authenticated credential; use CreateWithMetadata to
+// issue credentials with metadata.
+func (ac *AuthenticatedCredential) SetMetadata(key, value string) error {
+	metadata := make(map[string]string, len(ac.Credential.Metadata)+1)
+	for k, v := range ac.Credential.Metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+	if err := validateMetadata(metadata); err != nil {
+		return err
+	}
+	ac.Credential.Metadata = metadata
+	return nil
+}
@@ -0,0 +1,161 @@
+package credentials
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func fullMetadata() map[string]string {
+	out := make(map[string]string, MaxMetadataEntries)
+	for i := 0; i < MaxMetadataEntries; i++ {
+		out[fmt.Sprintf("key-%d", i)] = fmt.Sprintf("value-%d", i)
+	}
+	return out
+}
+
+// TestMetadataDeterministicMAC tests that map ordering never affects the MAC
+func TestMetadataDeterministicMAC(t *testing.T) {
+	key := []byte("Metadata test secret")
+	cm := NewCredentialManager(key)
+	cred, err := cm.CreateWithMetadata(time.Unix(1700000000, 0), make([]byte, 20), pb.OperatorType_OT_SOLO, fullMetadata())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The MAC is computed over the deterministic encoding
+	marshaled, err := proto.MarshalOptions{Deterministic: true}.Marshal(cred.Credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hmac.New(hashAlgo, key)
+	h.Write(marshaled)
+	if !hmac.Equal(h.Sum(nil), cred.Mac) {
+		t.Fatal("MAC differs from the MAC over the deterministic encoding")
+	}
+
+	// Go randomizes map iteration, and the default marshaler's map order with it, so repeat enough times
+	// that any dependence on ordering would show
+	for i := 0; i < 100; i++ {
+		wire, err := proto.Marshal(cred.Pb())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded AuthenticatedCredential
+		if err := proto.Unmarshal(wire, decoded.Pb()); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cm.Verify(&decoded); err != nil {
+			t.Fatalf("Verification %d failed: %v", i, err)
+		}
+
+		again, err := cm.CreateWithMetadata(time.Unix(1700000000, 0), make([]byte, 20), pb.OperatorType_OT_SOLO, fullMetadata())
+		if err != nil {
+			t.Fatal(err)
+		}
+		again.Credential.CredentialId = cred.Credential.CredentialId
+		if err := cm.authenticateCredential(again, nil); err != nil {
+			t.Fatal(err)
+		}
+		if !hmac.Equal(again.Mac, cred.Mac) {
+			t.Fatalf("Creation %d produced a different MAC", i)
+		}
+	}
+}
+
+// TestMetadataLimits tests that oversized metadata is rejected when creating and decoding
+func TestMetadataLimits(t *testing.T) {
+	cm := NewCredentialManager([]byte("Metadata test secret"))
+
+	tooMany := fullMetadata()
+	tooMany["one-more"] = ""
+	testCases := []struct {
+		name     string
+		metadata map[string]string
+		ok       bool
+	}{
+		{"Empty", nil, true},
+		{"Full", fullMetadata(), true},
+		{"MaxLengths", map[string]string{strings.Repeat("k", MaxMetadataKeyLength): strings.Repeat("v", MaxMetadataValueLength)}, true},
+		{"TooManyEntries", tooMany, false},
+		{"KeyTooLong", map[string]string{strings.Repeat("k", MaxMetadataKeyLength+1): ""}, false},
+		{"ValueTooLong", map[string]string{"ticket": strings.Repeat("v", MaxMetadataValueLength+1)}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := cm.CreateWithMetadata(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, tc.metadata)
+			if tc.ok != (err == nil) || (!tc.ok && !errors.Is(err, ErrMetadataTooLarge)) {
+				t.Errorf("Unexpected Create result %v", err)
+			}
+
+			// Hand-craft a credential carrying the metadata, as a client could
+			crafted, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+			if err != nil {
+				t.Fatal(err)
+			}
+			crafted.Credential.Metadata = tc.metadata
+
+			data, err := json.Marshal(crafted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fromJSON AuthenticatedCredential
+			err = json.Unmarshal(data, &fromJSON)
+			if tc.ok != (err == nil) || (!tc.ok && !errors.Is(err, ErrMetadataTooLarge)) {
+				t.Errorf("Unexpected JSON decoding result %v", err)
+			}
+
+			text, err := crafted.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fromText AuthenticatedCredential
+			err = fromText.UnmarshalText(text)
+			if tc.ok != (err == nil) || (!tc.ok && !errors.Is(err, ErrMetadataTooLarge)) {
+				t.Errorf("Unexpected text decoding result %v", err)
+			}
+		})
+	}
+}
+
+// TestMetadataAccessors tests GetMetadata and SetMetadata
+func TestMetadataAccessors(t *testing.T) {
+	cm := NewCredentialManager([]byte("Metadata test secret"))
+	cred, err := cm.CreateWithMetadata(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, map[string]string{"ticket": "1234"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := cred.GetMetadata("ticket"); !ok || v != "1234" {
+		t.Errorf("Expected ticket 1234, got %q %v", v, ok)
+	}
+	if _, ok := cred.GetMetadata("channel"); ok {
+		t.Error("Expected no channel")
+	}
+
+	// Metadata is covered by the MAC
+	if err := cred.SetMetadata("channel", "discord"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := cred.GetMetadata("channel"); v != "discord" {
+		t.Errorf("Expected channel discord, got %q", v)
+	}
+	if _, err := cm.Verify(cred); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+
+	full := &AuthenticatedCredential{Credential: &pb.Credential{Metadata: fullMetadata()}}
+	if err := full.SetMetadata("one-more", ""); !errors.Is(err, ErrMetadataTooLarge) {
+		t.Errorf("Expected ErrMetadataTooLarge, got %v", err)
+	}
+	if len(full.Credential.Metadata) != MaxMetadataEntries {
+		t.Error("Expected a rejected SetMetadata to leave the metadata untouched")
+	}
+}
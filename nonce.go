@@ -0,0 +1,77 @@
+package credentials
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// NonceLength is the length of the random nonces generated by CreateWithNonce
+const NonceLength = 16
+
+var ErrReplayedCredential = errors.New("credential nonce has already been used")
+
+// NonceChecker lets callers implement single-use credentials.
+// SeenNonce is called by Verify for every authentic credential that carries a nonce,
+// and should record the nonce and report whether it had been seen before.
+type NonceChecker interface {
+	SeenNonce(nonce []byte) bool
+}
+
+// WithNonceChecker makes Verify consult n for credentials carrying a nonce, failing with ErrReplayedCredential on reuse
+func WithNonceChecker(n NonceChecker) Option {
+	return func(c *CredentialManager) {
+		c.nonceChecker = n
+	}
+}
+
+// WithRandomNonce makes every Create method give credentials NonceLength random bytes of nonce, which are covered by the MAC.
+//
+// Without a nonce, two credentials for the same node, operator type and second are identical, down to their tokens.
+// A random nonce makes every credential unique, so a NonceChecker or ReplayCache can tell each one apart and refuse
+// reuse of any single token, and a token leaked from one session can't be mistaken for another's. CreateWithNonce
+// still sets the nonce explicitly.
+func WithRandomNonce() Option {
+	return func(c *CredentialManager) {
+		c.randomNonce = true
+	}
+}
+
+// WithRandomSource makes the manager draw credential IDs and nonces from r rather than crypto/rand, e.g. to make
+// tests deterministic. It must not be used in production, as predictable nonces defeat their purpose.
+func WithRandomSource(r io.Reader) Option {
+	return func(c *CredentialManager) {
+		c.random = r
+	}
+}
+
+// randomBytes returns n bytes from the manager's random source
+func (c *CredentialManager) randomBytes(n int) ([]byte, error) {
+	random := c.random
+	if random == nil {
+		random = rand.Reader
+	}
+	out := make([]byte, n)
+	if _, err := io.ReadFull(random, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateWithNonce is like Create, but the credential carries nonce, which is covered by the MAC.
+// If nonce is empty, NonceLength random bytes are used.
+func (c *CredentialManager) CreateWithNonce(timestamp time.Time, nodeID []byte, OperatorType OperatorType, nonce []byte) (*AuthenticatedCredential, error) {
+	return c.create(timestamp, nodeID, OperatorType, nil, func(credential *pb.Credential) error {
+		if len(nonce) == 0 {
+			var err error
+			if nonce, err = c.randomBytes(NonceLength); err != nil {
+				return err
+			}
+		}
+		credential.Nonce = nonce
+		return nil
+	})
+}
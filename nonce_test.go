@@ -0,0 +1,286 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+type mapNonceChecker struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (m *mapNonceChecker) SeenNonce(nonce []byte) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seen[string(nonce)] {
+		return true
+	}
+	m.seen[string(nonce)] = true
+	return false
+}
+
+// TestCreateWithNonce tests random and caller-supplied nonces, and that they are covered by the MAC
+func TestCreateWithNonce(t *testing.T) {
+	cm := NewCredentialManager([]byte("Nonce test secret"))
+	now := time.Now()
+
+	random1, err := cm.CreateWithNonce(now, make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	random2, err := cm.CreateWithNonce(now, make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(random1.Credential.Nonce) != NonceLength || bytes.Equal(random1.Credential.Nonce, random2.Credential.Nonce) {
+		t.Error("Expected distinct random nonces")
+	}
+
+	supplied, err := cm.CreateWithNonce(now, make([]byte, 20), pb.OperatorType_OT_SOLO, []byte("my nonce"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(supplied.Credential.Nonce) != "my nonce" {
+		t.Errorf("Unexpected nonce %q", supplied.Credential.Nonce)
+	}
+
+	// The nonce survives the password encoding
+	password, err := supplied.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AuthenticatedCredential
+	if err := decoded.Base64URLDecode(supplied.Base64URLEncodeUsername(), password); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(&decoded); err != nil {
+		t.Error(err)
+	}
+
+	decoded.Credential.Nonce = []byte("another nonce")
+	if _, err := cm.Verify(&decoded); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError after changing the nonce, got %v", err)
+	}
+
+	if _, err := cm.CreateWithNonce(now, []byte("short"), pb.OperatorType_OT_SOLO, nil); !errors.Is(err, ErrInvalidNodeIDLength) {
+		t.Errorf("Expected ErrInvalidNodeIDLength, got %v", err)
+	}
+}
+
+// TestNonceChecker tests that a credential carrying a nonce only verifies once
+func TestNonceChecker(t *testing.T) {
+	checker := &mapNonceChecker{seen: map[string]bool{}}
+	cm := NewCredentialManagerWithOptions([]byte("Nonce test secret"), nil, WithNonceChecker(checker))
+
+	cred, err := cm.CreateWithNonce(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); !errors.Is(err, ErrReplayedCredential) {
+		t.Errorf("Expected ErrReplayedCredential, got %v", err)
+	}
+
+	// Credentials without a nonce are unaffected
+	plain, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cm.Verify(plain); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// A forged credential must not burn the nonce
+	forged, err := cm.CreateWithNonce(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.Mac[0] ^= 1
+	if _, err := cm.Verify(forged); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+	if checker.seen[string(forged.Credential.Nonce)] {
+		t.Error("Nonce of a forged credential was recorded")
+	}
+}
+
+// TestNonceJSON tests that the nonce survives a JSON round-trip
+func TestNonceJSON(t *testing.T) {
+	cm := NewCredentialManager([]byte("Nonce test secret"))
+	cred, err := cm.CreateWithNonce(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := cred.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AuthenticatedCredential
+	if err := decoded.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Credential.Nonce, cred.Credential.Nonce) {
+		t.Error("Nonce mismatch after JSON round-trip")
+	}
+	if _, err := cm.Verify(&decoded); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestRandomNonce tests that WithRandomNonce makes credentials created from identical inputs distinct
+func TestRandomNonce(t *testing.T) {
+	cm := NewCredentialManagerWithOptions([]byte("Nonce test secret"), nil, WithRandomNonce())
+	now := time.Now()
+
+	a, err := cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Credential.Nonce) != NonceLength || bytes.Equal(a.Credential.Nonce, b.Credential.Nonce) {
+		t.Fatalf("Expected distinct random nonces, got %x and %x", a.Credential.Nonce, b.Credential.Nonce)
+	}
+	aPassword, err := a.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bPassword, err := b.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aPassword == bPassword {
+		t.Error("Expected distinct tokens")
+	}
+	if _, err := cm.Verify(a); err != nil {
+		t.Error(err)
+	}
+
+	batch, err := cm.CreateMany(now, batchNodeIDs(2), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch[0].Credential.Nonce) != NonceLength || bytes.Equal(batch[0].Credential.Nonce, batch[1].Credential.Nonce) {
+		t.Error("Expected every credential of a batch to get its own nonce")
+	}
+
+	explicit, err := cm.CreateWithNonce(now, make([]byte, 20), pb.OperatorType_OT_SOLO, []byte("explicit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(explicit.Credential.Nonce) != "explicit" {
+		t.Errorf("Expected CreateWithNonce to override the random nonce, got %q", explicit.Credential.Nonce)
+	}
+}
+
+// TestRandomSource tests that credential IDs and nonces are drawn from the configured source
+func TestRandomSource(t *testing.T) {
+	source := bytes.NewReader(append(bytes.Repeat([]byte{1}, CredentialIDLength), bytes.Repeat([]byte{2}, NonceLength)...))
+	cm := NewCredentialManagerWithOptions([]byte("Nonce test secret"), nil, WithRandomNonce(), WithRandomSource(source))
+
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cred.Credential.CredentialId, bytes.Repeat([]byte{1}, CredentialIDLength)) || !bytes.Equal(cred.Credential.Nonce, bytes.Repeat([]byte{2}, NonceLength)) {
+		t.Errorf("Unexpected credential ID %x and nonce %x", cred.Credential.CredentialId, cred.Credential.Nonce)
+	}
+
+	// The source is exhausted
+	if _, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+// TestDeterministicCredentialIDs tests that Create is deterministic for identical arguments when credential IDs are
+// derived, and that only random nonces then tell credentials apart
+func TestDeterministicCredentialIDs(t *testing.T) {
+	now := time.Now()
+	encode := func(t *testing.T, cred *AuthenticatedCredential) string {
+		t.Helper()
+		password, err := cred.Base64URLEncodePassword()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cred.Base64URLEncodeUsername() + ":" + password
+	}
+
+	// An empty random source fails any attempt to draw from it
+	cm := NewCredentialManagerWithOptions([]byte("Nonce test secret"), nil, WithDeterministicCredentialIDs(), WithRandomSource(bytes.NewReader(nil)))
+	a, err := cm.CreateWithMetadata(now, make([]byte, 20), pb.OperatorType_OT_SOLO, map[string]string{"ticket": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := cm.CreateWithMetadata(now, make([]byte, 20), pb.OperatorType_OT_SOLO, map[string]string{"ticket": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Credential.CredentialId) != CredentialIDLength {
+		t.Errorf("Expected a %d byte credential ID, got %x", CredentialIDLength, a.Credential.CredentialId)
+	}
+	if encode(t, a) != encode(t, b) {
+		t.Error("Expected identical Create calls to give byte-identical credentials")
+	}
+	if _, err := cm.Verify(a); err != nil {
+		t.Error(err)
+	}
+
+	other, err := cm.CreateWithMetadata(now, make([]byte, 20), pb.OperatorType_OT_SOLO, map[string]string{"ticket": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a.Credential.CredentialId, other.Credential.CredentialId) {
+		t.Error("Expected credentials which differ to get different IDs")
+	}
+
+	batch, err := cm.CreateMany(now, [][]byte{make([]byte, 20), batchNodeIDs(2)[1]}, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	single, err := cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encode(t, batch[0]) != encode(t, single) {
+		t.Error("Expected CreateMany to give the same credentials as Create")
+	}
+	batch, err = cm.CreateBatch(now, [][]byte{make([]byte, 20)}, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encode(t, batch[0]) != encode(t, single) {
+		t.Error("Expected CreateBatch to give the same credentials as Create")
+	}
+
+	// With random nonces, the nonce is the only difference
+	cm = NewCredentialManagerWithOptions([]byte("Nonce test secret"), nil, WithDeterministicCredentialIDs(), WithRandomNonce())
+	a, err = cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a.Credential.Nonce, b.Credential.Nonce) || bytes.Equal(a.Credential.CredentialId, b.Credential.CredentialId) {
+		t.Error("Expected random nonces to give credentials their own IDs")
+	}
+	b.Credential.Nonce = a.Credential.Nonce
+	b.Credential.CredentialId = a.Credential.CredentialId
+	if !proto.Equal(a.Credential, b.Credential) {
+		t.Errorf("Expected the nonce to be the only difference, got %v and %v", a.Credential, b.Credential)
+	}
+}
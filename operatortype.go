@@ -0,0 +1,76 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+var (
+	ErrOperatorTypeNotAllowed = errors.New("credential operator type not allowed")
+	ErrOperatorTypeUnset      = errors.New("credential operator type is the zero value")
+)
+
+// ParseOperatorType returns the operator type named s, in any case, by either its short name as returned by its Name
+// method, such as "solo", or its full enum name, such as "OT_SOLO", so flags and config files can name them readably.
+// Names of no operator type fail with ErrUnknownOperatorType.
+func ParseOperatorType(s string) (OperatorType, error) {
+	values := OperatorType(0).Descriptor().Values()
+	for i := 0; i < values.Len(); i++ {
+		name := string(values.Get(i).Name())
+		if strings.EqualFold(s, name) || strings.EqualFold(s, strings.TrimPrefix(name, pb.OperatorTypeNamePrefix)) {
+			return OperatorType(values.Get(i).Number()), nil
+		}
+	}
+	return 0, fmt.Errorf("%w %q", ErrUnknownOperatorType, s)
+}
+
+// WithAllowedOperatorTypes makes Verify reject credentials of any other operator type with ErrOperatorTypeNotAllowed.
+// Without any types, every operator type is accepted.
+func WithAllowedOperatorTypes(types ...OperatorType) Option {
+	return func(c *CredentialManager) {
+		c.allowedOperatorTypes = nil
+		if len(types) == 0 {
+			return
+		}
+		c.allowedOperatorTypes = make(map[OperatorType]struct{}, len(types))
+		for _, ot := range types {
+			c.allowedOperatorTypes[ot] = struct{}{}
+		}
+	}
+}
+
+// checkOperatorType enforces the allow-list configured with WithAllowedOperatorTypes
+func (c *CredentialManager) checkOperatorType(authenticatedCredential *AuthenticatedCredential) error {
+	if c.allowedOperatorTypes == nil {
+		return nil
+	}
+	ot := authenticatedCredential.Credential.GetOperatorType()
+	if _, ok := c.allowedOperatorTypes[ot]; !ok {
+		return fmt.Errorf("%w: %s", ErrOperatorTypeNotAllowed, ot)
+	}
+	return nil
+}
+
+// WithRejectZeroOperatorType makes Create and its variants reject the zero operator type with ErrOperatorTypeUnset,
+// to catch callers that forget to set one. The zero value is OT_ROCKETPOOL, so managers with this option can't
+// create Rocket Pool credentials; it is meant for deployments issuing only other operator types.
+func WithRejectZeroOperatorType() Option {
+	return func(c *CredentialManager) {
+		c.rejectZeroOperatorType = true
+	}
+}
+
+// validateOperatorType checks that credentials being created have a defined operator type,
+// and enforces WithRejectZeroOperatorType
+func (c *CredentialManager) validateOperatorType(ot OperatorType) error {
+	if err := validateOperatorTypeDefined(ot); err != nil {
+		return err
+	}
+	if c.rejectZeroOperatorType && ot == 0 {
+		return fmt.Errorf("%w (%s)", ErrOperatorTypeUnset, ot)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package credentials
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestAllowedOperatorTypes tests that Verify only accepts the allowed operator types
+func TestAllowedOperatorTypes(t *testing.T) {
+	key := []byte("Operator type test secret")
+	cm := NewCredentialManager(key)
+	solo, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rocketpool, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_ROCKETPOOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		allowed []OperatorType
+		cred    *AuthenticatedCredential
+		ok      bool
+	}{
+		{"AllowAll", nil, solo, true},
+		{"Allowed", []OperatorType{pb.OperatorType_OT_SOLO}, solo, true},
+		{"NotAllowed", []OperatorType{pb.OperatorType_OT_SOLO}, rocketpool, false},
+		{"BothAllowed", []OperatorType{pb.OperatorType_OT_SOLO, pb.OperatorType_OT_ROCKETPOOL}, rocketpool, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier := NewCredentialManagerWithOptions(key, nil, WithAllowedOperatorTypes(tc.allowed...))
+			_, err := verifier.Verify(tc.cred)
+			if tc.ok && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrOperatorTypeNotAllowed) {
+				t.Errorf("Expected ErrOperatorTypeNotAllowed, got %v", err)
+			}
+		})
+	}
+}
+
+// TestRejectZeroOperatorType tests that the zero operator type is only rejected when asked for, and only by Create
+func TestRejectZeroOperatorType(t *testing.T) {
+	strict := NewCredentialManagerWithOptions([]byte("Operator type test secret"), nil, WithRejectZeroOperatorType())
+	lax := NewCredentialManager([]byte("Operator type test secret"))
+
+	if _, err := strict.Create(time.Now(), make([]byte, 20), 0); !errors.Is(err, ErrOperatorTypeUnset) {
+		t.Errorf("Expected ErrOperatorTypeUnset, got %v", err)
+	}
+	if _, err := strict.CreateMany(time.Now(), batchNodeIDs(2), 0); !errors.Is(err, ErrOperatorTypeUnset) {
+		t.Errorf("Expected ErrOperatorTypeUnset from CreateMany, got %v", err)
+	}
+	if _, err := strict.CreateBatch(time.Now(), batchNodeIDs(2), 0); !errors.Is(err, ErrOperatorTypeUnset) {
+		t.Errorf("Expected ErrOperatorTypeUnset from CreateBatch, got %v", err)
+	}
+	if _, err := strict.CreateBundle(time.Now(), batchNodeIDs(2), 0); !errors.Is(err, ErrOperatorTypeUnset) {
+		t.Errorf("Expected ErrOperatorTypeUnset from CreateBundle, got %v", err)
+	}
+
+	solo, err := strict.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := strict.Verify(solo); err != nil {
+		t.Error(err)
+	}
+
+	// The default is unchanged, and verification is unaffected
+	zero, err := lax.Create(time.Now(), make([]byte, 20), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := strict.Verify(zero); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestParseOperatorType tests that operator types parse from their short and full names in any case,
+// and that every defined operator type's name parses back to it
+func TestParseOperatorType(t *testing.T) {
+	testCases := []struct {
+		s    string
+		want OperatorType
+	}{
+		{"solo", pb.OperatorType_OT_SOLO},
+		{"Solo", pb.OperatorType_OT_SOLO},
+		{"OT_SOLO", pb.OperatorType_OT_SOLO},
+		{"ot_solo", pb.OperatorType_OT_SOLO},
+		{"rocketpool", pb.OperatorType_OT_ROCKETPOOL},
+		{"OT_ROCKETPOOL", pb.OperatorType_OT_ROCKETPOOL},
+	}
+	for _, tc := range testCases {
+		got, err := ParseOperatorType(tc.s)
+		if err != nil {
+			t.Errorf("%q: %v", tc.s, err)
+		} else if got != tc.want {
+			t.Errorf("%q: expected %s, got %s", tc.s, tc.want, got)
+		}
+	}
+
+	for _, s := range []string{"", "OT_", "sol", "OT_SOLO ", "1", "OT_OT_SOLO"} {
+		if _, err := ParseOperatorType(s); !errors.Is(err, ErrUnknownOperatorType) {
+			t.Errorf("%q: expected ErrUnknownOperatorType, got %v", s, err)
+		}
+	}
+
+	values := OperatorType(0).Descriptor().Values()
+	for i := 0; i < values.Len(); i++ {
+		ot := OperatorType(values.Get(i).Number())
+		got, err := ParseOperatorType(ot.Name())
+		if err != nil || got != ot {
+			t.Errorf("Expected %q to parse as %s, got %s, %v", ot.Name(), ot, got, err)
+		}
+	}
+}
+
+// TestOperatorTypeName tests the short names of operator types
+func TestOperatorTypeName(t *testing.T) {
+	if name := pb.OperatorType_OT_SOLO.Name(); name != "solo" {
+		t.Errorf("Expected solo, got %q", name)
+	}
+	if name := pb.OperatorType_OT_ROCKETPOOL.Name(); name != "rocketpool" {
+		t.Errorf("Expected rocketpool, got %q", name)
+	}
+	if name := OperatorType(7).Name(); name != "" {
+		t.Errorf("Expected no name for an undefined operator type, got %q", name)
+	}
+}
@@ -0,0 +1,81 @@
+package credentials
+
+import "time"
+
+// Option configures optional CredentialManager behavior
+type Option func(*CredentialManager)
+
+func (c *CredentialManager) apply(opts []Option) {
+	for _, opt := range opts {
+		opt(c)
+	}
+}
+
+// WithDualMAC makes Create additionally authenticate every credential under secondaryKey, and makes Verify accept it.
+// During a key rotation, credentials created with the new key as primary and the old key as secondary
+// verify on managers configured with either key.
+func WithDualMAC(secondaryKey []byte) Option {
+	return func(c *CredentialManager) {
+		c.dual = newMACKey(secondaryKey)
+	}
+}
+
+// NewDualSignManager returns a manager for use while migrating from oldKey to newKey.
+// It signs every credential under both keys, and verifies credentials signed under either,
+// so that verifiers which have only one of the keys accept the credentials it creates.
+func NewDualSignManager(newKey, oldKey []byte, opts ...Option) *CredentialManager {
+	return NewCredentialManagerWithOptions(newKey, [][]byte{oldKey}, append([]Option{WithDualMAC(oldKey)}, opts...)...)
+}
+
+// WithClock makes the manager read the current time from clock instead of time.Now
+func WithClock(clock func() time.Time) Option {
+	return func(c *CredentialManager) {
+		c.clock = clock
+	}
+}
+
+// WithCreateTolerance makes Create reject timestamps more than tolerance away from the manager's clock,
+// in either direction, with ErrTimestampOutOfRange. This guards against minting credentials from a bad clock,
+// and is unrelated to how long credentials are accepted by Verify. It is disabled by default.
+func WithCreateTolerance(tolerance time.Duration) Option {
+	return func(c *CredentialManager) {
+		c.createTolerance = tolerance
+	}
+}
+
+// WithTimestampBounds makes Create reject timestamps before min, or at or after max, with ErrTimestampOutOfRange,
+// instead of those outside 2020 to 2200 UTC, which guard against signing uninitialized time.Time values and
+// nonsensical far-future ones. A zero min or max keeps that bound's default.
+func WithTimestampBounds(min, max time.Time) Option {
+	return func(c *CredentialManager) {
+		c.minTimestamp = min
+		c.maxTimestamp = max
+	}
+}
+
+// WithDeterministicCredentialIDs makes every Create method derive the credential ID from the rest of the credential,
+// instead of drawing it at random, so that creating a credential twice with identical arguments gives byte-identical
+// credentials which share their ID, e.g. to deduplicate them or retry Create safely. Random nonces, if enabled with
+// WithRandomNonce, are then the only thing that differs, and give each credential its own ID.
+func WithDeterministicCredentialIDs() Option {
+	return func(c *CredentialManager) {
+		c.deterministicIDs = true
+	}
+}
+
+// WithMaxCredentialSize makes VerifyRaw and VerifyFromBasicAuth reject passwords larger than n bytes,
+// before or after decompression, instead of MaxPasswordBytes. Raise it when credentials carry more fields
+// than DefaultMaxCredentialSize allows for; non-positive sizes keep MaxPasswordBytes.
+func WithMaxCredentialSize(n int) Option {
+	return func(c *CredentialManager) {
+		c.maxCredentialSize = n
+	}
+}
+
+// passwordLimit is the bound on the size of the passwords decoded by the manager
+func (c *CredentialManager) passwordLimit() int {
+	if c.maxCredentialSize > 0 {
+		return c.maxCredentialSize
+	}
+	return MaxPasswordBytes
+}
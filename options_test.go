@@ -0,0 +1,253 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestDualMACRotation simulates a rotation where creators and verifiers are upgraded at different times
+func TestDualMACRotation(t *testing.T) {
+	oldKey := []byte("Curiouser and curiouser")
+	newKey := []byte("We're all mad here")
+
+	// Before the rotation, everyone runs the old key
+	oldCreator := NewCredentialManager(oldKey)
+	oldVerifier := NewCredentialManager(oldKey)
+	// During the overlap, upgraded creators sign with both keys and upgraded verifiers accept both
+	newCreator := NewCredentialManagerWithOptions(newKey, nil, WithDualMAC(oldKey))
+	newVerifier := NewCredentialManager(newKey, oldKey)
+	// After the rotation, only the new key remains
+	finalVerifier := NewCredentialManager(newKey)
+
+	oldCred, err := oldCreator.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCred, err := newCreator.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(oldCred.AdditionalMacs) != 0 || len(newCred.AdditionalMacs) != 1 {
+		t.Fatalf("Unexpected number of additional MACs: %d, %d", len(oldCred.AdditionalMacs), len(newCred.AdditionalMacs))
+	}
+
+	// Round-trip the dual-MAC credential through both encodings first
+	username := newCred.Base64URLEncodeUsername()
+	password, err := newCred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AuthenticatedCredential
+	if err := decoded.Base64URLDecode(username, password); err != nil {
+		t.Fatal(err)
+	}
+	jsonData, err := json.Marshal(&decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON AuthenticatedCredential
+	if err := json.Unmarshal(jsonData, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name       string
+		verifier   *CredentialManager
+		credential *AuthenticatedCredential
+		matchedKey []byte
+	}{
+		{"OldVerifierOldCreator", oldVerifier, oldCred, oldKey},
+		{"OldVerifierNewCreator", oldVerifier, &fromJSON, oldKey},
+		{"NewVerifierOldCreator", newVerifier, oldCred, oldKey},
+		{"NewVerifierNewCreator", newVerifier, &fromJSON, newKey},
+		{"DualVerifierOldCreator", newCreator, oldCred, oldKey},
+		{"FinalVerifierNewCreator", finalVerifier, &fromJSON, newKey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := tc.verifier.Verify(tc.credential)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !id.Equals(idFromKey(tc.matchedKey)) {
+				t.Error("Credential verified under an unexpected key")
+			}
+		})
+	}
+
+	// Once the old key is gone, old credentials stop verifying
+	if _, err := finalVerifier.Verify(oldCred); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+
+	// Tampering with the credential invalidates every MAC it carries
+	fromJSON.Credential.OperatorType = pb.OperatorType_OT_ROCKETPOOL
+	if _, err := oldVerifier.Verify(&fromJSON); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+// TestDualSignManager tests that a dual-sign manager's credentials verify on managers with either key, and vice versa
+func TestDualSignManager(t *testing.T) {
+	oldKey := []byte("Curiouser and curiouser")
+	newKey := []byte("We're all mad here")
+	dual := NewDualSignManager(newKey, oldKey)
+
+	cred, err := dual.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range [][]byte{oldKey, newKey} {
+		verifier := NewCredentialManager(key)
+		if _, err := verifier.Verify(cred); err != nil {
+			t.Fatal(err)
+		}
+
+		single, err := verifier.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dual.Verify(single); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestDualMACKeyRing tests that dual MACs also work on KeyRing backed managers
+func TestDualMACKeyRing(t *testing.T) {
+	ring, err := NewKeyRing(KeyEntry{ID: "new", Key: []byte("new key"), NotBefore: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	creator := NewCredentialManagerFromKeyRing(ring, WithDualMAC([]byte("old key")))
+
+	cred, err := creator.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCredentialManager([]byte("old key")).Verify(cred); err != nil {
+		t.Error(err)
+	}
+	if _, err := creator.Verify(cred); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestCreateTolerance tests that Create rejects timestamps too far from the manager's clock
+func TestCreateTolerance(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	key := []byte("Curiouser and curiouser")
+	cm := NewCredentialManagerWithOptions(key, nil, WithClock(clock), WithCreateTolerance(time.Minute))
+
+	testCases := []struct {
+		name      string
+		timestamp time.Time
+		ok        bool
+	}{
+		{"Now", now, true},
+		{"SlightlyBehind", now.Add(-time.Minute), true},
+		{"SlightlyAhead", now.Add(time.Minute), true},
+		{"Stale", now.Add(-time.Minute - time.Second), false},
+		{"Future", now.Add(time.Hour), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := cm.Create(tc.timestamp, make([]byte, 20), pb.OperatorType_OT_SOLO)
+			if tc.ok && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrTimestampOutOfRange) {
+				t.Errorf("Expected ErrTimestampOutOfRange, got %v", err)
+			}
+			_, err = cm.CreateMany(tc.timestamp, batchNodeIDs(2), pb.OperatorType_OT_SOLO)
+			if tc.ok != (err == nil) {
+				t.Errorf("Unexpected CreateMany result %v", err)
+			}
+		})
+	}
+
+	// Disabled by default
+	if _, err := NewCredentialManager(key).Create(time.Unix(defaultMinTimestamp, 0), make([]byte, 20), pb.OperatorType_OT_SOLO); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestTimestampBounds tests that Create rejects timestamps outside 2020 to 2200 by default,
+// including those of uninitialized time.Time values
+func TestTimestampBounds(t *testing.T) {
+	cm := NewCredentialManager([]byte("Curiouser and curiouser"))
+	minTimestamp := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	maxTimestamp := time.Date(2200, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		timestamp time.Time
+		ok        bool
+	}{
+		{"Min", minTimestamp, true},
+		{"BeforeMax", maxTimestamp.Add(-time.Second), true},
+		{"ZeroValue", time.Time{}, false},
+		{"Negative", time.Unix(-1, 0), false},
+		{"BeforeMin", minTimestamp.Add(-time.Second), false},
+		{"Max", maxTimestamp, false},
+		{"FarFuture", time.Date(9999, time.January, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, createErr := cm.Create(tc.timestamp, make([]byte, 20), pb.OperatorType_OT_SOLO)
+			_, manyErr := cm.CreateMany(tc.timestamp, batchNodeIDs(2), pb.OperatorType_OT_SOLO)
+			_, batchErr := cm.CreateBatch(tc.timestamp, batchNodeIDs(2), pb.OperatorType_OT_SOLO)
+			for _, err := range []error{createErr, manyErr, batchErr} {
+				if tc.ok && err != nil {
+					t.Errorf("Expected success, got %v", err)
+				}
+				if !tc.ok && !errors.Is(err, ErrTimestampOutOfRange) {
+					t.Errorf("Expected ErrTimestampOutOfRange, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestWithTimestampBounds tests that managers can have their own timestamp bounds, without affecting others
+func TestWithTimestampBounds(t *testing.T) {
+	key := []byte("Curiouser and curiouser")
+	lo := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	hi := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	bounded := NewCredentialManagerWithOptions(key, nil, WithTimestampBounds(lo, hi))
+	minOnly := NewCredentialManagerWithOptions(key, nil, WithTimestampBounds(lo, time.Time{}))
+	unbounded := NewCredentialManager(key)
+
+	testCases := []struct {
+		name      string
+		cm        *CredentialManager
+		timestamp time.Time
+		ok        bool
+	}{
+		{"Min", bounded, lo, true},
+		{"BeforeMin", bounded, lo.Add(-time.Second), false},
+		{"BeforeMax", bounded, hi.Add(-time.Second), true},
+		{"Max", bounded, hi, false},
+		{"MinOnly/BeforeMin", minOnly, lo.Add(-time.Second), false},
+		{"MinOnly/DefaultMax", minOnly, time.Date(2199, time.January, 1, 0, 0, 0, 0, time.UTC), true},
+		{"Default", unbounded, lo.Add(-time.Second), true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.cm.Create(tc.timestamp, make([]byte, 20), pb.OperatorType_OT_SOLO)
+			if tc.ok && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrTimestampOutOfRange) {
+				t.Errorf("Expected ErrTimestampOutOfRange, got %v", err)
+			}
+		})
+	}
+}
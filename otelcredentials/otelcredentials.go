@@ -0,0 +1,135 @@
+// Package otelcredentials traces a credentials.CredentialManager with OpenTelemetry.
+// It is kept apart from the credentials package so that only users of tracing depend on OpenTelemetry.
+package otelcredentials
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials"
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys set on every span. Node IDs and MACs are never recorded.
+const (
+	OperatorTypeKey = attribute.Key("credentials.operator_type")
+	AgeKey          = attribute.Key("credentials.age")
+	OutcomeKey      = attribute.Key("credentials.outcome")
+)
+
+// OutcomeOK is the outcome of successful calls. Failed calls have their credentials.ErrorCategory as outcome.
+const OutcomeOK = "ok"
+
+// Manager wraps a CredentialManager, tracing Create, Verify and VerifyFromBasicAuth calls.
+// Spans are only annotated when they are recording, so with a no-op tracer the overhead is starting the span.
+type Manager struct {
+	cm     *credentials.CredentialManager
+	tracer trace.Tracer
+}
+
+// New traces the calls made through it to cm with tracer
+func New(cm *credentials.CredentialManager, tracer trace.Tracer) *Manager {
+	return &Manager{cm: cm, tracer: tracer}
+}
+
+// CredentialManager returns the wrapped manager, for calls which aren't traced
+func (m *Manager) CredentialManager() *credentials.CredentialManager {
+	return m.cm
+}
+
+// Create is credentials.CredentialManager.CreateContext, in a "credentials.Create" span
+func (m *Manager) Create(ctx context.Context, timestamp time.Time, nodeID []byte, operatorType credentials.OperatorType) (*credentials.AuthenticatedCredential, error) {
+	ctx, span := m.tracer.Start(ctx, "credentials.Create")
+	defer span.End()
+
+	cred, err := m.cm.CreateContext(ctx, timestamp, nodeID, operatorType)
+	if span.IsRecording() {
+		annotate(span, operatorType, timestamp, err)
+	}
+	return cred, err
+}
+
+// Verify is credentials.CredentialManager.VerifyContext, in a "credentials.Verify" span
+func (m *Manager) Verify(ctx context.Context, cred *credentials.AuthenticatedCredential) (*credentials.ID, error) {
+	ctx, span := m.tracer.Start(ctx, "credentials.Verify")
+	defer span.End()
+
+	id, err := m.cm.VerifyContext(ctx, cred)
+	if span.IsRecording() {
+		annotate(span, cred.Credential.GetOperatorType(), time.Unix(cred.Credential.GetTimestamp(), 0), err)
+	}
+	return id, err
+}
+
+// VerifyFromBasicAuth is credentials.CredentialManager.VerifyFromBasicAuth, in a "credentials.VerifyFromBasicAuth" span.
+// Credentials which fail to decode have no operator type or age.
+func (m *Manager) VerifyFromBasicAuth(ctx context.Context, username, password string) (*credentials.AuthenticatedCredential, error) {
+	_, span := m.tracer.Start(ctx, "credentials.VerifyFromBasicAuth")
+	defer span.End()
+
+	cred, err := m.cm.VerifyFromBasicAuth(username, password)
+	if !span.IsRecording() {
+		return cred, err
+	}
+
+	var verificationErr *credentials.VerificationError
+	switch {
+	case cred != nil:
+		annotate(span, cred.Credential.GetOperatorType(), time.Unix(cred.Credential.GetTimestamp(), 0), err)
+	case errors.As(err, &verificationErr):
+		annotate(span, verificationErr.OperatorType, time.Unix(verificationErr.Timestamp, 0), err)
+	default:
+		setOutcome(span, err)
+	}
+	return cred, err
+}
+
+// annotate sets the attributes and status of a span for a call about a credential
+func annotate(span trace.Span, operatorType credentials.OperatorType, timestamp time.Time, err error) {
+	span.SetAttributes(
+		OperatorTypeKey.String(operatorTypeName(operatorType)),
+		AgeKey.String(AgeBucket(time.Since(timestamp))),
+	)
+	setOutcome(span, err)
+}
+
+// setOutcome sets the outcome attribute of a span, and its status to the error's category if err is set
+func setOutcome(span trace.Span, err error) {
+	if err == nil {
+		span.SetAttributes(OutcomeKey.String(OutcomeOK))
+		return
+	}
+	category := credentials.ErrorCategory(err)
+	span.SetAttributes(OutcomeKey.String(category))
+	// The error's message is left out, as it may name the node
+	span.SetStatus(codes.Error, category)
+}
+
+func operatorTypeName(operatorType credentials.OperatorType) string {
+	if name, ok := pb.OperatorType_name[int32(operatorType)]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// AgeBucket coarsens a credential's age, so spans can be grouped by it without recording when it was issued
+func AgeBucket(age time.Duration) string {
+	switch {
+	case age < 0:
+		return "future"
+	case age < time.Minute:
+		return "<1m"
+	case age < time.Hour:
+		return "<1h"
+	case age < 24*time.Hour:
+		return "<1d"
+	case age < 7*24*time.Hour:
+		return "<7d"
+	default:
+		return ">=7d"
+	}
+}
@@ -0,0 +1,188 @@
+package otelcredentials
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials"
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingSpan records what is set on it
+type recordingSpan struct {
+	noop.Span
+	name        string
+	attrs       map[attribute.Key]string
+	code        codes.Code
+	description string
+	ended       bool
+}
+
+func (s *recordingSpan) IsRecording() bool {
+	return true
+}
+
+func (s *recordingSpan) SetAttributes(kvs ...attribute.KeyValue) {
+	for _, kv := range kvs {
+		s.attrs[kv.Key] = kv.Value.Emit()
+	}
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, description string) {
+	s.code, s.description = code, description
+}
+
+func (s *recordingSpan) End(...trace.SpanEndOption) {
+	s.ended = true
+}
+
+// recordingTracer keeps every span it starts
+type recordingTracer struct {
+	embedded.Tracer
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordingSpan{name: name, attrs: make(map[attribute.Key]string)}
+	t.spans = append(t.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// last returns the most recently started span
+func (t *recordingTracer) last() *recordingSpan {
+	return t.spans[len(t.spans)-1]
+}
+
+// TestManager tests the spans around each traced call
+func TestManager(t *testing.T) {
+	tracer := new(recordingTracer)
+	cm := credentials.NewCredentialManagerWithOptions([]byte("Tracing test secret"), nil, credentials.WithMaxAge(time.Hour))
+	m := New(cm, tracer)
+	ctx := context.Background()
+
+	cred, err := m.Create(ctx, time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := tracer.last()
+	if span.name != "credentials.Create" || !span.ended || span.code != codes.Unset {
+		t.Errorf("Unexpected span %+v", span)
+	}
+	expected := map[attribute.Key]string{OperatorTypeKey: "OT_SOLO", AgeKey: "<1m", OutcomeKey: OutcomeOK}
+	for k, v := range expected {
+		if span.attrs[k] != v {
+			t.Errorf("Expected %s=%s, got %q", k, v, span.attrs[k])
+		}
+	}
+
+	if _, err := m.Verify(ctx, cred); err != nil {
+		t.Fatal(err)
+	}
+	if span := tracer.last(); span.name != "credentials.Verify" || span.attrs[OutcomeKey] != OutcomeOK {
+		t.Errorf("Unexpected span %+v", span)
+	}
+
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.VerifyFromBasicAuth(ctx, cred.Base64URLEncodeUsername(), password); err != nil {
+		t.Fatal(err)
+	}
+	if span := tracer.last(); span.name != "credentials.VerifyFromBasicAuth" || span.attrs[OperatorTypeKey] != "OT_SOLO" {
+		t.Errorf("Unexpected span %+v", span)
+	}
+
+	// Failures set the span status to their category
+	stale, err := cm.Create(time.Now().Add(-2*time.Hour), make([]byte, 20), pb.OperatorType_OT_ROCKETPOOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Verify(ctx, stale); !errors.Is(err, credentials.ErrExpired) {
+		t.Fatalf("Expected ErrExpired, got %v", err)
+	}
+	span = tracer.last()
+	if span.code != codes.Error || span.description != "expired" || span.attrs[OutcomeKey] != "expired" || span.attrs[AgeKey] != "<1d" || span.attrs[OperatorTypeKey] != "OT_ROCKETPOOL" {
+		t.Errorf("Unexpected span %+v", span)
+	}
+
+	stalePassword, err := stale.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.VerifyFromBasicAuth(ctx, stale.Base64URLEncodeUsername(), stalePassword); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if span := tracer.last(); span.description != "expired" || span.attrs[OperatorTypeKey] != "OT_ROCKETPOOL" {
+		t.Errorf("Unexpected span %+v", span)
+	}
+
+	if _, err := m.VerifyFromBasicAuth(ctx, "not base64!", "password"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if span := tracer.last(); span.description != "malformed" || span.attrs[OperatorTypeKey] != "" {
+		t.Errorf("Unexpected span %+v", span)
+	}
+
+	// Nothing identifying the node is recorded
+	for _, span := range tracer.spans {
+		for k := range span.attrs {
+			if k != OperatorTypeKey && k != AgeKey && k != OutcomeKey {
+				t.Errorf("Unexpected attribute %s", k)
+			}
+		}
+	}
+}
+
+func TestAgeBucket(t *testing.T) {
+	testCases := []struct {
+		age      time.Duration
+		expected string
+	}{
+		{-time.Second, "future"},
+		{0, "<1m"},
+		{time.Minute, "<1h"},
+		{23 * time.Hour, "<1d"},
+		{24 * time.Hour, "<7d"},
+		{30 * 24 * time.Hour, ">=7d"},
+	}
+	for _, tc := range testCases {
+		if bucket := AgeBucket(tc.age); bucket != tc.expected {
+			t.Errorf("Expected %s for %s, got %s", tc.expected, tc.age, bucket)
+		}
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	cm := credentials.NewCredentialManager([]byte("Tracing test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		b.Fatal(err)
+	}
+	m := New(cm, noop.NewTracerProvider().Tracer("bench"))
+	ctx := context.Background()
+
+	b.Run("Untraced", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := cm.Verify(cred); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("NoopTracer", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := m.Verify(ctx, cred); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+var ErrPartnerNotAllowed = errors.New("credential partner not allowed")
+
+// PartnerNotAllowedError is returned by Verify for credentials requested through a partner outside the allow-list.
+// It matches ErrPartnerNotAllowed.
+type PartnerNotAllowedError struct {
+	PartnerID string
+}
+
+func (e *PartnerNotAllowedError) Error() string {
+	return fmt.Sprintf("%v: %q", ErrPartnerNotAllowed, e.PartnerID)
+}
+
+func (e *PartnerNotAllowedError) Is(target error) bool {
+	return target == ErrPartnerNotAllowed
+}
+
+// WithAllowedPartners makes Verify reject credentials requested through any other partner with a
+// *PartnerNotAllowedError. First-party credentials, which have no partner ID, are always accepted.
+// Without an allow-list, credentials from any partner are accepted.
+func WithAllowedPartners(partnerIDs ...string) Option {
+	return func(c *CredentialManager) {
+		c.allowedPartners = make(map[string]struct{}, len(partnerIDs))
+		for _, partnerID := range partnerIDs {
+			c.allowedPartners[partnerID] = struct{}{}
+		}
+	}
+}
+
+// CreateForPartner is like Create, but attributes the credential to the third party identified by partnerID,
+// e.g. for per-partner quotas and billing. The partner ID is covered by the MAC.
+func (c *CredentialManager) CreateForPartner(timestamp time.Time, nodeID []byte, OperatorType OperatorType, partnerID string) (*AuthenticatedCredential, error) {
+	return c.create(timestamp, nodeID, OperatorType, nil, func(credential *pb.Credential) error {
+		credential.PartnerId = partnerID
+		return nil
+	})
+}
+
+// PartnerID returns the ID of the partner the credential was requested through, or "" if it is first-party
+func (ac *AuthenticatedCredential) PartnerID() string {
+	return ac.Credential.GetPartnerId()
+}
+
+// checkPartner enforces the allow-list configured with WithAllowedPartners
+func (c *CredentialManager) checkPartner(authenticatedCredential *AuthenticatedCredential) error {
+	partnerID := authenticatedCredential.PartnerID()
+	if partnerID == "" || c.allowedPartners == nil {
+		return nil
+	}
+	if _, ok := c.allowedPartners[partnerID]; !ok {
+		return &PartnerNotAllowedError{PartnerID: partnerID}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestCreateForPartner tests that partner credentials round-trip through JSON and are checked against the allow-list
+func TestCreateForPartner(t *testing.T) {
+	key := []byte("Partner test secret")
+	cm := NewCredentialManager(key)
+	partnerCred, err := cm.CreateForPartner(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstParty, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(partnerCred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AuthenticatedCredential
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.PartnerID() != "acme" {
+		t.Fatalf("Expected partner acme after JSON round-trip, got %q", decoded.PartnerID())
+	}
+
+	testCases := []struct {
+		name     string
+		verifier *CredentialManager
+		cred     *AuthenticatedCredential
+		ok       bool
+	}{
+		{"NoAllowList", cm, &decoded, true},
+		{"Allowed", NewCredentialManagerWithOptions(key, nil, WithAllowedPartners("acme", "globex")), &decoded, true},
+		{"NotAllowed", NewCredentialManagerWithOptions(key, nil, WithAllowedPartners("globex")), &decoded, false},
+		{"EmptyAllowList", NewCredentialManagerWithOptions(key, nil, WithAllowedPartners()), &decoded, false},
+		{"FirstParty", NewCredentialManagerWithOptions(key, nil, WithAllowedPartners("globex")), firstParty, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := tc.verifier.VerifyClaims(tc.cred)
+			if tc.ok {
+				if err != nil {
+					t.Errorf("Expected success, got %v", err)
+				}
+				if claims.PartnerID != tc.cred.PartnerID() {
+					t.Errorf("Expected partner %q in claims, got %q", tc.cred.PartnerID(), claims.PartnerID)
+				}
+				return
+			}
+			var notAllowed *PartnerNotAllowedError
+			if !errors.Is(err, ErrPartnerNotAllowed) || !errors.As(err, &notAllowed) || notAllowed.PartnerID != "acme" {
+				t.Errorf("Expected a PartnerNotAllowedError, got %v", err)
+			}
+		})
+	}
+}
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.28.1
+// 	protoc-gen-go v1.33.0
 // 	protoc        v3.12.4
 // source: credential.proto
 
@@ -71,9 +71,22 @@ type Credential struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	NodeId       []byte       `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`                                                  // 20 bytes representing the Node address, or if a solo validator, the withdrawal address.
-	Timestamp    int64        `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                                         // UTC epoch time the credential was issued
-	OperatorType OperatorType `protobuf:"varint,3,opt,name=operator_type,json=operatorType,proto3,enum=credentials.OperatorType" json:"operator_type,omitempty"` // The type of Node Operator for whom the credential was issued.
+	NodeId        []byte            `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`                                                                                // 20 bytes representing the Node address, or if a solo validator, the withdrawal address.
+	Timestamp     int64             `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                                                                       // UTC epoch time the credential was issued
+	OperatorType  OperatorType      `protobuf:"varint,3,opt,name=operator_type,json=operatorType,proto3,enum=credentials.OperatorType" json:"operator_type,omitempty"`                               // The type of Node Operator for whom the credential was issued.
+	Nonce         []byte            `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`                                                                                                // Optional random value making the credential unique, for single-use semantics
+	CredentialId  []byte            `protobuf:"bytes,5,opt,name=credential_id,json=credentialId,proto3" json:"credential_id,omitempty"`                                                              // Optional random identifier of this credential, for tracking and revocation
+	ExpiresAt     int64             `protobuf:"varint,6,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`                                                                      // Optional UTC epoch time after which the credential is no longer valid
+	Audience      string            `protobuf:"bytes,7,opt,name=audience,proto3" json:"audience,omitempty"`                                                                                          // Optional name of the service the credential is intended for
+	Issuer        string            `protobuf:"bytes,8,opt,name=issuer,proto3" json:"issuer,omitempty"`                                                                                              // Optional name of the system that minted the credential
+	Scopes        uint64            `protobuf:"varint,9,opt,name=scopes,proto3" json:"scopes,omitempty"`                                                                                             // Optional bitmask of the APIs the credential grants access to
+	ChainId       uint64            `protobuf:"varint,10,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`                                                                           // Optional ID of the Ethereum network the credential is valid on
+	PartnerId     string            `protobuf:"bytes,11,opt,name=partner_id,json=partnerId,proto3" json:"partner_id,omitempty"`                                                                      // Optional ID of the third party the credential was requested through, empty for first-party
+	Metadata      map[string]string `protobuf:"bytes,12,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"` // Optional small key/value context, e.g. a ticket number
+	BundleNodeIds [][]byte          `protobuf:"bytes,13,rep,name=bundle_node_ids,json=bundleNodeIds,proto3" json:"bundle_node_ids,omitempty"`                                                        // For credentials covering several nodes, all of their node IDs. node_id is the first of them.
+	FeeRecipient  []byte            `protobuf:"bytes,14,opt,name=fee_recipient,json=feeRecipient,proto3" json:"fee_recipient,omitempty"`                                                             // Optional 20 byte fee recipient address the node operator attested to
+	Version       uint32            `protobuf:"varint,15,opt,name=version,proto3" json:"version,omitempty"`                                                                                          // Version of the credential format, which determines the MAC input. Absent means 1.
+	ScopeNames    []string          `protobuf:"bytes,16,rep,name=scope_names,json=scopeNames,proto3" json:"scope_names,omitempty"`                                                                   // Optional names of fine-grained permissions the credential grants, e.g. "read" or "submit"
 }
 
 func (x *Credential) Reset() {
@@ -129,19 +142,166 @@ func (x *Credential) GetOperatorType() OperatorType {
 	return OperatorType_OT_ROCKETPOOL
 }
 
+func (x *Credential) GetNonce() []byte {
+	if x != nil {
+		return x.Nonce
+	}
+	return nil
+}
+
+func (x *Credential) GetCredentialId() []byte {
+	if x != nil {
+		return x.CredentialId
+	}
+	return nil
+}
+
+func (x *Credential) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *Credential) GetAudience() string {
+	if x != nil {
+		return x.Audience
+	}
+	return ""
+}
+
+func (x *Credential) GetIssuer() string {
+	if x != nil {
+		return x.Issuer
+	}
+	return ""
+}
+
+func (x *Credential) GetScopes() uint64 {
+	if x != nil {
+		return x.Scopes
+	}
+	return 0
+}
+
+func (x *Credential) GetChainId() uint64 {
+	if x != nil {
+		return x.ChainId
+	}
+	return 0
+}
+
+func (x *Credential) GetPartnerId() string {
+	if x != nil {
+		return x.PartnerId
+	}
+	return ""
+}
+
+func (x *Credential) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Credential) GetBundleNodeIds() [][]byte {
+	if x != nil {
+		return x.BundleNodeIds
+	}
+	return nil
+}
+
+func (x *Credential) GetFeeRecipient() []byte {
+	if x != nil {
+		return x.FeeRecipient
+	}
+	return nil
+}
+
+func (x *Credential) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Credential) GetScopeNames() []string {
+	if x != nil {
+		return x.ScopeNames
+	}
+	return nil
+}
+
+type KeyedMac struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	KeyId []byte `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"` // The first 8 bytes of the ID of the key that computed the mac
+	Mac   []byte `protobuf:"bytes,2,opt,name=mac,proto3" json:"mac,omitempty"`                  // The (H)MAC
+}
+
+func (x *KeyedMac) Reset() {
+	*x = KeyedMac{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_credential_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KeyedMac) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyedMac) ProtoMessage() {}
+
+func (x *KeyedMac) ProtoReflect() protoreflect.Message {
+	mi := &file_credential_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyedMac.ProtoReflect.Descriptor instead.
+func (*KeyedMac) Descriptor() ([]byte, []int) {
+	return file_credential_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *KeyedMac) GetKeyId() []byte {
+	if x != nil {
+		return x.KeyId
+	}
+	return nil
+}
+
+func (x *KeyedMac) GetMac() []byte {
+	if x != nil {
+		return x.Mac
+	}
+	return nil
+}
+
 type AuthenticatedCredential struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Credential *Credential `protobuf:"bytes,1,opt,name=credential,proto3" json:"credential,omitempty"` // The credential itself
-	Mac        []byte      `protobuf:"bytes,2,opt,name=mac,proto3" json:"mac,omitempty"`               // The (H)MAC
+	Credential     *Credential `protobuf:"bytes,1,opt,name=credential,proto3" json:"credential,omitempty"`                               // The credential itself
+	Mac            []byte      `protobuf:"bytes,2,opt,name=mac,proto3" json:"mac,omitempty"`                                             // The (H)MAC
+	AdditionalMacs []*KeyedMac `protobuf:"bytes,3,rep,name=additional_macs,json=additionalMacs,proto3" json:"additional_macs,omitempty"` // MACs under other keys, emitted while keys are being rotated
 }
 
 func (x *AuthenticatedCredential) Reset() {
 	*x = AuthenticatedCredential{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_credential_proto_msgTypes[1]
+		mi := &file_credential_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -154,7 +314,7 @@ func (x *AuthenticatedCredential) String() string {
 func (*AuthenticatedCredential) ProtoMessage() {}
 
 func (x *AuthenticatedCredential) ProtoReflect() protoreflect.Message {
-	mi := &file_credential_proto_msgTypes[1]
+	mi := &file_credential_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -167,7 +327,7 @@ func (x *AuthenticatedCredential) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AuthenticatedCredential.ProtoReflect.Descriptor instead.
 func (*AuthenticatedCredential) Descriptor() ([]byte, []int) {
-	return file_credential_proto_rawDescGZIP(), []int{1}
+	return file_credential_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *AuthenticatedCredential) GetCredential() *Credential {
@@ -184,12 +344,19 @@ func (x *AuthenticatedCredential) GetMac() []byte {
 	return nil
 }
 
+func (x *AuthenticatedCredential) GetAdditionalMacs() []*KeyedMac {
+	if x != nil {
+		return x.AdditionalMacs
+	}
+	return nil
+}
+
 var File_credential_proto protoreflect.FileDescriptor
 
 var file_credential_proto_rawDesc = []byte{
 	0x0a, 0x10, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x2e, 0x70, 0x72, 0x6f,
 	0x74, 0x6f, 0x12, 0x0b, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x22,
-	0x83, 0x01, 0x0a, 0x0a, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x12, 0x17,
+	0xeb, 0x04, 0x0a, 0x0a, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x12, 0x17,
 	0x0a, 0x07, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
 	0x06, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73,
 	0x74, 0x61, 0x6d, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65,
@@ -197,17 +364,55 @@ var file_credential_proto_rawDesc = []byte{
 	0x72, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x19, 0x2e, 0x63,
 	0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x2e, 0x4f, 0x70, 0x65, 0x72, 0x61,
 	0x74, 0x6f, 0x72, 0x54, 0x79, 0x70, 0x65, 0x52, 0x0c, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x6f,
-	0x72, 0x54, 0x79, 0x70, 0x65, 0x22, 0x64, 0x0a, 0x17, 0x41, 0x75, 0x74, 0x68, 0x65, 0x6e, 0x74,
-	0x69, 0x63, 0x61, 0x74, 0x65, 0x64, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c,
-	0x12, 0x37, 0x0a, 0x0a, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61,
-	0x6c, 0x73, 0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x52, 0x0a, 0x63,
-	0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x61, 0x63,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x6d, 0x61, 0x63, 0x2a, 0x2e, 0x0a, 0x0c, 0x4f,
-	0x70, 0x65, 0x72, 0x61, 0x74, 0x6f, 0x72, 0x54, 0x79, 0x70, 0x65, 0x12, 0x11, 0x0a, 0x0d, 0x4f,
-	0x54, 0x5f, 0x52, 0x4f, 0x43, 0x4b, 0x45, 0x54, 0x50, 0x4f, 0x4f, 0x4c, 0x10, 0x00, 0x12, 0x0b,
-	0x0a, 0x07, 0x4f, 0x54, 0x5f, 0x53, 0x4f, 0x4c, 0x4f, 0x10, 0x01, 0x42, 0x06, 0x5a, 0x04, 0x2e,
-	0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x72, 0x54, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x63,
+	0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x5f, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x0c, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x49, 0x64,
+	0x12, 0x1d, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74, 0x12,
+	0x1a, 0x0a, 0x08, 0x61, 0x75, 0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x61, 0x75, 0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x69,
+	0x73, 0x73, 0x75, 0x65, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x69, 0x73, 0x73,
+	0x75, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x73, 0x18, 0x09, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x06, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x73, 0x12, 0x19, 0x0a, 0x08, 0x63,
+	0x68, 0x61, 0x69, 0x6e, 0x5f, 0x69, 0x64, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x63,
+	0x68, 0x61, 0x69, 0x6e, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x70, 0x61, 0x72, 0x74, 0x6e, 0x65,
+	0x72, 0x5f, 0x69, 0x64, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x61, 0x72, 0x74,
+	0x6e, 0x65, 0x72, 0x49, 0x64, 0x12, 0x41, 0x0a, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74,
+	0x61, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x25, 0x2e, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e,
+	0x74, 0x69, 0x61, 0x6c, 0x73, 0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c,
+	0x2e, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08,
+	0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x26, 0x0a, 0x0f, 0x62, 0x75, 0x6e, 0x64,
+	0x6c, 0x65, 0x5f, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x73, 0x18, 0x0d, 0x20, 0x03, 0x28,
+	0x0c, 0x52, 0x0d, 0x62, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x73,
+	0x12, 0x23, 0x0a, 0x0d, 0x66, 0x65, 0x65, 0x5f, 0x72, 0x65, 0x63, 0x69, 0x70, 0x69, 0x65, 0x6e,
+	0x74, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0c, 0x66, 0x65, 0x65, 0x52, 0x65, 0x63, 0x69,
+	0x70, 0x69, 0x65, 0x6e, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x0f, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x1f, 0x0a, 0x0b, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x10,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x73,
+	0x1a, 0x3b, 0x0a, 0x0d, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x33, 0x0a,
+	0x08, 0x4b, 0x65, 0x79, 0x65, 0x64, 0x4d, 0x61, 0x63, 0x12, 0x15, 0x0a, 0x06, 0x6b, 0x65, 0x79,
+	0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6b, 0x65, 0x79, 0x49, 0x64,
+	0x12, 0x10, 0x0a, 0x03, 0x6d, 0x61, 0x63, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x6d,
+	0x61, 0x63, 0x22, 0xa4, 0x01, 0x0a, 0x17, 0x41, 0x75, 0x74, 0x68, 0x65, 0x6e, 0x74, 0x69, 0x63,
+	0x61, 0x74, 0x65, 0x64, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x12, 0x37,
+	0x0a, 0x0a, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x17, 0x2e, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73,
+	0x2e, 0x43, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x52, 0x0a, 0x63, 0x72, 0x65,
+	0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x61, 0x63, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x03, 0x6d, 0x61, 0x63, 0x12, 0x3e, 0x0a, 0x0f, 0x61, 0x64, 0x64,
+	0x69, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x5f, 0x6d, 0x61, 0x63, 0x73, 0x18, 0x03, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x15, 0x2e, 0x63, 0x72, 0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73,
+	0x2e, 0x4b, 0x65, 0x79, 0x65, 0x64, 0x4d, 0x61, 0x63, 0x52, 0x0e, 0x61, 0x64, 0x64, 0x69, 0x74,
+	0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x4d, 0x61, 0x63, 0x73, 0x2a, 0x2e, 0x0a, 0x0c, 0x4f, 0x70, 0x65,
+	0x72, 0x61, 0x74, 0x6f, 0x72, 0x54, 0x79, 0x70, 0x65, 0x12, 0x11, 0x0a, 0x0d, 0x4f, 0x54, 0x5f,
+	0x52, 0x4f, 0x43, 0x4b, 0x45, 0x54, 0x50, 0x4f, 0x4f, 0x4c, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07,
+	0x4f, 0x54, 0x5f, 0x53, 0x4f, 0x4c, 0x4f, 0x10, 0x01, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70,
+	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -223,20 +428,24 @@ func file_credential_proto_rawDescGZIP() []byte {
 }
 
 var file_credential_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_credential_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_credential_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_credential_proto_goTypes = []interface{}{
 	(OperatorType)(0),               // 0: credentials.OperatorType
 	(*Credential)(nil),              // 1: credentials.Credential
-	(*AuthenticatedCredential)(nil), // 2: credentials.AuthenticatedCredential
+	(*KeyedMac)(nil),                // 2: credentials.KeyedMac
+	(*AuthenticatedCredential)(nil), // 3: credentials.AuthenticatedCredential
+	nil,                             // 4: credentials.Credential.MetadataEntry
 }
 var file_credential_proto_depIdxs = []int32{
 	0, // 0: credentials.Credential.operator_type:type_name -> credentials.OperatorType
-	1, // 1: credentials.AuthenticatedCredential.credential:type_name -> credentials.Credential
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	4, // 1: credentials.Credential.metadata:type_name -> credentials.Credential.MetadataEntry
+	1, // 2: credentials.AuthenticatedCredential.credential:type_name -> credentials.Credential
+	2, // 3: credentials.AuthenticatedCredential.additional_macs:type_name -> credentials.KeyedMac
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_credential_proto_init() }
@@ -258,6 +467,18 @@ func file_credential_proto_init() {
 			}
 		}
 		file_credential_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*KeyedMac); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_credential_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*AuthenticatedCredential); i {
 			case 0:
 				return &v.state
@@ -276,7 +497,7 @@ func file_credential_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_credential_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   2,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,16 @@
+package pb
+
+import "strings"
+
+// OperatorTypeNamePrefix prefixes the enum names of every OperatorType, and is left out of their short names
+const OperatorTypeNamePrefix = "OT_"
+
+// Name returns the short, lowercase name of x, such as "solo" for OT_SOLO, for display and configuration,
+// or "" if x isn't a defined value
+func (x OperatorType) Name() string {
+	v := x.Descriptor().Values().ByNumber(x.Number())
+	if v == nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(string(v.Name()), OperatorTypeNamePrefix))
+}
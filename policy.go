@@ -0,0 +1,37 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+var ErrPolicyRejected = errors.New("credential rejected by policy")
+
+// Policy is a deployment specific check, e.g. a node allow-list, applied to credentials after
+// their MAC has been verified. Its fields can therefore be trusted. Returning an error rejects the credential.
+type Policy func(*pb.Credential) error
+
+// VerifyWithPolicy is like Verify, but additionally rejects authentic credentials that policy rejects,
+// with an error wrapping both ErrPolicyRejected and the policy's error. policy is never called for
+// credentials which fail Verify. A nil policy accepts every credential.
+func (c *CredentialManager) VerifyWithPolicy(authenticatedCredential *AuthenticatedCredential, policy Policy) error {
+	if _, err := c.Verify(authenticatedCredential); err != nil {
+		return err
+	}
+	return applyPolicies(authenticatedCredential, policy)
+}
+
+// applyPolicies runs each non-nil policy against a verified credential, stopping at the first rejection
+func applyPolicies(authenticatedCredential *AuthenticatedCredential, policies ...Policy) error {
+	for _, policy := range policies {
+		if policy == nil {
+			continue
+		}
+		if err := policy(authenticatedCredential.Credential); err != nil {
+			return newVerificationError(authenticatedCredential, fmt.Errorf("%w: %w", ErrPolicyRejected, err))
+		}
+	}
+	return nil
+}
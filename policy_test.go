@@ -0,0 +1,101 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+var errNodeNotAllowed = errors.New("node not allowed")
+
+// allowNodes is a Policy accepting only the given nodes
+func allowNodes(nodeIDs ...[]byte) Policy {
+	return func(credential *pb.Credential) error {
+		for _, nodeID := range nodeIDs {
+			if bytes.Equal(nodeID, credential.NodeId) {
+				return nil
+			}
+		}
+		return errNodeNotAllowed
+	}
+}
+
+// TestVerifyWithPolicy tests that policies only run on authentic credentials, and that their errors are wrapped
+func TestVerifyWithPolicy(t *testing.T) {
+	cm := NewCredentialManager([]byte("Policy test secret"))
+	nodeIDs := batchNodeIDs(2)
+	allowed, err := cm.Create(time.Now(), nodeIDs[0], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied, err := cm.Create(time.Now(), nodeIDs[1], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cm.VerifyWithPolicy(allowed, allowNodes(nodeIDs[0])); err != nil {
+		t.Fatal(err)
+	}
+	err = cm.VerifyWithPolicy(denied, allowNodes(nodeIDs[0]))
+	if !errors.Is(err, ErrPolicyRejected) || !errors.Is(err, errNodeNotAllowed) {
+		t.Fatalf("Expected ErrPolicyRejected wrapping the policy's error, got %v", err)
+	}
+	var verificationErr *VerificationError
+	if !errors.As(err, &verificationErr) || !bytes.Equal(verificationErr.NodeID, nodeIDs[1]) {
+		t.Errorf("Expected a *VerificationError for the rejected node, got %v", err)
+	}
+
+	// A nil policy is the same as Verify
+	if err := cm.VerifyWithPolicy(denied, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Policies never see forged credentials
+	called := false
+	denied.Mac[0] ^= 1
+	err = cm.VerifyWithPolicy(denied, func(*pb.Credential) error {
+		called = true
+		return nil
+	})
+	if called {
+		t.Error("Policy was called for a credential with a bad MAC")
+	}
+	if !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+	if err := cm.VerifyWithPolicy(denied, nil); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError with a nil policy, got %v", err)
+	}
+}
+
+// TestAuthenticateRequestPolicy tests that request authentication applies policies, and maps rejections to 403
+func TestAuthenticateRequestPolicy(t *testing.T) {
+	cm := NewCredentialManager([]byte("Policy test secret"))
+	nodeIDs := batchNodeIDs(2)
+	cred, err := cm.Create(time.Now(), nodeIDs[1], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth(cred.Base64URLEncodeUsername(), password)
+
+	if _, err := cm.AuthenticateRequest(r, nil, allowNodes(nodeIDs[1])); err != nil {
+		t.Fatal(err)
+	}
+	_, err = cm.AuthenticateRequest(r, allowNodes(nodeIDs[1]), allowNodes(nodeIDs[0]))
+	if !errors.Is(err, ErrPolicyRejected) {
+		t.Fatalf("Expected ErrPolicyRejected, got %v", err)
+	}
+	if status := HTTPStatus(err); status != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", http.StatusForbidden, status)
+	}
+}
@@ -0,0 +1,109 @@
+// Package prometheus exports a credentials.CredentialManager's events as Prometheus metrics.
+// It is kept apart from the credentials package so that only users of Prometheus depend on its client.
+package prometheus
+
+import (
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials"
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// ResultOK is the result label of successful verifications. Failed ones are labeled with their credentials.ErrorCategory.
+const ResultOK = "ok"
+
+// Collector counts the credentials a manager creates and verifies, and observes how long verifications take and
+// how old the credentials verified are. Its labels are operator types and error categories, never anything
+// identifying a node, so their cardinality stays bounded.
+//
+// A Collector is fed by the manager's hooks, so it must be passed to the manager with Option, or its Hooks
+// combined with the caller's own. It implements prom.Collector, and is safe for concurrent use.
+type Collector struct {
+	creates  *prom.CounterVec
+	verifies *prom.CounterVec
+	duration prom.Histogram
+	age      prom.Histogram
+}
+
+// NewCollector returns a Collector whose metrics are named credentials_*
+func NewCollector() *Collector {
+	return &Collector{
+		creates: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: "credentials",
+			Name:      "creates_total",
+			Help:      "Credentials created, by operator type.",
+		}, []string{"operator_type"}),
+		verifies: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: "credentials",
+			Name:      "verifies_total",
+			Help:      "Credentials verified, by result.",
+		}, []string{"result"}),
+		duration: prom.NewHistogram(prom.HistogramOpts{
+			Namespace: "credentials",
+			Name:      "verify_duration_seconds",
+			Help:      "How long verifying credentials took.",
+			Buckets:   prom.ExponentialBuckets(1e-6, 4, 10),
+		}),
+		age: prom.NewHistogram(prom.HistogramOpts{
+			Namespace: "credentials",
+			Name:      "age_seconds",
+			Help:      "How old credentials were when they verified.",
+			Buckets:   []float64{60, 600, 3600, 6 * 3600, 24 * 3600, 7 * 24 * 3600, 30 * 24 * 3600},
+		}),
+	}
+}
+
+// Hooks returns the hooks that feed the collector
+func (c *Collector) Hooks() credentials.Hooks {
+	return credentials.Hooks{
+		OnCreate:        c.created,
+		OnVerifySuccess: c.verifySucceeded,
+		OnVerifyFailure: c.verifyFailed,
+	}
+}
+
+// Option makes a manager feed the collector. It replaces any other hooks the manager has.
+func (c *Collector) Option() credentials.Option {
+	return credentials.WithHooks(c.Hooks())
+}
+
+func (c *Collector) created(cred *credentials.AuthenticatedCredential) {
+	c.creates.WithLabelValues(operatorTypeName(cred.Credential.GetOperatorType())).Inc()
+}
+
+func (c *Collector) verifySucceeded(cred *credentials.AuthenticatedCredential, elapsed time.Duration) {
+	c.verifies.WithLabelValues(ResultOK).Inc()
+	c.duration.Observe(elapsed.Seconds())
+	c.age.Observe(time.Since(time.Unix(cred.Credential.GetTimestamp(), 0)).Seconds())
+}
+
+func (c *Collector) verifyFailed(reason error, elapsed time.Duration) {
+	c.verifies.WithLabelValues(credentials.ErrorCategory(reason)).Inc()
+	c.duration.Observe(elapsed.Seconds())
+}
+
+// Describe implements prom.Collector
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	c.creates.Describe(ch)
+	c.verifies.Describe(ch)
+	c.duration.Describe(ch)
+	c.age.Describe(ch)
+}
+
+// Collect implements prom.Collector
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	c.creates.Collect(ch)
+	c.verifies.Collect(ch)
+	c.duration.Collect(ch)
+	c.age.Collect(ch)
+}
+
+// operatorTypeName returns the enum name of operatorType, or "unknown" for values outside the enum,
+// so that arbitrary values can't add label values
+func operatorTypeName(operatorType credentials.OperatorType) string {
+	if name, ok := pb.OperatorType_name[int32(operatorType)]; ok {
+		return name
+	}
+	return "unknown"
+}
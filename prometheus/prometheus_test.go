@@ -0,0 +1,76 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials"
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestCollector tests the metrics scraped from a registry after a few Create and Verify calls
+func TestCollector(t *testing.T) {
+	collector := NewCollector()
+	registry := prom.NewPedanticRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatal(err)
+	}
+	cm := credentials.NewCredentialManagerWithOptions([]byte("Prometheus test secret"), nil, collector.Option())
+
+	solo, err := cm.Create(time.Now().Add(-2*time.Hour), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.CreateMany(time.Now(), [][]byte{make([]byte, 20), make([]byte, 20)}, pb.OperatorType_OT_ROCKETPOOL); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cm.Verify(solo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	solo.Credential.Timestamp++
+	if _, err := cm.Verify(solo); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if _, err := cm.VerifyFromBasicAuth("not base64!", "password"); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	expected := `
+# HELP credentials_creates_total Credentials created, by operator type.
+# TYPE credentials_creates_total counter
+credentials_creates_total{operator_type="OT_ROCKETPOOL"} 2
+credentials_creates_total{operator_type="OT_SOLO"} 1
+# HELP credentials_verifies_total Credentials verified, by result.
+# TYPE credentials_verifies_total counter
+credentials_verifies_total{result="mac_mismatch"} 1
+credentials_verifies_total{result="ok"} 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "credentials_creates_total", "credentials_verifies_total"); err != nil {
+		t.Error(err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]uint64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if h := metric.GetHistogram(); h != nil {
+				counts[family.GetName()] = h.GetSampleCount()
+			}
+		}
+	}
+	// Only decoded credentials are verified, and only verified ones have an age
+	if counts["credentials_verify_duration_seconds"] != 3 || counts["credentials_age_seconds"] != 2 {
+		t.Errorf("Unexpected histogram sample counts %v", counts)
+	}
+	if n := testutil.CollectAndCount(collector); n != 6 {
+		t.Errorf("Expected 6 metrics, got %d", n)
+	}
+}
@@ -0,0 +1,92 @@
+package credentials
+
+import (
+	"context"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Reissue verifies old, then mints a fresh credential for the same node and operator type, timestamped now.
+// Expired and revoked credentials fail verification, so they can't be reissued.
+// The scopes, scope names, partner ID, metadata, bundled node IDs and fee recipient carry over; an embedded expiry is renewed for the same lifetime,
+// and a credential with a nonce gets a fresh one. The audience, issuer and chain ID are the manager's own.
+func (c *CredentialManager) Reissue(old *AuthenticatedCredential) (*AuthenticatedCredential, error) {
+	return c.reissue(old, c.now(), 0)
+}
+
+// WithRefreshGrace lets Refresh renew authentic credentials up to grace past their expiry.
+// Verify still rejects them.
+func WithRefreshGrace(grace time.Duration) Option {
+	return func(c *CredentialManager) {
+		c.refreshGrace = grace
+	}
+}
+
+// Refresh is like Reissue, but the new credential is timestamped now, and credentials that expired
+// within the grace set with WithRefreshGrace can be refreshed too. Credentials that fail verification return its error.
+// As Verify requires the old credential's audience to be the manager's, the audience is preserved as well.
+func (c *CredentialManager) Refresh(old *AuthenticatedCredential, now time.Time) (*AuthenticatedCredential, error) {
+	return c.reissue(old, now, c.refreshGrace)
+}
+
+// ResignFrom verifies old with verifier, e.g. a manager with the key being migrated from, and returns a copy of it
+// authenticated by the manager's own key instead, for bulk migration of long-lived credentials.
+// Unlike Reissue, every field is kept as it is, including the node ID, timestamp, operator type, expiry, nonce and
+// credential ID, so the copy is the same credential under a new key. Credentials verifier rejects return its error.
+// As the audience and issuer are old's, the manager's Verify only accepts the copy if they are its own too.
+func (c *CredentialManager) ResignFrom(old *AuthenticatedCredential, verifier *CredentialManager) (*AuthenticatedCredential, error) {
+	if _, err := verifier.Verify(old); err != nil {
+		return nil, err
+	}
+	ot := old.Credential.GetOperatorType()
+	if c.timingHook != nil {
+		defer c.observe(OperationCreate, ot, time.Now())
+	}
+	if err := c.validateOperatorType(ot); err != nil {
+		return nil, err
+	}
+
+	out := &AuthenticatedCredential{Credential: proto.Clone(old.Credential).(*pb.Credential)}
+	if err := c.authenticateCredential(out, nil); err != nil {
+		return nil, err
+	}
+	c.notifyCreated(context.Background(), out)
+	return out, nil
+}
+
+// reissue verifies old, accepting it up to grace past its expiry, and mints its replacement timestamped now
+func (c *CredentialManager) reissue(old *AuthenticatedCredential, now time.Time, grace time.Duration) (*AuthenticatedCredential, error) {
+	if _, err := c.verify(context.Background(), old, nil, grace); err != nil {
+		return nil, err
+	}
+
+	prev := old.Credential
+	return c.create(now, prev.GetNodeId(), prev.GetOperatorType(), nil, func(credential *pb.Credential) error {
+		credential.Scopes = prev.GetScopes()
+		credential.ScopeNames = append([]string(nil), prev.GetScopeNames()...)
+		credential.PartnerId = prev.GetPartnerId()
+		credential.FeeRecipient = append([]byte(nil), prev.GetFeeRecipient()...)
+		for _, nodeID := range prev.GetBundleNodeIds() {
+			credential.BundleNodeIds = append(credential.BundleNodeIds, append([]byte(nil), nodeID...))
+		}
+		if len(prev.GetMetadata()) > 0 {
+			credential.Metadata = make(map[string]string, len(prev.GetMetadata()))
+			for k, v := range prev.GetMetadata() {
+				credential.Metadata[k] = v
+			}
+		}
+		if prev.GetExpiresAt() != 0 {
+			credential.ExpiresAt = now.Unix() + prev.GetExpiresAt() - prev.GetTimestamp()
+		}
+		if len(prev.GetNonce()) > 0 {
+			nonce, err := c.randomBytes(NonceLength)
+			if err != nil {
+				return err
+			}
+			credential.Nonce = nonce
+		}
+		return nil
+	})
+}
@@ -0,0 +1,205 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestReissue tests that reissued credentials keep the node identity with a fresh timestamp
+func TestReissue(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	now := issued
+	clock := func() time.Time { return now }
+	revoker := NewMemoryRevoker()
+	cm := NewCredentialManagerWithOptions([]byte("Reissue test secret"), nil, WithClock(clock), WithRevoker(revoker))
+
+	nodeID := make([]byte, 20)
+	nodeID[0] = 7
+	old, err := cm.CreateWithExpiry(issued, nodeID, pb.OperatorType_OT_SOLO, issued.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = issued.Add(50 * time.Minute)
+	reissued, err := cm.Reissue(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reissued.Credential.NodeId, nodeID) || reissued.Credential.OperatorType != pb.OperatorType_OT_SOLO {
+		t.Error("Reissued credential has a different identity")
+	}
+	if reissued.Credential.Timestamp != now.Unix() || reissued.Credential.ExpiresAt != now.Add(time.Hour).Unix() {
+		t.Errorf("Unexpected validity %d - %d", reissued.Credential.Timestamp, reissued.Credential.ExpiresAt)
+	}
+	if reissued.ID() == old.ID() {
+		t.Error("Expected a fresh credential ID")
+	}
+	if _, err := cm.Verify(reissued); err != nil {
+		t.Error(err)
+	}
+
+	// Expired credentials can't be reissued
+	now = issued.Add(2 * time.Hour)
+	if _, err := cm.Reissue(old); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+
+	// Nor can revoked ones
+	now = issued.Add(50 * time.Minute)
+	revoker.Revoke(nodeID)
+	if _, err := cm.Reissue(old); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Expected ErrRevoked, got %v", err)
+	}
+
+	// Nor forgeries
+	revoker.Unrevoke(nodeID)
+	old.Mac[0] ^= 1
+	if _, err := cm.Reissue(old); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+// TestReissueCarriesFields tests that credential-specific fields survive reissuing
+func TestReissueCarriesFields(t *testing.T) {
+	cm := NewCredentialManager([]byte("Reissue test secret"))
+	old, err := cm.CreateWithNonce(time.Now().Add(-time.Minute), make([]byte, 20), pb.OperatorType_OT_ROCKETPOOL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.Credential.Scopes = uint64(ScopeMetrics)
+	old.Credential.PartnerId = "acme"
+	old.Credential.Metadata = map[string]string{"ticket": "1234"}
+	if err := cm.authenticateCredential(old, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	reissued, err := cm.Reissue(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reissued.Scopes() != ScopeMetrics || reissued.PartnerID() != "acme" {
+		t.Errorf("Unexpected scopes %s or partner %q", reissued.Scopes(), reissued.PartnerID())
+	}
+	if v, _ := reissued.GetMetadata("ticket"); v != "1234" {
+		t.Errorf("Expected metadata to carry over, got %q", v)
+	}
+	if len(reissued.Credential.Nonce) != NonceLength || bytes.Equal(reissued.Credential.Nonce, old.Credential.Nonce) {
+		t.Error("Expected a fresh nonce")
+	}
+	if reissued.Credential.ExpiresAt != 0 {
+		t.Error("Expected no embedded expiry")
+	}
+}
+
+// TestRefresh tests refreshing at a given time, and within the grace window after expiry
+func TestRefresh(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	now := issued
+	clock := func() time.Time { return now }
+	cm := NewCredentialManagerWithOptions([]byte("Reissue test secret"), nil, WithClock(clock), WithAudience("rescue-proxy"), WithMaxAge(time.Hour), WithRefreshGrace(10*time.Minute))
+
+	nodeID := make([]byte, 20)
+	nodeID[0] = 7
+	old, err := cm.CreateWithScopes(issued, nodeID, pb.OperatorType_OT_ROCKETPOOL, ScopeBeaconAPI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired, but within the grace window
+	now = issued.Add(65 * time.Minute)
+	if _, err := cm.Verify(old); !errors.Is(err, ErrExpired) {
+		t.Fatalf("Expected ErrExpired, got %v", err)
+	}
+	refreshed, err := cm.Refresh(old, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credential := refreshed.Credential
+	if !bytes.Equal(credential.NodeId, nodeID) || credential.OperatorType != pb.OperatorType_OT_ROCKETPOOL {
+		t.Error("Refreshed credential has a different identity")
+	}
+	if credential.Timestamp != now.Unix() || credential.Audience != "rescue-proxy" || refreshed.Scopes() != ScopeBeaconAPI {
+		t.Errorf("Unexpected refreshed credential %v", credential)
+	}
+	if _, err := cm.Verify(refreshed); err != nil {
+		t.Error(err)
+	}
+
+	// Reissue has no grace window
+	if _, err := cm.Reissue(old); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired from Reissue, got %v", err)
+	}
+
+	// Past the grace window
+	now = issued.Add(71 * time.Minute)
+	if _, err := cm.Refresh(old, now); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+
+	// Forgeries return the verification error
+	now = issued
+	old.Credential.OperatorType = pb.OperatorType_OT_SOLO
+	_, err = cm.Refresh(old, now)
+	var verificationErr *VerificationError
+	if !errors.Is(err, MismatchError) || !errors.As(err, &verificationErr) {
+		t.Errorf("Expected a MismatchError VerificationError, got %v", err)
+	}
+}
+
+// TestResignFrom tests that credentials verified under an old key are re-signed under a new one, unchanged
+func TestResignFrom(t *testing.T) {
+	oldManager := NewCredentialManagerWithOptions([]byte("Old migration secret"), nil, WithRandomNonce())
+	ring, err := NewKeyRing(KeyEntry{ID: "new", Key: []byte("New migration secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created int
+	managers := map[string]*CredentialManager{
+		"Secret":  NewCredentialManagerWithOptions([]byte("New migration secret"), nil, WithHooks(Hooks{OnCreate: func(*AuthenticatedCredential) { created++ }})),
+		"KeyRing": NewCredentialManagerFromKeyRing(ring),
+	}
+
+	issued := time.Now().Add(-time.Hour)
+	old, err := oldManager.CreateWithMetadata(issued, batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO, map[string]string{"ticket": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := proto.Clone(old.Pb())
+
+	for name, cm := range managers {
+		t.Run(name, func(t *testing.T) {
+			resigned, err := cm.ResignFrom(old, oldManager)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(resigned.Credential, old.Credential) {
+				t.Errorf("Expected the fields to be unchanged, got %v", resigned.Credential)
+			}
+			if resigned.Credential == old.Credential {
+				t.Error("Expected the credential to be copied")
+			}
+			if _, err := cm.Verify(resigned); err != nil {
+				t.Errorf("Expected the new key to verify it, got %v", err)
+			}
+			if _, err := oldManager.Verify(resigned); !errors.Is(err, MismatchError) {
+				t.Errorf("Expected the old key not to verify it, got %v", err)
+			}
+
+			// Only credentials authentic under the old key are re-signed
+			if _, err := cm.ResignFrom(old, cm); !errors.Is(err, MismatchError) {
+				t.Errorf("Expected MismatchError, got %v", err)
+			}
+		})
+	}
+	if !proto.Equal(old.Pb(), before) {
+		t.Error("Expected the old credential to be left unchanged")
+	}
+	if created != 1 {
+		t.Errorf("Expected the OnCreate hook to be called once, got %d", created)
+	}
+}
@@ -0,0 +1,193 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNoReplayCache = errors.New("no replay cache configured")
+	ErrMissingNonce  = errors.New("credential has no nonce")
+	// ErrNonceNotRecordable is returned by VerifyOnce for credentials whose nonce can't be remembered for as long as
+	// they are valid: those which never expire when the replay TTL isn't positive, and those whose replay TTL and
+	// expiry have both passed
+	ErrNonceNotRecordable = errors.New("credential nonce can't be remembered for as long as it is valid")
+)
+
+// ReplayCache records the nonces of single-use credentials.
+// Seen must atomically record nonce until expiry and report whether it was already recorded.
+type ReplayCache interface {
+	Seen(nonce []byte, expiry time.Time) (bool, error)
+}
+
+// ContextReplayCache is optionally implemented by a ReplayCache which does I/O, e.g. one shared through a database.
+// VerifyOnceContext calls SeenContext instead of Seen, with its context.
+type ContextReplayCache interface {
+	SeenContext(ctx context.Context, nonce []byte, expiry time.Time) (bool, error)
+}
+
+// WithReplayCache configures the cache consulted by VerifyOnce.
+// Nonces are remembered until ttl after the credential's timestamp, or until the credential expires if that is
+// later. VerifyOnce rejects credentials which can be remembered no longer, so ttl should be at least as long as
+// credentials without an expiry are accepted for.
+func WithReplayCache(cache ReplayCache, ttl time.Duration) Option {
+	return func(c *CredentialManager) {
+		c.replayCache = cache
+		c.replayTTL = ttl
+	}
+}
+
+// VerifyOnce is like Verify, but additionally requires the credential to carry a nonce (see CreateWithNonce),
+// and records it in the configured ReplayCache so that each credential is only accepted once.
+// Of any number of concurrent calls with the same credential, exactly one succeeds.
+func (c *CredentialManager) VerifyOnce(authenticatedCredential *AuthenticatedCredential) (*ID, error) {
+	return c.VerifyOnceContext(context.Background(), authenticatedCredential)
+}
+
+// VerifyOnceContext is VerifyOnce, verifying with VerifyContext and passing ctx to the replay cache if it accepts one
+func (c *CredentialManager) VerifyOnceContext(ctx context.Context, authenticatedCredential *AuthenticatedCredential) (*ID, error) {
+	if c.replayCache == nil {
+		return nil, ErrNoReplayCache
+	}
+
+	id, err := c.VerifyContext(ctx, authenticatedCredential)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := authenticatedCredential.Credential.GetNonce()
+	if len(nonce) == 0 {
+		return nil, newVerificationError(authenticatedCredential, ErrMissingNonce)
+	}
+
+	expiry, err := c.replayExpiry(authenticatedCredential)
+	if err != nil {
+		return nil, newVerificationError(authenticatedCredential, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, newVerificationError(authenticatedCredential, err)
+	}
+	var seen bool
+	if rc, ok := c.replayCache.(ContextReplayCache); ok {
+		seen, err = rc.SeenContext(ctx, nonce, expiry)
+	} else {
+		seen, err = c.replayCache.Seen(nonce, expiry)
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, newVerificationError(authenticatedCredential, ctxErr)
+		}
+		return nil, newVerificationError(authenticatedCredential, fmt.Errorf("replay cache: %w", err))
+	}
+	if seen {
+		return nil, newVerificationError(authenticatedCredential, ErrReplayedCredential)
+	}
+	return id, nil
+}
+
+// replayExpiry returns until when the credential's nonce must be remembered: the later of replayTTL after its
+// timestamp and its expiry. It fails with ErrNonceNotRecordable if that is already past, or the credential never
+// expires and replayTTL isn't positive.
+func (c *CredentialManager) replayExpiry(authenticatedCredential *AuthenticatedCredential) (time.Time, error) {
+	expiry := time.Unix(authenticatedCredential.Credential.GetTimestamp(), 0).Add(c.replayTTL)
+	expiresAt, ok := c.expiry(authenticatedCredential)
+	switch {
+	case ok && expiresAt.After(expiry):
+		expiry = expiresAt
+	case !ok && c.replayTTL <= 0:
+		return time.Time{}, ErrNonceNotRecordable
+	}
+	// Caches treat nonces as unseen from their expiry on, so one expiring now can't be remembered
+	if !expiry.After(c.now()) {
+		return time.Time{}, ErrNonceNotRecordable
+	}
+	return expiry, nil
+}
+
+// MemoryReplayCache is an in-memory ReplayCache which periodically forgets expired nonces.
+// It is safe for concurrent use.
+type MemoryReplayCache struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	now     func() time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// defaultGCInterval is how often the in-memory caches collect garbage when given no positive interval
+const defaultGCInterval = time.Minute
+
+// gcIntervalOrDefault returns interval, or defaultGCInterval if it isn't positive
+func gcIntervalOrDefault(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return defaultGCInterval
+	}
+	return interval
+}
+
+// NewMemoryReplayCache creates a MemoryReplayCache that removes expired nonces every gcInterval, or every minute if
+// gcInterval isn't positive. Call Close to stop the background collection.
+func NewMemoryReplayCache(gcInterval time.Duration) *MemoryReplayCache {
+	out := &MemoryReplayCache{
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(gcIntervalOrDefault(gcInterval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				out.collect()
+			case <-out.stop:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Seen implements ReplayCache
+func (m *MemoryReplayCache) Seen(nonce []byte, expiry time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := string(nonce)
+	if existing, ok := m.entries[key]; ok && m.now().Before(existing) {
+		return true, nil
+	}
+	m.entries[key] = expiry
+	return false, nil
+}
+
+// Len returns the number of nonces currently remembered
+func (m *MemoryReplayCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// collect removes expired nonces
+func (m *MemoryReplayCache) collect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for nonce, expiry := range m.entries {
+		if !now.Before(expiry) {
+			delete(m.entries, nonce)
+		}
+	}
+}
+
+// Close stops the background garbage collection
+func (m *MemoryReplayCache) Close() {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+}
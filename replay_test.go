@@ -0,0 +1,246 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestVerifyOnce tests that single-use credentials are accepted exactly once
+func TestVerifyOnce(t *testing.T) {
+	cache := NewMemoryReplayCache(time.Minute)
+	defer cache.Close()
+	cm := NewCredentialManagerWithOptions([]byte("Replay test secret"), nil, WithReplayCache(cache, time.Hour))
+
+	cred, err := cm.CreateWithNonce(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyOnce(cred); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyOnce(cred); !errors.Is(err, ErrReplayedCredential) {
+		t.Errorf("Expected ErrReplayedCredential, got %v", err)
+	}
+
+	// Regular Verify still works for credentials with and without nonces
+	if _, err := cm.Verify(cred); err != nil {
+		t.Error(err)
+	}
+	plain, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(plain); err != nil {
+		t.Error(err)
+	}
+	if _, err := cm.VerifyOnce(plain); !errors.Is(err, ErrMissingNonce) {
+		t.Errorf("Expected ErrMissingNonce, got %v", err)
+	}
+
+	// Forgeries are rejected before touching the cache
+	forged, err := cm.CreateWithNonce(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.Mac[0] ^= 1
+	before := cache.Len()
+	if _, err := cm.VerifyOnce(forged); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+	if cache.Len() != before {
+		t.Error("Forged credential was recorded in the replay cache")
+	}
+
+	if _, err := NewCredentialManager([]byte("Replay test secret")).VerifyOnce(cred); !errors.Is(err, ErrNoReplayCache) {
+		t.Errorf("Expected ErrNoReplayCache, got %v", err)
+	}
+}
+
+// TestVerifyOnceOutlivesTTL tests that credentials valid for longer than the replay TTL are remembered until they
+// expire, and that credentials which can't be remembered for as long as they are valid are rejected
+func TestVerifyOnceOutlivesTTL(t *testing.T) {
+	cache := NewMemoryReplayCache(time.Minute)
+	defer cache.Close()
+	key := []byte("Replay test secret")
+	issued := time.Now().Add(-10 * time.Minute)
+
+	cm := NewCredentialManagerWithOptions(key, nil, WithReplayCache(cache, time.Minute), WithMaxAge(time.Hour))
+	cred, err := cm.CreateWithNonce(issued, make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyOnce(cred); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyOnce(cred); !errors.Is(err, ErrReplayedCredential) {
+		t.Errorf("Expected ErrReplayedCredential for a credential older than the replay TTL, got %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		cm   *CredentialManager
+	}{
+		{"NoExpiryTTLElapsed", NewCredentialManagerWithOptions(key, nil, WithReplayCache(cache, time.Minute))},
+		{"NoExpiryNoTTL", NewCredentialManagerWithOptions(key, nil, WithReplayCache(cache, 0))},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cred, err := tc.cm.CreateWithNonce(issued, make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				if _, err := tc.cm.VerifyOnce(cred); !errors.Is(err, ErrNonceNotRecordable) {
+					t.Errorf("Expected ErrNonceNotRecordable, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestVerifyOnceConcurrent tests that concurrent verifications of one credential admit exactly one
+func TestVerifyOnceConcurrent(t *testing.T) {
+	cache := NewMemoryReplayCache(time.Minute)
+	defer cache.Close()
+	cm := NewCredentialManagerWithOptions([]byte("Replay test secret"), nil, WithReplayCache(cache, time.Hour))
+
+	cred, err := cm.CreateWithNonce(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var admitted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cm.VerifyOnce(cred)
+			if err == nil {
+				admitted.Add(1)
+			} else if !errors.Is(err, ErrReplayedCredential) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if admitted.Load() != 1 {
+		t.Errorf("Expected exactly one admission, got %d", admitted.Load())
+	}
+}
+
+// TestMemoryReplayCacheExpiry tests that nonces are forgotten after they expire
+func TestMemoryReplayCacheExpiry(t *testing.T) {
+	cache := NewMemoryReplayCache(time.Hour)
+	defer cache.Close()
+	now := time.Unix(1700000000, 0)
+	cache.now = func() time.Time { return now }
+
+	seen, err := cache.Seen([]byte("a"), now.Add(time.Minute))
+	if err != nil || seen {
+		t.Fatalf("Expected a fresh nonce, got %v %v", seen, err)
+	}
+	if seen, _ := cache.Seen([]byte("a"), now.Add(time.Minute)); !seen {
+		t.Error("Expected the nonce to be remembered")
+	}
+	if _, err := cache.Seen([]byte("b"), now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	cache.collect()
+	if cache.Len() != 1 {
+		t.Errorf("Expected one nonce after collection, got %d", cache.Len())
+	}
+	if seen, _ := cache.Seen([]byte("a"), now.Add(time.Minute)); seen {
+		t.Error("Expected the expired nonce to be forgotten")
+	}
+	if seen, _ := cache.Seen([]byte("b"), now.Add(time.Hour)); !seen {
+		t.Error("Expected the unexpired nonce to be remembered")
+	}
+}
+
+// TestMemoryReplayCacheBackgroundCollection tests that the background goroutine collects expired nonces
+func TestMemoryReplayCacheBackgroundCollection(t *testing.T) {
+	cache := NewMemoryReplayCache(time.Millisecond)
+	defer cache.Close()
+
+	if _, err := cache.Seen([]byte("a"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for cache.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expired nonce was never collected")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cache.Close()
+}
+
+// TestMemoryReplayCacheNonPositiveInterval tests that caches can be created with no positive collection interval
+func TestMemoryReplayCacheNonPositiveInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		cache := NewMemoryReplayCache(interval)
+		if seen, err := cache.Seen([]byte("a"), time.Now().Add(time.Minute)); seen || err != nil {
+			t.Errorf("Expected a fresh nonce with interval %s, got %v, %v", interval, seen, err)
+		}
+		cache.Close()
+	}
+}
+
+// contextReplayCache is a ContextReplayCache which fails with its context's error once the context is done
+type contextReplayCache struct {
+	*MemoryReplayCache
+	calls atomic.Int64
+}
+
+func (c *contextReplayCache) SeenContext(ctx context.Context, nonce []byte, expiry time.Time) (bool, error) {
+	c.calls.Add(1)
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return c.Seen(nonce, expiry)
+}
+
+// TestVerifyOnceContext tests that VerifyOnceContext consults ContextReplayCaches with its context
+func TestVerifyOnceContext(t *testing.T) {
+	cache := &contextReplayCache{MemoryReplayCache: NewMemoryReplayCache(time.Minute)}
+	defer cache.Close()
+	cm := NewCredentialManagerWithOptions([]byte("Replay test secret"), nil, WithReplayCache(cache, time.Hour))
+
+	cred, err := cm.CreateWithNonce(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := cm.VerifyOnceContext(ctx, cred); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.VerifyOnce(cred); !errors.Is(err, ErrReplayedCredential) {
+		t.Errorf("Expected ErrReplayedCredential, got %v", err)
+	}
+	if cache.calls.Load() != 2 {
+		t.Errorf("Expected 2 calls to SeenContext, got %d", cache.calls.Load())
+	}
+
+	// Cancelled calls fail before recording the nonce
+	fresh, err := cm.CreateWithNonce(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := cm.VerifyOnceContext(ctx, fresh); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := cm.VerifyOnce(fresh); err != nil {
+		t.Errorf("Expected the nonce not to have been recorded, got %v", err)
+	}
+}
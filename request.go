@@ -0,0 +1,241 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// DefaultRequestWindow is how long after its timestamp IssueFromRequest accepts a request, unless configured otherwise
+const DefaultRequestWindow = 5 * time.Minute
+
+// requestNonceDomain separates the nonces of requests from those of credentials in a shared ReplayCache
+const requestNonceDomain = "\x00rescue-credential-request-nonce\x00"
+
+var (
+	ErrNoRequestVerifier       = errors.New("no credential request signature verifier configured")
+	ErrInvalidRequest          = errors.New("invalid credential request")
+	ErrRequestExpired          = errors.New("credential request expired")
+	ErrRequestInFuture         = errors.New("credential request timestamp is in the future")
+	ErrInvalidRequestSignature = errors.New("invalid credential request signature")
+	ErrRequestSignerMismatch   = errors.New("credential request not signed by its node")
+	ErrReplayedRequest         = errors.New("credential request nonce has already been used")
+)
+
+// RequestSignatureVerifier recovers the address which signed msg as an EIP-191 personal message with sig.
+// ethcredentials.VerifyIssuanceSignature is one.
+type RequestSignatureVerifier func(msg []byte, sig []byte) ([20]byte, error)
+
+// WithRequestVerifier lets IssueFromRequest mint credentials, recovering the signers of requests with verify, and
+// accepting requests for window after their timestamp, or DefaultRequestWindow if window isn't positive.
+// If the manager has a ReplayCache, as configured with WithReplayCache, each request nonce is accepted only once.
+func WithRequestVerifier(verify RequestSignatureVerifier, window time.Duration) Option {
+	return func(c *CredentialManager) {
+		c.requestVerifier = verify
+		c.requestWindow = window
+		if window <= 0 {
+			c.requestWindow = DefaultRequestWindow
+		}
+	}
+}
+
+// CredentialRequest is a node operator's request for a credential, signed with their node wallet to prove that they
+// control the node. Front-ends exchange it as JSON, and sign the EIP-191 digest of its Message.
+type CredentialRequest struct {
+	NodeID       []byte
+	OperatorType OperatorType
+	// Timestamp is when the client made the request, in seconds since the Unix epoch
+	Timestamp int64
+	Nonce     []byte
+	// Signature is r || s || v over Digest, and is set by Sign
+	Signature []byte
+}
+
+// NewCredentialRequest makes an unsigned request for a credential for nodeID, dated timestamp, with a NonceLength
+// random nonce. Node IDs of the wrong length fail with ErrInvalidNodeIDLength and undefined operator types with
+// ErrUnknownOperatorType.
+func NewCredentialRequest(nodeID []byte, OperatorType OperatorType, timestamp time.Time) (*CredentialRequest, error) {
+	if err := validateNodeID(nodeID); err != nil {
+		return nil, err
+	}
+	if err := validateOperatorTypeDefined(OperatorType); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, NonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &CredentialRequest{
+		NodeID:       append([]byte(nil), nodeID...),
+		OperatorType: OperatorType,
+		Timestamp:    timestamp.Unix(),
+		Nonce:        nonce,
+	}, nil
+}
+
+// Message returns the canonical serialization of the request, without its signature, which is what its node signs.
+// It is plain text, so that wallets show the operator what they are signing.
+func (r *CredentialRequest) Message() []byte {
+	return []byte(fmt.Sprintf("Rescue Node credential request\nNode: 0x%x\nOperator type: %s\nTimestamp: %d\nNonce: %x",
+		r.NodeID, r.OperatorType.Name(), r.Timestamp, r.Nonce))
+}
+
+// Digest returns the EIP-191 personal message hash of Message, i.e. what personal_sign signs
+func (r *CredentialRequest) Digest() []byte {
+	msg := r.Message()
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte("\x19Ethereum Signed Message:\n" + strconv.Itoa(len(msg))))
+	h.Write(msg)
+	return h.Sum(nil)
+}
+
+// Sign sets the request's signature to the one signer returns for Digest,
+// such as go-ethereum's crypto.Sign with the node's key
+func (r *CredentialRequest) Sign(signer func(digest []byte) ([]byte, error)) error {
+	sig, err := signer(r.Digest())
+	if err != nil {
+		return err
+	}
+	r.Signature = sig
+	return nil
+}
+
+// validate rejects requests which can't be issued whatever their signature, with ErrInvalidRequest
+func (r *CredentialRequest) validate() error {
+	if r == nil {
+		return fmt.Errorf("%w: no request", ErrInvalidRequest)
+	}
+	if err := validateNodeID(r.NodeID); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if err := validateOperatorTypeDefined(r.OperatorType); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if err := validateTimestampSet(r.Timestamp); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	if len(r.Nonce) == 0 {
+		return fmt.Errorf("%w: no nonce", ErrInvalidRequest)
+	}
+	if len(r.Signature) == 0 {
+		return fmt.Errorf("%w: no signature", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// IssueFromRequest mints a credential dated now for the node and operator type of req, once it has checked that req
+// was signed by the node it names. Requests fail with ErrInvalidRequest if malformed or unsigned, ErrRequestExpired if
+// dated more than the request window before now, ErrRequestInFuture if dated further ahead than the allowed clock
+// skew, ErrInvalidRequestSignature if no signer can be recovered, ErrRequestSignerMismatch if it isn't the node, and
+// ErrReplayedRequest if the manager has a ReplayCache which has seen the nonce. Managers without WithRequestVerifier
+// fail with ErrNoRequestVerifier.
+func (c *CredentialManager) IssueFromRequest(req *CredentialRequest, now time.Time) (*AuthenticatedCredential, error) {
+	if c.requestVerifier == nil {
+		return nil, ErrNoRequestVerifier
+	}
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Unix(req.Timestamp, 0)
+	if age := now.Sub(timestamp); age > c.requestWindow {
+		return nil, fmt.Errorf("%w: made %s ago, accepted for %s", ErrRequestExpired, age, c.requestWindow)
+	}
+	skew := DefaultClockSkew
+	if c.clockSkewSet {
+		skew = c.clockSkew
+	}
+	if delta := timestamp.Sub(now); skew >= 0 && delta > skew {
+		return nil, fmt.Errorf("%w: %s is %s ahead", ErrRequestInFuture, timestamp.UTC().Format(time.RFC3339), delta)
+	}
+
+	signer, err := c.requestVerifier(req.Message(), req.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequestSignature, err)
+	}
+	if !bytes.Equal(signer[:], req.NodeID) {
+		return nil, fmt.Errorf("%w: signed by 0x%x, requested for 0x%x", ErrRequestSignerMismatch, signer, req.NodeID)
+	}
+
+	if c.replayCache != nil {
+		// Requests are only accepted until the end of their window, so their nonces needn't be remembered for longer
+		key := append([]byte(requestNonceDomain), req.Nonce...)
+		seen, err := c.replayCache.Seen(key, timestamp.Add(c.requestWindow))
+		if err != nil {
+			return nil, fmt.Errorf("replay cache: %w", err)
+		}
+		if seen {
+			return nil, ErrReplayedRequest
+		}
+	}
+
+	return c.Create(now, req.NodeID, req.OperatorType)
+}
+
+type jsonCredentialRequest struct {
+	NodeID       string `json:"node_id"`
+	OperatorType string `json:"operator_type"`
+	Timestamp    int64  `json:"timestamp"`
+	Nonce        string `json:"nonce"`
+	Signature    string `json:"signature,omitempty"`
+}
+
+// MarshalJSON writes the request with 0x prefixed hex node ID, nonce and signature, and its operator type's short name
+func (r *CredentialRequest) MarshalJSON() ([]byte, error) {
+	j := jsonCredentialRequest{
+		NodeID:       "0x" + hex.EncodeToString(r.NodeID),
+		OperatorType: r.OperatorType.Name(),
+		Timestamp:    r.Timestamp,
+		Nonce:        "0x" + hex.EncodeToString(r.Nonce),
+	}
+	if len(r.Signature) > 0 {
+		j.Signature = "0x" + hex.EncodeToString(r.Signature)
+	}
+	return json.Marshal(&j)
+}
+
+// UnmarshalJSON reads requests written by MarshalJSON. Hex may be written with or without the 0x prefix,
+// and operator types by any name ParseOperatorType accepts.
+func (r *CredentialRequest) UnmarshalJSON(data []byte) error {
+	var j jsonCredentialRequest
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	decode := func(field, s string) ([]byte, error) {
+		b, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidRequest, field, err)
+		}
+		return b, nil
+	}
+
+	nodeID, err := decode("node_id", j.NodeID)
+	if err != nil {
+		return err
+	}
+	ot, err := ParseOperatorType(j.OperatorType)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	nonce, err := decode("nonce", j.Nonce)
+	if err != nil {
+		return err
+	}
+	sig, err := decode("signature", j.Signature)
+	if err != nil {
+		return err
+	}
+	*r = CredentialRequest{NodeID: nodeID, OperatorType: ot, Timestamp: j.Timestamp, Nonce: nonce, Signature: sig}
+	if len(sig) == 0 {
+		r.Signature = nil
+	}
+	return nil
+}
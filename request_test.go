@@ -0,0 +1,209 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// recoverRequestSigner is a RequestSignatureVerifier for tests, which can't import ethcredentials
+func recoverRequestSigner(msg []byte, sig []byte) ([20]byte, error) {
+	pubKey, err := crypto.SigToPub(accounts.TextHash(msg), sig)
+	if err != nil {
+		return [20]byte{}, err
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// signedRequest makes a request for the node of key, dated timestamp, and signs it with key
+func signedRequest(t *testing.T, key *ecdsa.PrivateKey, timestamp time.Time) *CredentialRequest {
+	t.Helper()
+	req, err := NewCredentialRequest(crypto.PubkeyToAddress(key.PublicKey).Bytes(), pb.OperatorType_OT_SOLO, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Sign(func(digest []byte) ([]byte, error) { return crypto.Sign(digest, key) }); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+// TestIssueFromRequest tests that signed requests mint credentials for their node
+func TestIssueFromRequest(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayCache := NewMemoryReplayCache(time.Minute)
+	defer replayCache.Close()
+	cm := NewCredentialManagerWithOptions([]byte("Request test secret"), nil,
+		WithRequestVerifier(recoverRequestSigner, time.Minute), WithReplayCache(replayCache, time.Hour))
+
+	now := time.Now()
+	req := signedRequest(t, key, now.Add(-30*time.Second))
+	cred, err := cm.IssueFromRequest(req, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cred.Credential.NodeId, req.NodeID) || cred.Credential.OperatorType != pb.OperatorType_OT_SOLO {
+		t.Errorf("Expected a solo credential for %x, got %v", req.NodeID, cred.Credential)
+	}
+	if cred.Credential.Timestamp != now.Unix() {
+		t.Errorf("Expected the credential to be dated %d, got %d", now.Unix(), cred.Credential.Timestamp)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Error(err)
+	}
+
+	// The same request can't be used twice
+	if _, err := cm.IssueFromRequest(req, now); !errors.Is(err, ErrReplayedRequest) {
+		t.Errorf("Expected ErrReplayedRequest, got %v", err)
+	}
+
+	// Without a replay cache, only the window limits reuse
+	cm = NewCredentialManagerWithOptions([]byte("Request test secret"), nil, WithRequestVerifier(recoverRequestSigner, 0))
+	for i := 0; i < 2; i++ {
+		if _, err := cm.IssueFromRequest(req, now); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := cm.IssueFromRequest(req, now.Add(DefaultRequestWindow)); !errors.Is(err, ErrRequestExpired) {
+		t.Errorf("Expected the default window to apply, got %v", err)
+	}
+}
+
+// TestIssueFromRequestRejects tests every way IssueFromRequest rejects requests
+func TestIssueFromRequestRejects(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	cm := NewCredentialManagerWithOptions([]byte("Request test secret"), nil, WithRequestVerifier(recoverRequestSigner, time.Minute))
+
+	with := func(f func(*CredentialRequest)) *CredentialRequest {
+		req := signedRequest(t, key, now)
+		f(req)
+		return req
+	}
+
+	testCases := []struct {
+		name string
+		cm   *CredentialManager
+		req  *CredentialRequest
+		want error
+	}{
+		{"NoVerifier", NewCredentialManager([]byte("Request test secret")), signedRequest(t, key, now), ErrNoRequestVerifier},
+		{"Nil", cm, nil, ErrInvalidRequest},
+		{"Unsigned", cm, with(func(r *CredentialRequest) { r.Signature = nil }), ErrInvalidRequest},
+		{"NoNonce", cm, with(func(r *CredentialRequest) { r.Nonce = nil }), ErrInvalidRequest},
+		{"NodeIDLength", cm, with(func(r *CredentialRequest) { r.NodeID = r.NodeID[:19] }), ErrInvalidNodeIDLength},
+		{"OperatorType", cm, with(func(r *CredentialRequest) { r.OperatorType = 7 }), ErrUnknownOperatorType},
+		{"ZeroTimestamp", cm, with(func(r *CredentialRequest) { r.Timestamp = 0 }), ErrInvalidRequest},
+		{"Expired", cm, signedRequest(t, key, now.Add(-2*time.Minute)), ErrRequestExpired},
+		{"Future", cm, signedRequest(t, key, now.Add(DefaultClockSkew+time.Minute)), ErrRequestInFuture},
+		{"BadSignature", cm, with(func(r *CredentialRequest) { r.Signature = r.Signature[:10] }), ErrInvalidRequestSignature},
+		{"OtherSigner", cm, with(func(r *CredentialRequest) { r.NodeID = crypto.PubkeyToAddress(other.PublicKey).Bytes() }), ErrRequestSignerMismatch},
+		{"TamperedOperatorType", cm, with(func(r *CredentialRequest) { r.OperatorType = pb.OperatorType_OT_ROCKETPOOL }), ErrRequestSignerMismatch},
+		{"TamperedNonce", cm, with(func(r *CredentialRequest) { r.Nonce[0] ^= 1 }), ErrRequestSignerMismatch},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.cm.IssueFromRequest(tc.req, now); !errors.Is(err, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+// TestCredentialRequestMessage tests that the signed preimage is stable, and that requests differ by their nonces
+func TestCredentialRequestMessage(t *testing.T) {
+	req := &CredentialRequest{
+		NodeID:       bytes.Repeat([]byte{0xab}, NodeIDLength),
+		OperatorType: pb.OperatorType_OT_SOLO,
+		Timestamp:    1700000000,
+		Nonce:        bytes.Repeat([]byte{1}, NonceLength),
+	}
+	want := "Rescue Node credential request\nNode: 0xabababababababababababababababababababab\nOperator type: solo\n" +
+		"Timestamp: 1700000000\nNonce: 01010101010101010101010101010101"
+	if got := string(req.Message()); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if !bytes.Equal(req.Digest(), accounts.TextHash(req.Message())) {
+		t.Error("Expected the digest to be the EIP-191 hash of the message")
+	}
+
+	a, err := NewCredentialRequest(req.NodeID, req.OperatorType, time.Unix(req.Timestamp, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewCredentialRequest(req.NodeID, req.OperatorType, time.Unix(req.Timestamp, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Nonce) != NonceLength || bytes.Equal(a.Nonce, b.Nonce) {
+		t.Errorf("Expected distinct random nonces, got %x and %x", a.Nonce, b.Nonce)
+	}
+
+	if _, err := NewCredentialRequest(make([]byte, 3), req.OperatorType, time.Now()); !errors.Is(err, ErrInvalidNodeIDLength) {
+		t.Errorf("Expected ErrInvalidNodeIDLength, got %v", err)
+	}
+	if _, err := NewCredentialRequest(req.NodeID, 7, time.Now()); !errors.Is(err, ErrUnknownOperatorType) {
+		t.Errorf("Expected ErrUnknownOperatorType, got %v", err)
+	}
+}
+
+// TestCredentialRequestJSON tests that requests survive a round trip through JSON, and can still be issued
+func TestCredentialRequestJSON(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	req := signedRequest(t, key, now)
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded CredentialRequest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Message(), req.Message()) || !bytes.Equal(decoded.Signature, req.Signature) {
+		t.Errorf("Expected %s to decode to the same request", data)
+	}
+	cm := NewCredentialManagerWithOptions([]byte("Request test secret"), nil, WithRequestVerifier(recoverRequestSigner, time.Minute))
+	if _, err := cm.IssueFromRequest(&decoded, now); err != nil {
+		t.Error(err)
+	}
+
+	unsigned, err := json.Marshal(&CredentialRequest{NodeID: req.NodeID, OperatorType: req.OperatorType, Timestamp: req.Timestamp, Nonce: req.Nonce})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(unsigned, []byte("signature")) {
+		t.Errorf("Expected unsigned requests to have no signature, got %s", unsigned)
+	}
+
+	for _, data := range []string{
+		`{"node_id":"0xzz","operator_type":"solo","timestamp":1,"nonce":"0x01"}`,
+		`{"node_id":"0x01","operator_type":"team","timestamp":1,"nonce":"0x01"}`,
+		`{"node_id":"0x01","operator_type":"solo","timestamp":1,"nonce":"0x01","signature":"0xg"}`,
+	} {
+		if err := json.Unmarshal([]byte(data), &decoded); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("%s: expected ErrInvalidRequest, got %v", data, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+var (
+	ErrRevoked         = errors.New("credential has been revoked")
+	ErrRevocationCheck = errors.New("unable to check credential revocation")
+)
+
+// Revoker reports whether credentials issued to nodeID at issuedAt have been revoked
+type Revoker interface {
+	IsRevoked(nodeID []byte, issuedAt time.Time) (bool, error)
+}
+
+// CredentialRevoker is optionally implemented by a Revoker to revoke individual credentials.
+// It is consulted for credentials that carry a credential ID.
+type CredentialRevoker interface {
+	IsCredentialRevoked(credentialID []byte) (bool, error)
+}
+
+// ContextRevoker is optionally implemented by a Revoker whose checks do I/O.
+// The manager calls IsRevokedContext instead of IsRevoked, with the context passed to VerifyContext,
+// or context.Background() for the methods without one.
+type ContextRevoker interface {
+	IsRevokedContext(ctx context.Context, nodeID []byte, issuedAt time.Time) (bool, error)
+}
+
+// ContextCredentialRevoker is to CredentialRevoker what ContextRevoker is to Revoker
+type ContextCredentialRevoker interface {
+	IsCredentialRevokedContext(ctx context.Context, credentialID []byte) (bool, error)
+}
+
+// WithRevoker makes Verify consult r after the MAC check passes, failing with ErrRevoked for revoked credentials.
+// Bundles are revoked if any node they cover is.
+// If r returns an error, verification fails with ErrRevocationCheck unless WithRevokerFailOpen is also given.
+// Checks cut short because the call's context is done always fail, with the context's error.
+func WithRevoker(r Revoker) Option {
+	return func(c *CredentialManager) {
+		c.revoker = r
+	}
+}
+
+// WithRevokerFailOpen makes Verify accept credentials when the Revoker returns an error, instead of rejecting them
+func WithRevokerFailOpen() Option {
+	return func(c *CredentialManager) {
+		c.revokerFailOpen = true
+	}
+}
+
+// checkRevoked consults the manager's revoker, if any
+func (c *CredentialManager) checkRevoked(ctx context.Context, authenticatedCredential *AuthenticatedCredential) error {
+	if c.revoker == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	revoked, err := c.isRevoked(ctx, authenticatedCredential.Credential)
+	if err != nil {
+		// A check that was cut short says nothing about the credential, so it isn't failed open
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if c.revokerFailOpen {
+			return nil
+		}
+		return fmt.Errorf("%w: %w", ErrRevocationCheck, err)
+	}
+	if revoked {
+		return ErrRevoked
+	}
+	return nil
+}
+
+// isRevoked asks the manager's revoker about the credential's node and every other node it bundles,
+// then about the credential itself
+func (c *CredentialManager) isRevoked(ctx context.Context, credential *pb.Credential) (bool, error) {
+	revoked, err := c.isNodeRevoked(ctx, credential.GetNodeId(), credential)
+	for _, nodeID := range credential.GetBundleNodeIds() {
+		if err != nil || revoked {
+			break
+		}
+		if !bytes.Equal(nodeID, credential.GetNodeId()) {
+			revoked, err = c.isNodeRevoked(ctx, nodeID, credential)
+		}
+	}
+	if err != nil || revoked || len(credential.GetCredentialId()) == 0 {
+		return revoked, err
+	}
+	if cr, ok := c.revoker.(ContextCredentialRevoker); ok {
+		return cr.IsCredentialRevokedContext(ctx, credential.GetCredentialId())
+	}
+	if cr, ok := c.revoker.(CredentialRevoker); ok {
+		return cr.IsCredentialRevoked(credential.GetCredentialId())
+	}
+	return false, nil
+}
+
+// isNodeRevoked asks the manager's revoker about the credential's issuance to nodeID
+func (c *CredentialManager) isNodeRevoked(ctx context.Context, nodeID []byte, credential *pb.Credential) (bool, error) {
+	issuedAt := time.Unix(credential.GetTimestamp(), 0)
+	if r, ok := c.revoker.(ContextRevoker); ok {
+		return r.IsRevokedContext(ctx, nodeID, issuedAt)
+	}
+	return c.revoker.IsRevoked(nodeID, issuedAt)
+}
+
+// MemoryRevoker is an in-memory Revoker keyed by node ID, which also implements CredentialRevoker.
+// It is safe for concurrent use.
+type MemoryRevoker struct {
+	mu           sync.RWMutex
+	nodes        map[string]struct{}
+	credentials  map[string]struct{}
+	issuedBefore time.Time
+}
+
+// NewMemoryRevoker creates an empty MemoryRevoker
+func NewMemoryRevoker() *MemoryRevoker {
+	return &MemoryRevoker{
+		nodes:       make(map[string]struct{}),
+		credentials: make(map[string]struct{}),
+	}
+}
+
+// Revoke revokes every credential issued to nodeID
+func (m *MemoryRevoker) Revoke(nodeID []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodes[string(nodeID)] = struct{}{}
+}
+
+// Unrevoke undoes Revoke, returning false if nodeID wasn't revoked
+func (m *MemoryRevoker) Unrevoke(nodeID []byte) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.nodes[string(nodeID)]
+	delete(m.nodes, string(nodeID))
+	return ok
+}
+
+// RevokeCredential revokes the single credential with the given credential ID
+func (m *MemoryRevoker) RevokeCredential(credentialID []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.credentials[string(credentialID)] = struct{}{}
+}
+
+// RevokeIssuedBefore revokes every credential, for any node, issued before t.
+// Passing the zero time removes the rule.
+func (m *MemoryRevoker) RevokeIssuedBefore(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.issuedBefore = t
+}
+
+// IsRevoked implements Revoker
+func (m *MemoryRevoker) IsRevoked(nodeID []byte, issuedAt time.Time) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if issuedAt.Before(m.issuedBefore) {
+		return true, nil
+	}
+	_, ok := m.nodes[string(nodeID)]
+	return ok, nil
+}
+
+// IsCredentialRevoked implements CredentialRevoker
+func (m *MemoryRevoker) IsCredentialRevoked(credentialID []byte) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.credentials[string(credentialID)]
+	return ok, nil
+}
@@ -0,0 +1,166 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+type errRevoker struct{}
+
+func (errRevoker) IsRevoked([]byte, time.Time) (bool, error) {
+	return false, errors.New("revocation list unavailable")
+}
+
+// TestRevoker tests that revoked credentials fail verification
+func TestRevoker(t *testing.T) {
+	revoker := NewMemoryRevoker()
+	cm := NewCredentialManagerWithOptions([]byte("Revocation test secret"), nil, WithRevoker(revoker))
+
+	banned := make([]byte, 20)
+	banned[0] = 1
+	issued := time.Unix(1700000000, 0)
+	bannedCred, err := cm.Create(issued, banned, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCred, err := cm.Create(issued, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revoker.Revoke(banned)
+	if _, err := cm.Verify(bannedCred); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Expected ErrRevoked, got %v", err)
+	}
+	if _, err := cm.Verify(otherCred); err != nil {
+		t.Error(err)
+	}
+
+	if !revoker.Unrevoke(banned) {
+		t.Error("Expected Unrevoke to report the node as revoked")
+	}
+	if _, err := cm.Verify(bannedCred); err != nil {
+		t.Error(err)
+	}
+
+	revoker.RevokeIssuedBefore(issued.Add(time.Second))
+	if _, err := cm.Verify(otherCred); !errors.Is(err, ErrRevoked) {
+		t.Errorf("Expected ErrRevoked, got %v", err)
+	}
+	later, err := cm.Create(issued.Add(time.Minute), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(later); err != nil {
+		t.Error(err)
+	}
+
+	// Forgeries fail the MAC check regardless of revocation
+	later.Mac[0] ^= 1
+	if _, err := cm.Verify(later); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+// TestRevokerErrors tests that revoker errors fail closed unless configured otherwise
+func TestRevokerErrors(t *testing.T) {
+	key := []byte("Revocation test secret")
+	cred, err := NewCredentialManager(key).Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed := NewCredentialManagerWithOptions(key, nil, WithRevoker(errRevoker{}))
+	if _, err := closed.Verify(cred); !errors.Is(err, ErrRevocationCheck) || errors.Is(err, ErrRevoked) {
+		t.Errorf("Expected ErrRevocationCheck, got %v", err)
+	}
+
+	open := NewCredentialManagerWithOptions(key, nil, WithRevoker(errRevoker{}), WithRevokerFailOpen())
+	if _, err := open.Verify(cred); err != nil {
+		t.Errorf("Expected fail-open verification to succeed, got %v", err)
+	}
+}
+
+// TestRevokeCredential tests that single credentials can be revoked by credential ID
+func TestRevokeCredential(t *testing.T) {
+	revoker := NewMemoryRevoker()
+	cm := NewCredentialManagerWithOptions([]byte("Revocation test secret"), nil, WithRevoker(revoker))
+
+	revokedCred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revoker.RevokeCredential(revokedCred.Credential.CredentialId)
+	_, err = cm.Verify(revokedCred)
+	if !errors.Is(err, ErrRevoked) {
+		t.Errorf("Expected ErrRevoked, got %v", err)
+	}
+	var ve *VerificationError
+	if !errors.As(err, &ve) || ve.Fields()["credential_id"] != revokedCred.ID() {
+		t.Errorf("Expected the credential ID in the error fields, got %v", err)
+	}
+	if _, err := cm.Verify(otherCred); err != nil {
+		t.Error(err)
+	}
+}
+
+// contextRevoker records the contexts it is called with, and fails once they are done
+type contextRevoker struct {
+	nodeCtx, credentialCtx context.Context
+}
+
+func (r *contextRevoker) IsRevoked([]byte, time.Time) (bool, error) {
+	panic("IsRevoked called instead of IsRevokedContext")
+}
+
+func (r *contextRevoker) IsRevokedContext(ctx context.Context, _ []byte, _ time.Time) (bool, error) {
+	r.nodeCtx = ctx
+	return false, ctx.Err()
+}
+
+func (r *contextRevoker) IsCredentialRevokedContext(ctx context.Context, _ []byte) (bool, error) {
+	r.credentialCtx = ctx
+	return false, nil
+}
+
+type revokerCtxKey struct{}
+
+// TestContextRevoker tests that ContextRevokers get the call's context, and that cancelled checks don't fail open
+func TestContextRevoker(t *testing.T) {
+	revoker := new(contextRevoker)
+	cm := NewCredentialManagerWithOptions([]byte("Revocation test secret"), nil, WithRevoker(revoker), WithRevokerFailOpen())
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), revokerCtxKey{}, true)
+	if _, err := cm.VerifyContext(ctx, cred); err != nil {
+		t.Fatal(err)
+	}
+	if revoker.nodeCtx != ctx || revoker.credentialCtx != ctx {
+		t.Error("The revoker wasn't passed the call's context")
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+	if revoker.nodeCtx != context.Background() {
+		t.Error("Verify didn't pass context.Background()")
+	}
+
+	// The revoker fails with the context's error, which is reported despite WithRevokerFailOpen
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := cm.checkRevoked(ctx, cred); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// Limits on credential scope names, so tokens can't balloon
+const (
+	MaxScopeNames      = 16
+	MaxScopeNameLength = 32
+)
+
+var ErrInvalidScopeNames = errors.New("invalid credential scope names")
+
+// validateScopeNames enforces the scope name limits. Names must be non-empty and unique.
+func validateScopeNames(names []string) error {
+	if len(names) > MaxScopeNames {
+		return fmt.Errorf("%w: %d names, at most %d allowed", ErrInvalidScopeNames, len(names), MaxScopeNames)
+	}
+	for i, name := range names {
+		if name == "" {
+			return fmt.Errorf("%w: empty name", ErrInvalidScopeNames)
+		}
+		if len(name) > MaxScopeNameLength {
+			return fmt.Errorf("%w: %.32q... is longer than %d bytes", ErrInvalidScopeNames, name, MaxScopeNameLength)
+		}
+		if slices.Contains(names[:i], name) {
+			return fmt.Errorf("%w: %q is repeated", ErrInvalidScopeNames, name)
+		}
+	}
+	return nil
+}
+
+// MissingScopeNamesError is returned by Verify for credentials lacking some of the scope names required with
+// WithRequiredScopeNames. It matches ErrMissingScopes.
+type MissingScopeNamesError struct {
+	Missing []string
+}
+
+func (e *MissingScopeNamesError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrMissingScopes, strings.Join(e.Missing, ", "))
+}
+
+func (e *MissingScopeNamesError) Is(target error) bool {
+	return target == ErrMissingScopes
+}
+
+// WithRequiredScopeNames makes Verify reject credentials which don't grant every one of names with a
+// *MissingScopeNamesError. Unlike the Scope bitmask, credentials without any scope names are rejected too.
+func WithRequiredScopeNames(names ...string) Option {
+	return func(c *CredentialManager) {
+		c.requiredScopeNames = append([]string(nil), names...)
+	}
+}
+
+// CreateWithScopeNames is like Create, but the credential grants the named scopes, e.g. "read" or "submit",
+// which are covered by the MAC. Names are stored sorted, and exceeding the limits, repeating a name or
+// an empty name fails with ErrInvalidScopeNames.
+func (c *CredentialManager) CreateWithScopeNames(timestamp time.Time, nodeID []byte, OperatorType OperatorType, names ...string) (*AuthenticatedCredential, error) {
+	return c.create(timestamp, nodeID, OperatorType, nil, func(credential *pb.Credential) error {
+		if err := validateScopeNames(names); err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		credential.ScopeNames = append([]string(nil), names...)
+		slices.Sort(credential.ScopeNames)
+		return nil
+	})
+}
+
+// ScopeNames returns the named scopes the credential grants
+func (ac *AuthenticatedCredential) ScopeNames() []string {
+	return ac.Credential.GetScopeNames()
+}
+
+// HasScopeName reports whether the credential grants the scope named name
+func (ac *AuthenticatedCredential) HasScopeName(name string) bool {
+	return slices.Contains(ac.ScopeNames(), name)
+}
+
+// checkScopeNames enforces the scope names required with WithRequiredScopeNames
+func (c *CredentialManager) checkScopeNames(authenticatedCredential *AuthenticatedCredential) error {
+	var missing []string
+	for _, name := range c.requiredScopeNames {
+		if !authenticatedCredential.HasScopeName(name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingScopeNamesError{Missing: missing}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestScopeNames tests that scope names are covered by the MAC, survive encoding, and are enforced by WithRequiredScopeNames
+func TestScopeNames(t *testing.T) {
+	key := []byte("Scope name test secret")
+	cm := NewCredentialManager(key)
+	strict := NewCredentialManagerWithOptions(key, nil, WithRequiredScopeNames("read", "submit"))
+
+	scoped, err := cm.CreateWithScopeNames(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, "submit", "read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(scoped.ScopeNames(), []string{"read", "submit"}) {
+		t.Errorf("Expected sorted scope names, got %v", scoped.ScopeNames())
+	}
+	if !scoped.HasScopeName("read") || !scoped.HasScopeName("submit") || scoped.HasScopeName("admin") {
+		t.Errorf("Unexpected HasScopeName results for %v", scoped.ScopeNames())
+	}
+
+	data, err := json.Marshal(scoped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AuthenticatedCredential
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := strict.Verify(&decoded); err != nil {
+		t.Errorf("Expected the decoded credential to verify, got %v", err)
+	}
+	password, err := scoped.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := strict.VerifyFromBasicAuth(scoped.Base64URLEncodeUsername(), password); err != nil {
+		t.Errorf("Expected basic auth to verify, got %v", err)
+	}
+
+	claims, err := cm.VerifyClaims(scoped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(claims.ScopeNames, []string{"read", "submit"}) {
+		t.Errorf("Unexpected claims scope names %v", claims.ScopeNames)
+	}
+
+	reissued, err := cm.Reissue(scoped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(reissued.ScopeNames(), scoped.ScopeNames()) {
+		t.Errorf("Expected the scope names to carry over, got %v", reissued.ScopeNames())
+	}
+
+	partial, err := cm.CreateWithScopeNames(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, "read", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unscoped, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		cred    *AuthenticatedCredential
+		missing []string
+	}{
+		{partial, []string{"submit"}},
+		{unscoped, []string{"read", "submit"}},
+	} {
+		_, err := strict.Verify(tc.cred)
+		var missing *MissingScopeNamesError
+		if !errors.Is(err, ErrMissingScopes) || !errors.As(err, &missing) || !slices.Equal(missing.Missing, tc.missing) {
+			t.Errorf("Expected missing scope names %v, got %v", tc.missing, err)
+		}
+	}
+
+	// Plain Verify doesn't require scope names, but they are covered by the MAC
+	if _, err := cm.Verify(unscoped); err != nil {
+		t.Fatal(err)
+	}
+	decoded.Credential.ScopeNames = append(decoded.Credential.ScopeNames, "admin")
+	if _, err := cm.Verify(&decoded); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected adding a scope name to break the MAC, got %v", err)
+	}
+}
+
+// TestScopeNameLimits tests that scope names exceeding the limits are rejected when created and decoded
+func TestScopeNameLimits(t *testing.T) {
+	cm := NewCredentialManager([]byte("Scope name test secret"))
+
+	tooMany := make([]string, MaxScopeNames+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("s", i+1)
+	}
+	testCases := []struct {
+		name  string
+		names []string
+		ok    bool
+	}{
+		{"None", nil, true},
+		{"Max", tooMany[:MaxScopeNames], true},
+		{"MaxLength", []string{strings.Repeat("s", MaxScopeNameLength)}, true},
+		{"TooMany", tooMany, false},
+		{"TooLong", []string{strings.Repeat("s", MaxScopeNameLength+1)}, false},
+		{"Empty", []string{"read", ""}, false},
+		{"Repeated", []string{"read", "submit", "read"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := cm.CreateWithScopeNames(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, tc.names...)
+			if tc.ok != (err == nil) || (!tc.ok && !errors.Is(err, ErrInvalidScopeNames)) {
+				t.Errorf("Unexpected Create result %v", err)
+			}
+
+			// Hand-craft a credential carrying the names, as a client could
+			crafted, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+			if err != nil {
+				t.Fatal(err)
+			}
+			crafted.Credential.ScopeNames = tc.names
+
+			data, err := json.Marshal(crafted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fromJSON AuthenticatedCredential
+			err = json.Unmarshal(data, &fromJSON)
+			if tc.ok != (err == nil) || (!tc.ok && !errors.Is(err, ErrInvalidScopeNames)) {
+				t.Errorf("Unexpected JSON decoding result %v", err)
+			}
+
+			text, err := crafted.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fromText AuthenticatedCredential
+			err = fromText.UnmarshalText(text)
+			if tc.ok != (err == nil) || (!tc.ok && !errors.Is(err, ErrInvalidScopeNames)) {
+				t.Errorf("Unexpected text decoding result %v", err)
+			}
+		})
+	}
+}
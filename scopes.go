@@ -0,0 +1,101 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// Scope is a bitmask of the APIs a credential grants access to
+type Scope uint64
+
+const (
+	// ScopeBeaconAPI grants access to the proxied beacon node API
+	ScopeBeaconAPI Scope = 1 << iota
+	// ScopeExecutionAPI grants access to the proxied execution client API
+	ScopeExecutionAPI
+	// ScopeMetrics grants access to metrics
+	ScopeMetrics
+)
+
+var scopeNames = []string{"beacon_api", "execution_api", "metrics"}
+
+// String lists the names of the scopes in s, separated by "|". Undefined bits are shown by their position.
+func (s Scope) String() string {
+	if s == 0 {
+		return "none"
+	}
+	var names []string
+	for rest := uint64(s); rest != 0; rest &= rest - 1 {
+		bit := bits.TrailingZeros64(rest)
+		if bit < len(scopeNames) {
+			names = append(names, scopeNames[bit])
+		} else {
+			names = append(names, fmt.Sprintf("bit%d", bit))
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+var ErrMissingScopes = errors.New("credential is missing required scopes")
+
+// MissingScopesError is returned by VerifyWithRequiredScopes for credentials lacking some required scopes.
+// It matches ErrMissingScopes.
+type MissingScopesError struct {
+	Missing Scope
+}
+
+func (e *MissingScopesError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrMissingScopes, e.Missing)
+}
+
+func (e *MissingScopesError) Is(target error) bool {
+	return target == ErrMissingScopes
+}
+
+// WithLegacyScopes makes VerifyWithRequiredScopes treat credentials without any scopes, such as those issued before
+// scopes existed, as having all of them
+func WithLegacyScopes() Option {
+	return func(c *CredentialManager) {
+		c.legacyScopes = true
+	}
+}
+
+// CreateWithScopes is like Create, but the credential grants only scopes, which is covered by the MAC
+func (c *CredentialManager) CreateWithScopes(timestamp time.Time, nodeID []byte, OperatorType OperatorType, scopes Scope) (*AuthenticatedCredential, error) {
+	return c.create(timestamp, nodeID, OperatorType, nil, func(credential *pb.Credential) error {
+		credential.Scopes = uint64(scopes)
+		return nil
+	})
+}
+
+// Scopes returns the scopes granted by the credential
+func (ac *AuthenticatedCredential) Scopes() Scope {
+	return Scope(ac.Credential.GetScopes())
+}
+
+// HasScope reports whether the credential grants every scope in s
+func (ac *AuthenticatedCredential) HasScope(s Scope) bool {
+	return ac.Scopes()&s == s
+}
+
+// VerifyWithRequiredScopes is like Verify, but additionally requires the credential to grant every scope in scopes,
+// failing with a *MissingScopesError otherwise
+func (c *CredentialManager) VerifyWithRequiredScopes(authenticatedCredential *AuthenticatedCredential, scopes Scope) (*ID, error) {
+	id, err := c.Verify(authenticatedCredential)
+	if err != nil {
+		return nil, err
+	}
+	granted := authenticatedCredential.Scopes()
+	if granted == 0 && c.legacyScopes {
+		return id, nil
+	}
+	if missing := scopes &^ granted; missing != 0 {
+		return nil, newVerificationError(authenticatedCredential, &MissingScopesError{Missing: missing})
+	}
+	return id, nil
+}
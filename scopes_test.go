@@ -0,0 +1,98 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestScopes tests that scopes are covered by the MAC, survive JSON, and are enforced by VerifyWithRequiredScopes
+func TestScopes(t *testing.T) {
+	key := []byte("Scope test secret")
+	cm := NewCredentialManager(key)
+	legacy := NewCredentialManagerWithOptions(key, nil, WithLegacyScopes())
+
+	scoped, err := cm.CreateWithScopes(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO, ScopeBeaconAPI|ScopeMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unscoped, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !scoped.HasScope(ScopeBeaconAPI) || !scoped.HasScope(ScopeBeaconAPI|ScopeMetrics) || scoped.HasScope(ScopeExecutionAPI) {
+		t.Errorf("Unexpected HasScope results for %s", scoped.Scopes())
+	}
+
+	data, err := json.Marshal(scoped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AuthenticatedCredential
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		verifier *CredentialManager
+		cred     *AuthenticatedCredential
+		required Scope
+		missing  Scope
+	}{
+		{"Granted", cm, &decoded, ScopeBeaconAPI, 0},
+		{"AllGranted", cm, &decoded, ScopeBeaconAPI | ScopeMetrics, 0},
+		{"NoneRequired", cm, unscoped, 0, 0},
+		{"Missing", cm, &decoded, ScopeExecutionAPI | ScopeBeaconAPI, ScopeExecutionAPI},
+		{"UnscopedStrict", cm, unscoped, ScopeMetrics, ScopeMetrics},
+		{"UnscopedLegacy", legacy, unscoped, ScopeMetrics | ScopeExecutionAPI, 0},
+		{"ScopedLegacy", legacy, &decoded, ScopeExecutionAPI, ScopeExecutionAPI},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.verifier.VerifyWithRequiredScopes(tc.cred, tc.required)
+			if tc.missing == 0 {
+				if err != nil {
+					t.Errorf("Expected success, got %v", err)
+				}
+				return
+			}
+			var missing *MissingScopesError
+			if !errors.Is(err, ErrMissingScopes) || !errors.As(err, &missing) || missing.Missing != tc.missing {
+				t.Errorf("Expected missing scopes %s, got %v", tc.missing, err)
+			}
+		})
+	}
+
+	// Plain Verify is scope-agnostic
+	if _, err := cm.Verify(&decoded); err != nil {
+		t.Error(err)
+	}
+	decoded.Credential.Scopes |= uint64(ScopeExecutionAPI)
+	if _, err := cm.Verify(&decoded); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+// TestScopeString tests the names of scopes
+func TestScopeString(t *testing.T) {
+	testCases := []struct {
+		scope    Scope
+		expected string
+	}{
+		{0, "none"},
+		{ScopeBeaconAPI, "beacon_api"},
+		{ScopeExecutionAPI | ScopeMetrics, "execution_api|metrics"},
+		{ScopeBeaconAPI | 1<<63, "beacon_api|bit63"},
+	}
+	for _, tc := range testCases {
+		if tc.scope.String() != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, tc.scope.String())
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package credentials
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"google.golang.org/protobuf/proto"
+)
+
+// Sealed tokens are laid out as
+//
+//	version (1) | nonce (24) | XChaCha20-Poly1305 ciphertext of the marshaled AuthenticatedCredential
+//
+// with the version byte as additional data, and base64url encoded.
+const (
+	sealVersion    = 1
+	sealKeyInfo    = "rescue-credential-seal-v1"
+	sealHeaderSize = 1 + chacha20poly1305.NonceSizeX
+)
+
+var ErrInvalidSealedToken = errors.New("invalid sealed credential token")
+
+// sealer derives the AEAD used to seal credentials from a manager secret
+func sealer(key []byte) cipher.AEAD {
+	derived := make([]byte, chacha20poly1305.KeySize)
+	defer zero(derived)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(sealKeyInfo)), derived); err != nil {
+		// hkdf can only fail when asked for more than 255 blocks of output
+		panic(err)
+	}
+	aead, err := chacha20poly1305.NewX(derived)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+// sealers returns the AEAD to seal with, followed by any others that should be accepted when opening
+func (c *CredentialManager) sealers() ([]cipher.AEAD, error) {
+	if c.ring == nil {
+		if len(c.sealAEADs) == 0 {
+			return nil, ErrNoActiveKey
+		}
+		return c.sealAEADs, nil
+	}
+
+	now := c.now()
+	signing := c.ring.signingKey(now)
+	if signing == nil {
+		return nil, ErrNoActiveKey
+	}
+	out := []cipher.AEAD{sealer(signing.entry.Key)}
+	for _, k := range c.ring.verificationKeys(now) {
+		if k != signing {
+			out = append(out, sealer(k.entry.Key))
+		}
+	}
+	return out, nil
+}
+
+// Seal encrypts cred so that its node ID and other fields are only readable with the manager's secret.
+// The encryption key is derived from the primary secret with HKDF, and a random nonce is used for every call.
+func (c *CredentialManager) Seal(cred *AuthenticatedCredential) (string, error) {
+	aeads, err := c.sealers()
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := proto.Marshal(cred.Pb())
+	if err != nil {
+		return "", errors.Join(err, SerializationError)
+	}
+
+	aead := aeads[0]
+	out := make([]byte, sealHeaderSize, sealHeaderSize+len(plaintext)+aead.Overhead())
+	out[0] = sealVersion
+	if _, err := io.ReadFull(rand.Reader, out[1:sealHeaderSize]); err != nil {
+		return "", err
+	}
+	out = aead.Seal(out, out[1:sealHeaderSize], plaintext, out[:1])
+
+	return base64.URLEncoding.EncodeToString(out), nil
+}
+
+// Open decrypts a token produced by Seal and verifies the credential inside it.
+// Tampered tokens are rejected by the AEAD before the credential is parsed.
+func (c *CredentialManager) Open(token string) (*AuthenticatedCredential, error) {
+	sealed, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidSealedToken, err)
+	}
+	if len(sealed) < sealHeaderSize {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidSealedToken)
+	}
+	if sealed[0] != sealVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSealedToken, sealed[0])
+	}
+
+	aeads, err := c.sealers()
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, ciphertext := sealed[1:sealHeaderSize], sealed[sealHeaderSize:]
+	var plaintext []byte
+	for _, aead := range aeads {
+		plaintext, err = aead.Open(nil, nonce, ciphertext, sealed[:1])
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: decryption failed", ErrInvalidSealedToken)
+	}
+
+	cred := new(AuthenticatedCredential)
+	if err := proto.Unmarshal(plaintext, cred.Pb()); err != nil {
+		return nil, errors.Join(ErrInvalidSealedToken, err)
+	}
+	if _, err := c.Verify(cred); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
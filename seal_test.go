@@ -0,0 +1,149 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestSealOpen tests that sealed credentials round-trip, hide their contents, and use fresh nonces
+func TestSealOpen(t *testing.T) {
+	cm := NewCredentialManager([]byte("Seal test secret"))
+	nodeID, err := hex.DecodeString("1234567890123456789012345678901234567890")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := cm.Create(time.Now(), nodeID, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := cm.Seal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token2, err := cm.Seal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == token2 {
+		t.Error("Sealing twice produced the same token")
+	}
+
+	raw, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw[0] != sealVersion {
+		t.Errorf("Unexpected version byte %d", raw[0])
+	}
+	if bytes.Contains(raw, nodeID) {
+		t.Error("Sealed token contains the plaintext node ID")
+	}
+
+	opened, err := cm.Open(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened.Credential.NodeId, nodeID) || !bytes.Equal(opened.Mac, cred.Mac) {
+		t.Error("Opened credential differs from the sealed one")
+	}
+
+	// A manager holding the secret as an extra can open it too
+	if _, err := NewCredentialManager([]byte("Another secret"), []byte("Seal test secret")).Open(token); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestOpenInvalid tests that garbage, tampered, and foreign tokens are rejected with ErrInvalidSealedToken
+func TestOpenInvalid(t *testing.T) {
+	cm := NewCredentialManager([]byte("Seal test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := cm.Seal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.URLEncoding.DecodeString(token)
+
+	tampered := append([]byte(nil), raw...)
+	tampered[len(tampered)-1] ^= 1
+	wrongVersion := append([]byte(nil), raw...)
+	wrongVersion[0] = 2
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{"NotBase64", "invalid!"},
+		{"TooShort", base64.URLEncoding.EncodeToString(raw[:10])},
+		{"Tampered", base64.URLEncoding.EncodeToString(tampered)},
+		{"WrongVersion", base64.URLEncoding.EncodeToString(wrongVersion)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := cm.Open(tc.token); !errors.Is(err, ErrInvalidSealedToken) {
+				t.Errorf("Expected ErrInvalidSealedToken, got %v", err)
+			}
+		})
+	}
+
+	if _, err := NewCredentialManager([]byte("Another secret")).Open(token); !errors.Is(err, ErrInvalidSealedToken) {
+		t.Errorf("Expected ErrInvalidSealedToken for a foreign key, got %v", err)
+	}
+}
+
+// TestOpenUnverifiable tests that a correctly sealed credential with a bad MAC fails verification
+func TestOpenUnverifiable(t *testing.T) {
+	cm := NewCredentialManager([]byte("Seal test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred.Credential.OperatorType = pb.OperatorType_OT_ROCKETPOOL
+	token, err := cm.Seal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Open(token); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+// TestSealKeyRing tests sealing with a KeyRing backed manager across a rotation
+func TestSealKeyRing(t *testing.T) {
+	now := time.Now()
+	ring, err := NewKeyRing(KeyEntry{ID: "old", Key: []byte("old key"), NotBefore: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := NewCredentialManagerFromKeyRing(ring)
+	cred, err := cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := cm.Seal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ring.Add(KeyEntry{ID: "new", Key: []byte("new key"), NotBefore: now.Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Open(token); err != nil {
+		t.Error(err)
+	}
+
+	ring.Remove("old")
+	ring.Remove("new")
+	if _, err := cm.Seal(cred); !errors.Is(err, ErrNoActiveKey) {
+		t.Errorf("Expected ErrNoActiveKey, got %v", err)
+	}
+}
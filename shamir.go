@@ -0,0 +1,206 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Shares are laid out as
+//
+//	version (1) | split ID (4) | threshold (1) | index (1) | share bytes (len(key)) | checksum (4)
+//
+// where the checksum is a truncated, domain-separated SHA-256 of everything before it.
+const (
+	shareVersion       = 1
+	shareSplitIDLength = 4
+	shareChecksumLen   = 4
+	shareHeaderLength  = 1 + shareSplitIDLength + 1 + 1
+	shareDomain        = "rescue-credential-share"
+)
+
+var (
+	ErrInvalidSplitParams = errors.New("invalid key split parameters")
+	ErrInvalidShare       = errors.New("invalid key share")
+	ErrShareMismatch      = errors.New("key shares are from different splits")
+	ErrInsufficientShares = errors.New("not enough key shares")
+)
+
+// GF(256) arithmetic with the AES reducing polynomial x^8 + x^4 + x^3 + x + 1, using 3 as the generator
+var gfExp, gfLog = func() (exp [510]byte, log [256]byte) {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i] = x
+		exp[i+255] = x
+		log[x] = byte(i)
+		// Multiply by the generator: x*3 = x*2 ^ x
+		hi := x & 0x80
+		x2 := x << 1
+		if hi != 0 {
+			x2 ^= 0x1b
+		}
+		x = x2 ^ x
+	}
+	return
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+func shareChecksum(body []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(shareDomain))
+	h.Write(body)
+	return h.Sum(nil)[:shareChecksumLen]
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// SplitKey splits key into n shares using Shamir's secret sharing over GF(256), such that any k of them
+// recombine into key with CombineKey and fewer than k reveal nothing about it.
+func SplitKey(key []byte, n, k int) ([][]byte, error) {
+	return splitKey(rand.Reader, key, n, k)
+}
+
+func splitKey(random io.Reader, key []byte, n, k int) ([][]byte, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%w: key is empty", ErrInvalidSplitParams)
+	}
+	if k < 2 || k > n || n > 255 {
+		return nil, fmt.Errorf("%w: need 2 <= k <= n <= 255, got n=%d k=%d", ErrInvalidSplitParams, n, k)
+	}
+
+	splitID := make([]byte, shareSplitIDLength)
+	if _, err := io.ReadFull(random, splitID); err != nil {
+		return nil, err
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		share := make([]byte, shareHeaderLength, shareHeaderLength+len(key)+shareChecksumLen)
+		share[0] = shareVersion
+		copy(share[1:], splitID)
+		share[1+shareSplitIDLength] = byte(k)
+		share[2+shareSplitIDLength] = byte(i + 1)
+		shares[i] = share
+	}
+
+	// One random polynomial of degree k-1 per key byte, with the key byte as the constant term
+	coefficients := make([]byte, k)
+	defer zero(coefficients)
+	for _, b := range key {
+		coefficients[0] = b
+		if _, err := io.ReadFull(random, coefficients[1:]); err != nil {
+			return nil, err
+		}
+		for i := range shares {
+			x := byte(i + 1)
+			// Horner's method
+			var y byte
+			for c := k - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coefficients[c]
+			}
+			shares[i] = append(shares[i], y)
+		}
+	}
+
+	for i, share := range shares {
+		shares[i] = append(share, shareChecksum(share)...)
+	}
+	return shares, nil
+}
+
+// CombineKey recovers a key from at least k of the shares produced by SplitKey.
+// Corrupted shares, shares from different splits or with different thresholds, shares with a threshold below 2, and
+// duplicate shares are rejected.
+func CombineKey(shares [][]byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrInsufficientShares
+	}
+
+	first := shares[0]
+	var threshold int
+	xs := make([]byte, 0, len(shares))
+	for i, share := range shares {
+		if len(share) <= shareHeaderLength+shareChecksumLen {
+			return nil, fmt.Errorf("%w: share %d is too short", ErrInvalidShare, i)
+		}
+		body := share[:len(share)-shareChecksumLen]
+		if !bytes.Equal(shareChecksum(body), share[len(body):]) {
+			return nil, fmt.Errorf("%w: share %d failed its checksum", ErrInvalidShare, i)
+		}
+		if share[0] != shareVersion {
+			return nil, fmt.Errorf("%w: share %d has unsupported version %d", ErrInvalidShare, i, share[0])
+		}
+		// The header up to the index holds the version, split ID and threshold, so every share must agree on them
+		if !bytes.Equal(share[:shareHeaderLength-1], first[:shareHeaderLength-1]) || len(share) != len(first) {
+			return nil, fmt.Errorf("%w: share %d", ErrShareMismatch, i)
+		}
+		if i == 0 {
+			// SplitKey never produces a threshold below 2, with which one share or none would reveal the key
+			threshold = int(share[1+shareSplitIDLength])
+			if threshold < 2 {
+				return nil, fmt.Errorf("%w: share %d has threshold %d", ErrInvalidShare, i, threshold)
+			}
+		}
+
+		x := share[shareHeaderLength-1]
+		if x == 0 || bytes.IndexByte(xs, x) >= 0 {
+			return nil, fmt.Errorf("%w: share %d has a duplicate or zero index", ErrInvalidShare, i)
+		}
+		xs = append(xs, x)
+	}
+
+	if len(shares) < threshold {
+		return nil, fmt.Errorf("%w: need %d, got %d", ErrInsufficientShares, threshold, len(shares))
+	}
+
+	// Lagrange interpolation at x=0, using exactly threshold shares
+	xs = xs[:threshold]
+	keyLength := len(first) - shareHeaderLength - shareChecksumLen
+	key := make([]byte, keyLength)
+	for i, xi := range xs {
+		// basis = prod(xj / (xj - xi)) for j != i, where subtraction is xor in GF(256)
+		basis := byte(1)
+		for j, xj := range xs {
+			if i != j {
+				basis = gfMul(basis, gfDiv(xj, xj^xi))
+			}
+		}
+		y := shares[i][shareHeaderLength : shareHeaderLength+keyLength]
+		for b := range key {
+			key[b] ^= gfMul(y[b], basis)
+		}
+	}
+	return key, nil
+}
+
+// NewCredentialManagerFromShares combines shares produced by SplitKey into the primary key of a new CredentialManager.
+// The manager keeps only the hash and cipher states derived from the recombined key, which is zeroed once they are.
+func NewCredentialManagerFromShares(shares [][]byte, opts ...Option) (*CredentialManager, error) {
+	key, err := CombineKey(shares)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+
+	return NewCredentialManagerWithOptions(key, nil, opts...), nil
+}
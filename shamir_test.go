@@ -0,0 +1,165 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestGF256 sanity-checks the field arithmetic used for secret sharing
+func TestGF256(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			p := gfMul(byte(a), byte(b))
+			if gfDiv(p, byte(b)) != byte(a) {
+				t.Fatalf("(%d * %d) / %d != %d", a, b, b, a)
+			}
+		}
+	}
+	// Known product from FIPS-197
+	if gfMul(0x57, 0x83) != 0xc1 {
+		t.Errorf("0x57 * 0x83 = %#x, expected 0xc1", gfMul(0x57, 0x83))
+	}
+}
+
+// TestSplitCombineKey tests that any k shares recombine into the original key
+func TestSplitCombineKey(t *testing.T) {
+	key := []byte("Curiouser and curiouser, cried Alice")
+
+	testCases := []struct{ n, k int }{
+		{2, 2},
+		{3, 2},
+		{5, 3},
+		{255, 255},
+	}
+
+	for _, tc := range testCases {
+		shares, err := SplitKey(key, tc.n, tc.k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(shares) != tc.n {
+			t.Fatalf("Expected %d shares, got %d", tc.n, len(shares))
+		}
+
+		// Every window of k consecutive shares, and all of them at once
+		for start := 0; start+tc.k <= tc.n; start++ {
+			combined, err := CombineKey(shares[start : start+tc.k])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(combined, key) {
+				t.Fatalf("n=%d k=%d: shares %d..%d recombined to the wrong key", tc.n, tc.k, start, start+tc.k)
+			}
+		}
+		combined, err := CombineKey(shares)
+		if err != nil || !bytes.Equal(combined, key) {
+			t.Fatalf("n=%d k=%d: all shares failed to recombine: %v", tc.n, tc.k, err)
+		}
+
+		if _, err := CombineKey(shares[:tc.k-1]); !errors.Is(err, ErrInsufficientShares) {
+			t.Errorf("n=%d k=%d: expected ErrInsufficientShares, got %v", tc.n, tc.k, err)
+		}
+	}
+}
+
+// TestSplitKeyInvalidParams tests that nonsensical splits are refused
+func TestSplitKeyInvalidParams(t *testing.T) {
+	testCases := []struct {
+		key  []byte
+		n, k int
+	}{
+		{nil, 3, 2},
+		{[]byte("key"), 3, 1},
+		{[]byte("key"), 2, 3},
+		{[]byte("key"), 256, 2},
+	}
+	for _, tc := range testCases {
+		if _, err := SplitKey(tc.key, tc.n, tc.k); !errors.Is(err, ErrInvalidSplitParams) {
+			t.Errorf("SplitKey(%q, %d, %d): expected ErrInvalidSplitParams, got %v", tc.key, tc.n, tc.k, err)
+		}
+	}
+}
+
+// TestCombineKeyBadShares tests that corrupted, duplicated, and mixed shares are detected
+func TestCombineKeyBadShares(t *testing.T) {
+	key := []byte("We're all mad here")
+	shares, err := SplitKey(key, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := SplitKey(key, 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupted := append([]byte(nil), shares[1]...)
+	corrupted[shareHeaderLength] ^= 1
+
+	wrongVersion := append([]byte(nil), shares[1]...)
+	wrongVersion[0] = 2
+	wrongVersion = append(wrongVersion[:len(wrongVersion)-shareChecksumLen], shareChecksum(wrongVersion[:len(wrongVersion)-shareChecksumLen])...)
+
+	// The checksum isn't secret, so anyone can forge a share's threshold
+	withThreshold := func(share []byte, k byte) []byte {
+		body := append([]byte(nil), share[:len(share)-shareChecksumLen]...)
+		body[1+shareSplitIDLength] = k
+		return append(body, shareChecksum(body)...)
+	}
+
+	testCases := []struct {
+		name     string
+		shares   [][]byte
+		expected error
+	}{
+		{"None", nil, ErrInsufficientShares},
+		{"ThresholdZero", [][]byte{withThreshold(shares[0], 0)}, ErrInvalidShare},
+		{"ThresholdOne", [][]byte{withThreshold(shares[0], 1)}, ErrInvalidShare},
+		{"MixedThresholds", [][]byte{shares[0], withThreshold(shares[1], 3), withThreshold(shares[2], 3)}, ErrShareMismatch},
+		{"Corrupted", [][]byte{shares[0], corrupted}, ErrInvalidShare},
+		{"Truncated", [][]byte{shares[0], shares[1][:5]}, ErrInvalidShare},
+		{"WrongVersion", [][]byte{shares[0], wrongVersion}, ErrInvalidShare},
+		{"Duplicate", [][]byte{shares[0], shares[0]}, ErrInvalidShare},
+		{"MixedSplits", [][]byte{shares[0], other[1]}, ErrShareMismatch},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := CombineKey(tc.shares); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+// TestCredentialManagerFromShares tests that a manager built from shares is equivalent to one built from the key
+func TestCredentialManagerFromShares(t *testing.T) {
+	key := []byte("Off with their heads!")
+	shares, err := SplitKey(key, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm, err := NewCredentialManagerFromShares(shares[1:4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cm.ID().Equals(idFromKey(key)) {
+		t.Fatal("Manager built from shares has an unexpected ID")
+	}
+
+	cred, err := NewCredentialManager(key).Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := NewCredentialManagerFromShares(shares[:2]); !errors.Is(err, ErrInsufficientShares) {
+		t.Errorf("Expected ErrInsufficientShares, got %v", err)
+	}
+}
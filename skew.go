@@ -0,0 +1,58 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultClockSkew is how far in the future Verify accepts credential timestamps, unless changed with WithClockSkew
+const DefaultClockSkew = 5 * time.Minute
+
+var ErrTimestampInFuture = errors.New("credential timestamp is in the future")
+
+// TimestampInFutureError is returned by Verify for credentials dated further ahead of the clock than the allowed skew.
+// It matches ErrTimestampInFuture.
+type TimestampInFutureError struct {
+	Timestamp time.Time
+	// Delta is how far ahead of the clock the timestamp is
+	Delta time.Duration
+}
+
+func (e *TimestampInFutureError) Error() string {
+	return fmt.Sprintf("%v: %s is %s ahead", ErrTimestampInFuture, e.Timestamp.UTC().Format(time.RFC3339), e.Delta)
+}
+
+func (e *TimestampInFutureError) Is(target error) bool {
+	return target == ErrTimestampInFuture
+}
+
+// WithClockSkew sets how far in the future Verify accepts credential timestamps, to allow for clients with slightly fast clocks.
+// A negative skew disables the check.
+func WithClockSkew(skew time.Duration) Option {
+	return func(c *CredentialManager) {
+		c.clockSkew = skew
+		c.clockSkewSet = true
+	}
+}
+
+// checkTimestamp fails with a *TimestampInFutureError if the credential is dated too far ahead of the clock
+func (c *CredentialManager) checkTimestamp(authenticatedCredential *AuthenticatedCredential) error {
+	skew := DefaultClockSkew
+	if c.clockSkewSet {
+		skew = c.clockSkew
+	}
+	return c.checkTimestampSkew(authenticatedCredential, skew)
+}
+
+// checkTimestampSkew is checkTimestamp with an explicit skew, which disables the check if negative
+func (c *CredentialManager) checkTimestampSkew(authenticatedCredential *AuthenticatedCredential, skew time.Duration) error {
+	if skew < 0 {
+		return nil
+	}
+	timestamp := time.Unix(authenticatedCredential.Credential.GetTimestamp(), 0)
+	if delta := timestamp.Sub(c.now()); delta > skew {
+		return &TimestampInFutureError{Timestamp: timestamp, Delta: delta}
+	}
+	return nil
+}
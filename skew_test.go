@@ -0,0 +1,60 @@
+package credentials
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestClockSkew tests that credentials dated too far in the future are rejected, and small skews allowed
+func TestClockSkew(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+
+	testCases := []struct {
+		name     string
+		opts     []Option
+		ahead    time.Duration
+		rejected bool
+	}{
+		{"Past", nil, -time.Hour, false},
+		{"Now", nil, 0, false},
+		{"WithinDefault", nil, DefaultClockSkew, false},
+		{"BeyondDefault", nil, DefaultClockSkew + time.Second, true},
+		{"Hours", nil, 3 * time.Hour, true},
+		{"WithinCustom", []Option{WithClockSkew(time.Hour)}, time.Hour, false},
+		{"BeyondCustom", []Option{WithClockSkew(time.Minute)}, 2 * time.Minute, true},
+		{"Zero", []Option{WithClockSkew(0)}, time.Second, true},
+		{"Disabled", []Option{WithClockSkew(-1)}, 1000 * time.Hour, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cm := NewCredentialManagerWithOptions([]byte("Skew test secret"), nil, append(tc.opts, WithClock(clock))...)
+			cred, err := cm.Create(now.Add(tc.ahead), make([]byte, 20), pb.OperatorType_OT_SOLO)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = cm.Verify(cred)
+			if !tc.rejected {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrTimestampInFuture) {
+				t.Fatalf("Expected ErrTimestampInFuture, got %v", err)
+			}
+			var futureErr *TimestampInFutureError
+			if !errors.As(err, &futureErr) || futureErr.Delta != tc.ahead {
+				t.Errorf("Expected a delta of %s, got %v", tc.ahead, err)
+			}
+			var verificationErr *VerificationError
+			if !errors.As(err, &verificationErr) {
+				t.Errorf("Expected a *VerificationError, got %T", err)
+			}
+		})
+	}
+}
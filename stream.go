@@ -0,0 +1,49 @@
+package credentials
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/encoding/protodelim"
+)
+
+// MaxStreamedCredentialSize bounds the size of each message read by DecodeAll, so a corrupt length prefix
+// can't trigger a huge allocation. Real credentials are a few hundred bytes at most.
+const MaxStreamedCredentialSize = 16 << 10
+
+// WriteTo implements io.WriterTo, writing the credential as a varint length-prefixed protobuf.
+// Credentials written back-to-back this way can be read with DecodeAll.
+func (ac *AuthenticatedCredential) WriteTo(w io.Writer) (int64, error) {
+	n, err := protodelim.MarshalTo(w, ac.Pb())
+	return int64(n), err
+}
+
+// DecodeAll reads varint length-prefixed credentials, as written by WriteTo, until r is exhausted.
+// Each is checked as DecodePassword checks passwords, so unknown fields are bounded by MaxUnknownFieldBytes.
+// The credentials are not verified.
+func DecodeAll(r io.Reader) ([]*AuthenticatedCredential, error) {
+	br, ok := r.(protodelim.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	opts := protodelim.UnmarshalOptions{MaxSize: MaxStreamedCredentialSize}
+
+	var out []*AuthenticatedCredential
+	for {
+		message := new(pb.AuthenticatedCredential)
+		err := opts.UnmarshalFrom(br, message)
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err == nil {
+			err = validateDecodedMessage((*AuthenticatedCredential)(message))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("credential %d: %w", len(out), err)
+		}
+		out = append(out, (*AuthenticatedCredential)(message))
+	}
+}
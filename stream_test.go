@@ -0,0 +1,102 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestDecodeAll tests that a stream written with WriteTo decodes back to the same credentials
+func TestDecodeAll(t *testing.T) {
+	cm := NewCredentialManager([]byte("Stream test secret"))
+	creds, err := cm.CreateMany(time.Now(), batchNodeIDs(5), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	for _, cred := range creds {
+		if _, err := cred.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Hide the buffer's ReadByte, to exercise the buffering path
+	decoded, err := DecodeAll(io.MultiReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(creds) {
+		t.Fatalf("Expected %d credentials, got %d", len(creds), len(decoded))
+	}
+	for i := range creds {
+		if !proto.Equal(decoded[i].Pb(), creds[i].Pb()) {
+			t.Errorf("Credential %d doesn't match", i)
+		}
+		if _, err := cm.Verify(decoded[i]); err != nil {
+			t.Error(err)
+		}
+	}
+
+	empty, err := DecodeAll(bytes.NewReader(nil))
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected no credentials from an empty stream, got %v %v", empty, err)
+	}
+}
+
+// TestDecodeAllErrors tests that truncated and oversized messages, and those DecodePassword would reject, are rejected
+func TestDecodeAllErrors(t *testing.T) {
+	cm := NewCredentialManager([]byte("Stream test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var valid bytes.Buffer
+	if _, err := cred.WriteTo(&valid); err != nil {
+		t.Fatal(err)
+	}
+
+	// Messages which decode but fail the checks DecodePassword makes
+	stream := func(modify func(*AuthenticatedCredential)) []byte {
+		modified := (*AuthenticatedCredential)(proto.Clone(cred.Pb()).(*pb.AuthenticatedCredential))
+		modify(modified)
+		var out bytes.Buffer
+		if _, err := modified.WriteTo(&out); err != nil {
+			t.Fatal(err)
+		}
+		return append(append([]byte(nil), valid.Bytes()...), out.Bytes()...)
+	}
+	unknown := stream(func(ac *AuthenticatedCredential) {
+		ac.Credential.ProtoReflect().SetUnknown(protowire.AppendBytes(protowire.AppendTag(nil, 1000, protowire.BytesType), []byte("padding")))
+	})
+	shortMAC := stream(func(ac *AuthenticatedCredential) { ac.Mac = ac.Mac[:4] })
+
+	var tooLarge *protodelim.SizeTooLargeError
+	testCases := []struct {
+		name   string
+		stream []byte
+		check  func(error) bool
+	}{
+		{"Truncated", valid.Bytes()[:valid.Len()-1], func(err error) bool { return errors.Is(err, io.ErrUnexpectedEOF) }},
+		{"HugeLength", protowire.AppendVarint(append([]byte(nil), valid.Bytes()...), 1<<40), func(err error) bool { return errors.As(err, &tooLarge) }},
+		{"InvalidProto", append(protowire.AppendVarint(nil, 3), 0xff, 0xff, 0xff), func(err error) bool { return err != nil }},
+		{"UnknownFields", unknown, func(err error) bool { return errors.Is(err, ErrUnknownFieldsTooLarge) }},
+		{"ShortMAC", shortMAC, func(err error) bool { return errors.Is(err, ErrInvalidMACLength) }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, err := DecodeAll(bytes.NewReader(tc.stream))
+			if decoded != nil || !tc.check(err) {
+				t.Errorf("Unexpected result %v %v", decoded, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// TokenDelimiter separates the username and password in the single-string token form of a credential
+const TokenDelimiter = ":"
+
+var ErrMalformedToken = errors.New("malformed credential token")
+
+// MarshalText implements encoding.TextMarshaler, producing the token form "<username>:<password>"
+func (ac *AuthenticatedCredential) MarshalText() ([]byte, error) {
+	username, err := ac.EncodeUsername()
+	if err != nil {
+		return nil, err
+	}
+	password, err := ac.Base64URLEncodePassword()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(username + TokenDelimiter + password), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing the token form produced by MarshalText
+func (ac *AuthenticatedCredential) UnmarshalText(text []byte) error {
+	username, password, found := strings.Cut(string(text), TokenDelimiter)
+	if !found || username == "" || password == "" || strings.Contains(password, TokenDelimiter) {
+		return fmt.Errorf("%w: expected <username>%s<password>", ErrMalformedToken, TokenDelimiter)
+	}
+
+	var decoded AuthenticatedCredential
+	if err := decoded.Base64URLDecode(username, password); err != nil {
+		return errors.Join(ErrMalformedToken, err)
+	}
+	ac.Pb().Reset()
+	proto.Merge(ac.Pb(), decoded.Pb())
+	return nil
+}
@@ -0,0 +1,65 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+var _ encoding.TextMarshaler = (*AuthenticatedCredential)(nil)
+var _ encoding.TextUnmarshaler = (*AuthenticatedCredential)(nil)
+
+// TestTextRoundTrip tests that the token form round-trips and still verifies
+func TestTextRoundTrip(t *testing.T) {
+	cm := NewCredentialManager([]byte("Text test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := cred.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != cred.Base64URLEncodeUsername()+":"+password {
+		t.Fatalf("Unexpected token form %s", text)
+	}
+
+	var decoded AuthenticatedCredential
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded.Mac, cred.Mac) || decoded.Credential.Timestamp != cred.Credential.Timestamp {
+		t.Error("Credential mismatch after text round-trip")
+	}
+	if _, err := cm.Verify(&decoded); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestUnmarshalTextErrors tests that garbage tokens are rejected with ErrMalformedToken
+func TestUnmarshalTextErrors(t *testing.T) {
+	testCases := []string{
+		"",
+		"no-delimiter",
+		":password",
+		"username:",
+		"a:b:c",
+		"invalid!:invalid!",
+	}
+
+	for _, tc := range testCases {
+		var ac AuthenticatedCredential
+		if err := ac.UnmarshalText([]byte(tc)); !errors.Is(err, ErrMalformedToken) {
+			t.Errorf("UnmarshalText(%q): expected ErrMalformedToken, got %v", tc, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package credentials
+
+import "time"
+
+// Operation names the CredentialManager call being timed
+type Operation string
+
+const (
+	OperationCreate Operation = "create"
+	OperationVerify Operation = "verify"
+)
+
+// TimingHook is called with the elapsed time of every Create and Verify call, and the operator type of the
+// credential involved, e.g. to feed a latency histogram
+type TimingHook func(op Operation, operatorType OperatorType, elapsed time.Duration)
+
+// WithTimingHook makes the manager report the duration of Create, CreateWithAAD, CreateWithNonce,
+// CreateWithExpiry, Verify and VerifyWithAAD calls, successful or not, to hook.
+// Without a hook, no time is measured.
+func WithTimingHook(hook TimingHook) Option {
+	return func(c *CredentialManager) {
+		c.timingHook = hook
+	}
+}
+
+// observe reports the time elapsed since start to the timing hook, which must be set
+func (c *CredentialManager) observe(op Operation, operatorType OperatorType, start time.Time) {
+	c.timingHook(op, operatorType, time.Since(start))
+}
@@ -0,0 +1,57 @@
+package credentials
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+type timing struct {
+	op           Operation
+	operatorType OperatorType
+	elapsed      time.Duration
+}
+
+// TestTimingHook tests that Create and Verify calls are timed, including failing ones
+func TestTimingHook(t *testing.T) {
+	var mu sync.Mutex
+	var timings []timing
+	hook := func(op Operation, operatorType OperatorType, elapsed time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		timings = append(timings, timing{op, operatorType, elapsed})
+	}
+	cm := NewCredentialManagerWithOptions([]byte("Timing test secret"), nil, WithTimingHook(hook))
+
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+	cred.Mac[0] ^= 1
+	if _, err := cm.Verify(cred); err == nil {
+		t.Fatal("Expected a mismatch")
+	}
+	if _, err := cm.CreateWithNonce(time.Now(), []byte("short"), pb.OperatorType_OT_ROCKETPOOL, nil); err == nil {
+		t.Fatal("Expected an invalid node ID")
+	}
+
+	expected := []timing{
+		{OperationCreate, pb.OperatorType_OT_SOLO, 0},
+		{OperationVerify, pb.OperatorType_OT_SOLO, 0},
+		{OperationVerify, pb.OperatorType_OT_SOLO, 0},
+		{OperationCreate, pb.OperatorType_OT_ROCKETPOOL, 0},
+	}
+	if len(timings) != len(expected) {
+		t.Fatalf("Expected %d timings, got %d", len(expected), len(timings))
+	}
+	for i, e := range expected {
+		if timings[i].op != e.op || timings[i].operatorType != e.operatorType || timings[i].elapsed < 0 {
+			t.Errorf("Timing %d: expected %s %s, got %+v", i, e.op, e.operatorType, timings[i])
+		}
+	}
+}
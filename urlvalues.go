@@ -0,0 +1,100 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	// DefaultUsernameParam is the default query parameter carrying the encoded username
+	DefaultUsernameParam = "u"
+	// DefaultPasswordParam is the default query parameter carrying the encoded password
+	DefaultPasswordParam = "p"
+)
+
+var ErrMissingURLParam = errors.New("missing credential query parameter")
+
+type urlValuesConfig struct {
+	usernameParam string
+	passwordParam string
+}
+
+// URLValuesOption configures FromURLValues and ToURLValues
+type URLValuesOption func(*urlValuesConfig)
+
+// WithURLParams overrides the query parameter names used for the username and password
+func WithURLParams(usernameParam, passwordParam string) URLValuesOption {
+	return func(c *urlValuesConfig) {
+		c.usernameParam = usernameParam
+		c.passwordParam = passwordParam
+	}
+}
+
+func newURLValuesConfig(opts []URLValuesOption) *urlValuesConfig {
+	cfg := &urlValuesConfig{
+		usernameParam: DefaultUsernameParam,
+		passwordParam: DefaultPasswordParam,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// urlParam returns the named parameter of v.
+// URL-safe base64 never contains '%', so any that remain after url.Values parsing
+// come from clients that percent-encoded the value twice, and are unescaped.
+func urlParam(v url.Values, name string) (string, error) {
+	value := v.Get(name)
+	if value == "" {
+		return "", fmt.Errorf("%w %q", ErrMissingURLParam, name)
+	}
+	if strings.Contains(value, "%") {
+		unescaped, err := url.QueryUnescape(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid escaping in query parameter %q: %w", name, err)
+		}
+		value = unescaped
+	}
+	return value, nil
+}
+
+// FromURLValues decodes a credential from query parameters, by default "u" for the username and "p" for the password
+func FromURLValues(v url.Values, opts ...URLValuesOption) (*AuthenticatedCredential, error) {
+	cfg := newURLValuesConfig(opts)
+
+	username, err := urlParam(v, cfg.usernameParam)
+	if err != nil {
+		return nil, err
+	}
+	password, err := urlParam(v, cfg.passwordParam)
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(AuthenticatedCredential)
+	if err := out.Base64URLDecode(username, password); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ToURLValues encodes the credential as query parameters understood by FromURLValues
+func (ac *AuthenticatedCredential) ToURLValues(opts ...URLValuesOption) (url.Values, error) {
+	cfg := newURLValuesConfig(opts)
+
+	username, err := ac.EncodeUsername()
+	if err != nil {
+		return nil, err
+	}
+	password, err := ac.Base64URLEncodePassword()
+	if err != nil {
+		return nil, err
+	}
+	out := url.Values{}
+	out.Set(cfg.usernameParam, username)
+	out.Set(cfg.passwordParam, password)
+	return out, nil
+}
@@ -0,0 +1,116 @@
+package credentials
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestURLValuesRoundTrip tests that credentials survive being carried in a query string
+func TestURLValuesRoundTrip(t *testing.T) {
+	cm := NewCredentialManager([]byte("URL test secret"))
+	nodeID := make([]byte, 20)
+	nodeID[3] = 0xfb
+	cred, err := cm.Create(time.Unix(1700000000, 0), nodeID, pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []URLValuesOption
+		username string
+	}{
+		{"Default", nil, "u"},
+		{"CustomParams", []URLValuesOption{WithURLParams("user", "pass")}, "user"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := cred.ToURLValues(tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Get(tc.username) == "" {
+				t.Fatalf("Expected parameter %q in %v", tc.username, v)
+			}
+
+			parsed, err := url.ParseQuery(v.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := FromURLValues(parsed, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(decoded.Pb(), cred.Pb()) {
+				t.Error("Decoded credential doesn't match")
+			}
+			if _, err := cm.Verify(decoded); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestFromURLValuesEscaping tests that percent-encoded padding is accepted
+func TestFromURLValuesEscaping(t *testing.T) {
+	cm := NewCredentialManager([]byte("URL test secret"))
+	cred, err := cm.Create(time.Unix(1700000000, 0), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Escaped once, and twice by an over-eager client
+	once := url.QueryEscape(cred.Base64URLEncodeUsername())
+	twice := url.QueryEscape(url.QueryEscape(password))
+	parsed, err := url.ParseQuery("u=" + once + "&p=" + twice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := FromURLValues(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(decoded); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestFromURLValuesErrors tests FromURLValues error cases
+func TestFromURLValuesErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		query   string
+		missing bool
+	}{
+		{"MissingUsername", "p=AAAA", true},
+		{"MissingPassword", "u=AAAA", true},
+		{"InvalidEscaping", "u=AAAA&p=%25zz", false},
+		{"InvalidBase64", "u=AAAA&p=invalid!", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = FromURLValues(v)
+			if err == nil {
+				t.Fatalf("Expected error for %s, got nil", tc.name)
+			}
+			if errors.Is(err, ErrMissingURLParam) != tc.missing {
+				t.Errorf("Unexpected error %v", err)
+			}
+		})
+	}
+}
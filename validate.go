@@ -0,0 +1,82 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MACLength is the length of the HMAC-SHA256 MACs computed by managers with keys
+const MACLength = 32
+
+var (
+	ErrZeroTimestamp    = errors.New("credential timestamp is zero")
+	ErrMissingMAC       = errors.New("credential has no MAC")
+	ErrInvalidMACLength = errors.New("invalid credential MAC length")
+)
+
+// Validate checks that ac is structurally well formed, without checking its MAC, and returns the first problem found:
+// ErrNilCredential without a credential, ErrInvalidNodeIDLength, ErrZeroTimestamp, ErrUnknownOperatorType for values
+// OperatorType doesn't define, ErrMissingMAC, or ErrInvalidMACLength for MACs which aren't MACLength bytes long.
+// It is cheap, so it can reject garbage before Verify, and its failures are told apart from cryptographic ones.
+// Credentials can only be created with values Validate accepts, but MACers may compute MACs of other lengths,
+// so credentials from managers created with NewCredentialManagerFromMACer may not pass.
+func (ac *AuthenticatedCredential) Validate() error {
+	if ac == nil || ac.Credential == nil {
+		return ErrNilCredential
+	}
+	credential := ac.Credential
+	if err := validateNodeID(credential.NodeId); err != nil {
+		return err
+	}
+	if err := validateTimestampSet(credential.Timestamp); err != nil {
+		return err
+	}
+	if err := validateOperatorTypeDefined(credential.OperatorType); err != nil {
+		return err
+	}
+	return validateMACLength(ac.Mac, MACLength)
+}
+
+// validateMACLength rejects missing MACs with ErrMissingMAC, and ones which aren't length bytes long with
+// ErrInvalidMACLength, so they aren't reported as mismatches. Lengths aren't secret, so this needn't be constant time.
+func validateMACLength(mac []byte, length int) error {
+	if len(mac) == 0 {
+		return ErrMissingMAC
+	}
+	if len(mac) != length {
+		return fmt.Errorf("%w. Expected %d, got %d", ErrInvalidMACLength, length, len(mac))
+	}
+	return nil
+}
+
+// validateDecodedMACs rejects decoded credentials carrying MACs of the wrong length, which can't have been computed
+// by any manager with keys. Credentials without a primary MAC are left for Verify to reject.
+func validateDecodedMACs(ac *AuthenticatedCredential) error {
+	if len(ac.Mac) > 0 {
+		if err := validateMACLength(ac.Mac, MACLength); err != nil {
+			return err
+		}
+	}
+	for _, km := range ac.AdditionalMacs {
+		if err := validateMACLength(km.GetMac(), MACLength); err != nil {
+			return fmt.Errorf("additional MAC: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateTimestampSet rejects the zero timestamp, which is what credentials missing one decode to
+func validateTimestampSet(timestamp int64) error {
+	if timestamp == 0 {
+		return ErrZeroTimestamp
+	}
+	return nil
+}
+
+// validateOperatorTypeDefined rejects operator types which OperatorType doesn't define
+func validateOperatorTypeDefined(ot OperatorType) error {
+	if ot.Descriptor().Values().ByNumber(ot.Number()) == nil {
+		return fmt.Errorf("%w %d", ErrUnknownOperatorType, ot)
+	}
+	return nil
+}
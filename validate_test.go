@@ -0,0 +1,183 @@
+package credentials
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestValidate tests that Validate reports the first structural problem of a credential
+func TestValidate(t *testing.T) {
+	cm := NewCredentialManager([]byte("Validate test secret"))
+	valid, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(change func(*AuthenticatedCredential)) *AuthenticatedCredential {
+		out := &AuthenticatedCredential{
+			Credential: &pb.Credential{NodeId: valid.Credential.NodeId, Timestamp: valid.Credential.Timestamp, OperatorType: valid.Credential.OperatorType},
+			Mac:        valid.Mac,
+		}
+		change(out)
+		return out
+	}
+	testCases := []struct {
+		name     string
+		cred     *AuthenticatedCredential
+		expected error
+	}{
+		{"Nil", nil, ErrNilCredential},
+		{"NilCredential", &AuthenticatedCredential{Mac: valid.Mac}, ErrNilCredential},
+		{"NoNodeID", with(func(ac *AuthenticatedCredential) { ac.Credential.NodeId = nil }), ErrInvalidNodeIDLength},
+		{"ShortNodeID", with(func(ac *AuthenticatedCredential) { ac.Credential.NodeId = make([]byte, 19) }), ErrInvalidNodeIDLength},
+		{"ZeroTimestamp", with(func(ac *AuthenticatedCredential) { ac.Credential.Timestamp = 0 }), ErrZeroTimestamp},
+		{"UnknownOperatorType", with(func(ac *AuthenticatedCredential) { ac.Credential.OperatorType = 7 }), ErrUnknownOperatorType},
+		{"NegativeOperatorType", with(func(ac *AuthenticatedCredential) { ac.Credential.OperatorType = -1 }), ErrUnknownOperatorType},
+		{"NoMAC", with(func(ac *AuthenticatedCredential) { ac.Mac = nil }), ErrMissingMAC},
+		{"ShortMAC", with(func(ac *AuthenticatedCredential) { ac.Mac = ac.Mac[:MACLength-1] }), ErrInvalidMACLength},
+		{"LongMAC", with(func(ac *AuthenticatedCredential) { ac.Mac = make([]byte, MACLength+1) }), ErrInvalidMACLength},
+		// Problems are reported in order
+		{"NoNodeIDOrMAC", with(func(ac *AuthenticatedCredential) { ac.Credential.NodeId, ac.Mac = nil, nil }), ErrInvalidNodeIDLength},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.cred.Validate(); !errors.Is(err, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, err)
+			}
+		})
+	}
+
+	// A forged credential is well formed, and only fails the MAC check
+	forged := with(func(ac *AuthenticatedCredential) { ac.Mac = make([]byte, MACLength) })
+	if err := forged.Validate(); err != nil {
+		t.Error(err)
+	}
+	if _, err := cm.Verify(forged); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+}
+
+// TestCreateValidates tests that Create rejects the inputs Validate would
+func TestCreateValidates(t *testing.T) {
+	cm := NewCredentialManager([]byte("Validate test secret"))
+	if _, err := cm.Create(time.Unix(0, 0), make([]byte, 20), pb.OperatorType_OT_SOLO); !errors.Is(err, ErrZeroTimestamp) {
+		t.Errorf("Expected ErrZeroTimestamp, got %v", err)
+	}
+	if _, err := cm.Create(time.Now(), make([]byte, 20), OperatorType(7)); !errors.Is(err, ErrUnknownOperatorType) {
+		t.Errorf("Expected ErrUnknownOperatorType, got %v", err)
+	}
+	if _, err := cm.CreateMany(time.Unix(0, 0), batchNodeIDs(2), pb.OperatorType_OT_SOLO); !errors.Is(err, ErrZeroTimestamp) {
+		t.Errorf("Expected ErrZeroTimestamp from CreateMany, got %v", err)
+	}
+	if _, err := cm.CreateBatch(time.Now(), batchNodeIDs(2), OperatorType(7)); !errors.Is(err, ErrUnknownOperatorType) {
+		t.Errorf("Expected ErrUnknownOperatorType from CreateBatch, got %v", err)
+	}
+}
+
+// TestVerifyMACLength tests that Verify tells MACs of the wrong length apart from wrong MACs, for every kind of manager
+func TestVerifyMACLength(t *testing.T) {
+	key := []byte("Validate test secret")
+	ring, err := NewKeyRing(KeyEntry{ID: "k1", Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	managers := map[string]*CredentialManager{
+		"Secret":  NewCredentialManager(key),
+		"Cached":  NewCredentialManagerWithOptions(key, nil, WithVerificationCache(8, time.Minute)),
+		"KeyRing": NewCredentialManagerFromKeyRing(ring),
+		"MACer":   NewCredentialManagerFromMACer(&remoteMACer{key: key}),
+	}
+	for name, cm := range managers {
+		t.Run(name, func(t *testing.T) {
+			cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testCases := []struct {
+				name     string
+				mac      []byte
+				expected error
+			}{
+				{"Empty", nil, ErrMissingMAC},
+				{"Short", []byte{1, 2, 3}, ErrInvalidMACLength},
+				{"Hex", []byte(hex.EncodeToString(cred.Mac)), ErrInvalidMACLength},
+				{"Wrong", make([]byte, MACLength), MismatchError},
+			}
+			for _, tc := range testCases {
+				t.Run(tc.name, func(t *testing.T) {
+					bad := proto.Clone(cred.Pb()).(*pb.AuthenticatedCredential)
+					bad.Mac = tc.mac
+					_, err := cm.Verify((*AuthenticatedCredential)(bad))
+					if !errors.Is(err, tc.expected) {
+						t.Errorf("Expected %v, got %v", tc.expected, err)
+					}
+					if tc.expected != MismatchError && errors.Is(err, MismatchError) {
+						t.Errorf("Expected the length error not to be a MismatchError, got %v", err)
+					}
+				})
+			}
+		})
+	}
+}
+
+// TestDecodeMACLength tests that MACs of the wrong length are rejected when decoded, including additional MACs
+func TestDecodeMACLength(t *testing.T) {
+	cm := NewDualSignManager([]byte("Validate test secret"), []byte("Second secret"))
+	cred, err := cm.Create(time.Now(), batchNodeIDs(2)[1], pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutations := map[string]func(*pb.AuthenticatedCredential){
+		"Hex":   func(ac *pb.AuthenticatedCredential) { ac.Mac = []byte(hex.EncodeToString(ac.Mac)) },
+		"Short": func(ac *pb.AuthenticatedCredential) { ac.Mac = ac.Mac[:3] },
+		"Additional": func(ac *pb.AuthenticatedCredential) {
+			ac.AdditionalMacs[0].Mac = ac.AdditionalMacs[0].Mac[:MACLength-1]
+		},
+	}
+	for name, mutate := range mutations {
+		t.Run(name, func(t *testing.T) {
+			bad := (*AuthenticatedCredential)(proto.Clone(cred.Pb()).(*pb.AuthenticatedCredential))
+			mutate(bad.Pb())
+
+			password, err := bad.Base64URLEncodePassword()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := new(AuthenticatedCredential).Base64URLDecode(bad.Base64URLEncodeUsername(), password); !errors.Is(err, ErrInvalidMACLength) {
+				t.Errorf("Expected ErrInvalidMACLength from Base64URLDecode, got %v", err)
+			}
+			if _, err := cm.VerifyFromBasicAuth(bad.Base64URLEncodeUsername(), password); !errors.Is(err, ErrInvalidMACLength) {
+				t.Errorf("Expected ErrInvalidMACLength from VerifyFromBasicAuth, got %v", err)
+			}
+			data, err := json.Marshal(bad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(data, new(AuthenticatedCredential)); !errors.Is(err, ErrInvalidMACLength) {
+				t.Errorf("Expected ErrInvalidMACLength from UnmarshalJSON, got %v", err)
+			}
+		})
+	}
+}
+
+func BenchmarkValidate(b *testing.B) {
+	cm := NewCredentialManager([]byte("Benchmark secret"))
+	cred := benchmarkCredential(b, cm)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cred.Validate(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package credentials
+
+import "time"
+
+// ValidityPolicy sets how long credentials without an embedded expiry are valid for, by operator type.
+// Issuers and verifiers should share one policy, so the expiry shown to users is the one that is enforced.
+type ValidityPolicy struct {
+	// Default applies to operator types missing from ByOperatorType. Zero means they never expire.
+	Default time.Duration
+	// ByOperatorType overrides Default for individual operator types
+	ByOperatorType map[OperatorType]time.Duration
+}
+
+// TTL returns how long credentials of the operator type are valid for, and false if they never expire
+func (p ValidityPolicy) TTL(OperatorType OperatorType) (time.Duration, bool) {
+	ttl, ok := p.ByOperatorType[OperatorType]
+	if !ok {
+		ttl = p.Default
+	}
+	return ttl, ttl > 0
+}
+
+// ExpiresAt returns when a credential of the operator type issued at issued expires, and false if it never does
+func (p ValidityPolicy) ExpiresAt(OperatorType OperatorType, issued time.Time) (time.Time, bool) {
+	ttl, ok := p.TTL(OperatorType)
+	if !ok {
+		return time.Time{}, false
+	}
+	return issued.Add(ttl), true
+}
+
+// WithValidityPolicy makes Verify reject credentials without an embedded expiry once they are older than
+// the policy allows for their operator type. It replaces any max age set with WithMaxAge.
+func WithValidityPolicy(policy ValidityPolicy) Option {
+	return func(c *CredentialManager) {
+		c.validity = policy.clone()
+	}
+}
+
+// clone returns a copy of p which doesn't share its map
+func (p ValidityPolicy) clone() ValidityPolicy {
+	byOperatorType := make(map[OperatorType]time.Duration, len(p.ByOperatorType))
+	for ot, ttl := range p.ByOperatorType {
+		byOperatorType[ot] = ttl
+	}
+	return ValidityPolicy{Default: p.Default, ByOperatorType: byOperatorType}
+}
+
+// ValidityPolicy returns the policy Verify enforces on credentials without an embedded expiry
+func (c *CredentialManager) ValidityPolicy() ValidityPolicy {
+	return c.validity.clone()
+}
@@ -0,0 +1,88 @@
+package credentials
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestValidityPolicy tests that credentials expire after the window for their operator type
+func TestValidityPolicy(t *testing.T) {
+	issued := time.Unix(1700000000, 0)
+	now := issued
+	clock := func() time.Time { return now }
+	policy := ValidityPolicy{
+		Default:        time.Hour,
+		ByOperatorType: map[OperatorType]time.Duration{pb.OperatorType_OT_SOLO: 10 * time.Hour},
+	}
+	cm := NewCredentialManagerWithOptions([]byte("Validity test secret"), nil, WithClock(clock), WithValidityPolicy(policy))
+
+	testCases := []struct {
+		name string
+		ot   OperatorType
+		ttl  time.Duration
+	}{
+		{"Solo", pb.OperatorType_OT_SOLO, 10 * time.Hour},
+		{"Default", pb.OperatorType_OT_ROCKETPOOL, time.Hour},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if ttl, ok := cm.ValidityPolicy().TTL(tc.ot); !ok || ttl != tc.ttl {
+				t.Fatalf("Expected a TTL of %s, got %s", tc.ttl, ttl)
+			}
+			expiresAt, ok := cm.ValidityPolicy().ExpiresAt(tc.ot, issued)
+			if !ok || !expiresAt.Equal(issued.Add(tc.ttl)) {
+				t.Fatalf("Unexpected expiry %s", expiresAt)
+			}
+
+			cred, err := cm.Create(issued, make([]byte, 20), tc.ot)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now = expiresAt
+			claims, err := cm.VerifyClaims(cred)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !claims.ExpiresAt.Equal(expiresAt) {
+				t.Errorf("Expected claims to expire at %s, got %s", expiresAt, claims.ExpiresAt)
+			}
+
+			now = expiresAt.Add(time.Second)
+			_, err = cm.Verify(cred)
+			var expired *ExpiredError
+			if !errors.Is(err, ErrExpired) || !errors.As(err, &expired) {
+				t.Fatalf("Expected an ExpiredError, got %v", err)
+			}
+			if expired.Window != tc.ttl || !expired.ExpiresAt.Equal(expiresAt) {
+				t.Errorf("Expected a window of %s ending at %s, got %s ending at %s", tc.ttl, expiresAt, expired.Window, expired.ExpiresAt)
+			}
+			now = issued
+		})
+	}
+
+	// Operator types this version doesn't define can't be created, but still get the default
+	if ttl, ok := cm.ValidityPolicy().TTL(OperatorType(42)); !ok || ttl != time.Hour {
+		t.Errorf("Expected the default TTL for an unknown operator type, got %s", ttl)
+	}
+
+	// Changing the caller's map doesn't change the manager's policy
+	policy.ByOperatorType[pb.OperatorType_OT_SOLO] = time.Minute
+	if ttl, _ := cm.ValidityPolicy().TTL(pb.OperatorType_OT_SOLO); ttl != 10*time.Hour {
+		t.Errorf("Policy was changed through the caller's map: %s", ttl)
+	}
+
+	// An operator type mapped to zero never expires, even with a default
+	unlimited := NewCredentialManagerWithOptions([]byte("Validity test secret"), nil, WithClock(clock),
+		WithValidityPolicy(ValidityPolicy{Default: time.Hour, ByOperatorType: map[OperatorType]time.Duration{pb.OperatorType_OT_SOLO: 0}}))
+	cred, err := unlimited.Create(issued, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = issued.Add(1000 * time.Hour)
+	if _, err := unlimited.Verify(cred); err != nil {
+		t.Error(err)
+	}
+}
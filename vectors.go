@@ -0,0 +1,133 @@
+package credentials
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// testVectorsJSON pins the exact bytes of every encoding, for implementations of the format in other languages
+//
+//go:embed testvectors.json
+var testVectorsJSON []byte
+
+var ErrTestVectorMismatch = errors.New("credential test vector mismatch")
+
+// TestVectorSet is a fixed key, and credentials authenticated with it in every encoding
+type TestVectorSet struct {
+	// Key is the hex encoded HMAC-SHA256 key
+	Key     string       `json:"key"`
+	Vectors []TestVector `json:"vectors"`
+}
+
+// TestVector is a credential's fields, followed by its expected MAC and encodings. Binary fields are hex encoded.
+type TestVector struct {
+	Name         string `json:"name"`
+	NodeID       string `json:"node_id"`
+	Timestamp    int64  `json:"timestamp"`
+	OperatorType int32  `json:"operator_type"`
+	Nonce        string `json:"nonce,omitempty"`
+	CredentialID string `json:"credential_id,omitempty"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"`
+	Audience     string `json:"audience,omitempty"`
+	Version      uint32 `json:"version,omitempty"`
+
+	// MAC is the hex encoded HMAC-SHA256 of the credential's canonical encoding
+	MAC string `json:"mac"`
+	// Username and Password are the padded base64url encodings, as produced by Base64URLEncodeUsername and Base64URLEncodePassword
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// JSON is produced by MarshalJSON
+	JSON string `json:"json"`
+	// Token is the compact "<username>:<password>" form produced by MarshalText
+	Token string `json:"token"`
+}
+
+// LoadTestVectors returns the embedded test vectors
+func LoadTestVectors() (*TestVectorSet, error) {
+	out := new(TestVectorSet)
+	if err := json.Unmarshal(testVectorsJSON, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VerifyTestVectors checks that this implementation still reproduces every embedded test vector,
+// returning an error wrapping ErrTestVectorMismatch for each one which doesn't match
+func VerifyTestVectors() error {
+	set, err := LoadTestVectors()
+	if err != nil {
+		return err
+	}
+	key, err := hex.DecodeString(set.Key)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, expected := range set.Vectors {
+		actual, err := computeTestVector(key, expected)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("test vector %q: %w", expected.Name, err))
+			continue
+		}
+		if actual != expected {
+			errs = append(errs, fmt.Errorf("%w %q:\nexpected %+v\ngot      %+v", ErrTestVectorMismatch, expected.Name, expected, actual))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// computeTestVector authenticates the credential described by v's fields under key, and fills in its MAC and encodings
+func computeTestVector(key []byte, v TestVector) (TestVector, error) {
+	nodeID, err := hex.DecodeString(strings.TrimPrefix(v.NodeID, "0x"))
+	if err != nil {
+		return v, err
+	}
+	nonce, err := hex.DecodeString(v.Nonce)
+	if err != nil {
+		return v, err
+	}
+	credentialID, err := hex.DecodeString(v.CredentialID)
+	if err != nil {
+		return v, err
+	}
+
+	cred := &AuthenticatedCredential{Credential: &pb.Credential{
+		NodeId:       nodeID,
+		Timestamp:    v.Timestamp,
+		OperatorType: OperatorType(v.OperatorType),
+		Nonce:        nonce,
+		CredentialId: credentialID,
+		ExpiresAt:    v.ExpiresAt,
+		Audience:     v.Audience,
+		Version:      v.Version,
+	}}
+	if err := NewCredentialManager(key).authenticateCredential(cred, nil); err != nil {
+		return v, err
+	}
+
+	v.MAC = hex.EncodeToString(cred.Mac)
+	if v.Username, err = cred.EncodeUsername(); err != nil {
+		return v, err
+	}
+	if v.Password, err = cred.Base64URLEncodePassword(); err != nil {
+		return v, err
+	}
+	data, err := cred.MarshalJSON()
+	if err != nil {
+		return v, err
+	}
+	v.JSON = string(data)
+	token, err := cred.MarshalText()
+	if err != nil {
+		return v, err
+	}
+	v.Token = string(token)
+	return v, nil
+}
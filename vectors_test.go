@@ -0,0 +1,99 @@
+package credentials
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+var updateTestVectors = flag.Bool("update-test-vectors", false, "regenerate testvectors.json")
+
+// testVectorInputs are the credentials in testvectors.json. Changing them requires -update-test-vectors.
+var testVectorInputs = []TestVector{
+	{Name: "Solo", NodeID: "0x0102030405060708090a0b0c0d0e0f1011121314", Timestamp: 1700000000, OperatorType: int32(pb.OperatorType_OT_SOLO),
+		CredentialID: "000102030405060708090a0b0c0d0e0f", Version: CurrentVersion},
+	{Name: "RocketPool", NodeID: "0xffffffffffffffffffffffffffffffffffffffff", Timestamp: 1718000000, OperatorType: int32(pb.OperatorType_OT_ROCKETPOOL),
+		CredentialID: "f0e0d0c0b0a090807060504030201000", Version: CurrentVersion},
+	{Name: "Nonce", NodeID: "0x1234567890123456789012345678901234567890", Timestamp: 1700000000, OperatorType: int32(pb.OperatorType_OT_SOLO),
+		Nonce: "00112233445566778899aabbccddeeff", CredentialID: "0f0e0d0c0b0a09080706050403020100", Version: CurrentVersion},
+	{Name: "ExpiryAndAudience", NodeID: "0x1234567890123456789012345678901234567890", Timestamp: 1700000000, OperatorType: int32(pb.OperatorType_OT_ROCKETPOOL),
+		CredentialID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", ExpiresAt: 1700036000, Audience: "rescue-proxy", Version: CurrentVersion},
+	{Name: "Legacy", NodeID: "0x0000000000000000000000000000000000000000", Timestamp: 1600000000, OperatorType: int32(pb.OperatorType_OT_ROCKETPOOL)},
+}
+
+// TestVectors tests that the implementation reproduces the embedded vectors, and that they verify and decode
+func TestVectors(t *testing.T) {
+	if *updateTestVectors {
+		key := []byte("Rescue node credential test vector key")
+		set := TestVectorSet{Key: hex.EncodeToString(key)}
+		for _, input := range testVectorInputs {
+			v, err := computeTestVector(key, input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set.Vectors = append(set.Vectors, v)
+		}
+		data, err := json.MarshalIndent(set, "", "\t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile("testvectors.json", append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		testVectorsJSON = data
+	}
+
+	if err := VerifyTestVectors(); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := LoadTestVectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Vectors) != len(testVectorInputs) {
+		t.Fatalf("Expected %d vectors, got %d", len(testVectorInputs), len(set.Vectors))
+	}
+	key, err := hex.DecodeString(set.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range set.Vectors {
+		t.Run(v.Name, func(t *testing.T) {
+			clock := func() time.Time { return time.Unix(v.Timestamp, 0) }
+			cm := NewCredentialManagerWithOptions(key, nil, WithClock(clock), WithAudience(v.Audience))
+
+			var fromToken, fromJSON AuthenticatedCredential
+			if err := fromToken.UnmarshalText([]byte(v.Token)); err != nil {
+				t.Fatal(err)
+			}
+			if err := fromJSON.UnmarshalJSON([]byte(v.JSON)); err != nil {
+				t.Fatal(err)
+			}
+			for _, cred := range []*AuthenticatedCredential{&fromToken, &fromJSON} {
+				if hex.EncodeToString(cred.Mac) != v.MAC {
+					t.Error("Decoded MAC differs from the vector's")
+				}
+				if _, err := cm.Verify(cred); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+
+	// Any change to the encoding is caught
+	original := testVectorsJSON
+	defer func() { testVectorsJSON = original }()
+	set.Vectors[0].Password = "x" + set.Vectors[0].Password
+	if testVectorsJSON, err = json.Marshal(set); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyTestVectors(); err == nil {
+		t.Error("Expected a mismatch after altering a vector")
+	}
+}
@@ -0,0 +1,151 @@
+package credentials
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// WithVerificationCache makes Verify, and everything built on it such as VerifyFromBasicAuth, remember up to size
+// credentials whose MACs verified, so that clients presenting the same credential on every request don't pay for
+// the MAC again. Entries are keyed on the exact bytes the MAC covers and the MACs presented, so any change to
+// a credential misses the cache. They last for ttl, or until the credential expires if that is sooner, and the
+// least recently used entry is evicted when the cache is full.
+//
+// Only the MAC check is cached: expiry, revocation, nonces and the manager's other checks still run on every call.
+// Failed verifications are never cached. Keys removed from a KeyRing may still be honored for cached credentials
+// for up to ttl. A size or ttl which isn't positive disables the cache.
+func WithVerificationCache(size int, ttl time.Duration) Option {
+	return func(c *CredentialManager) {
+		if size <= 0 || ttl <= 0 {
+			c.verificationCache = nil
+			return
+		}
+		c.verificationCache = newVerificationCache(size, ttl)
+	}
+}
+
+// verificationCache is an LRU cache of the IDs of the keys that authenticated credentials.
+// It is safe for concurrent use.
+type verificationCache struct {
+	size int
+	ttl  time.Duration
+	// bufs pools the buffers cache keys are built in
+	bufs sync.Pool
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru orders the entries from most to least recently used
+	lru *list.List
+}
+
+type verificationCacheEntry struct {
+	key     string
+	id      *ID
+	expires time.Time
+}
+
+func newVerificationCache(size int, ttl time.Duration) *verificationCache {
+	return &verificationCache{
+		size:    size,
+		ttl:     ttl,
+		bufs:    sync.Pool{New: func() any { return new([]byte) }},
+		entries: make(map[string]*list.Element, size),
+		lru:     list.New(),
+	}
+}
+
+// appendVerificationCacheKey appends the MAC input for the credential and aad, followed by its MACs, to dst.
+// Every part is length prefixed, so distinct credentials can't share a key.
+func appendVerificationCacheKey(dst []byte, authenticatedCredential *AuthenticatedCredential, aad []byte) ([]byte, error) {
+	start := len(dst)
+	dst, err := appendMACInput(dst, authenticatedCredential.Credential, aad)
+	if err != nil {
+		return dst, err
+	}
+	dst = insertLengthPrefix(dst, start)
+	dst = protowire.AppendBytes(dst, authenticatedCredential.Mac)
+	for _, km := range authenticatedCredential.AdditionalMacs {
+		dst = protowire.AppendBytes(dst, km.GetKeyId())
+		dst = protowire.AppendBytes(dst, km.GetMac())
+	}
+	return dst, nil
+}
+
+// get returns the ID cached for key, if it hasn't expired at now
+func (vc *verificationCache) get(key []byte, now time.Time) *ID {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	elem, ok := vc.entries[string(key)]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*verificationCacheEntry)
+	if now.After(entry.expires) {
+		vc.lru.Remove(elem)
+		delete(vc.entries, entry.key)
+		return nil
+	}
+	vc.lru.MoveToFront(elem)
+	return entry.id
+}
+
+// add caches id for key until expires, evicting the least recently used entry if the cache is full
+func (vc *verificationCache) add(key []byte, id *ID, expires time.Time) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	if elem, ok := vc.entries[string(key)]; ok {
+		entry := elem.Value.(*verificationCacheEntry)
+		entry.id, entry.expires = id, expires
+		vc.lru.MoveToFront(elem)
+		return
+	}
+	if vc.lru.Len() >= vc.size {
+		oldest := vc.lru.Back()
+		vc.lru.Remove(oldest)
+		delete(vc.entries, oldest.Value.(*verificationCacheEntry).key)
+	}
+	entry := &verificationCacheEntry{key: string(key), id: id, expires: expires}
+	vc.entries[entry.key] = vc.lru.PushFront(entry)
+}
+
+// len returns the number of cached entries, including expired ones not yet removed
+func (vc *verificationCache) len() int {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	return vc.lru.Len()
+}
+
+// verifyMACCached is verifyMAC, answered from the verification cache when possible
+func (c *CredentialManager) verifyMACCached(ctx context.Context, authenticatedCredential *AuthenticatedCredential, aad []byte) (*ID, error) {
+	vc := c.verificationCache
+	buf := vc.bufs.Get().(*[]byte)
+	defer vc.bufs.Put(buf)
+
+	key, err := appendVerificationCacheKey((*buf)[:0], authenticatedCredential, aad)
+	if err != nil {
+		return nil, err
+	}
+	*buf = key
+
+	now := c.now()
+	if id := vc.get(key, now); id != nil {
+		return id, nil
+	}
+	id, err := c.verifyMACUncached(ctx, authenticatedCredential, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	expires := now.Add(vc.ttl)
+	if expiresAt, ok := c.expiry(authenticatedCredential); ok && expiresAt.Before(expires) {
+		expires = expiresAt
+	}
+	vc.add(key, id, expires)
+	return id, nil
+}
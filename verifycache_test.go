@@ -0,0 +1,193 @@
+package credentials
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// revokeAll is a Revoker that revokes every credential once revoked is set
+type revokeAll struct {
+	revoked bool
+}
+
+func (r *revokeAll) IsRevoked([]byte, time.Time) (bool, error) {
+	return r.revoked, nil
+}
+
+// TestVerificationCache tests that repeated verifications skip the MAC, and that only authentic credentials are cached
+func TestVerificationCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	remote := &remoteMACer{key: []byte("Cache test secret")}
+	revoker := new(revokeAll)
+	cm := NewCredentialManagerFromMACer(remote, WithClock(clock), WithRevoker(revoker), WithVerificationCache(16, time.Minute))
+
+	cred, err := cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// macCalls returns how many MACs verifying cred computes
+	macCalls := func(cred *AuthenticatedCredential) (int64, error) {
+		before := remote.calls.Load()
+		_, err := cm.Verify(cred)
+		return remote.calls.Load() - before, err
+	}
+
+	if calls, err := macCalls(cred); err != nil || calls != 1 {
+		t.Fatalf("Expected the first Verify to compute the MAC, got %d calls, %v", calls, err)
+	}
+	if calls, err := macCalls(cred); err != nil || calls != 0 {
+		t.Fatalf("Expected a cache hit, got %d calls, %v", calls, err)
+	}
+
+	// Basic auth hits the same entries
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := remote.calls.Load()
+	if _, err := cm.VerifyFromBasicAuth(cred.Base64URLEncodeUsername(), password); err != nil {
+		t.Fatal(err)
+	}
+	if remote.calls.Load() != before {
+		t.Error("Expected VerifyFromBasicAuth to hit the cache")
+	}
+
+	// Tampered credentials miss the cache, and failures aren't cached
+	tampered := &AuthenticatedCredential{
+		Credential: &pb.Credential{NodeId: cred.Credential.NodeId, Timestamp: cred.Credential.Timestamp + 1, OperatorType: cred.Credential.OperatorType},
+		Mac:        cred.Mac,
+	}
+	for i := 0; i < 2; i++ {
+		if calls, err := macCalls(tampered); !errors.Is(err, MismatchError) || calls != 1 {
+			t.Fatalf("Expected a MismatchError computing the MAC, got %d calls, %v", calls, err)
+		}
+	}
+	if _, err := cm.VerifyWithAAD(cred, []byte("aad")); !errors.Is(err, ErrAADMismatch) {
+		t.Errorf("Expected ErrAADMismatch, got %v", err)
+	}
+
+	// The manager's other checks still run on hits
+	revoker.revoked = true
+	if calls, err := macCalls(cred); !errors.Is(err, ErrRevoked) || calls != 0 {
+		t.Errorf("Expected ErrRevoked from a cache hit, got %d calls, %v", calls, err)
+	}
+	revoker.revoked = false
+
+	// Entries expire after the ttl
+	now = now.Add(time.Minute + time.Second)
+	if calls, err := macCalls(cred); err != nil || calls != 1 {
+		t.Errorf("Expected the entry to have expired, got %d calls, %v", calls, err)
+	}
+
+	// Or when the credential expires, if that is sooner
+	expiring, err := cm.CreateWithExpiry(now, make([]byte, 20), pb.OperatorType_OT_SOLO, now.Add(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(expiring); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(11 * time.Second)
+	if calls, err := macCalls(expiring); !errors.Is(err, ErrExpired) || calls != 1 {
+		t.Errorf("Expected the entry to expire with the credential, got %d calls, %v", calls, err)
+	}
+}
+
+// TestVerificationCacheEviction tests that the least recently used entry is evicted when the cache is full
+func TestVerificationCacheEviction(t *testing.T) {
+	remote := &remoteMACer{key: []byte("Cache test secret")}
+	cm := NewCredentialManagerFromMACer(remote, WithVerificationCache(2, time.Hour))
+
+	var creds []*AuthenticatedCredential
+	for _, nodeID := range batchNodeIDs(3) {
+		cred, err := cm.Create(time.Now(), nodeID, pb.OperatorType_OT_SOLO)
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds = append(creds, cred)
+	}
+	verify := func(i int) bool {
+		before := remote.calls.Load()
+		if _, err := cm.Verify(creds[i]); err != nil {
+			t.Fatal(err)
+		}
+		return remote.calls.Load() == before
+	}
+
+	verify(0)
+	verify(1)
+	// Using the first entry makes the second the least recently used
+	if !verify(0) {
+		t.Error("Expected a hit for the first credential")
+	}
+	verify(2)
+	if cm.verificationCache.len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cm.verificationCache.len())
+	}
+	if !verify(0) || !verify(2) {
+		t.Error("Expected hits for the most recently used credentials")
+	}
+	if verify(1) {
+		t.Error("Expected the least recently used credential to have been evicted")
+	}
+}
+
+// TestVerificationCacheConcurrent tests that the cache is safe for concurrent use
+func TestVerificationCacheConcurrent(t *testing.T) {
+	cm := NewCredentialManagerWithOptions([]byte("Cache test secret"), nil, WithVerificationCache(4, time.Hour))
+	creds, err := cm.CreateMany(time.Now(), batchNodeIDs(8), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if _, err := cm.Verify(creds[(g+i)%len(creds)]); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := cm.verificationCache.len(); n > 4 {
+		t.Errorf("Expected at most 4 entries, got %d", n)
+	}
+}
+
+// TestVerificationCacheDisabled tests that sizes and ttls which aren't positive disable the cache
+func TestVerificationCacheDisabled(t *testing.T) {
+	for _, opt := range []Option{WithVerificationCache(0, time.Hour), WithVerificationCache(16, 0)} {
+		if cm := NewCredentialManagerWithOptions([]byte("Cache test secret"), nil, opt); cm.verificationCache != nil {
+			t.Error("Expected the cache to be disabled")
+		}
+	}
+}
+
+func BenchmarkVerifyCached(b *testing.B) {
+	cm := NewCredentialManagerWithOptions([]byte("Cache test secret"), nil, WithVerificationCache(1024, time.Hour))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cm.Verify(cred); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
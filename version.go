@@ -0,0 +1,76 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// Credential format versions
+const (
+	// CredentialVersion1 MACs the canonical encoding of the credential, followed by any aad and its length
+	CredentialVersion1 uint32 = 1
+
+	// CurrentVersion is the version Create issues
+	CurrentVersion = CredentialVersion1
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported credential version")
+
+// UnsupportedVersionError is returned for credentials of a version this library doesn't know,
+// instead of a MAC mismatch. It matches ErrUnsupportedVersion.
+type UnsupportedVersionError struct {
+	Version uint32
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("%v %d", ErrUnsupportedVersion, e.Version)
+}
+
+func (e *UnsupportedVersionError) Is(target error) bool {
+	return target == ErrUnsupportedVersion
+}
+
+// versionSpec describes how credentials of one version are authenticated
+type versionSpec struct {
+	// appendMACInput appends the bytes the MAC is computed over to dst
+	appendMACInput func(dst []byte, c *pb.Credential, aad []byte) []byte
+}
+
+// versions maps every supported version to its spec. Future changes to the MAC input are added here,
+// so credentials of every version can be verified side by side.
+var versions = map[uint32]versionSpec{
+	CredentialVersion1: {
+		appendMACInput: func(dst []byte, c *pb.Credential, aad []byte) []byte {
+			return appendAAD(appendCredential(dst, c), aad)
+		},
+	},
+}
+
+// credentialVersion returns the version of c. Credentials issued before versions existed don't carry one, and are version 1.
+func credentialVersion(c *pb.Credential) uint32 {
+	if v := c.GetVersion(); v != 0 {
+		return v
+	}
+	return CredentialVersion1
+}
+
+// lookupVersion returns the spec for c's version, or an *UnsupportedVersionError
+func lookupVersion(c *pb.Credential) (versionSpec, error) {
+	v := credentialVersion(c)
+	spec, ok := versions[v]
+	if !ok {
+		return versionSpec{}, &UnsupportedVersionError{Version: v}
+	}
+	return spec, nil
+}
+
+// appendMACInput appends the bytes c's MAC is computed over, according to its version, to dst
+func appendMACInput(dst []byte, c *pb.Credential, aad []byte) ([]byte, error) {
+	spec, err := lookupVersion(c)
+	if err != nil {
+		return dst, err
+	}
+	return spec.appendMACInput(dst, c, aad), nil
+}
@@ -0,0 +1,129 @@
+package credentials
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// synthesizeFutureCredential returns a credential claiming a version this library doesn't support
+func synthesizeFutureCredential(t *testing.T, cm *CredentialManager) *AuthenticatedCredential {
+	t.Helper()
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred.Credential.Version = 99
+	return cred
+}
+
+func expectUnsupportedVersion(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("Expected ErrUnsupportedVersion, got %v", err)
+	}
+	var versionErr *UnsupportedVersionError
+	if !errors.As(err, &versionErr) || versionErr.Version != 99 {
+		t.Fatalf("Expected the error to report version 99, got %v", err)
+	}
+	if errors.Is(err, MismatchError) {
+		t.Fatal("Expected no MAC mismatch")
+	}
+}
+
+// TestCreateSetsVersion tests that new credentials carry the current version
+func TestCreateSetsVersion(t *testing.T) {
+	cm := NewCredentialManager([]byte("Version test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Credential.Version != CurrentVersion {
+		t.Errorf("Expected version %d, got %d", CurrentVersion, cred.Credential.Version)
+	}
+}
+
+// TestVersionAbsentIsV1 tests that credentials issued before versions existed still verify
+func TestVersionAbsentIsV1(t *testing.T) {
+	cm := NewCredentialManager([]byte("Version test secret"))
+	cred, err := cm.Create(time.Now(), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred.Credential.Version = 0
+	if err := cm.authenticateCredential(cred, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cm.Verify(cred); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestUnsupportedVersionVerify tests that verifying a future credential doesn't report a MAC mismatch
+func TestUnsupportedVersionVerify(t *testing.T) {
+	cm := NewCredentialManager([]byte("Version test secret"))
+	_, err := cm.Verify(synthesizeFutureCredential(t, cm))
+	expectUnsupportedVersion(t, err)
+}
+
+// TestUnsupportedVersionJSON tests that a future credential is rejected when decoded from JSON
+func TestUnsupportedVersionJSON(t *testing.T) {
+	cm := NewCredentialManager([]byte("Version test secret"))
+	data, err := synthesizeFutureCredential(t, cm).MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectUnsupportedVersion(t, new(AuthenticatedCredential).UnmarshalJSON(data))
+}
+
+// TestUnsupportedVersionBasicAuth tests that a future credential is rejected when carried in basic auth
+func TestUnsupportedVersionBasicAuth(t *testing.T) {
+	cm := NewCredentialManager([]byte("Version test secret"))
+	cred := synthesizeFutureCredential(t, cm)
+	username := cred.Base64URLEncodeUsername()
+	password, err := cred.Base64URLEncodePassword()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectUnsupportedVersion(t, new(AuthenticatedCredential).Base64URLDecode(username, password))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth(username, password)
+	_, err = cm.AuthenticateRequest(r)
+	expectUnsupportedVersion(t, err)
+	if status := HTTPStatus(err); status != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+// TestUnsupportedVersionBinary tests that a future credential is rejected when decoded from a stream
+func TestUnsupportedVersionBinary(t *testing.T) {
+	cm := NewCredentialManager([]byte("Version test secret"))
+	cred := synthesizeFutureCredential(t, cm)
+
+	var buf bytes.Buffer
+	if _, err := cred.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	_, err := DecodeAll(&buf)
+	expectUnsupportedVersion(t, err)
+
+	// Unmarshaling the raw message succeeds, but verification still reports the version
+	marshaled, err := proto.Marshal(cred.Pb())
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(AuthenticatedCredential)
+	if err := proto.Unmarshal(marshaled, decoded.Pb()); err != nil {
+		t.Fatal(err)
+	}
+	_, err = cm.Verify(decoded)
+	expectUnsupportedVersion(t, err)
+}
@@ -0,0 +1,24 @@
+package credentials
+
+import "time"
+
+// VerifyWithinWindow is like Verify, but additionally requires the credential to have been issued within the last maxAge,
+// and no more than skew in the future, failing with an *ExpiredError or a *TimestampInFutureError respectively.
+// The bounds are applied on top of the manager's own checks, so they can only narrow what Verify accepts.
+// A negative maxAge or skew disables that bound.
+func (c *CredentialManager) VerifyWithinWindow(authenticatedCredential *AuthenticatedCredential, maxAge, skew time.Duration) error {
+	if _, err := c.Verify(authenticatedCredential); err != nil {
+		return err
+	}
+	if err := c.checkTimestampSkew(authenticatedCredential, skew); err != nil {
+		return newVerificationError(authenticatedCredential, err)
+	}
+	if maxAge < 0 {
+		return nil
+	}
+	expiresAt := time.Unix(authenticatedCredential.Credential.GetTimestamp(), 0).Add(maxAge)
+	if c.now().After(expiresAt) {
+		return newVerificationError(authenticatedCredential, &ExpiredError{ExpiresAt: expiresAt, Window: maxAge})
+	}
+	return nil
+}
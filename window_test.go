@@ -0,0 +1,76 @@
+package credentials
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Rocket-Rescue-Node/credentials/pb"
+)
+
+// TestVerifyWithinWindow tests that credentials outside the window are rejected with the matching typed error
+func TestVerifyWithinWindow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	cm := NewCredentialManagerWithOptions([]byte("Window test secret"), nil, WithClock(clock), WithClockSkew(time.Hour))
+
+	testCases := []struct {
+		name     string
+		age      time.Duration
+		maxAge   time.Duration
+		skew     time.Duration
+		expected error
+	}{
+		{"Now", 0, time.Hour, time.Minute, nil},
+		{"Oldest", time.Hour, time.Hour, time.Minute, nil},
+		{"TooOld", time.Hour + time.Second, time.Hour, time.Minute, ErrExpired},
+		{"SlightlyAhead", -time.Minute, time.Hour, time.Minute, nil},
+		{"TooFarAhead", -2 * time.Minute, time.Hour, time.Minute, ErrTimestampInFuture},
+		{"NoMaxAge", 1000 * time.Hour, -1, time.Minute, nil},
+		{"NoSkew", -30 * time.Minute, time.Hour, -1, nil},
+		// The manager's own checks still apply
+		{"BeyondManagerSkew", -2 * time.Hour, time.Hour, -1, ErrTimestampInFuture},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cred, err := cm.Create(now.Add(-tc.age), make([]byte, 20), pb.OperatorType_OT_SOLO)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = cm.VerifyWithinWindow(cred, tc.maxAge, tc.skew)
+			if tc.expected == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, err)
+			}
+			var verificationErr *VerificationError
+			if !errors.As(err, &verificationErr) {
+				t.Errorf("Expected a *VerificationError, got %T", err)
+			}
+		})
+	}
+
+	// Forged credentials fail the MAC check before the window is considered
+	cred, err := cm.Create(now, make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred.Credential.OperatorType = pb.OperatorType_OT_ROCKETPOOL
+	if err := cm.VerifyWithinWindow(cred, time.Hour, time.Minute); !errors.Is(err, MismatchError) {
+		t.Errorf("Expected MismatchError, got %v", err)
+	}
+
+	// An expired window reports when the credential stopped being acceptable
+	old, err := cm.Create(now.Add(-2*time.Hour), make([]byte, 20), pb.OperatorType_OT_SOLO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expiredErr *ExpiredError
+	if err := cm.VerifyWithinWindow(old, time.Hour, time.Minute); !errors.As(err, &expiredErr) || !expiredErr.ExpiresAt.Equal(now.Add(-time.Hour)) || expiredErr.Window != time.Hour {
+		t.Errorf("Unexpected error %v", err)
+	}
+}